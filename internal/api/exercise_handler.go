@@ -0,0 +1,186 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/middleware"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/store"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/utils"
+)
+
+// createExerciseRequest is the payload for adding a custom exercise.
+type createExerciseRequest struct {
+	Name             string   `json:"name"`
+	Aliases          []string `json:"aliases"`
+	PrimaryMuscles   []string `json:"primary_muscles"`
+	SecondaryMuscles []string `json:"secondary_muscles"`
+	Equipment        string   `json:"equipment"`
+	MovementPattern  string   `json:"movement_pattern"`
+	Unilateral       bool     `json:"unilateral"`
+	MET              *float64 `json:"met"`
+}
+
+// ExerciseHandler handles the exercise catalog endpoints.
+type ExerciseHandler struct {
+	exerciseStore store.ExerciseStore
+	logger        *log.Logger
+}
+
+// NewExerciseHandler creates a new ExerciseHandler with the given ExerciseStore
+func NewExerciseHandler(exerciseStore store.ExerciseStore, logger *log.Logger) *ExerciseHandler {
+	return &ExerciseHandler{
+		exerciseStore: exerciseStore,
+		logger:        logger,
+	}
+}
+
+// HandleSearchExercises handles GET /exercises
+// Query parameters (all optional):
+//   - q: case-insensitive substring of the name or an alias
+//   - muscle: primary or secondary muscle group
+//   - equipment: e.g. barbell, dumbbell, machine
+//   - limit: 1-200 (default 50)
+func (h *ExerciseHandler) HandleSearchExercises(w http.ResponseWriter, r *http.Request) {
+	currentUser := middleware.GetUser(r)
+	query := r.URL.Query()
+
+	limit, err := utils.ReadIntQuery(r, "limit", 50)
+	if err != nil || limit < 1 || limit > 200 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "limit must be between 1 and 200"})
+		return
+	}
+
+	exercises, err := h.exerciseStore.SearchExercises(store.ExerciseFilter{
+		UserID:    currentUser.ID,
+		Query:     query.Get("q"),
+		Muscle:    query.Get("muscle"),
+		Equipment: query.Get("equipment"),
+		Limit:     limit,
+	})
+	if err != nil {
+		h.logger.Printf("ERROR: searchExercises: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"exercises": exercises})
+}
+
+// HandleGetExercise handles GET /exercises/{id}
+func (h *ExerciseHandler) HandleGetExercise(w http.ResponseWriter, r *http.Request) {
+	exerciseID, err := utils.ReadIdParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid exercise id"})
+		return
+	}
+
+	currentUser := middleware.GetUser(r)
+	exercise, err := h.exerciseStore.GetExerciseByID(exerciseID, currentUser.ID)
+	if err != nil {
+		h.logger.Printf("ERROR: getExerciseByID: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	if exercise == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "exercise not found"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"exercise": exercise})
+}
+
+// HandleMatchExercise handles GET /exercises/match?name=...
+// "exercise" is the catalog exercise a free-text name would be linked to (its name or
+// an alias, null if none), "suggestions" are exercises with a similar name, so clients
+// can show "did you mean Bench Press?" before saving a workout.
+func (h *ExerciseHandler) HandleMatchExercise(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if strings.TrimSpace(name) == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "name is required"})
+		return
+	}
+
+	currentUser := middleware.GetUser(r)
+	exercise, suggestions, err := h.exerciseStore.MatchExercise(name, currentUser.ID)
+	if err != nil {
+		h.logger.Printf("ERROR: matchExercise: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	if exercise == nil && len(suggestions) == 0 {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "no matching exercise"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"exercise": exercise, "suggestions": suggestions})
+}
+
+// HandleCreateExercise handles POST /exercises
+// The exercise is private to the logged in user.
+func (h *ExerciseHandler) HandleCreateExercise(w http.ResponseWriter, r *http.Request) {
+	var req createExerciseRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decodingCreateExercise: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "name is required"})
+		return
+	}
+	if len(req.Name) > 255 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "name cannot be greater than 255 chars"})
+		return
+	}
+
+	// The MET value is what calorie estimates for the exercise are based on
+	if req.MET != nil && (*req.MET < 1 || *req.MET > 25) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "met must be between 1 and 25"})
+		return
+	}
+
+	currentUser := middleware.GetUser(r)
+	exercise := &store.Exercise{
+		UserID:           &currentUser.ID,
+		Name:             req.Name,
+		Aliases:          lowerAll(req.Aliases),
+		PrimaryMuscles:   lowerAll(req.PrimaryMuscles),
+		SecondaryMuscles: lowerAll(req.SecondaryMuscles),
+		Equipment:        strings.ToLower(req.Equipment),
+		MovementPattern:  strings.ToLower(req.MovementPattern),
+		Unilateral:       req.Unilateral,
+		MET:              req.MET,
+	}
+
+	err = h.exerciseStore.CreateExercise(exercise)
+	if err != nil {
+		var pgErr interface{ SQLState() string }
+		if errors.As(err, &pgErr) && pgErr.SQLState() == "23505" {
+			utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": "you already have an exercise with this name"})
+			return
+		}
+		h.logger.Printf("ERROR: createExercise: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"exercise": exercise})
+}
+
+// lowerAll lowercases every value so catalog filters match regardless of casing.
+func lowerAll(values []string) []string {
+	lowered := make([]string, 0, len(values))
+	for _, value := range values {
+		lowered = append(lowered, strings.ToLower(strings.TrimSpace(value)))
+	}
+	return lowered
+}
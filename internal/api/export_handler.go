@@ -0,0 +1,70 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/export"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/middleware"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/store"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/utils"
+)
+
+// ExportHandler handles the export of a user's data.
+type ExportHandler struct {
+	workoutStore store.WorkoutStore
+	logger       *log.Logger
+}
+
+// NewExportHandler creates a new ExportHandler with the given WorkoutStore
+func NewExportHandler(workoutStore store.WorkoutStore, logger *log.Logger) *ExportHandler {
+	return &ExportHandler{
+		workoutStore: workoutStore,
+		logger:       logger,
+	}
+}
+
+// HandleExport handles GET /me/export?format=json|ndjson|csv|zip
+//   - json (default): one document with the profile and every workout
+//   - ndjson: the profile, then one workout per line
+//   - csv: one row per set, with its workout and entry repeated on every row
+//   - zip: profile.csv, workouts.csv, groups.csv, entries.csv and sets.csv
+//
+// The response is streamed as workouts are read, instead of going through utils.WriteJSON,
+// which builds the whole body in memory. Once streaming has started an error can't change
+// the status anymore, so it is logged and the download ends truncated.
+func (h *ExportHandler) HandleExport(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = export.FormatJSON
+	}
+	contentType, ok := export.ContentType(format)
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "format must be json, ndjson, csv or zip"})
+		return
+	}
+
+	currentUser := middleware.GetUser(r)
+	src := export.Source{
+		Profile: currentUser,
+		Workouts: func(fn func(*store.Workout) error) error {
+			return h.workoutStore.EachWorkout(currentUser.ID, fn)
+		},
+	}
+
+	// A long history takes longer to send than the server's write timeout allows a regular request
+	controller := http.NewResponseController(w)
+	_ = controller.SetWriteDeadline(time.Time{})
+
+	filename := fmt.Sprintf("workouts-%s-%s.%s", currentUser.Username, time.Now().UTC().Format("2006-01-02"), format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	err := export.Write(w, format, src)
+	if err != nil {
+		h.logger.Printf("ERROR: export for user %d: %v", currentUser.ID, err)
+	}
+}
@@ -0,0 +1,280 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/goals"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/middleware"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/store"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/utils"
+)
+
+// goalRequest is the payload for setting or editing a goal.
+// Sending 0 for exercise_id removes the exercise of a volume goal.
+type goalRequest struct {
+	Type        *string    `json:"type"`
+	Title       *string    `json:"title"`
+	ExerciseID  *int       `json:"exercise_id"`
+	TargetValue *float64   `json:"target_value"`
+	TargetReps  *int       `json:"target_reps"`
+	StartsAt    *time.Time `json:"starts_at"`
+	Deadline    *time.Time `json:"deadline"`
+}
+
+// GoalHandler handles the goals of the current user. Their progress is kept up to date
+// by the stores whenever workouts, tracks or measurements change.
+type GoalHandler struct {
+	goalStore store.GoalStore
+	logger    *log.Logger
+}
+
+// NewGoalHandler creates a new GoalHandler with the given GoalStore
+func NewGoalHandler(goalStore store.GoalStore, logger *log.Logger) *GoalHandler {
+	return &GoalHandler{
+		goalStore: goalStore,
+		logger:    logger,
+	}
+}
+
+// authorizeGoal checks that the goal exists and belongs to the logged in user.
+// It writes the 404/403/500 response itself and returns false when the caller should stop.
+func (h *GoalHandler) authorizeGoal(w http.ResponseWriter, r *http.Request, goalID int64) bool {
+	owner, err := h.goalStore.GetGoalOwner(goalID)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "goal not found"})
+		return false
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: getGoalOwner: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return false
+	}
+
+	if owner != middleware.GetUser(r).ID {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you are not authorized to access this goal"})
+		return false
+	}
+
+	return true
+}
+
+// getAuthorizedGoal loads the goal of the {id} URL parameter and checks that it
+// belongs to the current user. It writes the error response and returns nil otherwise.
+func (h *GoalHandler) getAuthorizedGoal(w http.ResponseWriter, r *http.Request) *store.Goal {
+	goalID, err := utils.ReadIdParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid goal id"})
+		return nil
+	}
+
+	if !h.authorizeGoal(w, r, goalID) {
+		return nil
+	}
+
+	goal, err := h.goalStore.GetGoalByID(goalID)
+	if err != nil {
+		h.logger.Printf("ERROR: getGoalByID: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil
+	}
+	if goal == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "goal not found"})
+		return nil
+	}
+
+	return goal
+}
+
+// HandleListGoals handles GET /me/goals
+// Query parameters (all optional):
+//   - status: active, achieved or missed
+//
+// Every goal comes with its current_value, percent_complete, the projected_completion
+// date the trend so far leads to, and achieved_at once it was met.
+func (h *GoalHandler) HandleListGoals(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "" && status != goals.StatusActive && status != goals.StatusAchieved && status != goals.StatusMissed {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "status must be active, achieved or missed"})
+		return
+	}
+
+	found, err := h.goalStore.ListGoals(middleware.GetUser(r).ID)
+	if err != nil {
+		h.logger.Printf("ERROR: listGoals: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	// The status depends on the current time, so it is filtered here rather than in SQL
+	if status != "" {
+		filtered := []store.Goal{}
+		for _, goal := range found {
+			if goal.Status == status {
+				filtered = append(filtered, goal)
+			}
+		}
+		found = filtered
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"goals": found})
+}
+
+// HandleCreateGoal handles POST /me/goals
+// e.g. {"type": "strength", "exercise_id": 1, "target_value": 100, "deadline": "2027-03-01T00:00:00Z"}
+// or {"type": "frequency", "target_value": 4, "deadline": ...} to train 4 times every calendar week.
+// starts_at defaults to now; workouts logged since then already count.
+func (h *GoalHandler) HandleCreateGoal(w http.ResponseWriter, r *http.Request) {
+	var req goalRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decodingCreateGoal: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	if req.Type == nil || req.TargetValue == nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "type and target_value are required"})
+		return
+	}
+
+	goal := &store.Goal{UserID: middleware.GetUser(r).ID, StartsAt: time.Now()}
+	if !applyGoalRequest(w, goal, &req) {
+		return
+	}
+
+	err = h.goalStore.CreateGoal(goal)
+	if errors.Is(err, store.ErrUnknownExercise) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: createGoal: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to create goal"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"goal": goal})
+}
+
+// HandleGetGoal handles GET /me/goals/{id}
+func (h *GoalHandler) HandleGetGoal(w http.ResponseWriter, r *http.Request) {
+	goal := h.getAuthorizedGoal(w, r)
+	if goal == nil {
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"goal": goal})
+}
+
+// HandleUpdateGoal handles PUT /me/goals/{id}
+// Only the fields sent are changed, and the progress is evaluated again.
+func (h *GoalHandler) HandleUpdateGoal(w http.ResponseWriter, r *http.Request) {
+	goal := h.getAuthorizedGoal(w, r)
+	if goal == nil {
+		return
+	}
+
+	var req goalRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decodingUpdateGoal: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	if !applyGoalRequest(w, goal, &req) {
+		return
+	}
+
+	err = h.goalStore.UpdateGoal(goal)
+	if errors.Is(err, store.ErrUnknownExercise) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "goal not found"})
+		return
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: updateGoal: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"goal": goal})
+}
+
+// HandleDeleteGoal handles DELETE /me/goals/{id}
+func (h *GoalHandler) HandleDeleteGoal(w http.ResponseWriter, r *http.Request) {
+	goalID, err := utils.ReadIdParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid goal id"})
+		return
+	}
+
+	if !h.authorizeGoal(w, r, goalID) {
+		return
+	}
+
+	err = h.goalStore.DeleteGoal(goalID)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "goal not found"})
+		return
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: deleteGoal: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// applyGoalRequest copies the fields sent by the client onto the goal.
+// It writes a 400 response and returns false if the result is invalid.
+func applyGoalRequest(w http.ResponseWriter, goal *store.Goal, req *goalRequest) bool {
+	if req.Type != nil {
+		goal.Type = *req.Type
+	}
+	if req.Title != nil {
+		goal.Title = *req.Title
+	}
+	if req.ExerciseID != nil {
+		goal.ExerciseID = req.ExerciseID
+		if *req.ExerciseID == 0 {
+			goal.ExerciseID = nil
+		}
+	}
+	if req.TargetValue != nil {
+		goal.TargetValue = *req.TargetValue
+	}
+	if req.TargetReps != nil {
+		goal.TargetReps = req.TargetReps
+	}
+	if req.StartsAt != nil {
+		goal.StartsAt = *req.StartsAt
+	}
+	if req.Deadline != nil {
+		goal.Deadline = req.Deadline
+	}
+
+	// Reps only mean something for a lift, where a single rep is the default
+	if goal.Type != goals.TypeStrength {
+		goal.TargetReps = nil
+	} else if goal.TargetReps == nil {
+		one := 1
+		goal.TargetReps = &one
+	}
+
+	if err := goal.Validate(); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return false
+	}
+
+	return true
+}
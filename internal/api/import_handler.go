@@ -0,0 +1,256 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/importer"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/middleware"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/store"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/utils"
+)
+
+// maxImportBytes caps the size of an uploaded file; years of history fit well below it.
+const maxImportBytes = 32 << 20
+
+// importProgressEvery is how many workouts are saved between two progress updates of a running import.
+const importProgressEvery = 25
+
+// maxConcurrentImports is how many imports run at once; the others wait their turn as "pending".
+const maxConcurrentImports = 2
+
+// ImportHandler handles CSV imports of workout history from other apps.
+// Imports run in the background and save every workout through the workoutStore,
+// so exercise linking and personal records work like for a workout posted by hand.
+type ImportHandler struct {
+	importStore  store.ImportStore
+	workoutStore store.WorkoutStore
+	logger       *log.Logger
+	slots        chan struct{}
+}
+
+// NewImportHandler creates a new ImportHandler with the given stores
+func NewImportHandler(importStore store.ImportStore, workoutStore store.WorkoutStore, logger *log.Logger) *ImportHandler {
+	return &ImportHandler{
+		importStore:  importStore,
+		workoutStore: workoutStore,
+		logger:       logger,
+		slots:        make(chan struct{}, maxConcurrentImports),
+	}
+}
+
+// HandleCreateImport handles POST /imports
+// The file is sent either as the "file" field of a multipart form, or as the raw request body.
+// Options come as form fields or query parameters:
+//   - format: strong, hevy, fitnotes or generic; detected from the header row when omitted
+//   - tz: the time zone of the dates in the file (default UTC)
+//   - weight_unit: kg or lb, for files whose weight column doesn't say (default kg)
+//
+// The file is read right away so a file in an unknown format is rejected with a 400;
+// the workouts are then saved in the background and the import is returned with 202 Accepted.
+// Poll GET /imports/{id} for progress and the report.
+func (h *ImportHandler) HandleCreateImport(w http.ResponseWriter, r *http.Request) {
+	data, filename, ok := readUpload(w, r, maxImportBytes, h.logger)
+	if !ok {
+		return
+	}
+
+	var err error
+	opts := importer.Options{
+		Format:     strings.ToLower(r.FormValue("format")),
+		Location:   time.UTC,
+		WeightUnit: strings.ToLower(r.FormValue("weight_unit")),
+	}
+	if tz := r.FormValue("tz"); tz != "" {
+		opts.Location, err = time.LoadLocation(tz)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid tz"})
+			return
+		}
+	}
+
+	result, err := importer.Parse(bytes.NewReader(data), opts)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	imp := &store.Import{
+		UserID:   middleware.GetUser(r).ID,
+		Format:   result.Format,
+		Filename: filename,
+	}
+	err = h.importStore.CreateImport(imp)
+	if err != nil {
+		h.logger.Printf("ERROR: createImport: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to create import"})
+		return
+	}
+
+	// The job works on its own copy, the response must not race with it
+	job := *imp
+	go h.runImport(&job, result)
+
+	utils.WriteJSON(w, http.StatusAccepted, utils.Envelope{"import": imp})
+}
+
+// readUpload reads a file sent either as the "file" field of a multipart form or as the raw
+// request body, along with its name (from the form, or the filename query parameter).
+// On failure the error response is written and ok is false.
+func readUpload(w http.ResponseWriter, r *http.Request, limit int64, logger *log.Logger) (data []byte, filename string, ok bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+	tooLarge := fmt.Sprintf("the file cannot be larger than %dMB", limit>>20)
+
+	var err error
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		err = r.ParseMultipartForm(limit)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			utils.WriteJSON(w, http.StatusRequestEntityTooLarge, utils.Envelope{"error": tooLarge})
+			return nil, "", false
+		}
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid multipart form"})
+			return nil, "", false
+		}
+		file, header, formErr := r.FormFile("file")
+		if formErr != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "the file field is required"})
+			return nil, "", false
+		}
+		defer file.Close()
+		filename = header.Filename
+		data, err = io.ReadAll(file)
+	} else {
+		filename = r.URL.Query().Get("filename")
+		data, err = io.ReadAll(r.Body)
+	}
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		utils.WriteJSON(w, http.StatusRequestEntityTooLarge, utils.Envelope{"error": tooLarge})
+		return nil, "", false
+	}
+	if err != nil {
+		logger.Printf("ERROR: readingUpload: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return nil, "", false
+	}
+	return data, utils.Truncate(filename, 255), true
+}
+
+// runImport saves the workouts of a parsed file, skipping the ones the user already logged.
+// A workout that fails to save is reported and the import carries on with the next one.
+func (h *ImportHandler) runImport(imp *store.Import, result *importer.Result) {
+	h.slots <- struct{}{}
+	defer func() { <-h.slots }()
+
+	startedAt := time.Now()
+	imp.Status = store.ImportStatusRunning
+	imp.StartedAt = &startedAt
+	imp.TotalRows = result.Rows
+	imp.TotalWorkouts = len(result.Workouts)
+	imp.SkippedRows = result.Skipped
+	h.saveProgress(imp)
+
+	workouts := make([]*store.Workout, len(result.Workouts))
+	for i, parsed := range result.Workouts {
+		parsed.Workout.UserID = imp.UserID
+		workouts[i] = parsed.Workout
+	}
+
+	duplicates, err := h.importStore.FindDuplicateWorkouts(imp.UserID, workouts)
+	if err != nil {
+		h.logger.Printf("ERROR: findDuplicateWorkouts: %v", err)
+		h.failImport(imp, "internal server error")
+		return
+	}
+
+	for i, parsed := range result.Workouts {
+		workout := parsed.Workout
+
+		if duplicates[i] != 0 {
+			imp.DuplicateWorkouts++
+			imp.Duplicates = append(imp.Duplicates, store.ImportDuplicate{
+				Line:        parsed.FirstLine,
+				Title:       workout.Title,
+				PerformedAt: workout.PerformedAt,
+				WorkoutID:   duplicates[i],
+			})
+			continue
+		}
+
+		err = workout.ResolveTimes()
+		if err == nil {
+			_, err = h.workoutStore.CreateWorkout(workout)
+			if err != nil {
+				h.logger.Printf("ERROR: createWorkout from import %d: %v", imp.ID, err)
+				err = errors.New("the workout starting on this line could not be saved")
+			}
+		}
+		if err != nil {
+			imp.SkippedRows = append(imp.SkippedRows, store.ImportSkippedRow{Line: parsed.FirstLine, Reason: err.Error()})
+		} else {
+			imp.ImportedWorkouts++
+		}
+
+		if (i+1)%importProgressEvery == 0 {
+			h.saveProgress(imp)
+		}
+	}
+
+	finishedAt := time.Now()
+	imp.Status = store.ImportStatusCompleted
+	imp.FinishedAt = &finishedAt
+	h.saveProgress(imp)
+}
+
+// saveProgress writes the import's state; a failed write only costs the client a stale status.
+func (h *ImportHandler) saveProgress(imp *store.Import) {
+	err := h.importStore.UpdateImport(imp)
+	if err != nil {
+		h.logger.Printf("ERROR: updateImport: %v", err)
+	}
+}
+
+// failImport marks the import as failed with a message for the user.
+func (h *ImportHandler) failImport(imp *store.Import, message string) {
+	finishedAt := time.Now()
+	imp.Status = store.ImportStatusFailed
+	imp.Error = message
+	imp.FinishedAt = &finishedAt
+	h.saveProgress(imp)
+}
+
+// HandleGetImport handles GET /imports/{id}
+// It returns the status, the counters and the report of skipped rows and duplicates.
+func (h *ImportHandler) HandleGetImport(w http.ResponseWriter, r *http.Request) {
+	importID, err := utils.ReadIdParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid import id"})
+		return
+	}
+
+	imp, err := h.importStore.GetImportByID(importID)
+	if err != nil {
+		h.logger.Printf("ERROR: getImportByID: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	if imp == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "import not found"})
+		return
+	}
+
+	if imp.UserID != middleware.GetUser(r).ID {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you are not authorized to access this import"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"import": imp})
+}
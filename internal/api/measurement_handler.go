@@ -0,0 +1,311 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/middleware"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/store"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/trend"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/utils"
+)
+
+// maxTrendWindow caps the moving average window, in days.
+const maxTrendWindow = 365
+
+// measurementRequest is the payload for logging or editing a body measurement.
+// Sending 0 for bodyweight_kg or body_fat_percent clears it; circumferences_cm replaces every site at once.
+type measurementRequest struct {
+	MeasuredAt     *time.Time          `json:"measured_at"`
+	BodyweightKg   *float64            `json:"bodyweight_kg"`
+	BodyFatPercent *float64            `json:"body_fat_percent"`
+	Circumferences *map[string]float64 `json:"circumferences_cm"`
+	Notes          *string             `json:"notes"`
+}
+
+// MeasurementHandler handles the bodyweight, body fat and circumference log of the current user.
+type MeasurementHandler struct {
+	measurementStore store.MeasurementStore
+	logger           *log.Logger
+}
+
+// NewMeasurementHandler creates a new MeasurementHandler with the given MeasurementStore
+func NewMeasurementHandler(measurementStore store.MeasurementStore, logger *log.Logger) *MeasurementHandler {
+	return &MeasurementHandler{
+		measurementStore: measurementStore,
+		logger:           logger,
+	}
+}
+
+// authorizeMeasurement checks that the measurement exists and belongs to the logged in user.
+// It writes the 404/403/500 response itself and returns false when the caller should stop.
+func (h *MeasurementHandler) authorizeMeasurement(w http.ResponseWriter, r *http.Request, measurementID int64) bool {
+	owner, err := h.measurementStore.GetMeasurementOwner(measurementID)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "measurement not found"})
+		return false
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: getMeasurementOwner: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return false
+	}
+
+	if owner != middleware.GetUser(r).ID {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you are not authorized to access this measurement"})
+		return false
+	}
+
+	return true
+}
+
+// HandleListMeasurements handles GET /me/measurements
+// Query parameters (all optional):
+//   - from, to: measured_at range (YYYY-MM-DD or RFC3339), "to" is exclusive
+func (h *MeasurementHandler) HandleListMeasurements(w http.ResponseWriter, r *http.Request) {
+	filter := store.MeasurementFilter{UserID: middleware.GetUser(r).ID}
+
+	var err error
+	filter.From, err = utils.ReadTimeQuery(r, "from")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	filter.To, err = utils.ReadTimeQuery(r, "to")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	measurements, err := h.measurementStore.ListMeasurements(filter)
+	if err != nil {
+		h.logger.Printf("ERROR: listMeasurements: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"measurements": measurements})
+}
+
+// HandleCreateMeasurement handles POST /me/measurements
+// measured_at defaults to now, e.g. {"bodyweight_kg": 81.4} for a morning weigh-in.
+func (h *MeasurementHandler) HandleCreateMeasurement(w http.ResponseWriter, r *http.Request) {
+	var req measurementRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decodingCreateMeasurement: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	measurement := &store.BodyMeasurement{UserID: middleware.GetUser(r).ID}
+	if !applyMeasurementRequest(w, measurement, &req) {
+		return
+	}
+
+	err = h.measurementStore.CreateMeasurement(measurement)
+	if err != nil {
+		h.logger.Printf("ERROR: createMeasurement: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to save measurement"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"measurement": measurement})
+}
+
+// HandleGetMeasurement handles GET /me/measurements/{id}
+func (h *MeasurementHandler) HandleGetMeasurement(w http.ResponseWriter, r *http.Request) {
+	measurement := h.getAuthorizedMeasurement(w, r)
+	if measurement == nil {
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"measurement": measurement})
+}
+
+// HandleUpdateMeasurement handles PUT /me/measurements/{id}
+// Only the fields sent are changed.
+func (h *MeasurementHandler) HandleUpdateMeasurement(w http.ResponseWriter, r *http.Request) {
+	measurement := h.getAuthorizedMeasurement(w, r)
+	if measurement == nil {
+		return
+	}
+
+	var req measurementRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decodingUpdateMeasurement: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	if !applyMeasurementRequest(w, measurement, &req) {
+		return
+	}
+
+	err = h.measurementStore.UpdateMeasurement(measurement)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "measurement not found"})
+		return
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: updateMeasurement: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"measurement": measurement})
+}
+
+// HandleDeleteMeasurement handles DELETE /me/measurements/{id}
+func (h *MeasurementHandler) HandleDeleteMeasurement(w http.ResponseWriter, r *http.Request) {
+	measurementID, err := utils.ReadIdParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid measurement id"})
+		return
+	}
+
+	if !h.authorizeMeasurement(w, r, measurementID) {
+		return
+	}
+
+	err = h.measurementStore.DeleteMeasurement(measurementID)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "measurement not found"})
+		return
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: deleteMeasurement: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleGetTrend handles GET /me/measurements/trend
+// Query parameters:
+//   - metric: bodyweight (default), body_fat or a circumference site such as waist
+//   - window: moving average window in days (default 7)
+//   - from, to: measured_at range (YYYY-MM-DD or RFC3339), "to" is exclusive
+//   - tz: IANA timezone used to group measurements into days, e.g. Europe/Berlin (default UTC)
+//
+// The response has one point per day with a measurement, and the change of the
+// moving average over the range, e.g. -1.2 for 1.2 kg lost.
+func (h *MeasurementHandler) HandleGetTrend(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	metric := query.Get("metric")
+	if metric == "" {
+		metric = store.MetricBodyweight
+	}
+	if !store.ValidMetric(metric) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "metric must be bodyweight, body_fat or one of " + strings.Join(store.MeasurementSites, ", ")})
+		return
+	}
+
+	window, err := utils.ReadIntQuery(r, "window", trend.DefaultWindow)
+	if err != nil || window < 1 || window > maxTrendWindow {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "window must be between 1 and 365 days"})
+		return
+	}
+
+	from, err := utils.ReadTimeQuery(r, "from")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	to, err := utils.ReadTimeQuery(r, "to")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	loc := time.UTC
+	if tz := query.Get("tz"); tz != "" {
+		loc, err = time.LoadLocation(tz)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid tz"})
+			return
+		}
+	}
+
+	samples, err := h.measurementStore.GetMeasurementSeries(middleware.GetUser(r).ID, metric, from, to)
+	if err != nil {
+		h.logger.Printf("ERROR: getMeasurementSeries: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	points := trend.MovingAverage(samples, window, loc)
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"metric": metric,
+		"window": window,
+		"points": points,
+		"change": trend.Change(points),
+	})
+}
+
+// getAuthorizedMeasurement loads the measurement of the {id} URL parameter and checks that it
+// belongs to the current user. It writes the error response and returns nil otherwise.
+func (h *MeasurementHandler) getAuthorizedMeasurement(w http.ResponseWriter, r *http.Request) *store.BodyMeasurement {
+	measurementID, err := utils.ReadIdParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid measurement id"})
+		return nil
+	}
+
+	if !h.authorizeMeasurement(w, r, measurementID) {
+		return nil
+	}
+
+	measurement, err := h.measurementStore.GetMeasurementByID(measurementID)
+	if err != nil {
+		h.logger.Printf("ERROR: getMeasurementByID: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil
+	}
+	if measurement == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "measurement not found"})
+		return nil
+	}
+
+	return measurement
+}
+
+// applyMeasurementRequest copies the fields sent by the client onto the measurement.
+// It writes a 400 response and returns false if the result is invalid.
+func applyMeasurementRequest(w http.ResponseWriter, measurement *store.BodyMeasurement, req *measurementRequest) bool {
+	if req.MeasuredAt != nil {
+		measurement.MeasuredAt = *req.MeasuredAt
+	}
+	if req.BodyweightKg != nil {
+		measurement.BodyweightKg = req.BodyweightKg
+		if *req.BodyweightKg == 0 {
+			measurement.BodyweightKg = nil
+		}
+	}
+	if req.BodyFatPercent != nil {
+		measurement.BodyFatPercent = req.BodyFatPercent
+		if *req.BodyFatPercent == 0 {
+			measurement.BodyFatPercent = nil
+		}
+	}
+	if req.Circumferences != nil {
+		measurement.Circumferences = *req.Circumferences
+	}
+	if req.Notes != nil {
+		measurement.Notes = *req.Notes
+	}
+
+	if err := measurement.Validate(); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,439 @@
+package api
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/blob"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/middleware"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/photo"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/store"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/tokens"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxPhotoBytes caps the size of an uploaded photo; phone cameras write a few MB per picture.
+const maxPhotoBytes = 25 << 20
+
+// photoURLTTL is how long the signed download links of a photo keep working.
+// Clients fetch the photo again to get fresh links.
+const photoURLTTL = time.Hour
+
+// Variants of a photo that can be downloaded.
+const (
+	photoVariantFull      = "full"
+	photoVariantThumbnail = "thumbnail"
+)
+
+// photoRequest is the payload for editing the metadata of a photo.
+// Sending 0 for measurement_id unlinks the measurement.
+type photoRequest struct {
+	TakenAt       *time.Time `json:"taken_at"`
+	Pose          *string    `json:"pose"`
+	MeasurementID *int       `json:"measurement_id"`
+	Notes         *string    `json:"notes"`
+}
+
+// PhotoHandler handles progress photos. The images go to the blob store, their metadata
+// to the PhotoStore, and they are downloaded through links signed by the URLSigner.
+type PhotoHandler struct {
+	photoStore store.PhotoStore
+	blobStore  blob.Store
+	signer     *tokens.URLSigner
+	logger     *log.Logger
+}
+
+// NewPhotoHandler creates a new PhotoHandler with the given stores and URL signer
+func NewPhotoHandler(photoStore store.PhotoStore, blobStore blob.Store, signer *tokens.URLSigner, logger *log.Logger) *PhotoHandler {
+	return &PhotoHandler{
+		photoStore: photoStore,
+		blobStore:  blobStore,
+		signer:     signer,
+		logger:     logger,
+	}
+}
+
+// photoResource is the path of a photo download; it is what the signature covers.
+func photoResource(photoID int, variant string) string {
+	return fmt.Sprintf("/photos/%d/%s", photoID, variant)
+}
+
+// signPhoto fills in the download links of the photo.
+func (h *PhotoHandler) signPhoto(progressPhoto *store.ProgressPhoto) {
+	expiry := time.Now().Add(photoURLTTL).Truncate(time.Second)
+	link := func(variant string) string {
+		resource := photoResource(progressPhoto.ID, variant)
+		return resource + "?expires=" + strconv.FormatInt(expiry.Unix(), 10) + "&signature=" + h.signer.Sign(resource, expiry)
+	}
+
+	progressPhoto.URL = link(photoVariantFull)
+	progressPhoto.ThumbnailURL = link(photoVariantThumbnail)
+	progressPhoto.URLExpiresAt = &expiry
+}
+
+// authorizePhoto checks that the photo exists and belongs to the logged in user.
+// It writes the 404/403/500 response itself and returns false when the caller should stop.
+func (h *PhotoHandler) authorizePhoto(w http.ResponseWriter, r *http.Request, photoID int64) bool {
+	owner, err := h.photoStore.GetPhotoOwner(photoID)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "photo not found"})
+		return false
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: getPhotoOwner: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return false
+	}
+
+	if owner != middleware.GetUser(r).ID {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you are not authorized to access this photo"})
+		return false
+	}
+
+	return true
+}
+
+// getAuthorizedPhoto loads the photo of the {id} URL parameter and checks that it
+// belongs to the current user. It writes the error response and returns nil otherwise.
+func (h *PhotoHandler) getAuthorizedPhoto(w http.ResponseWriter, r *http.Request) *store.ProgressPhoto {
+	photoID, err := utils.ReadIdParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid photo id"})
+		return nil
+	}
+
+	if !h.authorizePhoto(w, r, photoID) {
+		return nil
+	}
+
+	progressPhoto, err := h.photoStore.GetPhotoByID(photoID)
+	if err != nil {
+		h.logger.Printf("ERROR: getPhotoByID: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil
+	}
+	if progressPhoto == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "photo not found"})
+		return nil
+	}
+
+	return progressPhoto
+}
+
+// HandleUploadPhoto handles POST /me/photos
+// The image (JPEG or PNG) is sent as the "file" field of a multipart form, with the optional
+// metadata as form fields, or as the raw body with the metadata in the query string:
+//   - taken_at: RFC3339 timestamp (default: the EXIF capture date, or now)
+//   - pose: front, back, left_side, right_side or other (default)
+//   - measurement_id: a measurement of the user to link the photo to
+//   - notes
+//
+// The photo is stored without its EXIF metadata (location, camera, ...), along with a thumbnail.
+func (h *PhotoHandler) HandleUploadPhoto(w http.ResponseWriter, r *http.Request) {
+	data, _, ok := readUpload(w, r, maxPhotoBytes, h.logger)
+	if !ok {
+		return
+	}
+
+	progressPhoto := &store.ProgressPhoto{
+		UserID: middleware.GetUser(r).ID,
+		Pose:   "other",
+		Notes:  r.FormValue("notes"),
+	}
+
+	if value := r.FormValue("pose"); value != "" {
+		if !store.ValidPose(value) {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "pose must be one of " + strings.Join(store.PhotoPoses, ", ")})
+			return
+		}
+		progressPhoto.Pose = value
+	}
+	if value := r.FormValue("measurement_id"); value != "" {
+		measurementID, err := strconv.Atoi(value)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "measurement_id must be an integer"})
+			return
+		}
+		progressPhoto.MeasurementID = &measurementID
+	}
+	var takenAt *time.Time
+	if value := r.FormValue("taken_at"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "taken_at must be an RFC3339 timestamp"})
+			return
+		}
+		takenAt = &parsed
+	}
+
+	processed, err := photo.Process(data)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	switch {
+	case takenAt != nil:
+		progressPhoto.TakenAt = *takenAt
+	case processed.TakenAt != nil:
+		progressPhoto.TakenAt = *processed.TakenAt
+	default:
+		progressPhoto.TakenAt = time.Now()
+	}
+	progressPhoto.Width = processed.Width
+	progressPhoto.Height = processed.Height
+	progressPhoto.SizeBytes = int64(len(processed.Image))
+
+	// Random keys, so a blob name says nothing about the photo and never collides
+	name := make([]byte, 16)
+	_, err = rand.Read(name)
+	if err != nil {
+		h.logger.Printf("ERROR: generating photo key: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	prefix := fmt.Sprintf("photos/%d/%s", progressPhoto.UserID, hex.EncodeToString(name))
+	progressPhoto.BlobKey = prefix + ".jpg"
+	progressPhoto.ThumbnailKey = prefix + "_thumb.jpg"
+
+	err = h.blobStore.Put(progressPhoto.BlobKey, bytes.NewReader(processed.Image))
+	if err == nil {
+		err = h.blobStore.Put(progressPhoto.ThumbnailKey, bytes.NewReader(processed.Thumbnail))
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: storing photo: %v", err)
+		h.deleteBlobs(progressPhoto)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to save photo"})
+		return
+	}
+
+	err = h.photoStore.CreatePhoto(progressPhoto)
+	if err != nil {
+		h.deleteBlobs(progressPhoto)
+	}
+	if errors.Is(err, store.ErrUnknownMeasurement) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: createPhoto: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to save photo"})
+		return
+	}
+
+	h.signPhoto(progressPhoto)
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"photo": progressPhoto})
+}
+
+// deleteBlobs removes the image and thumbnail of a photo, logging failures:
+// a leftover blob only wastes space, it can't be reached without its metadata.
+func (h *PhotoHandler) deleteBlobs(progressPhoto *store.ProgressPhoto) {
+	for _, key := range []string{progressPhoto.BlobKey, progressPhoto.ThumbnailKey} {
+		if err := h.blobStore.Delete(key); err != nil {
+			h.logger.Printf("ERROR: deleting blob %s: %v", key, err)
+		}
+	}
+}
+
+// HandleListPhotos handles GET /me/photos
+// Query parameters (all optional):
+//   - from, to: taken_at range (YYYY-MM-DD or RFC3339), "to" is exclusive
+//   - pose: only photos of this pose, e.g. to compare every front photo
+func (h *PhotoHandler) HandleListPhotos(w http.ResponseWriter, r *http.Request) {
+	filter := store.PhotoFilter{UserID: middleware.GetUser(r).ID}
+
+	var err error
+	filter.From, err = utils.ReadTimeQuery(r, "from")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	filter.To, err = utils.ReadTimeQuery(r, "to")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	filter.Pose = r.URL.Query().Get("pose")
+	if filter.Pose != "" && !store.ValidPose(filter.Pose) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "pose must be one of " + strings.Join(store.PhotoPoses, ", ")})
+		return
+	}
+
+	photos, err := h.photoStore.ListPhotos(filter)
+	if err != nil {
+		h.logger.Printf("ERROR: listPhotos: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	for i := range photos {
+		h.signPhoto(&photos[i])
+	}
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"photos": photos})
+}
+
+// HandleGetPhoto handles GET /me/photos/{id}
+// It returns the metadata with fresh download links.
+func (h *PhotoHandler) HandleGetPhoto(w http.ResponseWriter, r *http.Request) {
+	progressPhoto := h.getAuthorizedPhoto(w, r)
+	if progressPhoto == nil {
+		return
+	}
+
+	h.signPhoto(progressPhoto)
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"photo": progressPhoto})
+}
+
+// HandleUpdatePhoto handles PUT /me/photos/{id}
+// Only the metadata sent is changed; upload a new photo to change the image.
+func (h *PhotoHandler) HandleUpdatePhoto(w http.ResponseWriter, r *http.Request) {
+	progressPhoto := h.getAuthorizedPhoto(w, r)
+	if progressPhoto == nil {
+		return
+	}
+
+	var req photoRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decodingUpdatePhoto: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	if req.TakenAt != nil {
+		progressPhoto.TakenAt = *req.TakenAt
+	}
+	if req.Pose != nil {
+		if !store.ValidPose(*req.Pose) {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "pose must be one of " + strings.Join(store.PhotoPoses, ", ")})
+			return
+		}
+		progressPhoto.Pose = *req.Pose
+	}
+	if req.MeasurementID != nil {
+		progressPhoto.MeasurementID = req.MeasurementID
+		if *req.MeasurementID == 0 {
+			progressPhoto.MeasurementID = nil
+		}
+	}
+	if req.Notes != nil {
+		progressPhoto.Notes = *req.Notes
+	}
+
+	err = h.photoStore.UpdatePhoto(progressPhoto)
+	if errors.Is(err, store.ErrUnknownMeasurement) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "photo not found"})
+		return
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: updatePhoto: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	h.signPhoto(progressPhoto)
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"photo": progressPhoto})
+}
+
+// HandleDeletePhoto handles DELETE /me/photos/{id}
+// The metadata is deleted first, so the photo is gone for the API even if removing the files fails.
+func (h *PhotoHandler) HandleDeletePhoto(w http.ResponseWriter, r *http.Request) {
+	progressPhoto := h.getAuthorizedPhoto(w, r)
+	if progressPhoto == nil {
+		return
+	}
+
+	err := h.photoStore.DeletePhoto(int64(progressPhoto.ID))
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "photo not found"})
+		return
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: deletePhoto: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	h.deleteBlobs(progressPhoto)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleDownloadPhoto handles GET /photos/{id}/{variant}
+// variant is full or thumbnail. The request carries no bearer token: the expires and
+// signature query parameters of a link handed out by the other endpoints authorize it.
+func (h *PhotoHandler) HandleDownloadPhoto(w http.ResponseWriter, r *http.Request) {
+	photoID, err := utils.ReadIdParam(r)
+	variant := chi.URLParam(r, "variant")
+	if err != nil || (variant != photoVariantFull && variant != photoVariantThumbnail) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "photo not found"})
+		return
+	}
+
+	query := r.URL.Query()
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": tokens.ErrInvalidSignature.Error()})
+		return
+	}
+	expiry := time.Unix(expires, 0)
+	err = h.signer.Verify(photoResource(int(photoID), variant), expiry, query.Get("signature"), time.Now())
+	if err != nil {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	progressPhoto, err := h.photoStore.GetPhotoByID(photoID)
+	if err != nil {
+		h.logger.Printf("ERROR: getPhotoByID: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	if progressPhoto == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "photo not found"})
+		return
+	}
+
+	key := progressPhoto.BlobKey
+	if variant == photoVariantThumbnail {
+		key = progressPhoto.ThumbnailKey
+	}
+	file, err := h.blobStore.Open(key)
+	if err != nil {
+		h.logger.Printf("ERROR: opening blob %s: %v", key, err)
+		status, message := http.StatusInternalServerError, "internal server error"
+		if errors.Is(err, blob.ErrNotFound) {
+			status, message = http.StatusNotFound, "photo not found"
+		}
+		utils.WriteJSON(w, status, utils.Envelope{"error": message})
+		return
+	}
+	defer file.Close()
+
+	// Browsers may keep the image as long as the link is valid, but not share it
+	maxAge := int(time.Until(expiry).Seconds())
+	w.Header().Set("Content-Type", photo.ContentType)
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", maxAge))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	_, err = io.Copy(w, file)
+	if err != nil {
+		h.logger.Printf("ERROR: sending photo: %v", err)
+	}
+}
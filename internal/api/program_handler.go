@@ -0,0 +1,265 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/middleware"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/store"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/utils"
+)
+
+// programRequest is the payload of POST /programs. Days and rules are created together with the program.
+type programRequest struct {
+	Name        string                  `json:"name"`
+	Description string                  `json:"description"`
+	Days        []store.ProgramDay      `json:"days"`
+	Rules       []store.ProgressionRule `json:"rules"`
+}
+
+// ProgramHandler handles programs, enrollments and the next workout prescription.
+type ProgramHandler struct {
+	programStore store.ProgramStore
+	logger       *log.Logger
+}
+
+// NewProgramHandler creates a new ProgramHandler with the given store
+func NewProgramHandler(programStore store.ProgramStore, logger *log.Logger) *ProgramHandler {
+	return &ProgramHandler{
+		programStore: programStore,
+		logger:       logger,
+	}
+}
+
+// validateProgramRequest checks the program before anything is written.
+func (h *ProgramHandler) validateProgramRequest(req *programRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(req.Name) > 255 {
+		return errors.New("name cannot be greater than 255 chars")
+	}
+	if len(req.Days) == 0 {
+		return errors.New("a program needs at least one day")
+	}
+
+	seen := map[[2]int]bool{}
+	for _, day := range req.Days {
+		if day.Week < 1 || day.Day < 1 {
+			return errors.New("week and day start at 1")
+		}
+		if day.TemplateID < 1 {
+			return errors.New("every day needs a template_id")
+		}
+		key := [2]int{day.Week, day.Day}
+		if seen[key] {
+			return fmt.Errorf("week %d day %d is listed twice", day.Week, day.Day)
+		}
+		seen[key] = true
+	}
+
+	for _, rule := range req.Rules {
+		if rule.Increment < 0 {
+			return errors.New("increment cannot be negative")
+		}
+		if rule.DeloadPercent < 0 || rule.DeloadPercent >= 100 {
+			return errors.New("deload_percent must be between 0 and 100")
+		}
+		if rule.DeloadAfterFailures < 0 {
+			return errors.New("deload_after_failures cannot be negative")
+		}
+	}
+
+	return nil
+}
+
+// authorizeProgram checks that the program exists and belongs to the logged in user.
+// It writes the 404/403/500 response itself and returns false when the caller should stop.
+func (h *ProgramHandler) authorizeProgram(w http.ResponseWriter, r *http.Request, programID int64) bool {
+	owner, err := h.programStore.GetProgramOwner(programID)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "program not found"})
+		return false
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: getProgramOwner: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return false
+	}
+
+	if owner != middleware.GetUser(r).ID {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you are not authorized to access this program"})
+		return false
+	}
+
+	return true
+}
+
+// HandleListPrograms handles GET /programs
+func (h *ProgramHandler) HandleListPrograms(w http.ResponseWriter, r *http.Request) {
+	programs, err := h.programStore.ListPrograms(middleware.GetUser(r).ID)
+	if err != nil {
+		h.logger.Printf("ERROR: listPrograms: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"programs": programs})
+}
+
+// HandleCreateProgram handles POST /programs
+func (h *ProgramHandler) HandleCreateProgram(w http.ResponseWriter, r *http.Request) {
+	var req programRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decodingCreateProgram: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	err = h.validateProgramRequest(&req)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	program := &store.Program{
+		UserID:      middleware.GetUser(r).ID,
+		Name:        req.Name,
+		Description: req.Description,
+		Days:        req.Days,
+		Rules:       req.Rules,
+	}
+	if program.Rules == nil {
+		program.Rules = []store.ProgressionRule{}
+	}
+
+	err = h.programStore.CreateProgram(program)
+	if errors.Is(err, store.ErrUnknownTemplate) || errors.Is(err, store.ErrUnknownExercise) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: createProgram: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to create program"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"program": program})
+}
+
+// HandleGetProgram handles GET /programs/{id}
+func (h *ProgramHandler) HandleGetProgram(w http.ResponseWriter, r *http.Request) {
+	programID, err := utils.ReadIdParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid program id"})
+		return
+	}
+
+	if !h.authorizeProgram(w, r, programID) {
+		return
+	}
+
+	program, err := h.programStore.GetProgramByID(programID)
+	if err != nil {
+		h.logger.Printf("ERROR: getProgramByID: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	if program == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "program not found"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"program": program})
+}
+
+// HandleDeleteProgram handles DELETE /programs/{id}
+func (h *ProgramHandler) HandleDeleteProgram(w http.ResponseWriter, r *http.Request) {
+	programID, err := utils.ReadIdParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid program id"})
+		return
+	}
+
+	if !h.authorizeProgram(w, r, programID) {
+		return
+	}
+
+	err = h.programStore.DeleteProgram(programID)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "program not found"})
+		return
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: deleteProgram: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleEnroll handles POST /programs/{id}/enroll
+// The body is optional; started_at defaults to now and can be backdated
+// to count workouts that were already logged for the program.
+func (h *ProgramHandler) HandleEnroll(w http.ResponseWriter, r *http.Request) {
+	programID, err := utils.ReadIdParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid program id"})
+		return
+	}
+
+	if !h.authorizeProgram(w, r, programID) {
+		return
+	}
+
+	var req struct {
+		StartedAt *time.Time `json:"started_at"`
+	}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && err != io.EOF {
+		h.logger.Printf("ERROR: decodingEnroll: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	startedAt := time.Now()
+	if req.StartedAt != nil {
+		startedAt = *req.StartedAt
+	}
+
+	enrollment, err := h.programStore.Enroll(middleware.GetUser(r).ID, programID, startedAt)
+	if err != nil {
+		h.logger.Printf("ERROR: enroll: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"enrollment": enrollment})
+}
+
+// HandleGetNextWorkout handles GET /me/next-workout
+// Clients log the session with the returned day id as program_day_id, which moves the program forward.
+func (h *ProgramHandler) HandleGetNextWorkout(w http.ResponseWriter, r *http.Request) {
+	next, err := h.programStore.GetNextWorkout(middleware.GetUser(r).ID)
+	if err != nil {
+		h.logger.Printf("ERROR: getNextWorkout: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	if next == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "you are not enrolled in a program"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"next_workout": next})
+}
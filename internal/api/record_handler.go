@@ -0,0 +1,141 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/middleware"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/store"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/utils"
+)
+
+// RecordHandler handles the personal records endpoints.
+// The latest bodyweight comes from the MeasurementStore, to express lifts as bodyweight multiples.
+type RecordHandler struct {
+	recordStore      store.RecordStore
+	measurementStore store.MeasurementStore
+	logger           *log.Logger
+}
+
+// NewRecordHandler creates a new RecordHandler with the given stores
+func NewRecordHandler(recordStore store.RecordStore, measurementStore store.MeasurementStore, logger *log.Logger) *RecordHandler {
+	return &RecordHandler{
+		recordStore:      recordStore,
+		measurementStore: measurementStore,
+		logger:           logger,
+	}
+}
+
+// HandleGetUserRecords handles GET /users/{id}/records
+// Query parameters (all optional):
+//   - exercise_id: only records of this catalog exercise
+//   - type: heaviest_weight, max_reps_at_weight, best_e1rm, longest_duration or max_session_volume
+//   - history: "true" to include records that were later beaten
+//
+// Heaviest weight and best e1RM records include their bodyweight_multiple once the user logged a bodyweight.
+func (h *RecordHandler) HandleGetUserRecords(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ReadIdParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid user id"})
+		return
+	}
+
+	// Records are private for now, users can only see their own
+	currentUser := middleware.GetUser(r)
+	if int64(currentUser.ID) != userID {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you are not authorized to view these records"})
+		return
+	}
+
+	query := r.URL.Query()
+	filter := store.RecordFilter{
+		UserID:     currentUser.ID,
+		RecordType: query.Get("type"),
+		History:    query.Get("history") == "true",
+	}
+
+	if query.Get("exercise_id") != "" {
+		exerciseID, err := utils.ReadIntQuery(r, "exercise_id", 0)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+			return
+		}
+		filter.ExerciseID = &exerciseID
+	}
+
+	found, err := h.recordStore.GetRecordsForUser(filter)
+	if err != nil {
+		h.logger.Printf("ERROR: getRecordsForUser: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	bodyweight, err := h.measurementStore.GetLatestBodyweight(currentUser.ID)
+	if err != nil {
+		h.logger.Printf("ERROR: getLatestBodyweight: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	store.ApplyBodyweight(found, bodyweight)
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"records": found})
+}
+
+// HandleE1RMHistory handles GET /exercises/{id}/e1rm-history
+// Query parameters (all optional):
+//   - from, to: performed_at range (YYYY-MM-DD or RFC3339), "to" is exclusive
+//   - tz: IANA timezone used to group sets into days, e.g. Europe/Berlin (default UTC)
+//
+// The user's preferred e1RM formula is used. Each point includes its bodyweight_multiple
+// against the latest logged bodyweight, when there is one.
+func (h *RecordHandler) HandleE1RMHistory(w http.ResponseWriter, r *http.Request) {
+	exerciseID, err := utils.ReadIdParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid exercise id"})
+		return
+	}
+
+	currentUser := middleware.GetUser(r)
+	filter := store.E1RMHistoryFilter{
+		UserID:     currentUser.ID,
+		ExerciseID: int(exerciseID),
+		Formula:    currentUser.PreferredE1RMFormula(),
+	}
+
+	filter.From, err = utils.ReadTimeQuery(r, "from")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	filter.To, err = utils.ReadTimeQuery(r, "to")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	if tz := r.URL.Query().Get("tz"); tz != "" {
+		filter.Location, err = time.LoadLocation(tz)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid tz"})
+			return
+		}
+	}
+
+	points, err := h.recordStore.GetE1RMHistory(filter)
+	if err != nil {
+		h.logger.Printf("ERROR: getE1RMHistory: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	bodyweight, err := h.measurementStore.GetLatestBodyweight(currentUser.ID)
+	if err != nil {
+		h.logger.Printf("ERROR: getLatestBodyweight: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	store.ApplyBodyweightToHistory(points, bodyweight)
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"formula": filter.Formula, "bodyweight_kg": bodyweight, "history": points})
+}
@@ -0,0 +1,390 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/ical"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/middleware"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/store"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/tokens"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+// calendarTokenTTL is how long a calendar feed URL keeps working.
+// Calendar apps poll the URL forever, so it is long lived; creating a new one revokes the old URL.
+const calendarTokenTTL = 5 * 365 * 24 * time.Hour
+
+// calendarFeedPast is how far back the iCalendar feed goes.
+const calendarFeedPast = 90 * 24 * time.Hour
+
+// scheduleRequest is the payload for planning or editing a scheduled workout.
+type scheduleRequest struct {
+	TemplateID      *int       `json:"template_id"`
+	ProgramDayID    *int       `json:"program_day_id"`
+	Title           *string    `json:"title"`
+	Notes           *string    `json:"notes"`
+	PlannedAt       *time.Time `json:"planned_at"`
+	DurationMinutes *int       `json:"duration_minutes"`
+	Status          *string    `json:"status"`
+}
+
+// ScheduleHandler handles the workout calendar and its iCalendar feed.
+// The feed is authenticated by a calendar token in the URL, which is why it needs the user and token stores.
+type ScheduleHandler struct {
+	scheduleStore store.ScheduleStore
+	userStore     store.UserStore
+	tokenStore    store.TokenStore
+	logger        *log.Logger
+}
+
+// NewScheduleHandler creates a new ScheduleHandler with the given stores
+func NewScheduleHandler(scheduleStore store.ScheduleStore, userStore store.UserStore, tokenStore store.TokenStore, logger *log.Logger) *ScheduleHandler {
+	return &ScheduleHandler{
+		scheduleStore: scheduleStore,
+		userStore:     userStore,
+		tokenStore:    tokenStore,
+		logger:        logger,
+	}
+}
+
+// validStatus reports whether the status is one a scheduled workout can have.
+func validStatus(status string) bool {
+	switch status {
+	case store.ScheduleStatusPlanned, store.ScheduleStatusCompleted, store.ScheduleStatusSkipped:
+		return true
+	}
+	return false
+}
+
+// authorizeSchedule checks that the scheduled workout exists and belongs to the logged in user.
+// It writes the 404/403/500 response itself and returns false when the caller should stop.
+func (h *ScheduleHandler) authorizeSchedule(w http.ResponseWriter, r *http.Request, scheduleID int64) bool {
+	owner, err := h.scheduleStore.GetScheduledWorkoutOwner(scheduleID)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "scheduled workout not found"})
+		return false
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: getScheduledWorkoutOwner: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return false
+	}
+
+	if owner != middleware.GetUser(r).ID {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you are not authorized to access this scheduled workout"})
+		return false
+	}
+
+	return true
+}
+
+// HandleListSchedule handles GET /schedule
+// Query parameters (all optional):
+//   - from, to: planned_at range (YYYY-MM-DD or RFC3339), "to" is exclusive
+//   - status: planned, completed or skipped
+func (h *ScheduleHandler) HandleListSchedule(w http.ResponseWriter, r *http.Request) {
+	filter := store.ScheduleFilter{UserID: middleware.GetUser(r).ID}
+
+	var err error
+	filter.From, err = utils.ReadTimeQuery(r, "from")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	filter.To, err = utils.ReadTimeQuery(r, "to")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	filter.Status = r.URL.Query().Get("status")
+	if filter.Status != "" && !validStatus(filter.Status) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "status must be planned, completed or skipped"})
+		return
+	}
+
+	scheduled, err := h.scheduleStore.ListScheduledWorkouts(filter)
+	if err != nil {
+		h.logger.Printf("ERROR: listScheduledWorkouts: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"scheduled_workouts": scheduled})
+}
+
+// HandleCreateSchedule handles POST /schedule
+func (h *ScheduleHandler) HandleCreateSchedule(w http.ResponseWriter, r *http.Request) {
+	var req scheduleRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decodingCreateSchedule: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	if req.PlannedAt == nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "planned_at is required"})
+		return
+	}
+
+	scheduled := &store.ScheduledWorkout{UserID: middleware.GetUser(r).ID}
+	if !h.applyScheduleRequest(w, scheduled, &req) {
+		return
+	}
+
+	err = h.scheduleStore.CreateScheduledWorkout(scheduled)
+	if errors.Is(err, store.ErrUnknownTemplate) || errors.Is(err, store.ErrUnknownProgramDay) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: createScheduledWorkout: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to schedule workout"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"scheduled_workout": scheduled})
+}
+
+// HandleGetSchedule handles GET /schedule/{id}
+func (h *ScheduleHandler) HandleGetSchedule(w http.ResponseWriter, r *http.Request) {
+	scheduleID, err := utils.ReadIdParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid scheduled workout id"})
+		return
+	}
+
+	if !h.authorizeSchedule(w, r, scheduleID) {
+		return
+	}
+
+	scheduled, err := h.scheduleStore.GetScheduledWorkoutByID(scheduleID)
+	if err != nil {
+		h.logger.Printf("ERROR: getScheduledWorkoutByID: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	if scheduled == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "scheduled workout not found"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"scheduled_workout": scheduled})
+}
+
+// HandleUpdateSchedule handles PUT /schedule/{id}
+// Only the fields sent are changed, e.g. {"status": "skipped"} or a new planned_at to move a session.
+func (h *ScheduleHandler) HandleUpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	scheduleID, err := utils.ReadIdParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid scheduled workout id"})
+		return
+	}
+
+	if !h.authorizeSchedule(w, r, scheduleID) {
+		return
+	}
+
+	scheduled, err := h.scheduleStore.GetScheduledWorkoutByID(scheduleID)
+	if err != nil {
+		h.logger.Printf("ERROR: getScheduledWorkoutByID: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	if scheduled == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "scheduled workout not found"})
+		return
+	}
+
+	var req scheduleRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decodingUpdateSchedule: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	if !h.applyScheduleRequest(w, scheduled, &req) {
+		return
+	}
+
+	err = h.scheduleStore.UpdateScheduledWorkout(scheduled)
+	if errors.Is(err, store.ErrUnknownTemplate) || errors.Is(err, store.ErrUnknownProgramDay) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "scheduled workout not found"})
+		return
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: updateScheduledWorkout: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"scheduled_workout": scheduled})
+}
+
+// HandleDeleteSchedule handles DELETE /schedule/{id}
+func (h *ScheduleHandler) HandleDeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	scheduleID, err := utils.ReadIdParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid scheduled workout id"})
+		return
+	}
+
+	if !h.authorizeSchedule(w, r, scheduleID) {
+		return
+	}
+
+	err = h.scheduleStore.DeleteScheduledWorkout(scheduleID)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "scheduled workout not found"})
+		return
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: deleteScheduledWorkout: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// applyScheduleRequest copies the fields sent by the client onto the scheduled workout.
+// It writes a 400 response and returns false if a value is invalid.
+func (h *ScheduleHandler) applyScheduleRequest(w http.ResponseWriter, scheduled *store.ScheduledWorkout, req *scheduleRequest) bool {
+	if req.TemplateID != nil {
+		scheduled.TemplateID = req.TemplateID
+		if *req.TemplateID == 0 {
+			scheduled.TemplateID = nil
+		}
+	}
+	if req.ProgramDayID != nil {
+		scheduled.ProgramDayID = req.ProgramDayID
+		if *req.ProgramDayID == 0 {
+			scheduled.ProgramDayID = nil
+		}
+	}
+	if req.Title != nil {
+		scheduled.Title = *req.Title
+	}
+	if req.Notes != nil {
+		scheduled.Notes = *req.Notes
+	}
+	if req.PlannedAt != nil {
+		scheduled.PlannedAt = *req.PlannedAt
+	}
+	if req.DurationMinutes != nil {
+		if *req.DurationMinutes < 1 {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "duration_minutes must be positive"})
+			return false
+		}
+		scheduled.DurationMinutes = *req.DurationMinutes
+	}
+	if req.Status != nil {
+		if !validStatus(*req.Status) {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "status must be planned, completed or skipped"})
+			return false
+		}
+		scheduled.Status = *req.Status
+	}
+
+	if strings.TrimSpace(scheduled.Title) == "" && scheduled.TemplateID == nil && scheduled.ProgramDayID == nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "title is required without a template or program day"})
+		return false
+	}
+	if len(scheduled.Title) > 255 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "title cannot be greater than 255 chars"})
+		return false
+	}
+
+	return true
+}
+
+// HandleCreateCalendarToken handles POST /tokens/calendar
+// It returns a new secret feed URL. Older calendar URLs of the user stop working.
+func (h *ScheduleHandler) HandleCreateCalendarToken(w http.ResponseWriter, r *http.Request) {
+	currentUser := middleware.GetUser(r)
+
+	err := h.tokenStore.DeleteAllTokensForUser(currentUser.ID, tokens.ScopeCalendar)
+	if err != nil {
+		h.logger.Printf("ERROR: deleteCalendarTokens: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	token, err := h.tokenStore.CreateNewToken(currentUser.ID, calendarTokenTTL, tokens.ScopeCalendar)
+	if err != nil {
+		h.logger.Printf("ERROR: creating calendar token: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{
+		"calendar_token": token,
+		"feed_path":      fmt.Sprintf("/calendar/%s.ics", token.Plaintext),
+	})
+}
+
+// HandleCalendarFeed handles GET /calendar/{token}.ics
+// The feed is public to whoever knows the token, so an unknown token is a plain 404.
+// It lists the last 90 days and everything planned after that; skipped sessions are marked cancelled.
+func (h *ScheduleHandler) HandleCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	user, err := h.userStore.GetUserToken(tokens.ScopeCalendar, chi.URLParam(r, "token"))
+	if err != nil {
+		h.logger.Printf("ERROR: getUserToken: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	from := time.Now().Add(-calendarFeedPast)
+	scheduled, err := h.scheduleStore.ListScheduledWorkouts(store.ScheduleFilter{UserID: user.ID, From: &from})
+	if err != nil {
+		h.logger.Printf("ERROR: listScheduledWorkouts: %v", err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	calendar := &ical.Calendar{
+		ProductID: "-//go-workout-tracker//Workout Schedule//EN",
+		Name:      user.Username + "'s workouts",
+		Events:    make([]ical.Event, 0, len(scheduled)),
+	}
+	for _, session := range scheduled {
+		status := ical.StatusConfirmed
+		if session.Status == store.ScheduleStatusSkipped {
+			status = ical.StatusCancelled
+		}
+		calendar.Events = append(calendar.Events, ical.Event{
+			UID:         fmt.Sprintf("scheduled-workout-%d@go-workout-tracker", session.ID),
+			Summary:     session.Title,
+			Description: session.Notes,
+			Start:       session.PlannedAt,
+			End:         session.PlannedAt.Add(time.Duration(session.DurationMinutes) * time.Minute),
+			Status:      status,
+			Updated:     session.UpdatedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="workouts.ics"`)
+	err = calendar.Write(w)
+	if err != nil {
+		h.logger.Printf("ERROR: writing calendar feed: %v", err)
+	}
+}
@@ -0,0 +1,522 @@
+package api
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/live"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/middleware"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/store"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/timer"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/tokens"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/utils"
+)
+
+// sseKeepAlive is how often a comment is sent on idle event streams,
+// so proxies and phones don't drop the connection between sets.
+const sseKeepAlive = 15 * time.Second
+
+// logSetRequest is the payload of POST /sessions/{id}/sets.
+// RestSeconds is the rest prescribed after this set; it is recorded on the set
+// and starts a rest timer on every connected screen.
+type logSetRequest struct {
+	ExerciseID      *int     `json:"exercise_id"`
+	ExerciseName    string   `json:"exercise_name"`
+	Reps            *int     `json:"reps"`
+	DurationSeconds *int     `json:"duration_seconds"`
+	Weight          *float64 `json:"weight"`
+	RPE             *float64 `json:"rpe"`
+	SetType         string   `json:"set_type"`
+	Completed       *bool    `json:"completed"`
+	RestSeconds     *int     `json:"rest_seconds"`
+}
+
+// startTimerRequest is the payload of POST /sessions/{id}/timer.
+// Rounds, WorkSeconds and RestSeconds override the protocol's defaults;
+// Phases lists the intervals of a custom timer one by one.
+type startTimerRequest struct {
+	Protocol     string        `json:"protocol"`
+	ExerciseID   *int          `json:"exercise_id"`
+	ExerciseName string        `json:"exercise_name"`
+	Rounds       int           `json:"rounds"`
+	WorkSeconds  int           `json:"work_seconds"`
+	RestSeconds  int           `json:"rest_seconds"`
+	Phases       []timer.Phase `json:"phases"`
+}
+
+// SessionHandler handles live workout sessions and their event streams.
+// Finished sessions are saved through the workoutStore like any other workout.
+// Interval timers run on the server through timers, so every screen follows the same clock.
+type SessionHandler struct {
+	sessionStore store.SessionStore
+	workoutStore store.WorkoutStore
+	recordStore  store.RecordStore
+	hub          *live.Hub
+	timers       *timer.Service
+	logger       *log.Logger
+}
+
+// NewSessionHandler creates a new SessionHandler with the given stores, event hub and timer service
+func NewSessionHandler(sessionStore store.SessionStore, workoutStore store.WorkoutStore, recordStore store.RecordStore, hub *live.Hub, timers *timer.Service, logger *log.Logger) *SessionHandler {
+	return &SessionHandler{
+		sessionStore: sessionStore,
+		workoutStore: workoutStore,
+		recordStore:  recordStore,
+		hub:          hub,
+		timers:       timers,
+		logger:       logger,
+	}
+}
+
+// loadOwnSession reads the {id} parameter and loads the session of the logged in user.
+// It writes the 400/404/403/500 response itself and returns nil when the caller should stop.
+func (h *SessionHandler) loadOwnSession(w http.ResponseWriter, r *http.Request) *store.LiveSession {
+	sessionID, err := utils.ReadIdParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid session id"})
+		return nil
+	}
+
+	session, err := h.sessionStore.GetSessionByID(sessionID)
+	if err != nil {
+		h.logger.Printf("ERROR: getSessionByID: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil
+	}
+	if session == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "session not found"})
+		return nil
+	}
+
+	if session.UserID != middleware.GetUser(r).ID {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you are not authorized to access this session"})
+		return nil
+	}
+
+	return session
+}
+
+// HandleStartSession handles POST /sessions
+// The response carries a watch_token, shown only once, that lets another screen
+// (e.g. a coach's tablet) follow the session's events without the athlete's credentials.
+func (h *SessionHandler) HandleStartSession(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Title     string     `json:"title"`
+		StartedAt *time.Time `json:"started_at"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && err != io.EOF {
+		h.logger.Printf("ERROR: decodingStartSession: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	currentUser := middleware.GetUser(r)
+	session := &store.LiveSession{
+		UserID:    currentUser.ID,
+		Title:     strings.TrimSpace(req.Title),
+		StartedAt: time.Now(),
+	}
+	if session.Title == "" {
+		session.Title = "Workout"
+	}
+	if len(session.Title) > 255 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "title cannot be greater than 255 chars"})
+		return
+	}
+	if req.StartedAt != nil {
+		session.StartedAt = *req.StartedAt
+	}
+
+	// Only the hash is stored; the token never expires on its own, it dies with the session
+	watchToken, err := tokens.GenerateToken(currentUser.ID, 0, "")
+	if err != nil {
+		h.logger.Printf("ERROR: generating watch token: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	session.WatchTokenHash = watchToken.Hash
+
+	err = h.sessionStore.CreateSession(session)
+	if err != nil {
+		h.logger.Printf("ERROR: createSession: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to start session"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"session": session, "watch_token": watchToken.Plaintext})
+}
+
+// HandleGetSession handles GET /sessions/{id}
+func (h *SessionHandler) HandleGetSession(w http.ResponseWriter, r *http.Request) {
+	session := h.loadOwnSession(w, r)
+	if session == nil {
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"session": session})
+}
+
+// HandleLogSet handles POST /sessions/{id}/sets
+// The set is saved right away and pushed to every connected screen.
+func (h *SessionHandler) HandleLogSet(w http.ResponseWriter, r *http.Request) {
+	session := h.loadOwnSession(w, r)
+	if session == nil {
+		return
+	}
+
+	var req logSetRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decodingLogSet: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	if req.ExerciseID == nil && strings.TrimSpace(req.ExerciseName) == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "exercise_id or exercise_name is required"})
+		return
+	}
+	if (req.Reps == nil) == (req.DurationSeconds == nil) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "a set needs either reps or duration_seconds"})
+		return
+	}
+	switch req.SetType {
+	case "", store.SetTypeWarmup, store.SetTypeWorking, store.SetTypeDrop, store.SetTypeFailure:
+	default:
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "set_type must be warmup, working, drop or failure"})
+		return
+	}
+	if req.RestSeconds != nil && *req.RestSeconds < 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "rest_seconds cannot be negative"})
+		return
+	}
+
+	set := &store.SessionSet{
+		ExerciseID:            req.ExerciseID,
+		ExerciseName:          req.ExerciseName,
+		Reps:                  req.Reps,
+		DurationSeconds:       req.DurationSeconds,
+		Weight:                req.Weight,
+		RPE:                   req.RPE,
+		SetType:               req.SetType,
+		Completed:             req.Completed == nil || *req.Completed,
+		PrescribedRestSeconds: req.RestSeconds,
+	}
+
+	err = h.sessionStore.AddSessionSet(int64(session.ID), session.UserID, set)
+	if errors.Is(err, store.ErrSessionNotActive) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, store.ErrUnknownExercise) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: addSessionSet: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	h.hub.Publish(session.ID, live.Event{Type: live.EventSetLogged, Data: set})
+	if req.RestSeconds != nil && *req.RestSeconds > 0 {
+		h.hub.Publish(session.ID, live.Event{Type: live.EventRestTimerStarted, Data: utils.Envelope{
+			"set_id":  set.ID,
+			"seconds": *req.RestSeconds,
+			"ends_at": set.LoggedAt.Add(time.Duration(*req.RestSeconds) * time.Second),
+		}})
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"set": set})
+}
+
+// HandleFinishSession handles POST /sessions/{id}/finish
+// The session becomes a workout through WorkoutStore.CreateWorkout, so exercise linking,
+// records and schedule linking all happen exactly like for a workout posted in one go.
+func (h *SessionHandler) HandleFinishSession(w http.ResponseWriter, r *http.Request) {
+	session := h.loadOwnSession(w, r)
+	if session == nil {
+		return
+	}
+	sessionID := int64(session.ID)
+
+	// A running timer is cut short and saved, so its timings make it into the workout
+	h.timers.Stop(session.ID)
+
+	// Claim the session first so a double tap on "finish" can't save it twice
+	err := h.sessionStore.SetSessionStatus(sessionID, store.SessionStatusActive, store.SessionStatusFinishing)
+	if errors.Is(err, store.ErrSessionNotActive) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: claimSession: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	// Reload after claiming, a set may have been logged in between
+	session, err = h.sessionStore.GetSessionByID(sessionID)
+	if err != nil || session == nil {
+		h.logger.Printf("ERROR: getSessionByID: %v", err)
+		h.reopenSession(sessionID)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	if len(session.Sets) == 0 && len(session.Timers) == 0 {
+		h.reopenSession(sessionID)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "log at least one set or timer before finishing, or cancel the session"})
+		return
+	}
+
+	endedAt := time.Now()
+	workout := session.ToWorkout(endedAt)
+	err = workout.ResolveTimes()
+	if err != nil {
+		h.reopenSession(sessionID)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	createdWorkout, err := h.workoutStore.CreateWorkout(workout)
+	if err != nil {
+		h.logger.Printf("ERROR: createWorkout from session: %v", err)
+		h.reopenSession(sessionID)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to save workout"})
+		return
+	}
+
+	err = h.sessionStore.CompleteSession(sessionID, createdWorkout.ID, endedAt)
+	if err != nil {
+		// The workout is saved, which is what matters; the session just stays "finishing"
+		h.logger.Printf("ERROR: completeSession: %v", err)
+	}
+
+	newRecords, err := h.recordStore.GetRecordsForWorkout(int64(createdWorkout.ID))
+	if err != nil {
+		h.logger.Printf("ERROR: getRecordsForWorkout: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	h.hub.Publish(session.ID, live.Event{Type: live.EventSessionFinished, Data: utils.Envelope{"workout_id": createdWorkout.ID}})
+
+	createdWorkout.ApplyE1RM(middleware.GetUser(r).PreferredE1RMFormula())
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"workout": createdWorkout, "new_records": newRecords})
+}
+
+// reopenSession puts a session back to active after finishing it failed, so the athlete can retry.
+func (h *SessionHandler) reopenSession(sessionID int64) {
+	err := h.sessionStore.SetSessionStatus(sessionID, store.SessionStatusFinishing, store.SessionStatusActive)
+	if err != nil {
+		h.logger.Printf("ERROR: reopenSession: %v", err)
+	}
+}
+
+// HandleCancelSession handles DELETE /sessions/{id}
+func (h *SessionHandler) HandleCancelSession(w http.ResponseWriter, r *http.Request) {
+	session := h.loadOwnSession(w, r)
+	if session == nil {
+		return
+	}
+
+	h.timers.Stop(session.ID)
+
+	err := h.sessionStore.CancelSession(int64(session.ID))
+	if errors.Is(err, store.ErrSessionNotActive) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: cancelSession: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	h.hub.Publish(session.ID, live.Event{Type: live.EventSessionCancelled, Data: utils.Envelope{"session_id": session.ID}})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleStartTimer handles POST /sessions/{id}/timer
+// It runs an interval protocol (tabata, emom or custom) for one exercise on the server.
+// Every connected screen receives timer_phase_changed and timer_tick events, and the
+// timings are saved with the session when the timer ends, to end up on the workout entry.
+func (h *SessionHandler) HandleStartTimer(w http.ResponseWriter, r *http.Request) {
+	session := h.loadOwnSession(w, r)
+	if session == nil {
+		return
+	}
+
+	var req startTimerRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decodingStartTimer: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	if req.ExerciseID == nil && strings.TrimSpace(req.ExerciseName) == "" {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "exercise_id or exercise_name is required"})
+		return
+	}
+	if req.Rounds < 0 || req.WorkSeconds < 0 || req.RestSeconds < 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "rounds, work_seconds and rest_seconds cannot be negative"})
+		return
+	}
+	if session.Status != store.SessionStatusActive {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": store.ErrSessionNotActive.Error()})
+		return
+	}
+
+	protocol, err := timer.NewProtocol(req.Protocol, timer.ProtocolOptions{
+		Rounds:      req.Rounds,
+		WorkSeconds: req.WorkSeconds,
+		RestSeconds: req.RestSeconds,
+		Phases:      req.Phases,
+	})
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	sessionID, userID := session.ID, session.UserID
+	emit := func(event timer.Event) {
+		h.hub.Publish(sessionID, live.Event{Type: event.Type, Data: event})
+	}
+	done := func(timings timer.Log) {
+		sessionTimer := &store.SessionTimer{ExerciseID: req.ExerciseID, ExerciseName: req.ExerciseName, Timings: timings}
+		err := h.sessionStore.AddSessionTimer(int64(sessionID), userID, sessionTimer)
+		if err != nil {
+			h.logger.Printf("ERROR: addSessionTimer: %v", err)
+		}
+	}
+
+	err = h.timers.Start(sessionID, protocol, emit, done)
+	if errors.Is(err, timer.ErrAlreadyRunning) {
+		utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: startTimer: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"timer": protocol})
+}
+
+// HandleStopTimer handles DELETE /sessions/{id}/timer
+// The timer is cut short; what was done so far is saved like a timer that ran to the end.
+func (h *SessionHandler) HandleStopTimer(w http.ResponseWriter, r *http.Request) {
+	session := h.loadOwnSession(w, r)
+	if session == nil {
+		return
+	}
+
+	if !h.timers.Stop(session.ID) {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "no timer is running for this session"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleSessionEvents handles GET /sessions/{id}/events
+// It streams the session over Server-Sent Events. The owner authenticates as usual;
+// other screens pass ?watch_token= since EventSource can't send an Authorization header.
+// The first event is a snapshot of the whole session, so a reconnecting client resyncs.
+func (h *SessionHandler) HandleSessionEvents(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := utils.ReadIdParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid session id"})
+		return
+	}
+
+	// Subscribe before reading the snapshot so nothing logged in between is missed
+	events, unsubscribe := h.hub.Subscribe(int(sessionID))
+	defer unsubscribe()
+
+	session, err := h.sessionStore.GetSessionByID(sessionID)
+	if err != nil {
+		h.logger.Printf("ERROR: getSessionByID: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	if session == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "session not found"})
+		return
+	}
+
+	currentUser := middleware.GetUser(r)
+	isOwner := !currentUser.IsAnonymous() && currentUser.ID == session.UserID
+	if !isOwner && !validWatchToken(session, r.URL.Query().Get("watch_token")) {
+		if currentUser.IsAnonymous() {
+			utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"error": "you must be logged in or pass a watch_token"})
+			return
+		}
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you are not authorized to access this session"})
+		return
+	}
+
+	// The server's write timeout is meant for regular requests, a stream stays open
+	controller := http.NewResponseController(w)
+	_ = controller.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event live.Event) bool {
+		if err := live.WriteSSE(w, event); err != nil {
+			return false
+		}
+		return controller.Flush() == nil
+	}
+
+	if !send(live.Event{Type: live.EventSnapshot, Data: session}) {
+		return
+	}
+	if session.Status != store.SessionStatusActive {
+		return
+	}
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil || controller.Flush() != nil {
+				return
+			}
+		case event := <-events:
+			if !send(event) {
+				return
+			}
+			if event.Type == live.EventSessionFinished || event.Type == live.EventSessionCancelled {
+				return
+			}
+		}
+	}
+}
+
+// validWatchToken compares the token against the session's hash in constant time.
+func validWatchToken(session *store.LiveSession, plaintext string) bool {
+	if plaintext == "" || len(session.WatchTokenHash) == 0 {
+		return false
+	}
+	hash := sha256.Sum256([]byte(plaintext))
+	return subtle.ConstantTimeCompare(hash[:], session.WatchTokenHash) == 1
+}
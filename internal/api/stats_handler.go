@@ -0,0 +1,357 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/consistency"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/load"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/middleware"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/store"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/tokens"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/utils"
+)
+
+// StatsHandler handles the analytics endpoints.
+// The training load can be shared with a load token, which is why it needs the user and token stores.
+type StatsHandler struct {
+	statsStore store.StatsStore
+	userStore  store.UserStore
+	tokenStore store.TokenStore
+	logger     *log.Logger
+}
+
+// NewStatsHandler creates a new StatsHandler with the given stores
+func NewStatsHandler(statsStore store.StatsStore, userStore store.UserStore, tokenStore store.TokenStore, logger *log.Logger) *StatsHandler {
+	return &StatsHandler{
+		statsStore: statsStore,
+		userStore:  userStore,
+		tokenStore: tokenStore,
+		logger:     logger,
+	}
+}
+
+// HandleGetVolume handles GET /stats/volume
+// Query parameters (all optional):
+//   - period: week (default) or month; weeks start on Monday
+//   - group_by: exercise or muscle_group
+//   - from, to: performed_at range (YYYY-MM-DD or RFC3339), "to" is exclusive
+//   - tz: IANA timezone used for bucketing, e.g. America/New_York (default the user's timezone setting)
+func (h *StatsHandler) HandleGetVolume(w http.ResponseWriter, r *http.Request) {
+	currentUser := middleware.GetUser(r)
+	query := r.URL.Query()
+
+	filter := store.VolumeFilter{
+		UserID:  currentUser.ID,
+		Period:  query.Get("period"),
+		GroupBy: query.Get("group_by"),
+	}
+
+	if filter.Period == "" {
+		filter.Period = store.PeriodWeek
+	}
+	if filter.Period != store.PeriodWeek && filter.Period != store.PeriodMonth {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "period must be week or month"})
+		return
+	}
+
+	switch filter.GroupBy {
+	case "", store.GroupByExercise, store.GroupByMuscleGroup:
+	default:
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "group_by must be exercise or muscle_group"})
+		return
+	}
+
+	var err error
+	filter.From, err = utils.ReadTimeQuery(r, "from")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	filter.To, err = utils.ReadTimeQuery(r, "to")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	filter.Location = currentUser.Location()
+	if tz := query.Get("tz"); tz != "" {
+		filter.Location, err = time.LoadLocation(tz)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid tz"})
+			return
+		}
+	}
+
+	buckets, err := h.statsStore.GetVolume(filter)
+	if err != nil {
+		h.logger.Printf("ERROR: getVolume: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"volume": buckets,
+		"metadata": utils.Envelope{
+			"period":   filter.Period,
+			"group_by": filter.GroupBy,
+			"timezone": filter.Location.String(),
+		},
+	})
+}
+
+// HandleGetConsistency handles GET /me/consistency
+// Query parameters (all optional):
+//   - year: the year of the heatmap (default the current one)
+//   - tz: IANA timezone the days are taken in (default the user's timezone setting)
+//   - rest_days: days without training a daily streak survives, 0-6 (default 0)
+//   - weekly_target: workouts a week needs to count towards the weekly streak, 1-14 (default 1)
+//   - rest_weeks: weeks short of the target a weekly streak survives, 0-4 (default 0)
+//
+// The heatmap has one entry for every day of the year. Streaks always cover the whole
+// training history, not just the year shown.
+func (h *StatsHandler) HandleGetConsistency(w http.ResponseWriter, r *http.Request) {
+	currentUser := middleware.GetUser(r)
+
+	location := currentUser.Location()
+	if tz := r.URL.Query().Get("tz"); tz != "" {
+		var err error
+		location, err = time.LoadLocation(tz)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid tz"})
+			return
+		}
+	}
+	now := time.Now().In(location)
+
+	year, err := utils.ReadIntQuery(r, "year", now.Year())
+	if err != nil || year < 1970 || year > 9999 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "year must be between 1970 and 9999"})
+		return
+	}
+	restDays, err := utils.ReadIntQuery(r, "rest_days", 0)
+	if err != nil || restDays < 0 || restDays > 6 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "rest_days must be between 0 and 6"})
+		return
+	}
+	weeklyTarget, err := utils.ReadIntQuery(r, "weekly_target", 1)
+	if err != nil || weeklyTarget < 1 || weeklyTarget > 14 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "weekly_target must be between 1 and 14"})
+		return
+	}
+	restWeeks, err := utils.ReadIntQuery(r, "rest_weeks", 0)
+	if err != nil || restWeeks < 0 || restWeeks > 4 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "rest_weeks must be between 0 and 4"})
+		return
+	}
+
+	history, err := h.statsStore.GetDailyActivity(currentUser.ID, location, nil, nil)
+	if err != nil {
+		h.logger.Printf("ERROR: getDailyActivity: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	yearDays := []consistency.Day{}
+	workouts, activeDays := 0, 0
+	for _, day := range history {
+		if day.Date.Year() == year {
+			yearDays = append(yearDays, day)
+			workouts += day.Workouts
+			activeDays++
+		}
+	}
+
+	today := consistency.Date(now)
+	currentDaily, longestDaily := consistency.DailyStreaks(history, today, restDays)
+	currentWeekly, longestWeekly := consistency.WeeklyStreaks(history, today, weeklyTarget, restWeeks)
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"consistency": utils.Envelope{
+			"year":        year,
+			"days":        consistency.Calendar(year, yearDays),
+			"workouts":    workouts,
+			"active_days": activeDays,
+			"streaks": utils.Envelope{
+				"daily":  utils.Envelope{"current": currentDaily, "longest": longestDaily},
+				"weekly": utils.Envelope{"current": currentWeekly, "longest": longestWeekly},
+			},
+		},
+		"metadata": utils.Envelope{
+			"timezone":      location.String(),
+			"rest_days":     restDays,
+			"weekly_target": weeklyTarget,
+			"rest_weeks":    restWeeks,
+		},
+	})
+}
+
+// defaultLoadDays is how many days of training load are returned without a from date.
+const defaultLoadDays = 90
+
+// loadTokenTTL is how long a shared training load link keeps working.
+// Creating a new one revokes the old link, and so does DELETE /tokens/load.
+const loadTokenTTL = 365 * 24 * time.Hour
+
+// HandleGetLoad handles GET /users/{id}/load
+// The user reads their own load as usual. Anyone else, such as a coach with or without
+// an account, passes ?load_token= with a token the user created through POST /tokens/load.
+// Query parameters (all optional):
+//   - from, to: days to return (YYYY-MM-DD), "to" is exclusive (default the last 90 days)
+//   - tz: IANA timezone the days are taken in (default the user's timezone setting)
+//
+// The load of a workout is its session_rpe × duration_minutes; workouts without a session
+// RPE add nothing and are counted in unrated_workouts. Every day comes with the rolling and
+// EWMA acute:chronic workload ratios (7 vs 28 days), monotony, strain and the Banister
+// fitness, fatigue and form, and is flagged when a ratio leaves the 0.8-1.3 band.
+// "current" is the latest day returned.
+func (h *StatsHandler) HandleGetLoad(w http.ResponseWriter, r *http.Request) {
+	userID, err := utils.ReadIdParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid user id"})
+		return
+	}
+
+	currentUser := middleware.GetUser(r)
+	user := currentUser
+	if currentUser.IsAnonymous() || int64(currentUser.ID) != userID {
+		user = nil
+		if loadToken := r.URL.Query().Get("load_token"); loadToken != "" {
+			owner, err := h.userStore.GetUserToken(tokens.ScopeLoad, loadToken)
+			if err != nil {
+				h.logger.Printf("ERROR: getUserToken: %v", err)
+				utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+				return
+			}
+			if owner != nil && int64(owner.ID) == userID {
+				user = owner
+			}
+		}
+	}
+	if user == nil {
+		if currentUser.IsAnonymous() {
+			utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"error": "you must be logged in or pass a load_token"})
+			return
+		}
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you are not authorized to view this training load"})
+		return
+	}
+
+	location := user.Location()
+	if tz := r.URL.Query().Get("tz"); tz != "" {
+		location, err = time.LoadLocation(tz)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid tz"})
+			return
+		}
+	}
+
+	today := consistency.Date(time.Now().In(location))
+	to := today.AddDate(0, 0, 1)
+	toParam, err := utils.ReadTimeQuery(r, "to")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if toParam != nil {
+		to = consistency.Date(*toParam)
+	}
+	from := to.AddDate(0, 0, -defaultLoadDays)
+	fromParam, err := utils.ReadTimeQuery(r, "from")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if fromParam != nil {
+		from = consistency.Date(*fromParam)
+	}
+	if !from.Before(to) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "from must be before to"})
+		return
+	}
+
+	days, err := h.statsStore.GetDailyLoad(user.ID, location)
+	if err != nil {
+		h.logger.Printf("ERROR: getDailyLoad: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	// The model runs from the first workout so the chronic load is right on the first day shown
+	through := to.AddDate(0, 0, -1)
+	if through.After(today) {
+		through = today
+	}
+	first, last := from.Format(time.DateOnly), to.Format(time.DateOnly)
+	points := []load.Point{}
+	for _, point := range load.Compute(days, through, load.DefaultModel) {
+		if point.Date >= first && point.Date < last {
+			points = append(points, point)
+		}
+	}
+
+	var current *load.Point
+	if len(points) > 0 {
+		current = &points[len(points)-1]
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"load":    points,
+		"current": current,
+		"metadata": utils.Envelope{
+			"timezone":     location.String(),
+			"acute_days":   load.AcuteDays,
+			"chronic_days": load.ChronicDays,
+			"safe_acwr":    []float64{load.SafeACWRLow, load.SafeACWRHigh},
+			"model": utils.Envelope{
+				"fitness_days":   load.DefaultModel.FitnessDays,
+				"fatigue_days":   load.DefaultModel.FatigueDays,
+				"fitness_weight": load.DefaultModel.FitnessWeight,
+				"fatigue_weight": load.DefaultModel.FatigueWeight,
+			},
+		},
+	})
+}
+
+// HandleCreateLoadToken handles POST /tokens/load
+// It returns a new token that lets the holder, e.g. a coach, read the user's training load
+// through GET /users/{id}/load?load_token=. Older load tokens of the user stop working.
+func (h *StatsHandler) HandleCreateLoadToken(w http.ResponseWriter, r *http.Request) {
+	currentUser := middleware.GetUser(r)
+
+	err := h.tokenStore.DeleteAllTokensForUser(currentUser.ID, tokens.ScopeLoad)
+	if err != nil {
+		h.logger.Printf("ERROR: deleteLoadTokens: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	token, err := h.tokenStore.CreateNewToken(currentUser.ID, loadTokenTTL, tokens.ScopeLoad)
+	if err != nil {
+		h.logger.Printf("ERROR: creating load token: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{
+		"load_token": token,
+		"load_path":  fmt.Sprintf("/users/%d/load?load_token=%s", currentUser.ID, token.Plaintext),
+	})
+}
+
+// HandleDeleteLoadTokens handles DELETE /tokens/load
+// It stops sharing the user's training load with everyone holding a load token.
+func (h *StatsHandler) HandleDeleteLoadTokens(w http.ResponseWriter, r *http.Request) {
+	currentUser := middleware.GetUser(r)
+
+	err := h.tokenStore.DeleteAllTokensForUser(currentUser.ID, tokens.ScopeLoad)
+	if err != nil {
+		h.logger.Printf("ERROR: deleteLoadTokens: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,335 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/middleware"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/store"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/utils"
+)
+
+// templateRequest is the payload for creating or replacing a template.
+type templateRequest struct {
+	Name        string                `json:"name"`
+	Description string                `json:"description"`
+	Entries     []store.TemplateEntry `json:"entries"`
+}
+
+// instantiateTemplateRequest is the optional payload of POST /templates/{id}/instantiate.
+type instantiateTemplateRequest struct {
+	Title          *string    `json:"title"`
+	PerformedAt    *time.Time `json:"performed_at"`
+	UseLastWeights bool       `json:"use_last_weights"`
+}
+
+// TemplateHandler handles the workout template endpoints.
+// It needs the workoutStore to turn a template into a real workout.
+type TemplateHandler struct {
+	templateStore store.TemplateStore
+	workoutStore  store.WorkoutStore
+	logger        *log.Logger
+}
+
+// NewTemplateHandler creates a new TemplateHandler with the given stores
+func NewTemplateHandler(templateStore store.TemplateStore, workoutStore store.WorkoutStore, logger *log.Logger) *TemplateHandler {
+	return &TemplateHandler{
+		templateStore: templateStore,
+		workoutStore:  workoutStore,
+		logger:        logger,
+	}
+}
+
+// validateTemplateRequest checks the fields the database can't give a friendly error for.
+func (h *TemplateHandler) validateTemplateRequest(req *templateRequest) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(req.Name) > 255 {
+		return errors.New("name cannot be greater than 255 chars")
+	}
+
+	for _, entry := range req.Entries {
+		if entry.ExerciseID == nil && strings.TrimSpace(entry.ExerciseName) == "" {
+			return errors.New("every entry needs an exercise_id or exercise_name")
+		}
+		if entry.TargetSets < 1 {
+			return errors.New("target_sets must be at least 1")
+		}
+		if (entry.TargetReps == nil) == (entry.TargetDurationSeconds == nil) {
+			return errors.New("every entry needs either target_reps or target_duration_seconds")
+		}
+	}
+
+	return nil
+}
+
+// authorizeTemplate checks that the template exists and belongs to the logged in user.
+// It writes the 404/403/500 response itself and returns false when the caller should stop.
+func (h *TemplateHandler) authorizeTemplate(w http.ResponseWriter, r *http.Request, templateID int64) bool {
+	owner, err := h.templateStore.GetTemplateOwner(templateID)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "template not found"})
+		return false
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: getTemplateOwner: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return false
+	}
+
+	if owner != middleware.GetUser(r).ID {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you are not authorized to access this template"})
+		return false
+	}
+
+	return true
+}
+
+// HandleListTemplates handles GET /templates
+func (h *TemplateHandler) HandleListTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := h.templateStore.ListTemplates(middleware.GetUser(r).ID)
+	if err != nil {
+		h.logger.Printf("ERROR: listTemplates: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"templates": templates})
+}
+
+// HandleCreateTemplate handles POST /templates
+func (h *TemplateHandler) HandleCreateTemplate(w http.ResponseWriter, r *http.Request) {
+	var req templateRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decodingCreateTemplate: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	err = h.validateTemplateRequest(&req)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	template := &store.Template{
+		UserID:      middleware.GetUser(r).ID,
+		Name:        req.Name,
+		Description: req.Description,
+		Entries:     req.Entries,
+	}
+
+	err = h.templateStore.CreateTemplate(template)
+	if errors.Is(err, store.ErrUnknownExercise) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: createTemplate: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to create template"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"template": template})
+}
+
+// HandleGetTemplate handles GET /templates/{id}
+func (h *TemplateHandler) HandleGetTemplate(w http.ResponseWriter, r *http.Request) {
+	templateID, err := utils.ReadIdParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid template id"})
+		return
+	}
+
+	if !h.authorizeTemplate(w, r, templateID) {
+		return
+	}
+
+	template, err := h.templateStore.GetTemplateByID(templateID)
+	if err != nil {
+		h.logger.Printf("ERROR: getTemplateByID: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	if template == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "template not found"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"template": template})
+}
+
+// HandleUpdateTemplate handles PUT /templates/{id}
+// The whole template is replaced, including its entries.
+func (h *TemplateHandler) HandleUpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	templateID, err := utils.ReadIdParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid template id"})
+		return
+	}
+
+	if !h.authorizeTemplate(w, r, templateID) {
+		return
+	}
+
+	var req templateRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decodingUpdateTemplate: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	err = h.validateTemplateRequest(&req)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	template := &store.Template{
+		ID:          int(templateID),
+		UserID:      middleware.GetUser(r).ID,
+		Name:        req.Name,
+		Description: req.Description,
+		Entries:     req.Entries,
+	}
+
+	err = h.templateStore.UpdateTemplate(template)
+	if errors.Is(err, store.ErrUnknownExercise) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "template not found"})
+		return
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: updateTemplate: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	updated, err := h.templateStore.GetTemplateByID(templateID)
+	if err != nil || updated == nil {
+		h.logger.Printf("ERROR: getTemplateByID after update: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"template": updated})
+}
+
+// HandleDeleteTemplate handles DELETE /templates/{id}
+func (h *TemplateHandler) HandleDeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	templateID, err := utils.ReadIdParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid template id"})
+		return
+	}
+
+	if !h.authorizeTemplate(w, r, templateID) {
+		return
+	}
+
+	err = h.templateStore.DeleteTemplate(templateID)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "template not found"})
+		return
+	}
+	if err != nil {
+		// Programs reference templates with ON DELETE RESTRICT
+		var pgErr interface{ SQLState() string }
+		if errors.As(err, &pgErr) && pgErr.SQLState() == "23503" {
+			utils.WriteJSON(w, http.StatusConflict, utils.Envelope{"error": "template is used by a program"})
+			return
+		}
+		h.logger.Printf("ERROR: deleteTemplate: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleInstantiateTemplate handles POST /templates/{id}/instantiate
+// It creates a real workout prefilled with the template's entries. The body is optional:
+//   - title: defaults to the template name
+//   - performed_at: defaults to now
+//   - use_last_weights: replace target weights with the heaviest working weight of the last session
+func (h *TemplateHandler) HandleInstantiateTemplate(w http.ResponseWriter, r *http.Request) {
+	templateID, err := utils.ReadIdParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid template id"})
+		return
+	}
+
+	if !h.authorizeTemplate(w, r, templateID) {
+		return
+	}
+
+	var req instantiateTemplateRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && err != io.EOF {
+		h.logger.Printf("ERROR: decodingInstantiateTemplate: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	template, err := h.templateStore.GetTemplateByID(templateID)
+	if err != nil || template == nil {
+		h.logger.Printf("ERROR: getTemplateByID: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	workout := template.ToWorkout()
+	if req.Title != nil {
+		workout.Title = *req.Title
+	}
+	if req.PerformedAt != nil {
+		workout.PerformedAt = *req.PerformedAt
+	}
+
+	if req.UseLastWeights {
+		for i := range workout.Entries {
+			entry := &workout.Entries[i]
+			lastWeight, err := h.workoutStore.GetLastWeight(workout.UserID, entry.ExerciseID, entry.ExerciseName)
+			if err != nil {
+				h.logger.Printf("ERROR: getLastWeight: %v", err)
+				utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+				return
+			}
+			if lastWeight == nil {
+				continue
+			}
+			for j := range entry.Sets {
+				entry.Sets[j].Weight = lastWeight
+			}
+		}
+	}
+
+	err = workout.ResolveTimes()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	createdWorkout, err := h.workoutStore.CreateWorkout(workout)
+	if err != nil {
+		h.logger.Printf("ERROR: createWorkout from template: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to create workout"})
+		return
+	}
+
+	createdWorkout.ApplyE1RM(middleware.GetUser(r).PreferredE1RMFormula())
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"workout": createdWorkout})
+}
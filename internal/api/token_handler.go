@@ -0,0 +1,87 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/store"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/tokens"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/utils"
+)
+
+// authTokenTTL is how long a login token stays valid.
+const authTokenTTL = 24 * time.Hour
+
+// createTokenRequest is the login payload sent to POST /tokens/authentication.
+type createTokenRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// TokenHandler issues authentication tokens.
+// It needs the userStore to verify credentials and the tokenStore to persist the token.
+type TokenHandler struct {
+	tokenStore store.TokenStore
+	userStore  store.UserStore
+	logger     *log.Logger
+}
+
+// NewTokenHandler is a constructor for TokenHandler.
+func NewTokenHandler(tokenStore store.TokenStore, userStore store.UserStore, logger *log.Logger) *TokenHandler {
+	return &TokenHandler{
+		tokenStore: tokenStore,
+		userStore:  userStore,
+		logger:     logger,
+	}
+}
+
+// HandleCreateToken handles POST /tokens/authentication.
+// Steps:
+// 1. Decode the username/password payload.
+// 2. Look up the user and compare the password against the bcrypt hash.
+// 3. Create a new token and return its plain-text value (this is the only time it is visible).
+func (h *TokenHandler) HandleCreateToken(w http.ResponseWriter, r *http.Request) {
+	var req createTokenRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: createTokenRequest: %v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	user, err := h.userStore.GetUserByUsername(req.Username)
+	if err != nil {
+		h.logger.Printf("ERROR: GetUserByUsername: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	// Same response for unknown user and wrong password, so usernames can't be probed.
+	if user == nil {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"error": "invalid credentials"})
+		return
+	}
+
+	passwordsDoMatch, err := user.PasswordHash.Matches(req.Password)
+	if err != nil {
+		h.logger.Printf("ERROR: PasswordHash.Matches: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	if !passwordsDoMatch {
+		utils.WriteJSON(w, http.StatusUnauthorized, utils.Envelope{"error": "invalid credentials"})
+		return
+	}
+
+	token, err := h.tokenStore.CreateNewToken(user.ID, authTokenTTL, tokens.ScopeAuth)
+	if err != nil {
+		h.logger.Printf("ERROR: creating token: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"auth_token": token})
+}
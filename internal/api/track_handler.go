@@ -0,0 +1,265 @@
+package api
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/fit"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/middleware"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/store"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/track"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/utils"
+)
+
+// maxTrackBytes caps the size of an uploaded track; a long ride recorded every second is a few MB.
+const maxTrackBytes = 32 << 20
+
+// TrackHandler handles the GPS and heart rate recordings of cardio workouts,
+// and the FIT activity files recorded by Garmin devices.
+type TrackHandler struct {
+	trackStore   store.TrackStore
+	workoutStore store.WorkoutStore
+	recordStore  store.RecordStore
+	logger       *log.Logger
+}
+
+// NewTrackHandler creates a new TrackHandler with the given stores
+func NewTrackHandler(trackStore store.TrackStore, workoutStore store.WorkoutStore, recordStore store.RecordStore, logger *log.Logger) *TrackHandler {
+	return &TrackHandler{
+		trackStore:   trackStore,
+		workoutStore: workoutStore,
+		recordStore:  recordStore,
+		logger:       logger,
+	}
+}
+
+// authorizeWorkout checks that the workout exists and belongs to the current user.
+// It writes the error response and returns false otherwise.
+func (h *TrackHandler) authorizeWorkout(w http.ResponseWriter, r *http.Request, workoutID int64) bool {
+	workoutOwner, err := h.workoutStore.GetWorkoutOwner(workoutID)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "workout not found"})
+		return false
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: getWorkoutOwner: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return false
+	}
+
+	if workoutOwner != middleware.GetUser(r).ID {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you are not authorized to access this workout"})
+		return false
+	}
+
+	return true
+}
+
+// HandleUploadTrack handles POST /workouts/{id}/tracks
+// The GPX or TCX file is sent either as the "file" field of a multipart form, or as the raw
+// request body; the format is recognized from the file itself. The metrics are computed
+// right away, with heart rate zones based on the user's max_heart_rate setting.
+// A workout can have several tracks, e.g. a run recorded in two parts; its cardio metrics add them up.
+func (h *TrackHandler) HandleUploadTrack(w http.ResponseWriter, r *http.Request) {
+	workoutID, err := utils.ReadIdParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid workout id"})
+		return
+	}
+	if !h.authorizeWorkout(w, r, workoutID) {
+		return
+	}
+
+	data, filename, ok := readUpload(w, r, maxTrackBytes, h.logger)
+	if !ok {
+		return
+	}
+
+	parsed, err := track.Parse(data)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	name := parsed.Name
+	if name == "" {
+		name = strings.TrimSuffix(filename, "."+parsed.Format)
+	}
+	name = utils.Truncate(name, 255)
+
+	points := parsed.Points
+	workoutTrack := &store.WorkoutTrack{
+		WorkoutID: int(workoutID),
+		Format:    parsed.Format,
+		Name:      name,
+		StartedAt: points[0].Time,
+		EndedAt:   points[len(points)-1].Time,
+		Metrics:   track.Compute(points, middleware.GetUser(r).MaxHeartRate),
+		Points:    points,
+	}
+
+	err = h.trackStore.CreateTrack(workoutTrack)
+	if err != nil {
+		h.logger.Printf("ERROR: createTrack: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to save track"})
+		return
+	}
+
+	// The points were just uploaded, no need to send them back
+	workoutTrack.Points = nil
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"track": workoutTrack})
+}
+
+// HandleUploadFIT handles POST /workouts/fit
+// A FIT activity file, sent like a track, becomes a new workout: strength sets become its
+// entries and the recorded samples (position, altitude, heart rate) become its track.
+// The workout is saved through the WorkoutStore, so exercises are linked to the catalog
+// and records are detected like for a workout posted by hand.
+func (h *TrackHandler) HandleUploadFIT(w http.ResponseWriter, r *http.Request) {
+	data, _, ok := readUpload(w, r, maxTrackBytes, h.logger)
+	if !ok {
+		return
+	}
+
+	activity, err := fit.Parse(data)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	currentUser := middleware.GetUser(r)
+	workout := activity.Workout()
+	workout.UserID = currentUser.ID
+
+	err = workout.ResolveTimes()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	createdWorkout, err := h.workoutStore.CreateWorkout(workout)
+	if err != nil {
+		h.logger.Printf("ERROR: createWorkout from FIT: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to create workout"})
+		return
+	}
+
+	if parsed := activity.Track(); parsed != nil {
+		points := parsed.Points
+		workoutTrack := &store.WorkoutTrack{
+			WorkoutID: createdWorkout.ID,
+			Format:    parsed.Format,
+			Name:      parsed.Name,
+			StartedAt: points[0].Time,
+			EndedAt:   points[len(points)-1].Time,
+			Metrics:   track.Compute(points, currentUser.MaxHeartRate),
+			Points:    points,
+		}
+
+		err = h.trackStore.CreateTrack(workoutTrack)
+		if err != nil {
+			h.logger.Printf("ERROR: createTrack from FIT: %v", err)
+			// Don't leave a workout behind without the track it came with
+			if deleteErr := h.workoutStore.DeleteWorkout(int64(createdWorkout.ID)); deleteErr != nil {
+				h.logger.Printf("ERROR: deleteWorkout: %v", deleteErr)
+			}
+			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to create workout"})
+			return
+		}
+		createdWorkout.Cardio = track.Combine([]track.Metrics{workoutTrack.Metrics})
+	}
+
+	// Records were already saved with the workout, here we only look up which ones it set
+	newRecords, err := h.recordStore.GetRecordsForWorkout(int64(createdWorkout.ID))
+	if err != nil {
+		h.logger.Printf("ERROR: getRecordsForWorkout: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	createdWorkout.ApplyE1RM(currentUser.PreferredE1RMFormula())
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"workout": createdWorkout, "new_records": newRecords})
+}
+
+// HandleListTracks handles GET /workouts/{id}/tracks
+// Tracks are listed with their metrics only; fetch a single track for its points.
+func (h *TrackHandler) HandleListTracks(w http.ResponseWriter, r *http.Request) {
+	workoutID, err := utils.ReadIdParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid workout id"})
+		return
+	}
+	if !h.authorizeWorkout(w, r, workoutID) {
+		return
+	}
+
+	tracks, err := h.trackStore.ListTracks(workoutID)
+	if err != nil {
+		h.logger.Printf("ERROR: listTracks: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"tracks": tracks})
+}
+
+// getAuthorizedTrack loads the track of the {id} URL parameter and checks that its workout
+// belongs to the current user. It writes the error response and returns nil otherwise.
+func (h *TrackHandler) getAuthorizedTrack(w http.ResponseWriter, r *http.Request) *store.WorkoutTrack {
+	trackID, err := utils.ReadIdParam(r)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid track id"})
+		return nil
+	}
+
+	workoutTrack, err := h.trackStore.GetTrackByID(trackID)
+	if err != nil {
+		h.logger.Printf("ERROR: getTrackByID: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil
+	}
+	if workoutTrack == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "track not found"})
+		return nil
+	}
+
+	if !h.authorizeWorkout(w, r, int64(workoutTrack.WorkoutID)) {
+		return nil
+	}
+
+	return workoutTrack
+}
+
+// HandleGetTrack handles GET /tracks/{id}
+// It returns the track with all its points, e.g. to draw the route on a map.
+func (h *TrackHandler) HandleGetTrack(w http.ResponseWriter, r *http.Request) {
+	workoutTrack := h.getAuthorizedTrack(w, r)
+	if workoutTrack == nil {
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"track": workoutTrack})
+}
+
+// HandleDeleteTrack handles DELETE /tracks/{id}
+func (h *TrackHandler) HandleDeleteTrack(w http.ResponseWriter, r *http.Request) {
+	workoutTrack := h.getAuthorizedTrack(w, r)
+	if workoutTrack == nil {
+		return
+	}
+
+	err := h.trackStore.DeleteTrack(int64(workoutTrack.ID))
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "track not found"})
+		return
+	}
+	if err != nil {
+		h.logger.Printf("ERROR: deleteTrack: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
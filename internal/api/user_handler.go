@@ -6,7 +6,10 @@ import (
 	"log"
 	"net/http"
 	"regexp"
+	"time"
 
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/e1rm"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/middleware"
 	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/store"
 	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/utils"
 )
@@ -22,6 +25,15 @@ type registerUserRequest struct {
 	Bio      string `json:"bio"`
 }
 
+// updateSettingsRequest represents the payload for PATCH /me/settings.
+// Pointers let us tell "not sent" apart from an empty value.
+// A max_heart_rate of 0 clears it. timezone is an IANA name such as Europe/Berlin.
+type updateSettingsRequest struct {
+	E1RMFormula  *string `json:"e1rm_formula"`
+	MaxHeartRate *int    `json:"max_heart_rate"`
+	Timezone     *string `json:"timezone"`
+}
+
 // UserHandler is an HTTP handler that deals with user-related endpoints.
 // It depends on:
 // - userStore: interface for persistence (DB operations for users)
@@ -124,3 +136,69 @@ func (h *UserHandler) HandleRegisterUser(w http.ResponseWriter, r *http.Request)
 	// Respond with created user (JSON-encoded)
 	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"user": user})
 }
+
+// HandleUpdateSettings handles PATCH /me/settings for the logged in user.
+// Changing the e1RM formula rebuilds the user's records, so best_e1rm records match
+// the estimates shown on their workouts. Changing the maximum heart rate recomputes
+// the heart rate zones of their tracks. The rebuilds are saved together with the settings,
+// so a failed one leaves the old settings in place to retry. The timezone decides which
+// day a workout falls on in the consistency calendar and streaks.
+func (h *UserHandler) HandleUpdateSettings(w http.ResponseWriter, r *http.Request) {
+	var req updateSettingsRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("Error: decoding settings request :%v", err)
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	user := middleware.GetUser(r)
+	formulaChanged := false
+
+	if req.E1RMFormula != nil {
+		formula, err := e1rm.Parse(*req.E1RMFormula)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+			return
+		}
+		formulaChanged = string(formula) != user.E1RMFormula
+		user.E1RMFormula = string(formula)
+	}
+
+	maxHeartRateChanged := false
+	if req.MaxHeartRate != nil {
+		var maxHeartRate *int
+		if *req.MaxHeartRate != 0 {
+			if *req.MaxHeartRate < 100 || *req.MaxHeartRate > 250 {
+				utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "max_heart_rate must be between 100 and 250, or 0 to clear it"})
+				return
+			}
+			maxHeartRate = req.MaxHeartRate
+		}
+		switch {
+		case maxHeartRate == nil || user.MaxHeartRate == nil:
+			maxHeartRateChanged = maxHeartRate != user.MaxHeartRate
+		default:
+			maxHeartRateChanged = *maxHeartRate != *user.MaxHeartRate
+		}
+		user.MaxHeartRate = maxHeartRate
+	}
+
+	if req.Timezone != nil {
+		location, err := time.LoadLocation(*req.Timezone)
+		if err != nil || *req.Timezone == "" || *req.Timezone == "Local" {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "timezone must be an IANA timezone such as Europe/Berlin"})
+			return
+		}
+		user.Timezone = location.String()
+	}
+
+	err = h.userStore.UpdateSettings(user, formulaChanged, maxHeartRateChanged)
+	if err != nil {
+		h.logger.Printf("Error: updating user settings %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"user": user})
+}
@@ -3,11 +3,14 @@ package api
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/middleware"
 	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/store"
 	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/utils"
 	"github.com/go-chi/chi/v5"
@@ -16,17 +19,44 @@ import (
 // WorkoutHandler handles all workout-related HTTP requests
 type WorkoutHandler struct {
 	workoutStore store.WorkoutStore
+	recordStore  store.RecordStore
 	logger       *log.Logger
 }
 
 // NewWorkoutHandler creates a new WorkoutHandler with the given WorkoutStore
-func NewWorkoutHandler(workoutStore store.WorkoutStore, logger *log.Logger) *WorkoutHandler {
+// The RecordStore is used to report the personal records a new workout set.
+func NewWorkoutHandler(workoutStore store.WorkoutStore, recordStore store.RecordStore, logger *log.Logger) *WorkoutHandler {
 	return &WorkoutHandler{
 		workoutStore: workoutStore,
+		recordStore:  recordStore,
 		logger: logger,
 	}
 }
 
+// authorizeWorkout checks that the workout exists and belongs to the logged in user.
+// It writes the 404/403/500 response itself and returns false when the caller should stop.
+func (wh *WorkoutHandler) authorizeWorkout(w http.ResponseWriter, r *http.Request, workoutID int64) bool {
+	currentUser := middleware.GetUser(r)
+
+	workoutOwner, err := wh.workoutStore.GetWorkoutOwner(workoutID)
+	if err == sql.ErrNoRows {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "workout not found"})
+		return false
+	}
+	if err != nil {
+		wh.logger.Printf("ERROR: getWorkoutOwner: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return false
+	}
+
+	if workoutOwner != currentUser.ID {
+		utils.WriteJSON(w, http.StatusForbidden, utils.Envelope{"error": "you are not authorized to access this workout"})
+		return false
+	}
+
+	return true
+}
+
 // HandleWorkoutByID handles GET /workouts/{id}
 func (wh *WorkoutHandler) HandleWorkoutByID(w http.ResponseWriter, r *http.Request) {
 	workoutID, err := utils.ReadIdParam(r)
@@ -36,16 +66,108 @@ func (wh *WorkoutHandler) HandleWorkoutByID(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	if !wh.authorizeWorkout(w, r, workoutID) {
+		return
+	}
+
 	// Fetch workout from store
 	workout, err := wh.workoutStore.GetWorkoutById(workoutID)
 	if err != nil {
 		wh.logger.Printf("ERROR: getWorkoutBYID: %v", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "Invalid server error"})
+		return
+	}
+
+	// The workout may have been deleted between the ownership check and the fetch
+	if workout == nil {
+		utils.WriteJSON(w, http.StatusNotFound, utils.Envelope{"error": "workout not found"})
+		return
 	}
 
+	workout.ApplyE1RM(middleware.GetUser(r).PreferredE1RMFormula())
 	utils.WriteJSON(w,http.StatusOK, utils.Envelope{"workout" : workout })
 }
 
+// HandleListWorkouts handles GET /workouts
+// Query parameters (all optional):
+//   - from, to: performed_at range (YYYY-MM-DD or RFC3339), "to" is exclusive
+//   - title: case-insensitive substring of the workout title
+//   - min_duration: minimum duration in minutes
+//   - exercise: only workouts containing this exercise
+//   - sort: newest (default), longest or calories
+//   - cursor: next_cursor from the previous page
+//   - limit: page size, 1-100 (default 20)
+func (wh *WorkoutHandler) HandleListWorkouts(w http.ResponseWriter, r *http.Request) {
+	currentUser := middleware.GetUser(r)
+	query := r.URL.Query()
+
+	filter := store.WorkoutFilter{
+		UserID:       currentUser.ID,
+		Title:        query.Get("title"),
+		ExerciseName: query.Get("exercise"),
+		Sort:         query.Get("sort"),
+		Cursor:       query.Get("cursor"),
+	}
+
+	var err error
+	filter.Limit, err = utils.ReadIntQuery(r, "limit", 20)
+	if err != nil || filter.Limit < 1 || filter.Limit > 100 {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "limit must be between 1 and 100"})
+		return
+	}
+
+	filter.From, err = utils.ReadTimeQuery(r, "from")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	filter.To, err = utils.ReadTimeQuery(r, "to")
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	if query.Get("min_duration") != "" {
+		minDuration, err := utils.ReadIntQuery(r, "min_duration", 0)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+			return
+		}
+		filter.MinDuration = &minDuration
+	}
+
+	switch filter.Sort {
+	case "", store.WorkoutSortNewest, store.WorkoutSortLongest, store.WorkoutSortCalories:
+	default:
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "sort must be one of newest, longest, calories"})
+		return
+	}
+
+	page, err := wh.workoutStore.ListWorkouts(filter)
+	if errors.Is(err, store.ErrInvalidCursor) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": "invalid cursor"})
+		return
+	}
+	if err != nil {
+		wh.logger.Printf("ERROR: listWorkouts: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	for i := range page.Workouts {
+		page.Workouts[i].ApplyE1RM(currentUser.PreferredE1RMFormula())
+	}
+
+	utils.WriteJSON(w, http.StatusOK, utils.Envelope{
+		"workouts": page.Workouts,
+		"metadata": utils.Envelope{
+			"next_cursor": page.NextCursor,
+			"total_count": page.TotalCount,
+			"limit":       filter.Limit,
+		},
+	})
+}
+
 // HandleCreateWorkout handles POST /workouts
 func (wh *WorkoutHandler) HandleCreateWorkout(w http.ResponseWriter, r *http.Request) {
 	defer r.Body.Close() // ensure body is closed after reading
@@ -60,15 +182,54 @@ func (wh *WorkoutHandler) HandleCreateWorkout(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	// The owner always comes from the token, never from the request body
+	currentUser := middleware.GetUser(r)
+	workout.UserID = currentUser.ID
+
+	// Calories sent by the client are measured, without them the store estimates them
+	workout.CaloriesSource = ""
+
+	// Default performed_at and derive the duration from started_at/ended_at if needed
+	err = workout.ResolveTimes()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	err = workout.ValidateGroups()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	err = workout.ValidateSessionRPE()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
 	// Save workout using store
 	createdWorkout, err := wh.workoutStore.CreateWorkout(&workout)
+	if errors.Is(err, store.ErrUnknownExercise) || errors.Is(err, store.ErrUnknownProgramDay) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		wh.logger.Printf("ERROR: createWorkout: %v", err)
 		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to create workout"})
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"workout": createdWorkout})
+	// Records were already saved with the workout, here we only look up which ones it set
+	newRecords, err := wh.recordStore.GetRecordsForWorkout(int64(createdWorkout.ID))
+	if err != nil {
+		wh.logger.Printf("ERROR: getRecordsForWorkout: %v", err)
+		utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	createdWorkout.ApplyE1RM(currentUser.PreferredE1RMFormula())
+	utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"workout": createdWorkout, "new_records": newRecords})
 }
 
 func (wh *WorkoutHandler) HandleUpdateWorkoutByID(w http.ResponseWriter, r *http.Request) {
@@ -80,6 +241,10 @@ func (wh *WorkoutHandler) HandleUpdateWorkoutByID(w http.ResponseWriter, r *http
 		return
 	}
 
+	if !wh.authorizeWorkout(w, r, workoutID) {
+		return
+	}
+
 	existingWorkout, err := wh.workoutStore.GetWorkoutById(workoutID)
 	if err != nil {
 		wh.logger.Printf("ERROR: getWorkoutByID: %v", err)
@@ -98,6 +263,12 @@ func (wh *WorkoutHandler) HandleUpdateWorkoutByID(w http.ResponseWriter, r *http
 		Description     *string              `json:"description"`
 		DurationMinutes *int                 `json:"duration_minutes"`
 		CaloriesBurned  *int                 `json:"calories_burned"`
+		PerformedAt     *time.Time           `json:"performed_at"`
+		StartedAt       *time.Time           `json:"started_at"`
+		EndedAt         *time.Time           `json:"ended_at"`
+		ProgramDayID    *int                 `json:"program_day_id"`
+		SessionRPE      *float64             `json:"session_rpe"`
+		Groups          []store.EntryGroup   `json:"groups"`
 		Entries         []store.WorkoutEntry `json:"entries"`
 	}
 
@@ -118,20 +289,76 @@ func (wh *WorkoutHandler) HandleUpdateWorkoutByID(w http.ResponseWriter, r *http
 	if updateWorkoutRequest.DurationMinutes != nil {
 		existingWorkout.DurationMinutes = *updateWorkoutRequest.DurationMinutes
 	}
+	//Sent calories are measured; 0 drops them so they are estimated again, like calories never sent
 	if updateWorkoutRequest.CaloriesBurned != nil {
 		existingWorkout.CaloriesBurned = *updateWorkoutRequest.CaloriesBurned
+		existingWorkout.CaloriesSource = ""
+	}
+	if updateWorkoutRequest.PerformedAt != nil {
+		existingWorkout.PerformedAt = *updateWorkoutRequest.PerformedAt
+	}
+	if updateWorkoutRequest.StartedAt != nil {
+		existingWorkout.StartedAt = updateWorkoutRequest.StartedAt
+	}
+	if updateWorkoutRequest.EndedAt != nil {
+		existingWorkout.EndedAt = updateWorkoutRequest.EndedAt
 	}
 	if updateWorkoutRequest.Entries != nil {
 		existingWorkout.Entries = updateWorkoutRequest.Entries
 	}
+	//Groups are replaced when sent; entries sent without groups can still use the existing labels
+	if updateWorkoutRequest.Groups != nil {
+		existingWorkout.Groups = updateWorkoutRequest.Groups
+	}
+	//session_rpe 0 removes the rating
+	if updateWorkoutRequest.SessionRPE != nil {
+		existingWorkout.SessionRPE = updateWorkoutRequest.SessionRPE
+		if *updateWorkoutRequest.SessionRPE == 0 {
+			existingWorkout.SessionRPE = nil
+		}
+	}
+	//program_day_id 0 unlinks the workout from its program day
+	if updateWorkoutRequest.ProgramDayID != nil {
+		existingWorkout.ProgramDayID = updateWorkoutRequest.ProgramDayID
+		if *updateWorkoutRequest.ProgramDayID == 0 {
+			existingWorkout.ProgramDayID = nil
+		}
+	}
+
+	//A new start/end without an explicit duration means the duration should be derived again,
+	//which is only possible once the workout has both; otherwise the logged duration stays
+	changedTimes := updateWorkoutRequest.StartedAt != nil || updateWorkoutRequest.EndedAt != nil
+	if updateWorkoutRequest.DurationMinutes == nil && changedTimes && existingWorkout.StartedAt != nil && existingWorkout.EndedAt != nil {
+		existingWorkout.DurationMinutes = 0
+	}
+	err = existingWorkout.ResolveTimes()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	err = existingWorkout.ValidateGroups()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	err = existingWorkout.ValidateSessionRPE()
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
 
 	err = wh.workoutStore.UpdateWorkout(existingWorkout)
+	if errors.Is(err, store.ErrUnknownExercise) || errors.Is(err, store.ErrUnknownProgramDay) {
+		utils.WriteJSON(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		wh.logger.Printf("ERROR: updatingWorkout: %v", err)
 			utils.WriteJSON(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
 		return
 	}
 
+	existingWorkout.ApplyE1RM(middleware.GetUser(r).PreferredE1RMFormula())
 	utils.WriteJSON(w, http.StatusOK, utils.Envelope{"workout": existingWorkout})
 }
 
@@ -152,6 +379,10 @@ func (wh *WorkoutHandler) HandleDeleteWorkout(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	if !wh.authorizeWorkout(w, r, workoutId) {
+		return
+	}
+
 	err = wh.workoutStore.DeleteWorkout(workoutId)
 	if err == sql.ErrNoRows {
 		http.Error(w, "Workout not found", http.StatusNotFound)
@@ -1,22 +1,45 @@
 package app
 
 import (
+	"crypto/rand"
 	"database/sql"
-	"log"
-	"os"
 	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/api"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/blob"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/live"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/middleware"
 	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/migrations"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/seeds"
 	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/store"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/timer"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/tokens"
+	"log"
+	"os"
+	"path/filepath"
 )
 
 // Application bundles together all core dependencies of the app.
 // This avoids using global variables and makes it easier to pass
 // dependencies (like logger, DB, handlers) around the codebase.
 type Application struct {
-	Logger         *log.Logger
-	WorkoutHandler *api.WorkoutHandler
-	DB             *sql.DB
-	UserHandler    *api.UserHandler
+	Logger             *log.Logger
+	WorkoutHandler     *api.WorkoutHandler
+	DB                 *sql.DB
+	UserHandler        *api.UserHandler
+	TokenHandler       *api.TokenHandler
+	ExerciseHandler    *api.ExerciseHandler
+	RecordHandler      *api.RecordHandler
+	StatsHandler       *api.StatsHandler
+	TemplateHandler    *api.TemplateHandler
+	ProgramHandler     *api.ProgramHandler
+	ScheduleHandler    *api.ScheduleHandler
+	SessionHandler     *api.SessionHandler
+	ImportHandler      *api.ImportHandler
+	ExportHandler      *api.ExportHandler
+	TrackHandler       *api.TrackHandler
+	MeasurementHandler *api.MeasurementHandler
+	PhotoHandler       *api.PhotoHandler
+	GoalHandler        *api.GoalHandler
+	Middleware         middleware.UserMiddleware
 }
 
 // NewApplication sets up and returns a fully initialized Application instance.
@@ -43,18 +66,114 @@ func NewApplication() (*Application, error) {
 	//Initialize stores
 	workoutStore := store.NewPostgresWorkoutStore(pgDB)
 	userStore := store.NewPostgresUserStore(pgDB)
+	tokenStore := store.NewPostgresTokenStore(pgDB)
+	exerciseStore := store.NewPostgresExerciseStore(pgDB)
+	recordStore := store.NewPostgresRecordStore(pgDB)
+	statsStore := store.NewPostgresStatsStore(pgDB)
+	templateStore := store.NewPostgresTemplateStore(pgDB)
+	programStore := store.NewPostgresProgramStore(pgDB)
+	scheduleStore := store.NewPostgresScheduleStore(pgDB)
+	sessionStore := store.NewPostgresSessionStore(pgDB)
+	importStore := store.NewPostgresImportStore(pgDB)
+	trackStore := store.NewPostgresTrackStore(pgDB)
+	measurementStore := store.NewPostgresMeasurementStore(pgDB)
+	photoStore := store.NewPostgresPhotoStore(pgDB)
+	goalStore := store.NewPostgresGoalStore(pgDB)
+
+	// Progress photos are kept on the local disk, in PHOTO_DIR (default ./data/photos)
+	photoDir := os.Getenv("PHOTO_DIR")
+	if photoDir == "" {
+		photoDir = filepath.Join("data", "photos")
+	}
+	blobStore, err := blob.NewLocalStore(photoDir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Photo download links are signed with URL_SIGNING_KEY. Without one a random key is
+	// used, which is fine for development but breaks every handed out link on restart.
+	signingKey := []byte(os.Getenv("URL_SIGNING_KEY"))
+	if len(signingKey) == 0 {
+		logger.Println("WARNING: URL_SIGNING_KEY is not set, photo links won't survive a restart")
+		signingKey = make([]byte, 32)
+		_, err = rand.Read(signingKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Load the built-in exercise catalog and link entries logged before it existed
+	err = exerciseStore.SeedExercises(seeds.FS)
+	if err != nil {
+		return nil, err
+	}
+	linked, err := exerciseStore.LinkLegacyEntries()
+	if err != nil {
+		return nil, err
+	}
+	if linked > 0 {
+		logger.Printf("Linked %d legacy workout entries to the exercise catalog\n", linked)
+	}
+
+	// Compute personal records for workouts logged before records were tracked
+	backfilled, err := recordStore.BackfillRecords()
+	if err != nil {
+		return nil, err
+	}
+	if backfilled > 0 {
+		logger.Printf("Backfilled personal records for %d users\n", backfilled)
+	}
+
+	// Import jobs only live in memory, so the ones a restart cut off can't resume
+	interrupted, err := importStore.FailInterruptedImports()
+	if err != nil {
+		return nil, err
+	}
+	if interrupted > 0 {
+		logger.Printf("Marked %d interrupted imports as failed\n", interrupted)
+	}
 
 	// Initialize handlers
-	workoutHandler := api.NewWorkoutHandler(workoutStore, logger)
+	workoutHandler := api.NewWorkoutHandler(workoutStore, recordStore, logger)
 	userHandler := api.NewUserHandler(userStore, logger)
+	tokenHandler := api.NewTokenHandler(tokenStore, userStore, logger)
+	exerciseHandler := api.NewExerciseHandler(exerciseStore, logger)
+	recordHandler := api.NewRecordHandler(recordStore, measurementStore, logger)
+	statsHandler := api.NewStatsHandler(statsStore, userStore, tokenStore, logger)
+	templateHandler := api.NewTemplateHandler(templateStore, workoutStore, logger)
+	programHandler := api.NewProgramHandler(programStore, logger)
+	scheduleHandler := api.NewScheduleHandler(scheduleStore, userStore, tokenStore, logger)
+	importHandler := api.NewImportHandler(importStore, workoutStore, logger)
+	exportHandler := api.NewExportHandler(workoutStore, logger)
+	trackHandler := api.NewTrackHandler(trackStore, workoutStore, recordStore, logger)
+	measurementHandler := api.NewMeasurementHandler(measurementStore, logger)
+	photoHandler := api.NewPhotoHandler(photoStore, blobStore, tokens.NewURLSigner(signingKey), logger)
+	goalHandler := api.NewGoalHandler(goalStore, logger)
+	sessionHandler := api.NewSessionHandler(sessionStore, workoutStore, recordStore, live.NewHub(), timer.NewService(), logger)
+	middlewareHandler := middleware.UserMiddleware{UserStore: userStore}
 
 	// Bundle dependencies into Application
 	app := &Application{
-		Logger:         logger,
-		UserHandler:    userHandler,
-		WorkoutHandler: workoutHandler,
-		DB:             pgDB,
+		Logger:             logger,
+		UserHandler:        userHandler,
+		WorkoutHandler:     workoutHandler,
+		TokenHandler:       tokenHandler,
+		ExerciseHandler:    exerciseHandler,
+		RecordHandler:      recordHandler,
+		StatsHandler:       statsHandler,
+		TemplateHandler:    templateHandler,
+		ProgramHandler:     programHandler,
+		ScheduleHandler:    scheduleHandler,
+		SessionHandler:     sessionHandler,
+		ImportHandler:      importHandler,
+		ExportHandler:      exportHandler,
+		TrackHandler:       trackHandler,
+		MeasurementHandler: measurementHandler,
+		PhotoHandler:       photoHandler,
+		GoalHandler:        goalHandler,
+		Middleware:         middlewareHandler,
+		DB:                 pgDB,
 	}
 
 	return app, nil
-}
\ No newline at end of file
+}
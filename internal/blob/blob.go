@@ -0,0 +1,21 @@
+package blob
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when no blob is stored under the key.
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps binary files (photos for now) out of the database.
+// Keys are slash separated paths chosen by the caller, e.g. "photos/12/4f1c.jpg".
+// Other backends (S3, GCS, ...) can be plugged in by implementing this interface.
+type Store interface {
+	// Put stores the content of r under key, replacing any previous blob.
+	Put(key string, r io.Reader) error
+	// Open returns the blob stored under key, or ErrNotFound. The caller closes it.
+	Open(key string) (io.ReadCloser, error)
+	// Delete removes the blob. Deleting a missing blob is not an error.
+	Delete(key string) error
+}
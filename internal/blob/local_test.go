@@ -0,0 +1,34 @@
+package blob
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStore(t *testing.T) {
+	blobs, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	require.NoError(t, blobs.Put("photos/1/a.jpg", strings.NewReader("first")))
+	require.NoError(t, blobs.Put("photos/1/a.jpg", strings.NewReader("second")))
+
+	file, err := blobs.Open("photos/1/a.jpg")
+	require.NoError(t, err)
+	content, err := io.ReadAll(file)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	assert.Equal(t, "second", string(content))
+
+	require.NoError(t, blobs.Delete("photos/1/a.jpg"))
+	require.NoError(t, blobs.Delete("photos/1/a.jpg"))
+	_, err = blobs.Open("photos/1/a.jpg")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	for _, key := range []string{"", "../escape", "/etc/passwd", "photos/../../escape"} {
+		assert.Error(t, blobs.Put(key, strings.NewReader("x")), key)
+	}
+}
@@ -0,0 +1,174 @@
+package calories
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Values assumed when nothing better is known.
+const (
+	// DefaultBodyweightKg is used for users who never logged their bodyweight.
+	DefaultBodyweightKg = 70.0
+	// DefaultMET is general resistance training, for exercises and workout time
+	// that can't be attributed to anything more specific.
+	DefaultMET = 3.5
+
+	secondsPerRep      = 4
+	defaultSetSeconds  = 30
+	defaultRestSeconds = 90
+)
+
+// patternMETs are the MET values of the catalog's movement patterns, for exercises
+// that don't have a value of their own (custom exercises mostly).
+var patternMETs = map[string]float64{
+	"squat":           5.0,
+	"hinge":           5.0,
+	"lunge":           5.0,
+	"horizontal push": 5.0,
+	"vertical push":   5.0,
+	"horizontal pull": 5.0,
+	"vertical pull":   5.0,
+	"isolation":       3.5,
+	"core":            3.0,
+	"carry":           6.0,
+	"cardio":          7.0,
+}
+
+// activityMETs map words of a workout's title to the MET value of the activity, for
+// workouts without exercises such as an imported run or ride. A word matches whole
+// title words, or the start of one when it ends in "*" ("cycl*" matches "cycling").
+// The first match wins, so more specific words come first.
+var activityMETs = []struct {
+	word string
+	met  float64
+}{
+	{"jog*", 7.0},
+	{"run", 9.8},
+	{"runs", 9.8},
+	{"running", 9.8},
+	{"mountaineering", 8.0},
+	{"hike", 6.0},
+	{"hiking", 6.0},
+	{"walk", 3.5},
+	{"walking", 3.5},
+	{"ride", 7.5},
+	{"cycl*", 7.5},
+	{"bike", 7.5},
+	{"biking", 7.5},
+	{"swim*", 7.0},
+	{"row", 7.0},
+	{"rowing", 7.0},
+	{"ski", 7.0},
+	{"skiing", 7.0},
+	{"paddle", 5.0},
+	{"paddling", 5.0},
+	{"climb*", 8.0},
+	{"yoga", 2.5},
+	{"stretch*", 2.3},
+	{"cardio", 7.0},
+	{"hiit", 8.0},
+}
+
+// PatternMET returns the MET value of a movement pattern, or DefaultMET if it isn't known.
+func PatternMET(pattern string) float64 {
+	if met, ok := patternMETs[strings.ToLower(pattern)]; ok {
+		return met
+	}
+	return DefaultMET
+}
+
+// ActivityMET returns the MET value of the activity a workout title names, e.g. "Morning Run".
+func ActivityMET(title string) (float64, bool) {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for _, activity := range activityMETs {
+		prefix, isPrefix := strings.CutSuffix(activity.word, "*")
+		for _, word := range words {
+			if word == activity.word || isPrefix && strings.HasPrefix(word, prefix) {
+				return activity.met, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// Set is the part of a logged set the estimate needs.
+type Set struct {
+	Reps            *int
+	DurationSeconds *int
+	RestSeconds     *int
+	Completed       bool
+}
+
+// Entry is one exercise of the session with the MET value it is done at.
+type Entry struct {
+	MET  float64
+	Sets []Set
+}
+
+// Session is a workout to estimate. ActivityMET applies to the time of the workout the
+// entries don't account for; 0 means DefaultMET. BodyweightKg 0 means DefaultBodyweightKg.
+type Session struct {
+	DurationMinutes int
+	BodyweightKg    float64
+	ActivityMET     float64
+	Entries         []Entry
+}
+
+// Estimate returns the kilocalories burned in the session: MET × bodyweight (kg) × hours,
+// summed over the entries. The time of an entry is that of its completed sets: their
+// duration, or 4 seconds a rep, plus the rest logged after them (90 seconds after a rep
+// based set when none was logged). When the workout has a duration it wins: time left over
+// is spent at ActivityMET, and entries that add up to more are scaled down to fit.
+func Estimate(session Session) int {
+	bodyweight := session.BodyweightKg
+	if bodyweight <= 0 {
+		bodyweight = DefaultBodyweightKg
+	}
+	activityMET := session.ActivityMET
+	if activityMET <= 0 {
+		activityMET = DefaultMET
+	}
+
+	// MET-minutes and minutes of the entries
+	metMinutes, minutes := 0.0, 0.0
+	for _, entry := range session.Entries {
+		seconds := 0
+		for _, set := range entry.Sets {
+			if set.Completed {
+				seconds += setSeconds(set)
+			}
+		}
+		metMinutes += entry.MET * float64(seconds) / 60
+		minutes += float64(seconds) / 60
+	}
+
+	duration := float64(session.DurationMinutes)
+	switch {
+	case duration <= 0:
+	case minutes > duration:
+		metMinutes *= duration / minutes
+	default:
+		metMinutes += activityMET * (duration - minutes)
+	}
+
+	return int(math.Round(metMinutes * bodyweight / 60))
+}
+
+// setSeconds is how long a set and the rest after it took.
+func setSeconds(set Set) int {
+	work, rest := defaultSetSeconds, 0
+	switch {
+	case set.DurationSeconds != nil:
+		work = *set.DurationSeconds
+	case set.Reps != nil:
+		work = *set.Reps * secondsPerRep
+		rest = defaultRestSeconds
+	}
+	if set.RestSeconds != nil {
+		rest = *set.RestSeconds
+	}
+	return work + rest
+}
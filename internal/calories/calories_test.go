@@ -0,0 +1,105 @@
+package calories
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func reps(n int) Set {
+	return Set{Reps: &n, Completed: true}
+}
+
+func timed(seconds int) Set {
+	return Set{DurationSeconds: &seconds, Completed: true}
+}
+
+func TestEstimate(t *testing.T) {
+	// 3 sets of 10 reps: (40s + 90s rest) × 3 = 6.5 minutes
+	strength := Entry{MET: 6, Sets: []Set{reps(10), reps(10), reps(10)}}
+	skipped := reps(10)
+	skipped.Completed = false
+
+	tests := []struct {
+		name    string
+		session Session
+		want    int
+	}{
+		{
+			name:    "nothing to go on",
+			session: Session{},
+			want:    0,
+		},
+		{
+			name:    "duration only",
+			session: Session{DurationMinutes: 60, BodyweightKg: 80},
+			want:    280, // 3.5 × 80 × 1h
+		},
+		{
+			name:    "activity of the title",
+			session: Session{DurationMinutes: 30, BodyweightKg: 80, ActivityMET: 9.8},
+			want:    392,
+		},
+		{
+			name:    "sets without a duration",
+			session: Session{BodyweightKg: 80, Entries: []Entry{strength}},
+			want:    52, // 6 × 80 × 6.5/60
+		},
+		{
+			name:    "default bodyweight and skipped sets",
+			session: Session{Entries: []Entry{{MET: 6, Sets: []Set{reps(10), skipped}}}},
+			want:    15, // 6 × 70 × 130s
+		},
+		{
+			name:    "left over time at the activity met",
+			session: Session{DurationMinutes: 20, BodyweightKg: 80, Entries: []Entry{{MET: 9.8, Sets: []Set{timed(600)}}}},
+			want:    177, // (9.8 × 10 + 3.5 × 10) × 80 / 60
+		},
+		{
+			name:    "entries scaled down to the duration",
+			session: Session{DurationMinutes: 5, BodyweightKg: 80, Entries: []Entry{{MET: 9.8, Sets: []Set{timed(600)}}}},
+			want:    65,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Estimate(tt.session))
+		})
+	}
+}
+
+func TestActivityMET(t *testing.T) {
+	tests := []struct {
+		title   string
+		wantMET float64
+	}{
+		{title: "Morning Run", wantMET: 9.8},
+		{title: "Easy jog", wantMET: 7.0},
+		{title: "Jogging with the dog", wantMET: 7.0},
+		{title: "Cycling commute", wantMET: 7.5},
+		{title: "Row", wantMET: 7.0},
+		{title: "Cross-Country Ski", wantMET: 7.0},
+		{title: "Push Day"},
+		// Titles that only contain an activity word inside another word
+		{title: "Core & Crunches"},
+		{title: "Trunk work"},
+		{title: "Narrow Grip Bench"},
+		{title: "Throws"},
+		{title: "Skill session"},
+		{title: "Brunch walkthrough"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			met, ok := ActivityMET(tt.title)
+			assert.Equal(t, tt.wantMET != 0, ok)
+			assert.Equal(t, tt.wantMET, met)
+		})
+	}
+}
+
+func TestPatternMET(t *testing.T) {
+	assert.Equal(t, 5.0, PatternMET("Squat"))
+	assert.Equal(t, DefaultMET, PatternMET(""))
+}
@@ -0,0 +1,151 @@
+package consistency
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Day is the training done on one calendar day. Date is midnight UTC of that day,
+// the way Postgres DATE values are scanned, whatever timezone the day was taken in.
+type Day struct {
+	Date            time.Time
+	Workouts        int
+	DurationMinutes int
+	Tonnage         float64
+}
+
+// MarshalJSON writes the date as YYYY-MM-DD, the key a heatmap cell is drawn for.
+func (d Day) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Date            string  `json:"date"`
+		Workouts        int     `json:"workouts"`
+		DurationMinutes int     `json:"duration_minutes"`
+		Tonnage         float64 `json:"tonnage"`
+	}{d.Date.Format(time.DateOnly), d.Workouts, d.DurationMinutes, d.Tonnage})
+}
+
+// Streak is a run of training days or weeks. Length counts every day (week) from the
+// first to the last one trained, the allowed rest included; Active only the trained ones.
+// Start and End are empty when there is no streak.
+type Streak struct {
+	Length int    `json:"length"`
+	Active int    `json:"active"`
+	Start  string `json:"start,omitempty"`
+	End    string `json:"end,omitempty"`
+}
+
+// Date returns midnight UTC of the calendar day t falls on in its own location.
+func Date(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// Calendar returns one Day for every date of the year, with the training of days
+// and zeros for the rest, ready to be drawn as a heatmap. days must be in date order.
+func Calendar(year int, days []Day) []Day {
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, 0)
+
+	calendar := []Day{}
+	i := 0
+	for date := start; date.Before(end); date = date.AddDate(0, 0, 1) {
+		for i < len(days) && days[i].Date.Before(date) {
+			i++
+		}
+		if i < len(days) && days[i].Date.Equal(date) {
+			calendar = append(calendar, days[i])
+			continue
+		}
+		calendar = append(calendar, Day{Date: date})
+	}
+	return calendar
+}
+
+// DailyStreaks returns the current and the longest run of training days. Up to restDays
+// days without training may sit between two training days without breaking the run.
+// The current streak is the one still alive on today: today itself hasn't been missed
+// yet, so a streak whose last day was yesterday is current even with no rest allowed.
+// days must be in date order; days after today are ignored.
+func DailyStreaks(days []Day, today time.Time, restDays int) (current, longest Streak) {
+	dates := []time.Time{}
+	for _, day := range days {
+		if day.Workouts > 0 && !day.Date.After(today) {
+			dates = append(dates, day.Date)
+		}
+	}
+	return streaks(dates, today, restDays, 1)
+}
+
+// WeeklyStreaks returns the current and the longest run of training weeks. A week
+// counts once it has at least target workouts, and up to restWeeks weeks that don't
+// may sit between two that do. Weeks start on Monday. The current week never breaks
+// a streak, it is still being trained. days must be in date order.
+func WeeklyStreaks(days []Day, today time.Time, target, restWeeks int) (current, longest Streak) {
+	if target < 1 {
+		target = 1
+	}
+
+	weeks := []time.Time{}
+	workouts := 0
+	for i, day := range days {
+		if day.Date.After(today) {
+			break
+		}
+		week := WeekStart(day.Date)
+		workouts += day.Workouts
+		if i+1 < len(days) && !days[i+1].Date.After(today) && WeekStart(days[i+1].Date).Equal(week) {
+			continue
+		}
+		if workouts >= target {
+			weeks = append(weeks, week)
+		}
+		workouts = 0
+	}
+	return streaks(weeks, WeekStart(today), restWeeks, 7)
+}
+
+// WeekStart returns the Monday of the week date falls in.
+func WeekStart(date time.Time) time.Time {
+	offset := (int(date.Weekday()) + 6) % 7
+	return date.AddDate(0, 0, -offset)
+}
+
+// streaks walks the sorted periods (days or weeks of step days) and joins those at most
+// rest periods apart. now is the period in progress, which doesn't count as missed.
+func streaks(periods []time.Time, now time.Time, rest, step int) (current, longest Streak) {
+	if len(periods) == 0 {
+		return Streak{}, Streak{}
+	}
+	between := func(a, b time.Time) int {
+		return int(b.Sub(a).Hours()/24) / step
+	}
+	finish := func(start, end time.Time, active int) Streak {
+		return Streak{
+			Length: between(start, end) + 1,
+			Active: active,
+			Start:  start.Format(time.DateOnly),
+			End:    end.Format(time.DateOnly),
+		}
+	}
+
+	start, active := periods[0], 1
+	for i := 1; i < len(periods); i++ {
+		if between(periods[i-1], periods[i])-1 > rest {
+			if s := finish(start, periods[i-1], active); s.Length > longest.Length {
+				longest = s
+			}
+			start, active = periods[i], 0
+		}
+		active++
+	}
+
+	last := periods[len(periods)-1]
+	s := finish(start, last, active)
+	if s.Length > longest.Length {
+		longest = s
+	}
+	if between(last, now)-1 <= rest {
+		current = s
+	}
+	return current, longest
+}
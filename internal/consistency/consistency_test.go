@@ -0,0 +1,116 @@
+package consistency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func trained(dates ...string) []Day {
+	days := []Day{}
+	for _, d := range dates {
+		days = append(days, Day{Date: date(d), Workouts: 1})
+	}
+	return days
+}
+
+func TestDailyStreaks(t *testing.T) {
+	tests := []struct {
+		name        string
+		days        []Day
+		today       string
+		restDays    int
+		wantCurrent Streak
+		wantLongest Streak
+	}{
+		{
+			name:  "no workouts",
+			today: "2026-03-10",
+		},
+		{
+			name:        "trained today",
+			days:        trained("2026-03-08", "2026-03-09", "2026-03-10"),
+			today:       "2026-03-10",
+			wantCurrent: Streak{Length: 3, Active: 3, Start: "2026-03-08", End: "2026-03-10"},
+			wantLongest: Streak{Length: 3, Active: 3, Start: "2026-03-08", End: "2026-03-10"},
+		},
+		{
+			name:        "yesterday keeps it alive",
+			days:        trained("2026-03-08", "2026-03-09"),
+			today:       "2026-03-10",
+			wantCurrent: Streak{Length: 2, Active: 2, Start: "2026-03-08", End: "2026-03-09"},
+			wantLongest: Streak{Length: 2, Active: 2, Start: "2026-03-08", End: "2026-03-09"},
+		},
+		{
+			name:        "a missed day breaks it",
+			days:        trained("2026-03-01", "2026-03-02", "2026-03-03", "2026-03-07"),
+			today:       "2026-03-10",
+			wantLongest: Streak{Length: 3, Active: 3, Start: "2026-03-01", End: "2026-03-03"},
+		},
+		{
+			name:        "rest days allowed",
+			days:        trained("2026-03-01", "2026-03-03", "2026-03-05", "2026-03-08"),
+			today:       "2026-03-09",
+			restDays:    1,
+			wantCurrent: Streak{Length: 1, Active: 1, Start: "2026-03-08", End: "2026-03-08"},
+			wantLongest: Streak{Length: 5, Active: 3, Start: "2026-03-01", End: "2026-03-05"},
+		},
+		{
+			name:        "future days are ignored",
+			days:        trained("2026-03-09", "2026-03-12"),
+			today:       "2026-03-10",
+			wantCurrent: Streak{Length: 1, Active: 1, Start: "2026-03-09", End: "2026-03-09"},
+			wantLongest: Streak{Length: 1, Active: 1, Start: "2026-03-09", End: "2026-03-09"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current, longest := DailyStreaks(tt.days, date(tt.today), tt.restDays)
+			assert.Equal(t, tt.wantCurrent, current)
+			assert.Equal(t, tt.wantLongest, longest)
+		})
+	}
+}
+
+func TestWeeklyStreaks(t *testing.T) {
+	// Mondays: 2026-02-16, 02-23, 03-02, 03-09
+	days := trained("2026-02-17", "2026-02-19", "2026-02-24", "2026-02-26", "2026-03-03", "2026-03-10")
+
+	current, longest := WeeklyStreaks(days, date("2026-03-11"), 1, 0)
+	assert.Equal(t, Streak{Length: 4, Active: 4, Start: "2026-02-16", End: "2026-03-09"}, current)
+	assert.Equal(t, current, longest)
+
+	// Two workouts a week: the week of 03-02 falls short, the current one is still open
+	current, longest = WeeklyStreaks(days, date("2026-03-11"), 2, 0)
+	assert.Equal(t, Streak{}, current)
+	assert.Equal(t, Streak{Length: 2, Active: 2, Start: "2026-02-16", End: "2026-02-23"}, longest)
+
+	// One week off is allowed, and the open week of 03-09 doesn't count as missed
+	current, _ = WeeklyStreaks(days, date("2026-03-11"), 2, 1)
+	assert.Equal(t, Streak{Length: 2, Active: 2, Start: "2026-02-16", End: "2026-02-23"}, current)
+}
+
+func TestCalendar(t *testing.T) {
+	calendar := Calendar(2024, []Day{{Date: date("2024-02-29"), Workouts: 2, DurationMinutes: 90, Tonnage: 5400}})
+
+	assert.Len(t, calendar, 366)
+	assert.Equal(t, date("2024-01-01"), calendar[0].Date)
+	assert.Equal(t, 2, calendar[59].Workouts)
+	assert.Equal(t, 0, calendar[60].Workouts)
+	assert.Equal(t, date("2024-12-31"), calendar[365].Date)
+}
+
+func TestWeekStart(t *testing.T) {
+	assert.Equal(t, date("2026-03-09"), WeekStart(date("2026-03-09")))
+	assert.Equal(t, date("2026-03-09"), WeekStart(date("2026-03-15")))
+}
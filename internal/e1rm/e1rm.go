@@ -0,0 +1,68 @@
+package e1rm
+
+import (
+	"fmt"
+	"math"
+)
+
+// Formula is one of the estimated one-rep-max formulas users can pick from.
+type Formula string
+
+// Supported formulas. Epley is the default for new users.
+const (
+	Epley    Formula = "epley"
+	Brzycki  Formula = "brzycki"
+	Lombardi Formula = "lombardi"
+	Wathan   Formula = "wathan"
+
+	Default = Epley
+)
+
+// MaxReps is the highest rep count an estimate is made for.
+// All formulas drift apart quickly past ~12 reps, and Brzycki breaks down at 37.
+const MaxReps = 12
+
+// Formulas lists every supported formula, in the order they are documented.
+var Formulas = []Formula{Epley, Brzycki, Lombardi, Wathan}
+
+// Parse validates a formula name. An empty name returns the Default.
+func Parse(name string) (Formula, error) {
+	if name == "" {
+		return Default, nil
+	}
+
+	for _, formula := range Formulas {
+		if Formula(name) == formula {
+			return formula, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown e1rm formula %q, must be one of epley, brzycki, lombardi, wathan", name)
+}
+
+// Estimate returns the estimated one rep max for weight lifted for reps, rounded to 0.1.
+// A single is returned as is so a real 1RM is never inflated, and sets outside 1..MaxReps
+// or without weight return 0.
+func (f Formula) Estimate(weight float64, reps int) float64 {
+	if weight <= 0 || reps < 1 || reps > MaxReps {
+		return 0
+	}
+	if reps == 1 {
+		return weight
+	}
+
+	r := float64(reps)
+	var estimate float64
+	switch f {
+	case Brzycki:
+		estimate = weight * 36 / (37 - r)
+	case Lombardi:
+		estimate = weight * math.Pow(r, 0.10)
+	case Wathan:
+		estimate = 100 * weight / (48.8 + 53.8*math.Exp(-0.075*r))
+	default:
+		estimate = weight * (1 + r/30)
+	}
+
+	return math.Round(estimate*10) / 10
+}
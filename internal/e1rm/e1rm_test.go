@@ -0,0 +1,45 @@
+package e1rm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		name    string
+		formula Formula
+		weight  float64
+		reps    int
+		want    float64
+	}{
+		{name: "epley", formula: Epley, weight: 100, reps: 5, want: 116.7},
+		{name: "brzycki", formula: Brzycki, weight: 100, reps: 5, want: 112.5},
+		{name: "lombardi", formula: Lombardi, weight: 100, reps: 5, want: 117.5},
+		{name: "wathan", formula: Wathan, weight: 100, reps: 5, want: 116.6},
+		{name: "single is the 1RM", formula: Wathan, weight: 140, reps: 1, want: 140},
+		{name: "too many reps", formula: Epley, weight: 50, reps: 20, want: 0},
+		{name: "bodyweight set", formula: Epley, weight: 0, reps: 10, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.formula.Estimate(tt.weight, tt.reps))
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	formula, err := Parse("")
+	require.NoError(t, err)
+	assert.Equal(t, Default, formula)
+
+	formula, err = Parse("brzycki")
+	require.NoError(t, err)
+	assert.Equal(t, Brzycki, formula)
+
+	_, err = Parse("mayhew")
+	assert.Error(t, err)
+}
@@ -0,0 +1,324 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/store"
+)
+
+// Formats accepted by Write.
+const (
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+	FormatZip    = "zip"
+)
+
+// Source is the data of one user. Workouts streams every workout to fn, oldest first,
+// like WorkoutStore.EachWorkout; it is called once per table for the zip bundle.
+type Source struct {
+	Profile  *store.User
+	Workouts func(fn func(*store.Workout) error) error
+}
+
+// ContentType returns the media type of a format, and false if the format isn't known.
+func ContentType(format string) (string, bool) {
+	switch format {
+	case FormatJSON:
+		return "application/json", true
+	case FormatNDJSON:
+		return "application/x-ndjson", true
+	case FormatCSV:
+		return "text/csv; charset=utf-8", true
+	case FormatZip:
+		return "application/zip", true
+	}
+	return "", false
+}
+
+// Write streams the export in the given format. Every workout is encoded and written
+// as soon as it is read, so nothing but the current batch of workouts is held in memory.
+func Write(w io.Writer, format string, src Source) error {
+	buffered := bufio.NewWriter(w)
+
+	var err error
+	switch format {
+	case FormatJSON:
+		err = writeJSON(buffered, src)
+	case FormatNDJSON:
+		err = writeNDJSON(buffered, src)
+	case FormatCSV:
+		err = writeSetsCSV(buffered, src)
+	case FormatZip:
+		err = writeZip(buffered, src)
+	default:
+		return fmt.Errorf("unknown export format %q", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	return buffered.Flush()
+}
+
+// writeJSON writes one document: {"profile": {...}, "workouts": [...]}.
+// The workouts array is written element by element instead of marshalling the whole slice.
+func writeJSON(w io.Writer, src Source) error {
+	profile, err := json.Marshal(src.Profile)
+	if err != nil {
+		return err
+	}
+	if _, err = fmt.Fprintf(w, "{\"profile\":%s,\"workouts\":[", profile); err != nil {
+		return err
+	}
+
+	first := true
+	err = src.Workouts(func(workout *store.Workout) error {
+		encoded, err := json.Marshal(workout)
+		if err != nil {
+			return err
+		}
+		if !first {
+			if _, err = io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+		_, err = w.Write(encoded)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "]}\n")
+	return err
+}
+
+// ndjsonLine is one line of an NDJSON export.
+type ndjsonLine struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// writeNDJSON writes one JSON object per line: the profile first, then one line per workout.
+func writeNDJSON(w io.Writer, src Source) error {
+	encoder := json.NewEncoder(w)
+
+	err := encoder.Encode(ndjsonLine{Type: "profile", Data: src.Profile})
+	if err != nil {
+		return err
+	}
+
+	return src.Workouts(func(workout *store.Workout) error {
+		return encoder.Encode(ndjsonLine{Type: "workout", Data: workout})
+	})
+}
+
+// setColumns is the header of the flat CSV export: one row per set, repeating its workout and entry.
+var setColumns = []string{
+	"workout_id", "workout_title", "performed_at", "duration_minutes", "calories_burned",
+	"entry_id", "exercise_id", "exercise_name", "order_index", "group", "entry_notes",
+	"set_number", "set_type", "reps", "duration_seconds", "weight", "rpe", "completed",
+	"rest_seconds", "prescribed_rest_seconds",
+}
+
+// writeSetsCSV writes the flat CSV export, which spreadsheets can open directly.
+// A workout without entries still gets a row, with the entry and set columns empty.
+func writeSetsCSV(w io.Writer, src Source) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(setColumns); err != nil {
+		return err
+	}
+
+	err := src.Workouts(func(workout *store.Workout) error {
+		head := []string{
+			strconv.Itoa(workout.ID), workout.Title, formatTime(&workout.PerformedAt),
+			strconv.Itoa(workout.DurationMinutes), strconv.Itoa(workout.CaloriesBurned),
+		}
+		if len(workout.Entries) == 0 {
+			return writer.Write(append(head, make([]string, len(setColumns)-len(head))...))
+		}
+
+		for _, entry := range workout.Entries {
+			entryColumns := []string{
+				strconv.Itoa(entry.ID), formatInt(entry.ExerciseID), entry.ExerciseName,
+				strconv.Itoa(entry.OrderIndex), entry.Group, entry.Notes,
+			}
+			for _, set := range entry.Sets {
+				row := make([]string, 0, len(setColumns))
+				row = append(row, head...)
+				row = append(row, entryColumns...)
+				row = append(row, setRow(set)...)
+				if err := writer.Write(row); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// setRow formats the columns of a set shared by the flat export and sets.csv.
+func setRow(set store.WorkoutSet) []string {
+	return []string{
+		strconv.Itoa(set.SetNumber), set.SetType, formatInt(set.Reps), formatInt(set.DurationSeconds),
+		formatFloat(set.Weight), formatFloat(set.RPE), strconv.FormatBool(set.Completed),
+		formatInt(set.RestSeconds), formatInt(set.PrescribedRestSeconds),
+	}
+}
+
+// zipTable is one CSV file of the zip bundle; row returns the rows of one workout.
+type zipTable struct {
+	name    string
+	columns []string
+	rows    func(workout *store.Workout) [][]string
+}
+
+// zipTables are the tables of the bundle, each a pass over the workouts.
+var zipTables = []zipTable{
+	{
+		name: "workouts.csv",
+		columns: []string{"id", "title", "description", "performed_at", "started_at", "ended_at",
+			"duration_minutes", "calories_burned", "calories_source", "session_rpe", "program_day_id", "created_at", "updated_at"},
+		rows: func(workout *store.Workout) [][]string {
+			return [][]string{{
+				strconv.Itoa(workout.ID), workout.Title, workout.Description, formatTime(&workout.PerformedAt),
+				formatTime(workout.StartedAt), formatTime(workout.EndedAt), strconv.Itoa(workout.DurationMinutes),
+				strconv.Itoa(workout.CaloriesBurned), workout.CaloriesSource, formatFloat(workout.SessionRPE), formatInt(workout.ProgramDayID),
+				formatTime(&workout.CreatedAt), formatTime(&workout.UpdatedAt),
+			}}
+		},
+	},
+	{
+		name:    "groups.csv",
+		columns: []string{"id", "workout_id", "label", "type", "rounds", "rest_between_rounds_seconds"},
+		rows: func(workout *store.Workout) [][]string {
+			rows := make([][]string, 0, len(workout.Groups))
+			for _, group := range workout.Groups {
+				rows = append(rows, []string{
+					strconv.Itoa(group.ID), strconv.Itoa(workout.ID), group.Label, group.Type,
+					formatInt(group.Rounds), formatInt(group.RestBetweenRoundsSeconds),
+				})
+			}
+			return rows
+		},
+	},
+	{
+		name:    "entries.csv",
+		columns: []string{"id", "workout_id", "exercise_id", "exercise_name", "order_index", "group", "notes"},
+		rows: func(workout *store.Workout) [][]string {
+			rows := make([][]string, 0, len(workout.Entries))
+			for _, entry := range workout.Entries {
+				rows = append(rows, []string{
+					strconv.Itoa(entry.ID), strconv.Itoa(workout.ID), formatInt(entry.ExerciseID), entry.ExerciseName,
+					strconv.Itoa(entry.OrderIndex), entry.Group, entry.Notes,
+				})
+			}
+			return rows
+		},
+	},
+	{
+		name: "sets.csv",
+		columns: []string{"id", "entry_id", "set_number", "set_type", "reps", "duration_seconds", "weight", "rpe",
+			"completed", "rest_seconds", "prescribed_rest_seconds"},
+		rows: func(workout *store.Workout) [][]string {
+			var rows [][]string
+			for _, entry := range workout.Entries {
+				for _, set := range entry.Sets {
+					row := []string{strconv.Itoa(set.ID), strconv.Itoa(entry.ID)}
+					rows = append(rows, append(row, setRow(set)...))
+				}
+			}
+			return rows
+		},
+	},
+}
+
+// writeZip writes the bundle: profile.csv and one CSV per table, linked by their id columns.
+// Zip entries are written one after the other, so the workouts are streamed once per table.
+func writeZip(w io.Writer, src Source) error {
+	archive := zip.NewWriter(w)
+
+	file, err := archive.Create("profile.csv")
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(file)
+	profile := src.Profile
+	err = writer.WriteAll([][]string{
+		{"id", "username", "email", "bio", "e1rm_formula", "max_heart_rate", "timezone", "created_at"},
+		{strconv.Itoa(profile.ID), profile.Username, profile.Email, profile.Bio, profile.E1RMFormula,
+			formatInt(profile.MaxHeartRate), profile.Timezone, formatTime(&profile.CreatedAt)},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, table := range zipTables {
+		file, err := archive.Create(table.name)
+		if err != nil {
+			return err
+		}
+		writer := csv.NewWriter(file)
+		if err = writer.Write(table.columns); err != nil {
+			return err
+		}
+
+		err = src.Workouts(func(workout *store.Workout) error {
+			for _, row := range table.rows(workout) {
+				if err := writer.Write(row); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		writer.Flush()
+		if err = writer.Error(); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// formatTime formats an optional time as RFC 3339 in UTC; nil is an empty cell.
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// formatInt formats an optional number; nil is an empty cell.
+func formatInt(n *int) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.Itoa(*n)
+}
+
+// formatFloat formats an optional decimal without trailing zeros; nil is an empty cell.
+func formatFloat(n *float64) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*n, 'f', -1, 64)
+}
@@ -0,0 +1,158 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/store"
+)
+
+func testSource(passes *int) Source {
+	performedAt := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	reps, weight := 5, 100.0
+	plank := 60
+
+	workouts := []store.Workout{
+		{
+			ID: 1, UserID: 7, Title: "Push, heavy", PerformedAt: performedAt,
+			Groups: []store.EntryGroup{{ID: 3, Label: "A", Type: store.GroupTypeSuperset}},
+			Entries: []store.WorkoutEntry{
+				{ID: 10, ExerciseName: "Bench Press", OrderIndex: 1, Group: "A", Sets: []store.WorkoutSet{
+					{ID: 100, SetNumber: 1, Reps: &reps, Weight: &weight, SetType: store.SetTypeWorking, Completed: true},
+					{ID: 101, SetNumber: 2, Reps: &reps, Weight: &weight, SetType: store.SetTypeWorking, Completed: false},
+				}},
+				{ID: 11, ExerciseName: "Plank", OrderIndex: 2, Group: "A", Sets: []store.WorkoutSet{
+					{ID: 102, SetNumber: 1, DurationSeconds: &plank, SetType: store.SetTypeWorking, Completed: true},
+				}},
+			},
+		},
+		{ID: 2, UserID: 7, Title: "Walk", PerformedAt: performedAt.Add(24 * time.Hour), Entries: []store.WorkoutEntry{}},
+	}
+
+	return Source{
+		Profile: &store.User{ID: 7, Username: "ada", Email: "ada@example.com"},
+		Workouts: func(fn func(*store.Workout) error) error {
+			*passes++
+			for i := range workouts {
+				if err := fn(&workouts[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var passes int
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatJSON, testSource(&passes)))
+
+	var doc struct {
+		Profile  store.User      `json:"profile"`
+		Workouts []store.Workout `json:"workouts"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, "ada", doc.Profile.Username)
+	require.Len(t, doc.Workouts, 2)
+	assert.Len(t, doc.Workouts[0].Entries[0].Sets, 2)
+	assert.Equal(t, 1, passes)
+	assert.NotContains(t, buf.String(), "password")
+}
+
+func TestWriteNDJSON(t *testing.T) {
+	var passes int
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatNDJSON, testSource(&passes)))
+
+	var types []string
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var line struct {
+			Type string          `json:"type"`
+			Data json.RawMessage `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		types = append(types, line.Type)
+	}
+	assert.Equal(t, []string{"profile", "workout", "workout"}, types)
+}
+
+func TestWriteCSV(t *testing.T) {
+	var passes int
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatCSV, testSource(&passes)))
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 5, "header, three sets and the workout without entries")
+	assert.Equal(t, setColumns, rows[0])
+	assert.Equal(t, "Push, heavy", rows[1][1])
+	assert.Equal(t, "100", rows[1][15])
+	assert.Equal(t, "false", rows[2][17])
+	assert.Equal(t, "60", rows[3][14])
+	assert.Equal(t, "Walk", rows[4][1])
+	assert.Equal(t, "", rows[4][7])
+}
+
+func TestWriteZip(t *testing.T) {
+	var passes int
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, FormatZip, testSource(&passes)))
+	assert.Equal(t, len(zipTables), passes)
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	counts := map[string]int{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		require.NoError(t, err)
+		rows, err := csv.NewReader(reader).ReadAll()
+		require.NoError(t, err)
+		reader.Close()
+		counts[file.Name] = len(rows) - 1
+	}
+	assert.Equal(t, map[string]int{
+		"profile.csv":  1,
+		"workouts.csv": 2,
+		"groups.csv":   1,
+		"entries.csv":  2,
+		"sets.csv":     3,
+	}, counts)
+}
+
+func TestWriteStopsOnError(t *testing.T) {
+	failing := errors.New("connection lost")
+	src := Source{
+		Profile: &store.User{},
+		Workouts: func(fn func(*store.Workout) error) error {
+			return failing
+		},
+	}
+
+	for _, format := range []string{FormatJSON, FormatNDJSON, FormatCSV, FormatZip} {
+		assert.ErrorIs(t, Write(io.Discard, format, src), failing, format)
+	}
+	assert.Error(t, Write(io.Discard, "xml", src))
+}
+
+func TestContentType(t *testing.T) {
+	contentType, ok := ContentType(FormatNDJSON)
+	assert.True(t, ok)
+	assert.True(t, strings.HasPrefix(contentType, "application/"))
+
+	_, ok = ContentType("xml")
+	assert.False(t, ok)
+}
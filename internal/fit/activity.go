@@ -0,0 +1,264 @@
+package fit
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// fitEpoch is the zero of FIT timestamps, which count seconds since 1989-12-31 00:00 UTC.
+var fitEpoch = time.Date(1989, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// semicircle converts FIT positions (sint32 semicircles) to degrees.
+const semicircle = 180.0 / (1 << 31)
+
+// Field numbers of the session and lap messages.
+const (
+	fieldStartTime      = 2
+	fieldSport          = 5
+	fieldSubSport       = 6
+	fieldElapsedTime    = 7
+	fieldTimerTime      = 8
+	fieldTotalDistance  = 9
+	fieldTotalCalories  = 11
+	fieldSessionAvgHR   = 16
+	fieldSessionMaxHR   = 17
+	fieldLapAvgHR       = 15
+	fieldLapMaxHR       = 16
+	fieldSessionAscent  = 22
+	fieldSessionDescent = 23
+)
+
+// Field numbers of the record message.
+const (
+	fieldPositionLat      = 0
+	fieldPositionLong     = 1
+	fieldAltitude         = 2
+	fieldHeartRate        = 3
+	fieldDistance         = 5
+	fieldEnhancedAltitude = 78
+)
+
+// Field numbers of the set message; its timestamp is 254 rather than the usual 253.
+const (
+	fieldSetTimestamp = 254
+	fieldSetDuration  = 0
+	fieldSetReps      = 3
+	fieldSetWeight    = 4
+	fieldSetType      = 5
+	fieldSetStartTime = 6
+	fieldSetCategory  = 7
+)
+
+// Values of the set_type field.
+const (
+	setTypeRest   = 0
+	setTypeActive = 1
+)
+
+// Session is the summary of an activity, or of one sport of a multisport activity.
+type Session struct {
+	StartTime      time.Time
+	Sport          uint8
+	SubSport       uint8
+	ElapsedSeconds float64
+	TimerSeconds   float64
+	DistanceMeters *float64
+	Calories       *int
+	AvgHeartRate   *int
+	MaxHeartRate   *int
+	AscentMeters   *int
+	DescentMeters  *int
+}
+
+// Lap is the summary of one lap; devices write at least one per session.
+type Lap struct {
+	StartTime      time.Time
+	ElapsedSeconds float64
+	TimerSeconds   float64
+	DistanceMeters *float64
+	Calories       *int
+	AvgHeartRate   *int
+	MaxHeartRate   *int
+}
+
+// Record is one sample of the recording, usually taken every second. DistanceMeters is
+// the distance covered since the start, also written by indoor devices without a position.
+type Record struct {
+	Time           time.Time
+	Lat            *float64
+	Lon            *float64
+	Altitude       *float64
+	HeartRate      *int
+	DistanceMeters *float64
+}
+
+// Set is one set of a strength activity, or the rest between two sets.
+// Category is the Garmin exercise category, e.g. 0 for bench press.
+type Set struct {
+	StartTime       time.Time
+	Rest            bool
+	DurationSeconds *float64
+	Reps            *int
+	WeightKg        *float64
+	Category        *uint16
+}
+
+// Activity holds the messages of a FIT activity file we use.
+type Activity struct {
+	Sessions []Session
+	Laps     []Lap
+	Records  []Record
+	Sets     []Set
+}
+
+// Parse decodes a FIT file and extracts its sessions, laps, records and sets.
+// Returns an error if the file isn't valid FIT, or has none of these messages.
+func Parse(data []byte) (*Activity, error) {
+	messages, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	activity := &Activity{}
+	for _, message := range messages {
+		switch message.Global {
+		case MesgSession:
+			activity.Sessions = append(activity.Sessions, parseSession(message))
+		case MesgLap:
+			activity.Laps = append(activity.Laps, parseLap(message))
+		case MesgRecord:
+			if record, ok := parseRecord(message); ok {
+				activity.Records = append(activity.Records, record)
+			}
+		case MesgSet:
+			activity.Sets = append(activity.Sets, parseSet(message))
+		}
+	}
+
+	if len(activity.Sessions) == 0 && len(activity.Laps) == 0 && len(activity.Records) == 0 {
+		return nil, errors.New("the FIT file is not an activity")
+	}
+	return activity, nil
+}
+
+func parseSession(message Message) Session {
+	session := Session{
+		StartTime:      timeField(message, fieldStartTime),
+		ElapsedSeconds: scaled(message, fieldElapsedTime, 1000),
+		TimerSeconds:   scaled(message, fieldTimerTime, 1000),
+		DistanceMeters: scaledPtr(message, fieldTotalDistance, 100),
+		Calories:       intField(message, fieldTotalCalories),
+		AvgHeartRate:   intField(message, fieldSessionAvgHR),
+		MaxHeartRate:   intField(message, fieldSessionMaxHR),
+		AscentMeters:   intField(message, fieldSessionAscent),
+		DescentMeters:  intField(message, fieldSessionDescent),
+	}
+	if sport, ok := message.Uint(fieldSport); ok {
+		session.Sport = uint8(sport)
+	}
+	if subSport, ok := message.Uint(fieldSubSport); ok {
+		session.SubSport = uint8(subSport)
+	}
+	return session
+}
+
+func parseLap(message Message) Lap {
+	return Lap{
+		StartTime:      timeField(message, fieldStartTime),
+		ElapsedSeconds: scaled(message, fieldElapsedTime, 1000),
+		TimerSeconds:   scaled(message, fieldTimerTime, 1000),
+		DistanceMeters: scaledPtr(message, fieldTotalDistance, 100),
+		Calories:       intField(message, fieldTotalCalories),
+		AvgHeartRate:   intField(message, fieldLapAvgHR),
+		MaxHeartRate:   intField(message, fieldLapMaxHR),
+	}
+}
+
+// parseRecord reads a record; records without a timestamp are useless and reported as not ok.
+func parseRecord(message Message) (Record, bool) {
+	record := Record{Time: timeField(message, fieldTimestamp)}
+	if record.Time.IsZero() {
+		return record, false
+	}
+
+	lat, latOK := message.Int(fieldPositionLat)
+	lon, lonOK := message.Int(fieldPositionLong)
+	if latOK && lonOK {
+		latitude, longitude := float64(lat)*semicircle, float64(lon)*semicircle
+		record.Lat, record.Lon = &latitude, &longitude
+	}
+
+	// Altitude is stored with a scale of 5 and an offset of 500 m
+	if altitude := scaledPtr(message, fieldEnhancedAltitude, 5); altitude != nil {
+		record.Altitude = offsetAltitude(*altitude)
+	} else if altitude := scaledPtr(message, fieldAltitude, 5); altitude != nil {
+		record.Altitude = offsetAltitude(*altitude)
+	}
+
+	record.HeartRate = intField(message, fieldHeartRate)
+	record.DistanceMeters = scaledPtr(message, fieldDistance, 100)
+	return record, true
+}
+
+func parseSet(message Message) Set {
+	set := Set{
+		StartTime:       timeField(message, fieldSetStartTime),
+		DurationSeconds: scaledPtr(message, fieldSetDuration, 1000),
+		Reps:            intField(message, fieldSetReps),
+		WeightKg:        scaledPtr(message, fieldSetWeight, 16),
+	}
+	if set.StartTime.IsZero() {
+		set.StartTime = timeField(message, fieldSetTimestamp)
+	}
+	if setType, ok := message.Uint(fieldSetType); ok {
+		set.Rest = setType == setTypeRest
+	}
+	if category, ok := message.Uint(fieldSetCategory); ok {
+		value := uint16(category)
+		set.Category = &value
+	}
+	return set
+}
+
+// timeField reads a date_time field; the zero time if it is missing.
+func timeField(message Message, num byte) time.Time {
+	seconds, ok := message.Uint(num)
+	if !ok {
+		return time.Time{}
+	}
+	return fitEpoch.Add(time.Duration(seconds) * time.Second)
+}
+
+// intField reads an integer field; nil if it is missing.
+func intField(message Message, num byte) *int {
+	value, ok := message.Uint(num)
+	if !ok {
+		return nil
+	}
+	n := int(value)
+	return &n
+}
+
+// scaledPtr reads a field stored as an integer multiplied by scale; nil if it is missing.
+func scaledPtr(message Message, num byte, scale float64) *float64 {
+	value, ok := message.Uint(num)
+	if !ok {
+		return nil
+	}
+	scaledValue := float64(value) / scale
+	return &scaledValue
+}
+
+// scaled is scaledPtr with 0 for a missing field.
+func scaled(message Message, num byte, scale float64) float64 {
+	if value := scaledPtr(message, num, scale); value != nil {
+		return *value
+	}
+	return 0
+}
+
+func offsetAltitude(altitude float64) *float64 {
+	value := math.Round((altitude-500)*10) / 10
+	return &value
+}
@@ -0,0 +1,247 @@
+package fit
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/store"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/track"
+)
+
+// Sport and sub sport values used for the workout title.
+const (
+	subSportStrengthTraining = 20
+	subSportCardioTraining   = 26
+)
+
+var sportTitles = map[uint8]string{
+	1:  "Run",
+	2:  "Ride",
+	4:  "Workout",
+	5:  "Swim",
+	10: "Training",
+	11: "Walk",
+	12: "Cross-Country Ski",
+	13: "Ski",
+	14: "Snowboard",
+	15: "Row",
+	16: "Mountaineering",
+	17: "Hike",
+	18: "Multisport",
+	19: "Paddle",
+}
+
+// categoryNames maps Garmin exercise categories to exercise names. The names are the ones
+// of the built-in catalog where it has a matching exercise, so sets get linked to it;
+// the subtype (e.g. incline or dumbbell bench press) isn't used.
+var categoryNames = map[uint16]string{
+	0:  "Bench Press",
+	1:  "Calf Raise",
+	2:  "Cardio",
+	3:  "Farmer's Walk",
+	4:  "Chop",
+	5:  "Core",
+	6:  "Crunch",
+	7:  "Barbell Curl",
+	8:  "Deadlift",
+	9:  "Flye",
+	10: "Hip Thrust",
+	11: "Hip Stability",
+	12: "Kettlebell Swing",
+	13: "Hyperextension",
+	14: "Lateral Raise",
+	15: "Leg Curl",
+	16: "Hanging Leg Raise",
+	17: "Lunge",
+	18: "Olympic Lift",
+	19: "Plank",
+	20: "Plyometrics",
+	21: "Pull Up",
+	22: "Push Up",
+	23: "Barbell Row",
+	24: "Overhead Press",
+	25: "Shoulder Stability",
+	26: "Shrug",
+	27: "Sit Up",
+	28: "Back Squat",
+	29: "Total Body",
+	30: "Triceps Extension",
+	31: "Warm Up",
+	32: "Running",
+}
+
+// unknownExercise names the sets the watch didn't identify.
+const unknownExercise = "Unknown Exercise"
+
+// Title names the workout after its sport, e.g. "Run" or "Strength Training".
+func (a *Activity) Title() string {
+	if len(a.Sessions) == 0 {
+		if len(a.Sets) > 0 {
+			return "Strength Training"
+		}
+		return "Workout"
+	}
+	if len(a.Sessions) > 1 {
+		return "Multisport"
+	}
+
+	session := a.Sessions[0]
+	switch session.SubSport {
+	case subSportStrengthTraining:
+		return "Strength Training"
+	case subSportCardioTraining:
+		return "Cardio Training"
+	}
+	if title, ok := sportTitles[session.Sport]; ok {
+		return title
+	}
+	return "Workout"
+}
+
+// Workout maps the activity to a workout, ready for WorkoutStore.CreateWorkout once the
+// owner is set. The times and calories come from the sessions, or from the laps and records
+// when the device didn't write a session. Active sets become entries, one per exercise in
+// the order they were first done; a rest set becomes the rest after the set before it.
+func (a *Activity) Workout() *store.Workout {
+	startedAt, elapsed := a.span()
+	endedAt := startedAt.Add(elapsed)
+
+	workout := &store.Workout{
+		Title:       a.Title(),
+		PerformedAt: startedAt,
+		StartedAt:   &startedAt,
+		EndedAt:     &endedAt,
+		Entries:     []store.WorkoutEntry{},
+	}
+	if len(a.Sessions) > 0 {
+		for _, session := range a.Sessions {
+			if session.Calories != nil {
+				workout.CaloriesBurned += *session.Calories
+			}
+		}
+	} else {
+		for _, lap := range a.Laps {
+			if lap.Calories != nil {
+				workout.CaloriesBurned += *lap.Calories
+			}
+		}
+	}
+
+	entryIndex := map[string]int{}
+	// The last active set, so a rest that follows can be recorded on it
+	var lastSet *store.WorkoutSet
+	for _, set := range a.Sets {
+		if set.Rest {
+			if lastSet != nil && set.DurationSeconds != nil {
+				rest := int(math.Round(*set.DurationSeconds))
+				lastSet.RestSeconds = &rest
+			}
+			continue
+		}
+
+		workoutSet, ok := convertSet(set)
+		if !ok {
+			continue
+		}
+
+		name := unknownExercise
+		if set.Category != nil {
+			if categoryName, found := categoryNames[*set.Category]; found {
+				name = categoryName
+			}
+		}
+		i, found := entryIndex[name]
+		if !found {
+			i = len(workout.Entries)
+			entryIndex[name] = i
+			workout.Entries = append(workout.Entries, store.WorkoutEntry{ExerciseName: name, OrderIndex: i + 1})
+		}
+
+		entry := &workout.Entries[i]
+		workoutSet.SetNumber = len(entry.Sets) + 1
+		entry.Sets = append(entry.Sets, workoutSet)
+		lastSet = &entry.Sets[len(entry.Sets)-1]
+	}
+
+	return workout
+}
+
+// convertSet maps an active set. Sets with neither reps nor a duration are skipped;
+// the duration is only kept for timed sets, like a plank, and a weight of 0 is bodyweight.
+func convertSet(set Set) (store.WorkoutSet, bool) {
+	workoutSet := store.WorkoutSet{SetType: store.SetTypeWorking, Completed: true}
+
+	if set.Reps != nil && *set.Reps > 0 {
+		reps := *set.Reps
+		workoutSet.Reps = &reps
+	} else if set.DurationSeconds != nil && *set.DurationSeconds >= 1 {
+		duration := int(math.Round(*set.DurationSeconds))
+		workoutSet.DurationSeconds = &duration
+	} else {
+		return workoutSet, false
+	}
+
+	if set.WeightKg != nil && *set.WeightKg > 0 {
+		weight := math.Round(*set.WeightKg*100) / 100
+		workoutSet.Weight = &weight
+	}
+	return workoutSet, true
+}
+
+// Track returns the records as a track, or nil when there are fewer than two,
+// e.g. a strength activity recorded without heart rate.
+func (a *Activity) Track() *track.Track {
+	if len(a.Records) < 2 {
+		return nil
+	}
+
+	points := make([]track.Point, len(a.Records))
+	for i, record := range a.Records {
+		points[i] = track.Point{
+			Time:           record.Time,
+			Lat:            record.Lat,
+			Lon:            record.Lon,
+			Elevation:      record.Altitude,
+			HeartRate:      record.HeartRate,
+			DistanceMeters: record.DistanceMeters,
+		}
+	}
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].Time.Before(points[j].Time)
+	})
+
+	return &track.Track{Format: track.FormatFIT, Name: a.Title(), Points: points}
+}
+
+// span returns when the activity started and how long it lasted.
+func (a *Activity) span() (time.Time, time.Duration) {
+	var start time.Time
+	var elapsed float64
+
+	switch {
+	case len(a.Sessions) > 0:
+		start = a.Sessions[0].StartTime
+		for _, session := range a.Sessions {
+			elapsed += session.ElapsedSeconds
+		}
+	case len(a.Laps) > 0:
+		start = a.Laps[0].StartTime
+		for _, lap := range a.Laps {
+			elapsed += lap.ElapsedSeconds
+		}
+	}
+
+	// Fall back on the records for devices that leave the summaries incomplete
+	if len(a.Records) > 0 {
+		first, last := a.Records[0].Time, a.Records[len(a.Records)-1].Time
+		if start.IsZero() {
+			start = first
+		}
+		if elapsed == 0 {
+			elapsed = last.Sub(first).Seconds()
+		}
+	}
+
+	return start.UTC(), time.Duration(math.Round(elapsed)) * time.Second
+}
@@ -0,0 +1,360 @@
+package fit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// A FIT file is a 12 or 14 byte header, a stream of records and a CRC over both.
+// Each record is either a definition message, which describes the layout of a local
+// message type (which global message it is, which fields, their sizes and base types),
+// or a data message laid out according to the last definition of its local type.
+// See the FIT protocol description in the Garmin FIT SDK.
+
+// Global message numbers of the messages we read.
+const (
+	MesgSession = 18
+	MesgLap     = 19
+	MesgRecord  = 20
+	MesgSet     = 225
+)
+
+// fieldTimestamp is the timestamp field, shared by most messages.
+const fieldTimestamp = 253
+
+var (
+	// ErrNotFIT is returned for data that doesn't start with a FIT header.
+	ErrNotFIT = errors.New("the file is not a FIT file")
+	// ErrCorrupt is returned when the file is truncated or its checksum doesn't match.
+	ErrCorrupt = errors.New("the FIT file is truncated or corrupted")
+)
+
+// Base types, as found in the low 5 bits of a field definition's base type byte.
+const (
+	baseEnum    = 0x00
+	baseSint8   = 0x01
+	baseUint8   = 0x02
+	baseSint16  = 0x03
+	baseUint16  = 0x04
+	baseSint32  = 0x05
+	baseUint32  = 0x06
+	baseString  = 0x07
+	baseFloat32 = 0x08
+	baseFloat64 = 0x09
+	baseUint8z  = 0x0A
+	baseUint16z = 0x0B
+	baseUint32z = 0x0C
+	baseByte    = 0x0D
+	baseSint64  = 0x0E
+	baseUint64  = 0x0F
+	baseUint64z = 0x10
+)
+
+// baseSizes is the size in bytes of one value of each base type.
+var baseSizes = map[byte]int{
+	baseEnum: 1, baseSint8: 1, baseUint8: 1, baseSint16: 2, baseUint16: 2, baseSint32: 4, baseUint32: 4,
+	baseString: 1, baseFloat32: 4, baseFloat64: 8, baseUint8z: 1, baseUint16z: 2, baseUint32z: 4,
+	baseByte: 1, baseSint64: 8, baseUint64: 8, baseUint64z: 8,
+}
+
+// Message is a decoded data message. Field values keep their raw bytes and are
+// read through Uint and Int, which know the invalid value of each base type.
+type Message struct {
+	Global uint16
+	fields map[byte]fieldValue
+}
+
+type fieldValue struct {
+	baseType  byte
+	bigEndian bool
+	data      []byte
+}
+
+// Uint returns the first value of an unsigned or enum field.
+// ok is false if the message doesn't have the field or it holds the invalid value.
+func (m Message) Uint(num byte) (value uint64, ok bool) {
+	values := m.Uints(num)
+	if len(values) == 0 {
+		return 0, false
+	}
+	return values[0], true
+}
+
+// Uints returns the valid values of an array field.
+func (m Message) Uints(num byte) []uint64 {
+	field, ok := m.fields[num]
+	if !ok {
+		return nil
+	}
+
+	size := baseSizes[field.baseType]
+	var values []uint64
+	for offset := 0; offset+size <= len(field.data); offset += size {
+		raw := readUint(field.data[offset:offset+size], field.bigEndian)
+		if field.valid(raw, size) {
+			values = append(values, raw)
+		}
+	}
+	return values
+}
+
+// Int returns the first value of a signed field, sign-extended.
+func (m Message) Int(num byte) (value int64, ok bool) {
+	field, found := m.fields[num]
+	if !found {
+		return 0, false
+	}
+	raw, ok := m.Uint(num)
+	if !ok {
+		return 0, false
+	}
+
+	switch baseSizes[field.baseType] {
+	case 1:
+		return int64(int8(raw)), true
+	case 2:
+		return int64(int16(raw)), true
+	case 4:
+		return int64(int32(raw)), true
+	}
+	return int64(raw), true
+}
+
+// valid reports whether a raw value isn't the invalid marker of the field's base type.
+func (f fieldValue) valid(raw uint64, size int) bool {
+	switch f.baseType {
+	case baseUint8z, baseUint16z, baseUint32z, baseUint64z, baseString:
+		return raw != 0
+	case baseSint8, baseSint16, baseSint32, baseSint64:
+		// The invalid value of signed types is the largest positive one, e.g. 0x7F
+		return raw != math.MaxUint64>>(64-size*8+1)
+	case baseFloat32:
+		return raw != math.MaxUint32
+	case baseFloat64:
+		return raw != math.MaxUint64
+	}
+	// Unsigned, enum and byte types use all bits set
+	return raw != math.MaxUint64>>(64-size*8)
+}
+
+func readUint(data []byte, bigEndian bool) uint64 {
+	var value uint64
+	for i := range data {
+		b := data[i]
+		if !bigEndian {
+			b = data[len(data)-1-i]
+		}
+		value = value<<8 | uint64(b)
+	}
+	return value
+}
+
+type fieldDefinition struct {
+	num      byte
+	size     int
+	baseType byte
+}
+
+type definition struct {
+	global     uint16
+	bigEndian  bool
+	fields     []fieldDefinition
+	developer  int // total size of the developer fields, which are skipped
+	messageLen int
+}
+
+// Decode reads every data message of a FIT file, in file order. Files made of several
+// FIT files chained together are read in full. Messages of unknown types are returned too;
+// callers pick the ones they need by Global.
+func Decode(data []byte) ([]Message, error) {
+	var messages []Message
+	for len(data) > 0 {
+		chunk, rest, err := decodeFile(data)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, chunk...)
+		data = rest
+	}
+	return messages, nil
+}
+
+// decodeFile reads one FIT file from the start of data and returns what follows it.
+func decodeFile(data []byte) ([]Message, []byte, error) {
+	if len(data) < 12 || !bytes.Equal(data[8:12], []byte(".FIT")) {
+		return nil, nil, ErrNotFIT
+	}
+	headerSize := int(data[0])
+	if headerSize < 12 || headerSize > len(data) {
+		return nil, nil, ErrNotFIT
+	}
+
+	dataSize := int(binary.LittleEndian.Uint32(data[4:8]))
+	end := headerSize + dataSize
+	if end+2 > len(data) {
+		return nil, nil, ErrCorrupt
+	}
+	if crc(data[:end]) != binary.LittleEndian.Uint16(data[end:end+2]) {
+		return nil, nil, ErrCorrupt
+	}
+
+	messages, err := decodeRecords(data[headerSize:end])
+	if err != nil {
+		return nil, nil, err
+	}
+	return messages, data[end+2:], nil
+}
+
+// decodeRecords reads the records between the header and the CRC.
+func decodeRecords(data []byte) ([]Message, error) {
+	definitions := map[byte]*definition{}
+	var messages []Message
+	var lastTimestamp uint32
+
+	for pos := 0; pos < len(data); {
+		header := data[pos]
+		pos++
+
+		// Compressed timestamp header: a data message whose timestamp is an offset
+		// of up to 31 seconds from the last full timestamp
+		if header&0x80 != 0 {
+			local := (header >> 5) & 0x03
+			offset := uint32(header & 0x1F)
+			timestamp := lastTimestamp&^0x1F + offset
+			if offset < lastTimestamp&0x1F {
+				timestamp += 0x20
+			}
+			lastTimestamp = timestamp
+
+			message, n, err := decodeData(data[pos:], definitions[local])
+			if err != nil {
+				return nil, err
+			}
+			pos += n
+			if _, ok := message.fields[fieldTimestamp]; !ok {
+				raw := make([]byte, 4)
+				binary.LittleEndian.PutUint32(raw, timestamp)
+				message.fields[fieldTimestamp] = fieldValue{baseType: baseUint32, data: raw}
+			}
+			messages = append(messages, message)
+			continue
+		}
+
+		local := header & 0x0F
+		if header&0x40 != 0 {
+			def, n, err := decodeDefinition(data[pos:], header&0x20 != 0)
+			if err != nil {
+				return nil, err
+			}
+			definitions[local] = def
+			pos += n
+			continue
+		}
+
+		message, n, err := decodeData(data[pos:], definitions[local])
+		if err != nil {
+			return nil, err
+		}
+		pos += n
+		if timestamp, ok := message.Uint(fieldTimestamp); ok {
+			lastTimestamp = uint32(timestamp)
+		}
+		messages = append(messages, message)
+	}
+
+	return messages, nil
+}
+
+// decodeDefinition reads a definition message and returns it with the number of bytes it used.
+func decodeDefinition(data []byte, hasDeveloperFields bool) (*definition, int, error) {
+	if len(data) < 5 {
+		return nil, 0, ErrCorrupt
+	}
+
+	def := &definition{bigEndian: data[1] == 1}
+	if def.bigEndian {
+		def.global = binary.BigEndian.Uint16(data[2:4])
+	} else {
+		def.global = binary.LittleEndian.Uint16(data[2:4])
+	}
+
+	count := int(data[4])
+	pos := 5
+	if len(data) < pos+count*3 {
+		return nil, 0, ErrCorrupt
+	}
+	for i := 0; i < count; i++ {
+		field := fieldDefinition{num: data[pos], size: int(data[pos+1]), baseType: data[pos+2] & 0x1F}
+		if _, ok := baseSizes[field.baseType]; !ok {
+			return nil, 0, fmt.Errorf("%w: unknown base type %#x", ErrCorrupt, data[pos+2])
+		}
+		def.fields = append(def.fields, field)
+		def.messageLen += field.size
+		pos += 3
+	}
+
+	if hasDeveloperFields {
+		if len(data) < pos+1 {
+			return nil, 0, ErrCorrupt
+		}
+		count = int(data[pos])
+		pos++
+		if len(data) < pos+count*3 {
+			return nil, 0, ErrCorrupt
+		}
+		for i := 0; i < count; i++ {
+			def.developer += int(data[pos+1])
+			pos += 3
+		}
+		def.messageLen += def.developer
+	}
+
+	return def, pos, nil
+}
+
+// decodeData reads a data message laid out by def and returns it with the number of bytes it used.
+func decodeData(data []byte, def *definition) (Message, int, error) {
+	if def == nil {
+		return Message{}, 0, fmt.Errorf("%w: data message without a definition", ErrCorrupt)
+	}
+	if len(data) < def.messageLen {
+		return Message{}, 0, ErrCorrupt
+	}
+
+	message := Message{Global: def.global, fields: make(map[byte]fieldValue, len(def.fields))}
+	pos := 0
+	for _, field := range def.fields {
+		message.fields[field.num] = fieldValue{
+			baseType:  field.baseType,
+			bigEndian: def.bigEndian,
+			data:      data[pos : pos+field.size],
+		}
+		pos += field.size
+	}
+
+	return message, def.messageLen, nil
+}
+
+// crcTable is the nibble table of the FIT CRC-16.
+var crcTable = [16]uint16{
+	0x0000, 0xCC01, 0xD801, 0x1400, 0xF001, 0x3C00, 0x2800, 0xE401,
+	0xA001, 0x6C00, 0x7800, 0xB401, 0x5000, 0x9C01, 0x8801, 0x4400,
+}
+
+// crc computes the FIT CRC-16 of data.
+func crc(data []byte) uint16 {
+	var sum uint16
+	for _, b := range data {
+		tmp := crcTable[sum&0xF]
+		sum = (sum >> 4) & 0x0FFF
+		sum = sum ^ tmp ^ crcTable[b&0xF]
+
+		tmp = crcTable[sum&0xF]
+		sum = (sum >> 4) & 0x0FFF
+		sum = sum ^ tmp ^ crcTable[(b>>4)&0xF]
+	}
+	return sum
+}
@@ -0,0 +1,290 @@
+package fit
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/store"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/track"
+)
+
+// testField is a field of a message written by fitBuilder.
+type testField struct {
+	num      byte
+	baseType byte
+	value    uint64
+}
+
+// fitBuilder writes FIT files for the tests, one definition per message.
+type fitBuilder struct {
+	records   []byte
+	bigEndian bool
+}
+
+func (b *fitBuilder) message(local byte, global uint16, fields ...testField) {
+	b.define(local, global, fields...)
+	b.records = append(b.records, local)
+	b.data(fields...)
+}
+
+// define writes a definition message for the fields, their values are ignored.
+func (b *fitBuilder) define(local byte, global uint16, fields ...testField) {
+	architecture := byte(0)
+	if b.bigEndian {
+		architecture = 1
+	}
+	def := []byte{0x40 | local, 0, architecture, 0, 0, byte(len(fields))}
+	b.order().PutUint16(def[3:5], global)
+	for _, field := range fields {
+		def = append(def, field.num, byte(baseSizes[field.baseType&0x1F]), field.baseType)
+	}
+	b.records = append(b.records, def...)
+}
+
+// data writes the values of a data message, without its header.
+func (b *fitBuilder) data(fields ...testField) {
+	for _, field := range fields {
+		size := baseSizes[field.baseType&0x1F]
+		raw := make([]byte, 8)
+		b.order().PutUint64(raw, field.value)
+		if b.bigEndian {
+			b.records = append(b.records, raw[8-size:]...)
+		} else {
+			b.records = append(b.records, raw[:size]...)
+		}
+	}
+}
+
+func (b *fitBuilder) order() binary.ByteOrder {
+	if b.bigEndian {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+func (b *fitBuilder) bytes() []byte {
+	header := []byte{14, 0x20, 0, 0, 0, 0, 0, 0, '.', 'F', 'I', 'T', 0, 0}
+	binary.LittleEndian.PutUint32(header[4:8], uint32(len(b.records)))
+	file := append(header, b.records...)
+	return binary.LittleEndian.AppendUint16(file, crc(file))
+}
+
+// fitTime converts a time to a FIT timestamp.
+func fitTime(t time.Time) uint64 {
+	return uint64(t.Sub(fitEpoch) / time.Second)
+}
+
+var testStart = time.Date(2026, 3, 1, 7, 0, 0, 0, time.UTC)
+
+func TestDecode(t *testing.T) {
+	south := int32(-536870912)
+	b := &fitBuilder{bigEndian: true}
+	b.message(0, MesgRecord,
+		testField{fieldTimestamp, 0x86, fitTime(testStart)},
+		testField{fieldPositionLat, 0x85, uint64(uint32(south))},
+		testField{fieldPositionLong, 0x85, 0x7FFFFFFF},
+		testField{fieldHeartRate, 0x02, 140},
+	)
+	// A record with a compressed timestamp header, 3 seconds after the first one
+	compressed := []testField{
+		{fieldPositionLat, 0x85, 0},
+		{fieldPositionLong, 0x85, 0},
+		{fieldHeartRate, 0x02, 0xFF},
+	}
+	b.define(1, MesgRecord, compressed...)
+	offset := byte((fitTime(testStart) + 3) & 0x1F)
+	b.records = append(b.records, 0x80|1<<5|offset)
+	b.data(compressed...)
+
+	messages, err := Decode(b.bytes())
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+
+	first := messages[0]
+	assert.Equal(t, uint16(MesgRecord), first.Global)
+	lat, ok := first.Int(fieldPositionLat)
+	assert.True(t, ok)
+	assert.Equal(t, int64(-536870912), lat, "signed values are sign-extended")
+	_, ok = first.Int(fieldPositionLong)
+	assert.False(t, ok, "0x7FFFFFFF is the invalid sint32")
+
+	second := messages[1]
+	timestamp, ok := second.Uint(fieldTimestamp)
+	assert.True(t, ok)
+	assert.Equal(t, fitTime(testStart)+3, timestamp)
+	_, ok = second.Uint(fieldHeartRate)
+	assert.False(t, ok, "0xFF is the invalid uint8")
+}
+
+func TestDecodeErrors(t *testing.T) {
+	b := &fitBuilder{}
+	b.message(0, MesgRecord, testField{fieldTimestamp, 0x86, fitTime(testStart)})
+	valid := b.bytes()
+
+	corrupted := append([]byte{}, valid...)
+	corrupted[16] ^= 0xFF
+	_, err := Decode(corrupted)
+	assert.ErrorIs(t, err, ErrCorrupt)
+
+	_, err = Decode(valid[:len(valid)-4])
+	assert.ErrorIs(t, err, ErrCorrupt)
+
+	_, err = Decode([]byte("<gpx></gpx> and some more bytes"))
+	assert.ErrorIs(t, err, ErrNotFIT)
+
+	// A data message of a local type that was never defined
+	b = &fitBuilder{records: []byte{0x03, 0x00}}
+	_, err = Decode(b.bytes())
+	assert.ErrorIs(t, err, ErrCorrupt)
+
+	_, err = Parse(valid[:0])
+	assert.Error(t, err)
+}
+
+func strengthActivity() []byte {
+	b := &fitBuilder{}
+	set := func(start time.Time, setType, reps, weight, duration, category uint64) {
+		b.message(2, MesgSet,
+			testField{fieldSetTimestamp, 0x86, fitTime(start)},
+			testField{fieldSetStartTime, 0x86, fitTime(start)},
+			testField{fieldSetType, 0x02, setType},
+			testField{fieldSetReps, 0x84, reps},
+			testField{fieldSetWeight, 0x84, weight},
+			testField{fieldSetDuration, 0x86, duration},
+			testField{fieldSetCategory, 0x84, category},
+		)
+	}
+
+	b.message(1, MesgRecord, testField{fieldTimestamp, 0x86, fitTime(testStart)}, testField{fieldHeartRate, 0x02, 95})
+	set(testStart, setTypeActive, 5, 100*16, 30000, 0)
+	set(testStart.Add(30*time.Second), setTypeRest, 0xFFFF, 0xFFFF, 90000, 0xFFFF)
+	set(testStart.Add(2*time.Minute), setTypeActive, 5, 1620, 28000, 0)
+	set(testStart.Add(4*time.Minute), setTypeActive, 0xFFFF, 0, 60000, 19)
+	set(testStart.Add(6*time.Minute), setTypeActive, 12, 0xFFFF, 40000, 0xFFFF)
+	set(testStart.Add(8*time.Minute), setTypeActive, 0, 0, 500, 0)
+	b.message(1, MesgRecord, testField{fieldTimestamp, 0x86, fitTime(testStart.Add(10 * time.Minute))}, testField{fieldHeartRate, 0x02, 120})
+
+	b.message(3, MesgSession,
+		testField{fieldTimestamp, 0x86, fitTime(testStart.Add(10 * time.Minute))},
+		testField{fieldStartTime, 0x86, fitTime(testStart)},
+		testField{fieldSport, 0x00, 10},
+		testField{fieldSubSport, 0x00, subSportStrengthTraining},
+		testField{fieldElapsedTime, 0x86, 600000},
+		testField{fieldTotalCalories, 0x84, 85},
+	)
+	return b.bytes()
+}
+
+func TestStrengthWorkout(t *testing.T) {
+	activity, err := Parse(strengthActivity())
+	require.NoError(t, err)
+	require.Len(t, activity.Sets, 6)
+
+	workout := activity.Workout()
+	assert.Equal(t, "Strength Training", workout.Title)
+	assert.Equal(t, testStart, workout.PerformedAt)
+	assert.Equal(t, testStart.Add(10*time.Minute), *workout.EndedAt)
+	assert.Equal(t, 85, workout.CaloriesBurned)
+
+	require.Len(t, workout.Entries, 3)
+	bench := workout.Entries[0]
+	assert.Equal(t, "Bench Press", bench.ExerciseName)
+	assert.Equal(t, 1, bench.OrderIndex)
+	require.Len(t, bench.Sets, 2)
+	assert.Equal(t, store.WorkoutSet{
+		SetNumber: 1, Reps: intPtr(5), Weight: floatPtr(100), SetType: store.SetTypeWorking, Completed: true,
+		RestSeconds: intPtr(90),
+	}, bench.Sets[0])
+	assert.Equal(t, 101.25, *bench.Sets[1].Weight)
+	assert.Equal(t, 2, bench.Sets[1].SetNumber)
+	assert.Nil(t, bench.Sets[1].RestSeconds)
+
+	plank := workout.Entries[1]
+	assert.Equal(t, "Plank", plank.ExerciseName)
+	assert.Nil(t, plank.Sets[0].Reps)
+	assert.Equal(t, 60, *plank.Sets[0].DurationSeconds)
+	assert.Nil(t, plank.Sets[0].Weight, "a weight of 0 is bodyweight")
+
+	unknown := workout.Entries[2]
+	assert.Equal(t, unknownExercise, unknown.ExerciseName)
+	assert.Len(t, unknown.Sets, 1, "the set without reps or duration is skipped")
+
+	require.NoError(t, workout.ResolveTimes())
+	assert.Equal(t, 10, workout.DurationMinutes)
+
+	track := activity.Track()
+	require.NotNil(t, track)
+	assert.Len(t, track.Points, 2)
+	assert.Equal(t, 120, *track.Points[1].HeartRate)
+	assert.Nil(t, track.Points[0].Lat)
+}
+
+func TestCardioWorkout(t *testing.T) {
+	b := &fitBuilder{}
+	for i := 0; i < 3; i++ {
+		b.message(0, MesgRecord,
+			testField{fieldTimestamp, 0x86, fitTime(testStart.Add(time.Duration(i) * 10 * time.Second))},
+			testField{fieldPositionLat, 0x85, uint64(uint32(int32(536870912 + i*12000)))},
+			testField{fieldPositionLong, 0x85, 83513253},
+			testField{fieldEnhancedAltitude, 0x86, uint64((100 + 500) * 5)},
+		)
+	}
+	b.message(1, MesgLap,
+		testField{fieldStartTime, 0x86, fitTime(testStart)},
+		testField{fieldElapsedTime, 0x86, 20000},
+		testField{fieldTotalDistance, 0x86, 5000},
+	)
+
+	activity, err := Parse(b.bytes())
+	require.NoError(t, err)
+	assert.Empty(t, activity.Sessions)
+
+	workout := activity.Workout()
+	assert.Equal(t, "Workout", workout.Title)
+	assert.Equal(t, testStart.Add(20*time.Second), *workout.EndedAt, "the span comes from the laps without a session")
+	assert.Empty(t, workout.Entries)
+
+	track := activity.Track()
+	require.Len(t, track.Points, 3)
+	assert.InDelta(t, 45.0, *track.Points[0].Lat, 1e-9)
+	assert.InDelta(t, 7.0, *track.Points[0].Lon, 1e-6)
+	assert.Equal(t, 100.0, *track.Points[0].Elevation)
+	assert.Equal(t, 50.0, *activity.Laps[0].DistanceMeters)
+}
+
+func TestIndoorWorkout(t *testing.T) {
+	// A treadmill run: no positions, only the distance of the foot pod in cm
+	b := &fitBuilder{}
+	for i, distance := range []uint64{0, 5000, 10000} {
+		b.message(0, MesgRecord,
+			testField{fieldTimestamp, 0x86, fitTime(testStart.Add(time.Duration(i) * 20 * time.Second))},
+			testField{fieldDistance, 0x86, distance},
+			testField{fieldHeartRate, 0x02, 150},
+		)
+	}
+
+	activity, err := Parse(b.bytes())
+	require.NoError(t, err)
+	assert.Nil(t, activity.Records[1].Lat)
+	assert.Equal(t, 50.0, *activity.Records[1].DistanceMeters)
+
+	points := activity.Track().Points
+	require.Len(t, points, 3)
+	assert.Equal(t, 100.0, *points[2].DistanceMeters)
+
+	metrics := track.Compute(points, nil)
+	assert.Equal(t, 100.0, metrics.DistanceMeters)
+	assert.Equal(t, 400.0, *metrics.PaceSecondsPerKm)
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,316 @@
+package goals
+
+import (
+	"math"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/consistency"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/trend"
+)
+
+// Goal types. Strength and bodyweight goals are about reaching a level
+// (a lift, a weight on the scale); the others add up what was done during the goal.
+const (
+	// TypeStrength: lift TargetValue kg on an exercise, for at least the target reps.
+	TypeStrength = "strength"
+	// TypeFrequency: train TargetValue times every calendar week until the deadline.
+	TypeFrequency = "frequency"
+	// TypeVolume: lift TargetValue kg in total (weight × reps of working sets), optionally on one exercise.
+	TypeVolume = "volume"
+	// TypeDistance: cover TargetValue km in recorded tracks.
+	TypeDistance = "distance"
+	// TypeBodyweight: weigh TargetValue kg, by losing or gaining.
+	TypeBodyweight = "bodyweight"
+)
+
+// Types lists every goal type.
+var Types = []string{TypeStrength, TypeFrequency, TypeVolume, TypeDistance, TypeBodyweight}
+
+// Statuses of a goal, see Status.
+const (
+	StatusActive   = "active"
+	StatusAchieved = "achieved"
+	StatusMissed   = "missed"
+)
+
+// ValidType reports whether the goal type is one of Types.
+func ValidType(goalType string) bool {
+	for _, known := range Types {
+		if goalType == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Unit is the unit of the target and current values of a goal type.
+func Unit(goalType string) string {
+	switch goalType {
+	case TypeFrequency:
+		return "workouts"
+	case TypeDistance:
+		return "km"
+	default:
+		return "kg"
+	}
+}
+
+// Goal is what Evaluate needs to know about a goal. Location is the timezone the weeks
+// of a frequency goal are taken in, UTC when nil.
+type Goal struct {
+	Type        string
+	TargetValue float64
+	StartsAt    time.Time
+	Deadline    *time.Time
+	Location    *time.Location
+}
+
+// Progress is where a goal stands.
+type Progress struct {
+	// StartValue is where a strength or bodyweight goal started from; nil for the other types.
+	StartValue *float64
+	// CurrentValue is the best lift, the latest bodyweight, or the total done so far. Workouts
+	// of a frequency goal only count up to the target of their week.
+	CurrentValue float64
+	Percent      float64
+	// AchievedAt is when the goal was first met, nil while it isn't.
+	AchievedAt *time.Time
+	// WeeksMet is how many weeks of a frequency goal reached their target.
+	WeeksMet int
+	// ShortfallAt is when the first week of a frequency goal that is short of its target
+	// ends; once it has passed the goal is missed. nil for the other types and once achieved.
+	ShortfallAt *time.Time
+	// ProjectedAt is when the trend of the progress so far meets the goal,
+	// nil once achieved or when the trend doesn't lead there.
+	ProjectedAt *time.Time
+}
+
+// Week is one calendar week of a frequency goal, Monday to Sunday. Start is midnight of the
+// Monday in the goal's location and End the next Monday, or the deadline in the last week.
+type Week struct {
+	Start    time.Time
+	End      time.Time
+	Target   int
+	Workouts int
+}
+
+// Weeks splits a frequency goal into its calendar weeks. A week the goal only covers part
+// of has a target for the days it covers, rounded up: 4 a week starting on a Friday is 2.
+func Weeks(goal Goal) []Week {
+	if goal.Type != TypeFrequency || goal.Deadline == nil {
+		return nil
+	}
+	location := goal.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	first := midnight(goal.StartsAt.In(location))
+	// The day of the deadline counts unless the goal ends right as it starts
+	last := midnight(goal.Deadline.In(location))
+	if last.Before(*goal.Deadline) {
+		last = last.AddDate(0, 0, 1)
+	}
+
+	weeks := []Week{}
+	for start := consistency.WeekStart(first); start.Before(last); start = start.AddDate(0, 0, 7) {
+		end := start.AddDate(0, 0, 7)
+		from, to := start, end
+		if from.Before(first) {
+			from = first
+		}
+		if to.After(last) {
+			to = last
+		}
+		days := math.Round(consistency.Date(to).Sub(consistency.Date(from)).Hours() / 24)
+
+		if end.After(*goal.Deadline) {
+			end = *goal.Deadline
+		}
+		weeks = append(weeks, Week{Start: start, End: end, Target: int(math.Ceil(goal.TargetValue * days / 7))})
+	}
+	return weeks
+}
+
+// midnight returns the start of the day t falls on, in its location.
+func midnight(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// TargetTotal is the amount a goal adds up to. For frequency goals it is the workouts
+// of every week together, e.g. 4 a week for 3 weeks is 12.
+func TargetTotal(goal Goal) float64 {
+	weeks := Weeks(goal)
+	if weeks == nil {
+		return goal.TargetValue
+	}
+	total := 0
+	for _, week := range weeks {
+		total += week.Target
+	}
+	return float64(total)
+}
+
+// Status tells whether a goal is achieved, missed (its deadline, or for a frequency goal a
+// week short of its target, passed first) or still active.
+func Status(achievedAt, shortfallAt, deadline *time.Time, now time.Time) string {
+	switch {
+	case achievedAt != nil:
+		return StatusAchieved
+	case deadline != nil && !now.Before(*deadline):
+		return StatusMissed
+	case shortfallAt != nil && !now.Before(*shortfallAt):
+		return StatusMissed
+	default:
+		return StatusActive
+	}
+}
+
+// Evaluate computes the progress of a goal from the samples recorded since it started, in time order.
+//   - strength: the best weight of each workout with the exercise; baseline is the best before the goal
+//   - bodyweight: every weigh-in; baseline is the last weigh-in before the goal
+//   - frequency: one sample per workout, with value 1; every week has to reach its target
+//   - volume: the volume of each workout, in kg
+//   - distance: the distance of each workout, in km
+//
+// baseline is nil when there is nothing from before the goal, and ignored for the adding up types.
+func Evaluate(goal Goal, baseline *float64, samples []trend.Sample) Progress {
+	switch goal.Type {
+	case TypeStrength, TypeBodyweight:
+		return evaluateLevel(goal, baseline, samples)
+	case TypeFrequency:
+		return evaluateWeeks(goal, samples)
+	}
+	return evaluateTotal(goal, samples)
+}
+
+// evaluateLevel tracks a value that has to reach the target, upwards for a lift and
+// either way for bodyweight depending on where it started.
+func evaluateLevel(goal Goal, baseline *float64, samples []trend.Sample) Progress {
+	var start float64
+	switch {
+	case baseline != nil:
+		start = *baseline
+	case goal.Type == TypeBodyweight && len(samples) > 0:
+		start = samples[0].Value
+	}
+
+	increasing := goal.Type == TypeStrength || goal.TargetValue > start
+	reached := func(value float64) bool {
+		if increasing {
+			return value >= goal.TargetValue
+		}
+		return value <= goal.TargetValue
+	}
+
+	progress := Progress{StartValue: &start, CurrentValue: start}
+	for i, sample := range samples {
+		// A lift counts at its best, a bodyweight at its latest
+		if goal.Type == TypeBodyweight || i == 0 || sample.Value > progress.CurrentValue {
+			progress.CurrentValue = sample.Value
+		}
+		if progress.AchievedAt == nil && reached(sample.Value) {
+			achievedAt := sample.Time
+			progress.AchievedAt = &achievedAt
+		}
+	}
+
+	if progress.AchievedAt == nil && goal.TargetValue != start {
+		progress.Percent = (progress.CurrentValue - start) / (goal.TargetValue - start) * 100
+	}
+	finish(&progress, samples, goal.TargetValue)
+	return progress
+}
+
+// evaluateTotal adds up the samples until they reach the target total.
+func evaluateTotal(goal Goal, samples []trend.Sample) Progress {
+	target := TargetTotal(goal)
+
+	// The running total, starting from 0 when the goal started, is what gets fitted
+	totals := []trend.Sample{{Time: goal.StartsAt, Value: 0}}
+	var progress Progress
+	for _, sample := range samples {
+		progress.CurrentValue += sample.Value
+		totals = append(totals, trend.Sample{Time: sample.Time, Value: progress.CurrentValue})
+		if progress.AchievedAt == nil && progress.CurrentValue >= target {
+			achievedAt := sample.Time
+			progress.AchievedAt = &achievedAt
+		}
+	}
+
+	progress.Percent = progress.CurrentValue / target * 100
+	finish(&progress, totals, target)
+	return progress
+}
+
+// evaluateWeeks counts the workouts of every week of a frequency goal. Workouts beyond the
+// target of their week don't make up for another week, so the goal is achieved when the
+// last week reaches its target, and missed as soon as a week ends short of it.
+func evaluateWeeks(goal Goal, samples []trend.Sample) Progress {
+	weeks := Weeks(goal)
+	target := TargetTotal(goal)
+
+	totals := []trend.Sample{{Time: goal.StartsAt, Value: 0}}
+	var progress Progress
+	i := 0
+	for _, sample := range samples {
+		for i < len(weeks) && !sample.Time.Before(weeks[i].End) {
+			i++
+		}
+		if i == len(weeks) {
+			break
+		}
+		if sample.Time.Before(weeks[i].Start) {
+			continue
+		}
+
+		weeks[i].Workouts++
+		if weeks[i].Workouts > weeks[i].Target {
+			continue
+		}
+		progress.CurrentValue++
+		totals = append(totals, trend.Sample{Time: sample.Time, Value: progress.CurrentValue})
+		if weeks[i].Workouts == weeks[i].Target {
+			progress.WeeksMet++
+			if progress.WeeksMet == len(weeks) {
+				achievedAt := sample.Time
+				progress.AchievedAt = &achievedAt
+			}
+		}
+	}
+
+	if progress.AchievedAt == nil {
+		for _, week := range weeks {
+			if week.Workouts < week.Target {
+				shortfallAt := week.End
+				progress.ShortfallAt = &shortfallAt
+				break
+			}
+		}
+	}
+
+	progress.Percent = progress.CurrentValue / target * 100
+	finish(&progress, totals, target)
+	return progress
+}
+
+// finish clamps and rounds the percentage and projects the completion date.
+func finish(progress *Progress, series []trend.Sample, target float64) {
+	progress.CurrentValue = math.Round(progress.CurrentValue*100) / 100
+	if progress.AchievedAt != nil {
+		progress.Percent = 100
+		return
+	}
+	// Rounded down so that 100% only ever means achieved
+	progress.Percent = math.Floor(math.Max(0, math.Min(progress.Percent, 100))*10) / 10
+
+	line, ok := trend.Fit(series)
+	if !ok {
+		return
+	}
+	projectedAt, ok := line.Reaches(target, series[len(series)-1].Time)
+	if ok {
+		progress.ProjectedAt = &projectedAt
+	}
+}
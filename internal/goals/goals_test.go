@@ -0,0 +1,155 @@
+package goals
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/trend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+
+func day(d int) time.Time { return start.AddDate(0, 0, d) }
+
+func kg(v float64) *float64 { return &v }
+
+// at is a sample taken d days after the start.
+func at(d int, value float64) trend.Sample { return trend.Sample{Time: day(d), Value: value} }
+
+func TestEvaluateStrength(t *testing.T) {
+	goal := Goal{Type: TypeStrength, TargetValue: 100, StartsAt: start}
+
+	progress := Evaluate(goal, kg(90), []trend.Sample{at(2, 92.5), at(9, 90), at(16, 95)})
+	assert.Equal(t, 90.0, *progress.StartValue)
+	assert.Equal(t, 95.0, progress.CurrentValue)
+	assert.Equal(t, 50.0, progress.Percent)
+	assert.Nil(t, progress.AchievedAt)
+	require.NotNil(t, progress.ProjectedAt)
+	assert.True(t, progress.ProjectedAt.After(day(16)))
+
+	progress = Evaluate(goal, kg(90), []trend.Sample{at(2, 97.5), at(9, 100), at(16, 102.5)})
+	assert.Equal(t, 102.5, progress.CurrentValue)
+	assert.Equal(t, 100.0, progress.Percent)
+	assert.Equal(t, day(9), *progress.AchievedAt)
+	assert.Nil(t, progress.ProjectedAt)
+
+	// Never lifted before: progress counts from zero, one workout is not a trend
+	progress = Evaluate(goal, nil, []trend.Sample{at(2, 60)})
+	assert.Equal(t, 60.0, progress.Percent)
+	assert.Nil(t, progress.ProjectedAt)
+}
+
+func TestEvaluateBodyweight(t *testing.T) {
+	goal := Goal{Type: TypeBodyweight, TargetValue: 80, StartsAt: start}
+
+	// Cutting from 84 kg, 2 of the 4 kg lost, at 0.5 kg a week
+	progress := Evaluate(goal, kg(84), []trend.Sample{at(0, 84), at(14, 83), at(28, 82)})
+	assert.Equal(t, 82.0, progress.CurrentValue)
+	assert.Equal(t, 50.0, progress.Percent)
+	require.NotNil(t, progress.ProjectedAt)
+	assert.Equal(t, day(56), *progress.ProjectedAt)
+
+	// Gaining instead: the trend leads away from the goal
+	progress = Evaluate(goal, kg(84), []trend.Sample{at(0, 84), at(14, 85)})
+	assert.Equal(t, 0.0, progress.Percent)
+	assert.Nil(t, progress.ProjectedAt)
+
+	// Bulking without an earlier weigh-in starts from the first one
+	goal.TargetValue = 75
+	progress = Evaluate(goal, nil, []trend.Sample{at(0, 72), at(7, 74), at(14, 75.2)})
+	assert.Equal(t, 72.0, *progress.StartValue)
+	assert.Equal(t, day(14), *progress.AchievedAt)
+}
+
+func TestEvaluateTotals(t *testing.T) {
+	deadline := day(21)
+	frequency := Goal{Type: TypeFrequency, TargetValue: 4, StartsAt: start, Deadline: &deadline}
+	assert.Equal(t, 12.0, TargetTotal(frequency))
+
+	var workouts []trend.Sample
+	for _, d := range []int{0, 1, 3, 5, 7, 8} {
+		workouts = append(workouts, trend.Sample{Time: day(d), Value: 1})
+	}
+	progress := Evaluate(frequency, nil, workouts)
+	assert.Nil(t, progress.StartValue)
+	assert.Equal(t, 6.0, progress.CurrentValue)
+	assert.Equal(t, 50.0, progress.Percent)
+	require.NotNil(t, progress.ProjectedAt)
+	assert.True(t, progress.ProjectedAt.After(day(8)) && progress.ProjectedAt.Before(day(21)))
+	assert.Equal(t, 1, progress.WeeksMet)
+	// The second week is the first one short of 4
+	assert.Equal(t, day(14), *progress.ShortfallAt)
+
+	distance := Goal{Type: TypeDistance, TargetValue: 20, StartsAt: start}
+	progress = Evaluate(distance, nil, []trend.Sample{at(1, 8), at(3, 10), at(6, 5)})
+	assert.Equal(t, 23.0, progress.CurrentValue)
+	assert.Equal(t, 100.0, progress.Percent)
+	assert.Equal(t, day(6), *progress.AchievedAt)
+
+	progress = Evaluate(Goal{Type: TypeVolume, TargetValue: 3000, StartsAt: start}, nil, []trend.Sample{at(1, 2999.99)})
+	assert.Equal(t, 99.9, progress.Percent)
+}
+
+func TestEvaluateFrequencyWeeks(t *testing.T) {
+	deadline := day(21)
+	goal := Goal{Type: TypeFrequency, TargetValue: 4, StartsAt: start, Deadline: &deadline}
+
+	// All 12 workouts in the first week only meet that week
+	var workouts []trend.Sample
+	for i := 0; i < 12; i++ {
+		workouts = append(workouts, trend.Sample{Time: day(i % 7).Add(time.Duration(i) * time.Hour), Value: 1})
+	}
+	progress := Evaluate(goal, nil, workouts)
+	assert.Nil(t, progress.AchievedAt)
+	assert.Equal(t, 4.0, progress.CurrentValue)
+	assert.Equal(t, 1, progress.WeeksMet)
+	assert.Equal(t, 33.3, progress.Percent)
+	require.NotNil(t, progress.ShortfallAt)
+	assert.Equal(t, day(14), *progress.ShortfallAt)
+	assert.Equal(t, StatusActive, Status(progress.AchievedAt, progress.ShortfallAt, &deadline, day(13)))
+	assert.Equal(t, StatusMissed, Status(progress.AchievedAt, progress.ShortfallAt, &deadline, day(14)))
+
+	// 4 every week: achieved with the last workout of the last week
+	workouts = nil
+	for _, d := range []int{0, 1, 2, 3, 7, 9, 11, 13, 14, 15, 16, 20} {
+		workouts = append(workouts, at(d, 1))
+	}
+	progress = Evaluate(goal, nil, workouts)
+	assert.Equal(t, 3, progress.WeeksMet)
+	assert.Equal(t, day(20), *progress.AchievedAt)
+	assert.Nil(t, progress.ShortfallAt)
+}
+
+func TestWeeks(t *testing.T) {
+	// Friday to the Wednesday 12 days later: 3 days, a whole week and 2 days
+	deadline := day(16)
+	weeks := Weeks(Goal{Type: TypeFrequency, TargetValue: 4, StartsAt: day(4).Add(18 * time.Hour), Deadline: &deadline})
+	require.Len(t, weeks, 3)
+	assert.Equal(t, day(0), weeks[0].Start)
+	assert.Equal(t, []int{2, 4, 2}, []int{weeks[0].Target, weeks[1].Target, weeks[2].Target})
+	assert.Equal(t, deadline, weeks[2].End)
+
+	// Weeks follow the calendar of the user, not UTC
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+	deadline = day(7)
+	weeks = Weeks(Goal{Type: TypeFrequency, TargetValue: 3, StartsAt: day(-1).Add(20 * time.Hour), Deadline: &deadline, Location: tokyo})
+	require.Len(t, weeks, 2)
+	assert.Equal(t, time.Date(2026, 1, 5, 0, 0, 0, 0, tokyo), weeks[0].Start)
+	assert.Equal(t, 3, weeks[0].Target)
+
+	assert.Nil(t, Weeks(Goal{Type: TypeDistance, TargetValue: 20, StartsAt: start, Deadline: &deadline}))
+}
+
+func TestStatus(t *testing.T) {
+	deadline := day(10)
+	shortfall := day(7)
+	assert.Equal(t, StatusActive, Status(nil, nil, nil, day(20)))
+	assert.Equal(t, StatusActive, Status(nil, nil, &deadline, day(9)))
+	assert.Equal(t, StatusMissed, Status(nil, nil, &deadline, day(10)))
+	assert.Equal(t, StatusAchieved, Status(&deadline, nil, &deadline, day(20)))
+	assert.Equal(t, StatusActive, Status(nil, &shortfall, &deadline, day(6)))
+	assert.Equal(t, StatusMissed, Status(nil, &shortfall, &deadline, day(7)))
+}
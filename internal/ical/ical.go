@@ -0,0 +1,109 @@
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Event statuses defined by RFC 5545.
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+// Event is the subset of a VEVENT the workout calendar needs.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Start       time.Time
+	End         time.Time
+	Status      string
+	Updated     time.Time
+}
+
+// Calendar is a VCALENDAR with its events.
+type Calendar struct {
+	ProductID string
+	Name      string
+	Events    []Event
+}
+
+// maxLineOctets is the longest content line RFC 5545 allows before it has to be folded.
+const maxLineOctets = 75
+
+// dateTimeFormat is the UTC DATE-TIME form, e.g. 20260105T180000Z.
+const dateTimeFormat = "20060102T150405Z"
+
+// Write encodes the calendar as an RFC 5545 iCalendar stream:
+// CRLF line endings, long lines folded, and text values escaped.
+func (c *Calendar) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	writeLine := func(name, value string) {
+		bw.WriteString(fold(name + ":" + value))
+	}
+
+	writeLine("BEGIN", "VCALENDAR")
+	writeLine("VERSION", "2.0")
+	writeLine("PRODID", c.ProductID)
+	writeLine("CALSCALE", "GREGORIAN")
+	writeLine("METHOD", "PUBLISH")
+	if c.Name != "" {
+		writeLine("X-WR-CALNAME", escapeText(c.Name))
+	}
+
+	for _, event := range c.Events {
+		stamp := event.Updated
+		if stamp.IsZero() {
+			stamp = time.Now()
+		}
+
+		writeLine("BEGIN", "VEVENT")
+		writeLine("UID", event.UID)
+		writeLine("DTSTAMP", stamp.UTC().Format(dateTimeFormat))
+		writeLine("DTSTART", event.Start.UTC().Format(dateTimeFormat))
+		writeLine("DTEND", event.End.UTC().Format(dateTimeFormat))
+		writeLine("SUMMARY", escapeText(event.Summary))
+		if event.Description != "" {
+			writeLine("DESCRIPTION", escapeText(event.Description))
+		}
+		if event.Status != "" {
+			writeLine("STATUS", event.Status)
+		}
+		writeLine("END", "VEVENT")
+	}
+
+	writeLine("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+// escapeText escapes a TEXT value: backslashes, semicolons, commas and newlines.
+func escapeText(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+	return replacer.Replace(value)
+}
+
+// fold splits a content line into lines of at most 75 octets, continuation lines
+// starting with a single space, without cutting a UTF-8 character in half.
+// The returned string ends with CRLF.
+func fold(line string) string {
+	var b strings.Builder
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// The leading space counts towards the next line
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	return b.String()
+}
@@ -0,0 +1,71 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	start := time.Date(2026, 3, 2, 18, 30, 0, 0, time.FixedZone("CET", 3600))
+	calendar := &Calendar{
+		ProductID: "-//test//EN",
+		Name:      "Workouts",
+		Events: []Event{{
+			UID:         "scheduled-1@test",
+			Summary:     "Push Day A, heavy; bench\nfocus",
+			Description: strings.Repeat("long description ", 10),
+			Start:       start,
+			End:         start.Add(time.Hour),
+			Status:      StatusConfirmed,
+			Updated:     start,
+		}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, calendar.Write(&buf))
+	out := buf.String()
+
+	assert.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(out, "END:VEVENT\r\nEND:VCALENDAR\r\n"))
+	// Times are written in UTC
+	assert.Contains(t, out, "DTSTART:20260302T173000Z\r\n")
+	assert.Contains(t, out, "DTEND:20260302T183000Z\r\n")
+	assert.Contains(t, out, `SUMMARY:Push Day A\, heavy\; bench\nfocus`+"\r\n")
+
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75, line)
+	}
+}
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{name: "short", line: "SUMMARY:Legs"},
+		{name: "ascii", line: "DESCRIPTION:" + strings.Repeat("a", 200)},
+		{name: "multibyte", line: "DESCRIPTION:" + strings.Repeat("ü", 100)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			folded := fold(tt.line)
+			lines := strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n")
+			for i, line := range lines {
+				assert.LessOrEqual(t, len(line), 75)
+				assert.True(t, strings.ToValidUTF8(line, "") == line, "line %d cuts a character", i)
+				if i > 0 {
+					assert.True(t, strings.HasPrefix(line, " "))
+				}
+			}
+
+			// Unfolding gives back the original line
+			assert.Equal(t, tt.line, strings.ReplaceAll(strings.TrimSuffix(folded, "\r\n"), "\r\n ", ""))
+		})
+	}
+}
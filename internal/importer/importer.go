@@ -0,0 +1,315 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/store"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/utils"
+)
+
+// Weight units accepted in Options.WeightUnit.
+const (
+	WeightUnitKg = "kg"
+	WeightUnitLb = "lb"
+)
+
+// kgPerLb converts pounds to the kilograms weights are stored in.
+const kgPerLb = 0.45359237
+
+// defaultTitle is used for workouts of formats that don't name them.
+const defaultTitle = "Workout"
+
+// Options configures how a file is read.
+type Options struct {
+	// Format is the name of a registered mapper; empty detects it from the header row
+	Format string
+	// Location is the time zone of the dates in the file, which carry no offset. Defaults to UTC.
+	Location *time.Location
+	// WeightUnit is the unit of weight columns that don't say which unit they are in. Defaults to kg.
+	WeightUnit string
+}
+
+// Row is one set of the file, as read by a Mapper.
+// Rows with the same StartedAt and WorkoutTitle make up one workout.
+type Row struct {
+	WorkoutTitle    string
+	WorkoutNotes    string
+	StartedAt       time.Time
+	EndedAt         *time.Time
+	DurationMinutes int
+	Exercise        string
+	ExerciseNotes   string
+	Set             store.WorkoutSet
+}
+
+// Mapper turns the rows of one app's CSV export into Rows.
+type Mapper interface {
+	// Name is what clients pass as the format, e.g. "strong"
+	Name() string
+	// Detect reports whether a file with these (lowercased) columns is in this format
+	Detect(columns map[string]int) bool
+	// Map reads one row. An error skips the row and is reported as the reason.
+	Map(record Record, opts Options) (Row, error)
+}
+
+// mappers are tried in order when detecting the format, so the most specific come first.
+var mappers = []Mapper{hevyMapper{}, strongMapper{}, fitNotesMapper{}, genericMapper{}}
+
+// Register adds a mapper for another app. It is tried before the built-in ones when detecting
+// the format, and replaces a built-in mapper with the same name.
+func Register(mapper Mapper) {
+	for i, existing := range mappers {
+		if existing.Name() == mapper.Name() {
+			mappers = append(mappers[:i], mappers[i+1:]...)
+			break
+		}
+	}
+	mappers = append([]Mapper{mapper}, mappers...)
+}
+
+// Formats lists the names of the registered mappers.
+func Formats() []string {
+	names := make([]string, len(mappers))
+	for i, mapper := range mappers {
+		names[i] = mapper.Name()
+	}
+	return names
+}
+
+// Record is one line of the file, read by column name.
+type Record struct {
+	Line    int
+	columns map[string]int
+	fields  []string
+}
+
+// Get returns the trimmed value of the first of the columns the file has, or "".
+// Column names are matched case-insensitively.
+func (r Record) Get(names ...string) string {
+	for _, name := range names {
+		if i, ok := r.columns[strings.ToLower(name)]; ok && i < len(r.fields) {
+			return strings.TrimSpace(r.fields[i])
+		}
+	}
+	return ""
+}
+
+// Has reports whether the file has the column.
+func (r Record) Has(name string) bool {
+	_, ok := r.columns[strings.ToLower(name)]
+	return ok
+}
+
+// ParsedWorkout is a workout read from the file. FirstLine is where its rows start,
+// to point the user at it in the report.
+type ParsedWorkout struct {
+	Workout   *store.Workout
+	FirstLine int
+}
+
+// Result is everything read from a file, before anything is saved.
+type Result struct {
+	Format   string
+	Rows     int
+	Workouts []ParsedWorkout
+	Skipped  []store.ImportSkippedRow
+}
+
+// Parse reads a CSV export, maps every row with the mapper of its format and groups the rows
+// into workouts, oldest first. Rows the mapper rejects are skipped and reported, they don't fail the file.
+// Returns an error if the file isn't CSV or its format isn't known.
+func Parse(r io.Reader, opts Options) (*Result, error) {
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	if opts.WeightUnit == "" {
+		opts.WeightUnit = WeightUnitKg
+	}
+	if opts.WeightUnit != WeightUnitKg && opts.WeightUnit != WeightUnitLb {
+		return nil, fmt.Errorf("weight unit must be %s or %s", WeightUnitKg, WeightUnitLb)
+	}
+
+	buffered := bufio.NewReader(r)
+	reader := csv.NewReader(buffered)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	// European exports use semicolons, since the comma is their decimal separator
+	firstLine, _ := buffered.Peek(4096)
+	if line, _, _ := bytes.Cut(firstLine, []byte("\n")); bytes.Count(line, []byte(";")) > bytes.Count(line, []byte(",")) {
+		reader.Comma = ';'
+	}
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("the file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("the file is not valid CSV: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if _, ok := columns[name]; !ok {
+			columns[name] = i
+		}
+	}
+
+	mapper, err := findMapper(opts.Format, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Format: mapper.Name(), Skipped: []store.ImportSkippedRow{}}
+	grouper := newGrouper()
+
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				result.Rows++
+				result.Skipped = append(result.Skipped, store.ImportSkippedRow{Line: parseErr.StartLine, Reason: parseErr.Err.Error()})
+				continue
+			}
+			return nil, err
+		}
+		if isBlank(fields) {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		result.Rows++
+
+		row, err := mapper.Map(Record{Line: line, columns: columns, fields: fields}, opts)
+		if err != nil {
+			result.Skipped = append(result.Skipped, store.ImportSkippedRow{Line: line, Reason: err.Error()})
+			continue
+		}
+		grouper.add(line, row)
+	}
+
+	result.Workouts = grouper.workouts()
+	return result, nil
+}
+
+// findMapper returns the mapper named format, or the first one that recognizes the columns.
+func findMapper(format string, columns map[string]int) (Mapper, error) {
+	for _, mapper := range mappers {
+		if format == "" && mapper.Detect(columns) {
+			return mapper, nil
+		}
+		if format != "" && mapper.Name() == format {
+			if !mapper.Detect(columns) {
+				return nil, fmt.Errorf("the file doesn't have the columns of a %s export", format)
+			}
+			return mapper, nil
+		}
+	}
+
+	if format != "" {
+		return nil, fmt.Errorf("unknown format %q, expected one of: %s", format, strings.Join(Formats(), ", "))
+	}
+	return nil, fmt.Errorf("couldn't recognize the format from the header row, pass one of: %s", strings.Join(Formats(), ", "))
+}
+
+// isBlank reports whether every field of a line is empty, like the trailing lines some apps export.
+func isBlank(fields []string) bool {
+	for _, field := range fields {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// grouper collects rows into workouts, and the sets of a workout into one entry per exercise.
+type grouper struct {
+	byKey map[string]*ParsedWorkout
+	order []*ParsedWorkout
+	// entryIndex maps workout key + exercise to the position of the entry in the workout
+	entryIndex map[string]int
+}
+
+func newGrouper() *grouper {
+	return &grouper{byKey: map[string]*ParsedWorkout{}, entryIndex: map[string]int{}}
+}
+
+func (g *grouper) add(line int, row Row) {
+	workoutKey := row.StartedAt.UTC().Format(time.RFC3339) + "|" + strings.ToLower(row.WorkoutTitle)
+	parsed, ok := g.byKey[workoutKey]
+	if !ok {
+		title := row.WorkoutTitle
+		if title == "" {
+			title = defaultTitle
+		}
+		title = utils.Truncate(title, 255)
+		startedAt := row.StartedAt
+		parsed = &ParsedWorkout{FirstLine: line, Workout: &store.Workout{
+			Title:       title,
+			Description: row.WorkoutNotes,
+			PerformedAt: row.StartedAt,
+			StartedAt:   &startedAt,
+			Entries:     []store.WorkoutEntry{},
+		}}
+		g.byKey[workoutKey] = parsed
+		g.order = append(g.order, parsed)
+	}
+
+	workout := parsed.Workout
+	if row.EndedAt != nil && workout.EndedAt == nil && !row.EndedAt.Before(row.StartedAt) {
+		endedAt := *row.EndedAt
+		workout.EndedAt = &endedAt
+	}
+	if row.DurationMinutes > workout.DurationMinutes {
+		workout.DurationMinutes = row.DurationMinutes
+	}
+	if workout.Description == "" {
+		workout.Description = row.WorkoutNotes
+	}
+
+	entryKey := workoutKey + "|" + strings.ToLower(row.Exercise)
+	i, ok := g.entryIndex[entryKey]
+	if !ok {
+		i = len(workout.Entries)
+		g.entryIndex[entryKey] = i
+		workout.Entries = append(workout.Entries, store.WorkoutEntry{
+			ExerciseName: row.Exercise,
+			OrderIndex:   i + 1,
+		})
+	}
+
+	entry := &workout.Entries[i]
+	if entry.Notes == "" {
+		entry.Notes = row.ExerciseNotes
+	}
+	set := row.Set
+	set.SetNumber = len(entry.Sets) + 1
+	if set.SetType == "" {
+		set.SetType = store.SetTypeWorking
+	}
+	entry.Sets = append(entry.Sets, set)
+}
+
+// workouts returns the grouped workouts, oldest first.
+func (g *grouper) workouts() []ParsedWorkout {
+	workouts := make([]ParsedWorkout, len(g.order))
+	for i, parsed := range g.order {
+		workouts[i] = *parsed
+	}
+	sort.SliceStable(workouts, func(i, j int) bool {
+		return workouts[i].Workout.PerformedAt.Before(workouts[j].Workout.PerformedAt)
+	})
+	return workouts
+}
@@ -0,0 +1,179 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/store"
+)
+
+func TestParseStrong(t *testing.T) {
+	csv := `Date,Workout Name,Duration,Exercise Name,Set Order,Weight,Reps,Distance,Seconds,Notes,Workout Notes,RPE
+2024-03-15 17:30:00,Push,1h 5m,Bench Press (Barbell),W,60,10,0,0,,Felt good,
+2024-03-15 17:30:00,Push,1h 5m,Bench Press (Barbell),1,100,5,0,0,Paused,Felt good,8
+2024-03-15 17:30:00,Push,1h 5m,Bench Press (Barbell),Rest Timer,0,0,0,120,,,
+2024-03-15 17:30:00,Push,1h 5m,Plank,1,0,0,0,60,,,
+2024-03-15 17:30:00,Push,1h 5m,Bench Press (Barbell),2,100,5,0,0,,,11
+2024-03-17 09:00:00,Pull,45m,Deadlift (Barbell),1,140,0,0,0,,,
+`
+
+	result, err := Parse(strings.NewReader(csv), Options{})
+	require.NoError(t, err)
+	assert.Equal(t, "strong", result.Format)
+	assert.Equal(t, 6, result.Rows)
+	assert.Equal(t, []store.ImportSkippedRow{
+		{Line: 4, Reason: `set order "Rest Timer" is not a set`},
+		{Line: 7, Reason: "the set has no reps or duration"},
+	}, result.Skipped)
+
+	require.Len(t, result.Workouts, 1)
+	parsed := result.Workouts[0]
+	assert.Equal(t, 2, parsed.FirstLine)
+
+	workout := parsed.Workout
+	assert.Equal(t, "Push", workout.Title)
+	assert.Equal(t, "Felt good", workout.Description)
+	assert.Equal(t, 65, workout.DurationMinutes)
+	assert.Equal(t, time.Date(2024, 3, 15, 17, 30, 0, 0, time.UTC), workout.PerformedAt)
+
+	require.Len(t, workout.Entries, 2)
+	bench := workout.Entries[0]
+	assert.Equal(t, "Bench Press (Barbell)", bench.ExerciseName)
+	assert.Equal(t, "Paused", bench.Notes)
+	require.Len(t, bench.Sets, 3)
+	assert.Equal(t, store.SetTypeWarmup, bench.Sets[0].SetType)
+	assert.Equal(t, 3, bench.Sets[2].SetNumber)
+	assert.Equal(t, 8.0, *bench.Sets[1].RPE)
+	assert.Nil(t, bench.Sets[2].RPE, "out of range RPE is dropped")
+
+	plank := workout.Entries[1]
+	assert.Equal(t, 2, plank.OrderIndex)
+	assert.Nil(t, plank.Sets[0].Reps)
+	assert.Equal(t, 60, *plank.Sets[0].DurationSeconds)
+	assert.Nil(t, plank.Sets[0].Weight, "a weight of 0 is bodyweight")
+}
+
+func TestParseStrongSemicolons(t *testing.T) {
+	csv := "Date;Workout Name;Duration;Exercise Name;Set Order;Weight;Reps;Distance;Seconds;Notes;Workout Notes;RPE\n" +
+		"2024-03-15 17:30:00;Push;3600;Bench Press;1;102,5;5;0;0;;;\n"
+
+	result, err := Parse(strings.NewReader(csv), Options{})
+	require.NoError(t, err)
+	require.Len(t, result.Workouts, 1)
+	assert.Equal(t, 60, result.Workouts[0].Workout.DurationMinutes)
+	assert.Equal(t, 102.5, *result.Workouts[0].Workout.Entries[0].Sets[0].Weight)
+}
+
+func TestParseHevy(t *testing.T) {
+	csv := `"title","start_time","end_time","description","exercise_title","superset_id","exercise_notes","set_index","set_type","weight_lbs","reps","distance_miles","duration_seconds","rpe"
+"Legs","15 Mar 2024, 07:00","15 Mar 2024, 08:10","","Squat (Barbell)",,"",0,"warmup",135,5,,,
+"Legs","15 Mar 2024, 07:00","15 Mar 2024, 08:10","","Squat (Barbell)",,"",1,"normal",225,5,,,9
+"Legs","15 Mar 2024, 07:00","15 Mar 2024, 08:10","","Squat (Barbell)",,"",2,"superset",225,5,,,
+`
+
+	location, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	result, err := Parse(strings.NewReader(csv), Options{Location: location})
+	require.NoError(t, err)
+	assert.Equal(t, "hevy", result.Format)
+	require.Len(t, result.Skipped, 1)
+	assert.Equal(t, 4, result.Skipped[0].Line)
+
+	require.Len(t, result.Workouts, 1)
+	workout := result.Workouts[0].Workout
+	require.NoError(t, workout.ResolveTimes())
+	assert.Equal(t, time.Date(2024, 3, 15, 6, 0, 0, 0, time.UTC), workout.PerformedAt.UTC())
+	assert.Equal(t, 70, workout.DurationMinutes)
+
+	sets := workout.Entries[0].Sets
+	require.Len(t, sets, 2)
+	assert.Equal(t, store.SetTypeWarmup, sets[0].SetType)
+	assert.Equal(t, 102.06, *sets[1].Weight, "pounds are converted to kg")
+}
+
+func TestParseFitNotes(t *testing.T) {
+	csv := `Date,Exercise,Category,Weight (kgs),Reps,Distance,Distance Unit,Time,Comment
+2024-03-16,Flat Barbell Bench Press,Chest,100.0,5,,,,
+2024-03-15,Flat Barbell Bench Press,Chest,97.5,5,,,,
+2024-03-16,Running (Treadmill),Cardio,,,5.0,km,0:25:30,
+2024-03-16,Flat Barbell Bench Press,Chest,100.0,4,,,,last one
+`
+
+	result, err := Parse(strings.NewReader(csv), Options{})
+	require.NoError(t, err)
+	assert.Equal(t, "fitnotes", result.Format)
+	assert.Empty(t, result.Skipped)
+
+	// One workout per day, oldest first
+	require.Len(t, result.Workouts, 2)
+	assert.Equal(t, 3, result.Workouts[0].FirstLine)
+	day := result.Workouts[1].Workout
+	assert.Equal(t, "Workout", day.Title)
+	require.Len(t, day.Entries, 2)
+	assert.Len(t, day.Entries[0].Sets, 2)
+	assert.Equal(t, "last one", day.Entries[0].Notes)
+	assert.Equal(t, 1530, *day.Entries[1].Sets[0].DurationSeconds)
+}
+
+func TestParseGeneric(t *testing.T) {
+	csv := `date,title,exercise,set_type,reps,weight,rpe
+2024-03-15T18:00:00+01:00,Evening,Pull-up,,8,,
+2024-03-15T18:00:00+01:00,Evening,Pull-up,rest-pause,8,,
+2024-03-15T18:00:00+01:00,Evening,,working,8,,
+`
+
+	result, err := Parse(strings.NewReader(csv), Options{WeightUnit: WeightUnitLb})
+	require.NoError(t, err)
+	assert.Equal(t, "generic", result.Format)
+	assert.Len(t, result.Skipped, 2)
+	require.Len(t, result.Workouts, 1)
+	assert.Equal(t, 1, len(result.Workouts[0].Workout.Entries[0].Sets))
+}
+
+func TestParseLongTitle(t *testing.T) {
+	// 300 two-byte characters: cutting at 255 bytes would split one in half
+	title := strings.Repeat("é", 300)
+	csv := "date,title,exercise,reps\n2024-03-15,\"" + title + "\",Squat,5\n"
+
+	result, err := Parse(strings.NewReader(csv), Options{})
+	require.NoError(t, err)
+	require.Len(t, result.Workouts, 1)
+	truncated := result.Workouts[0].Workout.Title
+	assert.True(t, utf8.ValidString(truncated))
+	assert.Equal(t, strings.Repeat("é", 255), truncated)
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+		opts Options
+	}{
+		{name: "empty file", csv: ""},
+		{name: "unknown columns", csv: "when,what\n2024-03-15,Squat\n"},
+		{name: "unknown format", csv: "date,exercise\n", opts: Options{Format: "myfitnesspal"}},
+		{name: "columns of another format", csv: "date,exercise\n", opts: Options{Format: "hevy"}},
+		{name: "bad weight unit", csv: "date,exercise\n", opts: Options{WeightUnit: "stone"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.csv), tt.opts)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseStrongDuration(t *testing.T) {
+	assert.Equal(t, 65, parseStrongDuration("1h 5m"))
+	assert.Equal(t, 45, parseStrongDuration("45m"))
+	assert.Equal(t, 1, parseStrongDuration("50s"))
+	assert.Equal(t, 2, parseStrongDuration("120"))
+	assert.Equal(t, 0, parseStrongDuration(""))
+}
@@ -0,0 +1,406 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/store"
+)
+
+// strongMapper reads the export of the Strong app:
+//
+//	Date,Workout Name,Duration,Exercise Name,Set Order,Weight,Reps,Distance,Seconds,Notes,Workout Notes,RPE
+//
+// Weights are in the unit the app was set to, which older exports name in a "Weight Unit" column.
+type strongMapper struct{}
+
+func (strongMapper) Name() string { return "strong" }
+
+func (strongMapper) Detect(columns map[string]int) bool {
+	return hasColumns(columns, "date", "workout name", "exercise name", "set order")
+}
+
+func (strongMapper) Map(record Record, opts Options) (Row, error) {
+	startedAt, err := parseTime(record.Get("Date"), opts.Location, "2006-01-02 15:04:05", "2006-01-02 15:04")
+	if err != nil {
+		return Row{}, err
+	}
+
+	setType := store.SetTypeWorking
+	switch order := strings.ToUpper(record.Get("Set Order")); order {
+	case "W":
+		setType = store.SetTypeWarmup
+	case "D":
+		setType = store.SetTypeDrop
+	case "F":
+		setType = store.SetTypeFailure
+	default:
+		// Strong logs rest timers as rows of their own
+		if _, err := strconv.Atoi(order); err != nil {
+			return Row{}, fmt.Errorf("set order %q is not a set", record.Get("Set Order"))
+		}
+	}
+
+	unit := opts.WeightUnit
+	if record.Has("Weight Unit") {
+		unit = normalizeUnit(record.Get("Weight Unit"))
+	}
+
+	set, err := newSet(record.Get("Reps"), record.Get("Seconds"), record.Get("Weight"), unit, record.Get("RPE"), setType)
+	if err != nil {
+		return Row{}, err
+	}
+	exercise, err := exerciseName(record.Get("Exercise Name"))
+	if err != nil {
+		return Row{}, err
+	}
+
+	return Row{
+		WorkoutTitle:    record.Get("Workout Name"),
+		WorkoutNotes:    record.Get("Workout Notes"),
+		StartedAt:       startedAt,
+		DurationMinutes: parseStrongDuration(record.Get("Duration")),
+		Exercise:        exercise,
+		ExerciseNotes:   record.Get("Notes"),
+		Set:             set,
+	}, nil
+}
+
+// strongDurationPart matches the pieces of a Strong duration such as "1h 5m".
+var strongDurationPart = regexp.MustCompile(`(\d+)\s*([hms])`)
+
+// parseStrongDuration reads "1h 5m", "45m" or, in older exports, a number of seconds, into minutes.
+func parseStrongDuration(value string) int {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return int(math.Round(float64(seconds) / 60))
+	}
+
+	var total time.Duration
+	for _, part := range strongDurationPart.FindAllStringSubmatch(value, -1) {
+		n, _ := strconv.Atoi(part[1])
+		switch part[2] {
+		case "h":
+			total += time.Duration(n) * time.Hour
+		case "m":
+			total += time.Duration(n) * time.Minute
+		case "s":
+			total += time.Duration(n) * time.Second
+		}
+	}
+	return int(total.Round(time.Minute) / time.Minute)
+}
+
+// hevyMapper reads the export of the Hevy app:
+//
+//	title,start_time,end_time,description,exercise_title,superset_id,exercise_notes,set_index,set_type,weight_kg,reps,distance_km,duration_seconds,rpe
+//
+// Depending on the account's settings weights come as weight_kg or weight_lbs.
+type hevyMapper struct{}
+
+func (hevyMapper) Name() string { return "hevy" }
+
+func (hevyMapper) Detect(columns map[string]int) bool {
+	return hasColumns(columns, "title", "start_time", "exercise_title")
+}
+
+// hevyLayouts are the date formats Hevy exports have used.
+var hevyLayouts = []string{"2 Jan 2006, 15:04", "2 Jan 2006 15:04", "2006-01-02 15:04:05", time.RFC3339}
+
+func (hevyMapper) Map(record Record, opts Options) (Row, error) {
+	startedAt, err := parseTime(record.Get("start_time"), opts.Location, hevyLayouts...)
+	if err != nil {
+		return Row{}, err
+	}
+	var endedAt *time.Time
+	if value := record.Get("end_time"); value != "" {
+		end, err := parseTime(value, opts.Location, hevyLayouts...)
+		if err != nil {
+			return Row{}, err
+		}
+		endedAt = &end
+	}
+
+	var setType string
+	switch record.Get("set_type") {
+	case "", "normal":
+		setType = store.SetTypeWorking
+	case "warmup":
+		setType = store.SetTypeWarmup
+	case "dropset":
+		setType = store.SetTypeDrop
+	case "failure":
+		setType = store.SetTypeFailure
+	default:
+		return Row{}, fmt.Errorf("unknown set type %q", record.Get("set_type"))
+	}
+
+	weight, unit := record.Get("weight_kg"), WeightUnitKg
+	if !record.Has("weight_kg") {
+		weight, unit = record.Get("weight_lbs"), WeightUnitLb
+	}
+
+	set, err := newSet(record.Get("reps"), record.Get("duration_seconds"), weight, unit, record.Get("rpe"), setType)
+	if err != nil {
+		return Row{}, err
+	}
+	exercise, err := exerciseName(record.Get("exercise_title"))
+	if err != nil {
+		return Row{}, err
+	}
+
+	return Row{
+		WorkoutTitle:  record.Get("title"),
+		WorkoutNotes:  record.Get("description"),
+		StartedAt:     startedAt,
+		EndedAt:       endedAt,
+		Exercise:      exercise,
+		ExerciseNotes: record.Get("exercise_notes"),
+		Set:           set,
+	}, nil
+}
+
+// fitNotesMapper reads the export of the FitNotes app:
+//
+//	Date,Exercise,Category,Weight (kgs),Reps,Distance,Distance Unit,Time,Comment
+//
+// FitNotes has no workouts, only days, so every day becomes one workout.
+type fitNotesMapper struct{}
+
+func (fitNotesMapper) Name() string { return "fitnotes" }
+
+func (fitNotesMapper) Detect(columns map[string]int) bool {
+	return hasColumns(columns, "date", "exercise", "category")
+}
+
+func (fitNotesMapper) Map(record Record, opts Options) (Row, error) {
+	startedAt, err := parseTime(record.Get("Date"), opts.Location, "2006-01-02")
+	if err != nil {
+		return Row{}, err
+	}
+
+	weight, unit := record.Get("Weight"), opts.WeightUnit
+	switch {
+	case record.Has("Weight (kgs)") || record.Has("Weight (kg)"):
+		weight, unit = record.Get("Weight (kgs)", "Weight (kg)"), WeightUnitKg
+	case record.Has("Weight (lbs)") || record.Has("Weight (lb)"):
+		weight, unit = record.Get("Weight (lbs)", "Weight (lb)"), WeightUnitLb
+	}
+
+	seconds := ""
+	if value := record.Get("Time"); value != "" {
+		parsed, err := parseClock(value)
+		if err != nil {
+			return Row{}, err
+		}
+		seconds = strconv.Itoa(parsed)
+	}
+
+	set, err := newSet(record.Get("Reps"), seconds, weight, unit, "", store.SetTypeWorking)
+	if err != nil {
+		return Row{}, err
+	}
+	exercise, err := exerciseName(record.Get("Exercise"))
+	if err != nil {
+		return Row{}, err
+	}
+
+	return Row{
+		StartedAt:     startedAt,
+		Exercise:      exercise,
+		ExerciseNotes: record.Get("Comment"),
+		Set:           set,
+	}, nil
+}
+
+// parseClock reads a "H:MM:SS" or "MM:SS" duration into seconds.
+func parseClock(value string) (int, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+
+	seconds := 0
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid time %q", value)
+		}
+		seconds = seconds*60 + n
+	}
+	return seconds, nil
+}
+
+// genericMapper reads a plain format for data coming from anywhere else:
+//
+//	date,title,exercise,set_type,reps,weight,duration_seconds,rpe,notes,workout_notes,duration_minutes
+//
+// Only date and exercise are required. Dates are RFC 3339 or "2006-01-02 15:04[:05]" in the file's
+// time zone, and weights are in the unit picked for the import.
+type genericMapper struct{}
+
+func (genericMapper) Name() string { return "generic" }
+
+func (genericMapper) Detect(columns map[string]int) bool {
+	return hasColumns(columns, "date", "exercise")
+}
+
+func (genericMapper) Map(record Record, opts Options) (Row, error) {
+	startedAt, err := parseTime(record.Get("date"), opts.Location,
+		time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02T15:04:05", "2006-01-02")
+	if err != nil {
+		return Row{}, err
+	}
+
+	setType := strings.ToLower(record.Get("set_type"))
+	switch setType {
+	case "":
+		setType = store.SetTypeWorking
+	case store.SetTypeWarmup, store.SetTypeWorking, store.SetTypeDrop, store.SetTypeFailure:
+	default:
+		return Row{}, fmt.Errorf("set_type must be warmup, working, drop or failure, got %q", setType)
+	}
+
+	set, err := newSet(record.Get("reps"), record.Get("duration_seconds"), record.Get("weight"), opts.WeightUnit, record.Get("rpe"), setType)
+	if err != nil {
+		return Row{}, err
+	}
+	exercise, err := exerciseName(record.Get("exercise"))
+	if err != nil {
+		return Row{}, err
+	}
+
+	durationMinutes := 0
+	if value := record.Get("duration_minutes"); value != "" {
+		durationMinutes, err = strconv.Atoi(value)
+		if err != nil || durationMinutes < 0 {
+			return Row{}, fmt.Errorf("invalid duration_minutes %q", value)
+		}
+	}
+
+	return Row{
+		WorkoutTitle:    record.Get("title"),
+		WorkoutNotes:    record.Get("workout_notes"),
+		StartedAt:       startedAt,
+		DurationMinutes: durationMinutes,
+		Exercise:        exercise,
+		ExerciseNotes:   record.Get("notes"),
+		Set:             set,
+	}, nil
+}
+
+// hasColumns reports whether every name is a column of the file.
+func hasColumns(columns map[string]int, names ...string) bool {
+	for _, name := range names {
+		if _, ok := columns[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// parseTime parses value with the first layout that fits; layouts without an offset are read in location.
+func parseTime(value string, location *time.Location, layouts ...string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("the date is missing")
+	}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, value, location); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// parseNumber reads a decimal number, accepting a comma as the decimal separator.
+// An empty value is nil.
+func parseNumber(value string) (*float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if !strings.Contains(value, ".") {
+		value = strings.Replace(value, ",", ".", 1)
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+		return nil, fmt.Errorf("invalid number %q", value)
+	}
+	return &n, nil
+}
+
+// normalizeUnit maps the ways apps spell weight units onto WeightUnitKg and WeightUnitLb.
+func normalizeUnit(unit string) string {
+	switch strings.ToLower(unit) {
+	case "lb", "lbs", "pound", "pounds":
+		return WeightUnitLb
+	}
+	return WeightUnitKg
+}
+
+// newSet builds a set from the raw columns of a row. A set has reps, or a duration when it has no reps
+// (planks, cardio); rows with neither are rejected. Weights are converted to kg, a weight of 0 means
+// bodyweight, and an RPE outside 1-10 is dropped rather than rejecting the set.
+func newSet(reps, seconds, weight, unit, rpe, setType string) (store.WorkoutSet, error) {
+	set := store.WorkoutSet{SetType: setType, Completed: true}
+
+	repCount, err := parseNumber(reps)
+	if err != nil {
+		return set, err
+	}
+	duration, err := parseNumber(seconds)
+	if err != nil {
+		return set, err
+	}
+	switch {
+	case repCount != nil && *repCount > 0:
+		n := int(math.Round(*repCount))
+		set.Reps = &n
+	case duration != nil && *duration > 0:
+		n := int(math.Round(*duration))
+		set.DurationSeconds = &n
+	default:
+		return set, errors.New("the set has no reps or duration")
+	}
+
+	load, err := parseNumber(weight)
+	if err != nil {
+		return set, err
+	}
+	if load != nil && *load != 0 {
+		kg := *load
+		if unit == WeightUnitLb {
+			kg *= kgPerLb
+		}
+		kg = math.Round(kg*100) / 100
+		if kg < 0 || kg >= 10000 {
+			return set, fmt.Errorf("weight %s is out of range", weight)
+		}
+		set.Weight = &kg
+	}
+
+	effort, err := parseNumber(rpe)
+	if err != nil {
+		return set, err
+	}
+	if effort != nil && *effort >= 1 && *effort <= 10 {
+		rounded := math.Round(*effort*10) / 10
+		set.RPE = &rounded
+	}
+
+	return set, nil
+}
+
+// exerciseName checks the exercise of a row.
+func exerciseName(name string) (string, error) {
+	if name == "" {
+		return "", errors.New("the exercise name is missing")
+	}
+	if len(name) > 255 {
+		return "", errors.New("the exercise name is longer than 255 chars")
+	}
+	return name, nil
+}
@@ -0,0 +1,111 @@
+package live
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Event types sent to the clients following a session.
+const (
+	EventSnapshot         = "snapshot"
+	EventSetLogged        = "set_logged"
+	EventRestTimerStarted = "rest_timer_started"
+	EventSessionFinished  = "session_finished"
+	EventSessionCancelled = "session_cancelled"
+)
+
+// subscriberBuffer is how many events a slow client may fall behind before events are dropped for it.
+const subscriberBuffer = 32
+
+// Event is one message of a session stream. Data is encoded as JSON.
+type Event struct {
+	Type string
+	Data interface{}
+	At   time.Time
+}
+
+// Hub fans out session events to every connected client of that session.
+// It lives in memory, so clients have to be connected to the instance that logs the sets;
+// the snapshot sent on connect lets a client catch up after reconnecting.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[int]map[chan Event]struct{}
+}
+
+// NewHub is a constructor for Hub.
+func NewHub() *Hub {
+	return &Hub{subscribers: map[int]map[chan Event]struct{}{}}
+}
+
+// Subscribe registers a client for the session's events.
+// The returned function unsubscribes and must be called when the client goes away.
+func (h *Hub) Subscribe(sessionID int) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	h.mu.Lock()
+	if h.subscribers[sessionID] == nil {
+		h.subscribers[sessionID] = map[chan Event]struct{}{}
+	}
+	h.subscribers[sessionID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subscribers[sessionID], ch)
+			if len(h.subscribers[sessionID]) == 0 {
+				delete(h.subscribers, sessionID)
+			}
+			h.mu.Unlock()
+		})
+	}
+
+	return ch, unsubscribe
+}
+
+// Publish sends the event to every client of the session without blocking:
+// a client whose buffer is full misses the event rather than stalling the logger.
+func (h *Hub) Publish(sessionID int, event Event) {
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subscribers[sessionID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Subscribers returns how many clients follow the session.
+func (h *Hub) Subscribers(sessionID int) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.subscribers[sessionID])
+}
+
+// WriteSSE writes the event in the text/event-stream format:
+//
+//	event: set_logged
+//	data: {"at":"...","data":{...}}
+func WriteSSE(w io.Writer, event Event) error {
+	payload, err := json.Marshal(struct {
+		At   time.Time   `json:"at"`
+		Data interface{} `json:"data"`
+	}{At: event.At, Data: event.Data})
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, payload)
+	return err
+}
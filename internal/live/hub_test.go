@@ -0,0 +1,74 @@
+package live
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHubPublish(t *testing.T) {
+	hub := NewHub()
+
+	coach, unsubscribeCoach := hub.Subscribe(1)
+	phone, unsubscribePhone := hub.Subscribe(1)
+	other, unsubscribeOther := hub.Subscribe(2)
+	defer unsubscribeOther()
+	assert.Equal(t, 2, hub.Subscribers(1))
+
+	hub.Publish(1, Event{Type: EventSetLogged, Data: 42})
+
+	for _, ch := range []<-chan Event{coach, phone} {
+		select {
+		case event := <-ch:
+			assert.Equal(t, EventSetLogged, event.Type)
+			assert.Equal(t, 42, event.Data)
+			assert.False(t, event.At.IsZero())
+		case <-time.After(time.Second):
+			t.Fatal("event not delivered")
+		}
+	}
+
+	select {
+	case <-other:
+		t.Fatal("event delivered to another session")
+	default:
+	}
+
+	unsubscribeCoach()
+	unsubscribeCoach()
+	assert.Equal(t, 1, hub.Subscribers(1))
+	unsubscribePhone()
+	assert.Equal(t, 0, hub.Subscribers(1))
+}
+
+func TestHubPublishDoesNotBlock(t *testing.T) {
+	hub := NewHub()
+	_, unsubscribe := hub.Subscribe(1)
+	defer unsubscribe()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < subscriberBuffer*2; i++ {
+			hub.Publish(1, Event{Type: EventSetLogged})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("publish blocked on a slow subscriber")
+	}
+}
+
+func TestWriteSSE(t *testing.T) {
+	var buf bytes.Buffer
+	at := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	err := WriteSSE(&buf, Event{Type: EventRestTimerStarted, Data: map[string]int{"seconds": 90}, At: at})
+	require.NoError(t, err)
+	assert.Equal(t, "event: rest_timer_started\ndata: {\"at\":\"2026-05-01T10:00:00Z\",\"data\":{\"seconds\":90}}\n\n", buf.String())
+}
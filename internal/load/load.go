@@ -0,0 +1,207 @@
+package load
+
+import (
+	"math"
+	"time"
+)
+
+// Windows of the acute:chronic workload ratio, in days.
+const (
+	AcuteDays   = 7
+	ChronicDays = 28
+)
+
+// The ACWR band research associates with the lowest injury risk. Outside of it the
+// athlete trains a lot more (a spike) or less than they are used to.
+const (
+	SafeACWRLow  = 0.8
+	SafeACWRHigh = 1.3
+)
+
+// Flags raised on a day whose ratio leaves the safe band.
+const (
+	FlagRollingACWRHigh = "rolling_acwr_high"
+	FlagRollingACWRLow  = "rolling_acwr_low"
+	FlagEWMAACWRHigh    = "ewma_acwr_high"
+	FlagEWMAACWRLow     = "ewma_acwr_low"
+)
+
+// Model holds the parameters of the Banister fitness-fatigue model: fitness and fatigue
+// decay with the time constants (in days), and form is their weighted difference.
+type Model struct {
+	FitnessDays   float64
+	FatigueDays   float64
+	FitnessWeight float64
+	FatigueWeight float64
+}
+
+// DefaultModel uses the time constants commonly used for the model: fitness fades over
+// about six weeks, fatigue over a week but weighs twice as much.
+var DefaultModel = Model{FitnessDays: 42, FatigueDays: 7, FitnessWeight: 1, FatigueWeight: 2}
+
+// Day is the internal training load of one calendar day: session RPE × minutes summed over
+// its workouts, in arbitrary units. Unrated counts the workouts of the day without a
+// session RPE, which add no load. Date is midnight UTC of that day.
+type Day struct {
+	Date    time.Time
+	Load    float64
+	Unrated int
+}
+
+// Point is the state of the athlete at the end of one day.
+//   - AcuteLoad and ChronicLoad are the mean daily load of the last 7 and 28 days,
+//     RollingACWR their ratio; EWMAAcute, EWMAChronic and EWMAACWR the same with
+//     exponentially weighted moving averages. Ratios are nil until 28 days of history
+//     exist, or while the chronic load is 0.
+//   - Monotony is the mean daily load of the last 7 days divided by its standard
+//     deviation, Strain the weekly load times the monotony; nil when every day was the same.
+//   - Fitness, Fatigue and Form come from the Banister model.
+//   - UnratedWorkouts are the workouts of the day that have no session RPE to count.
+type Point struct {
+	Date        string   `json:"date"`
+	Load        float64  `json:"load"`
+	AcuteLoad   float64  `json:"acute_load"`
+	ChronicLoad float64  `json:"chronic_load"`
+	RollingACWR *float64 `json:"rolling_acwr"`
+	EWMAAcute   float64  `json:"ewma_acute"`
+	EWMAChronic float64  `json:"ewma_chronic"`
+	EWMAACWR    *float64 `json:"ewma_acwr"`
+	Monotony    *float64 `json:"monotony"`
+	Strain      *float64 `json:"strain"`
+	Fitness     float64  `json:"fitness"`
+	Fatigue     float64  `json:"fatigue"`
+	Form        float64  `json:"form"`
+	Flags       []string `json:"flags,omitempty"`
+
+	UnratedWorkouts int `json:"unrated_workouts,omitempty"`
+}
+
+// Compute returns one point for every day from the first day with a load through the
+// given date, oldest first. days must be in date order; days after through are ignored.
+func Compute(days []Day, through time.Time, model Model) []Point {
+	if len(days) == 0 || days[0].Date.After(through) {
+		return []Point{}
+	}
+
+	// Daily loads, rest days included
+	start := days[0].Date
+	loads, unrated := []float64{}, []int{}
+	next := 0
+	for date := start; !date.After(through); date = date.AddDate(0, 0, 1) {
+		load, count := 0.0, 0
+		for next < len(days) && !days[next].Date.After(date) {
+			if days[next].Date.Equal(date) {
+				load += days[next].Load
+				count += days[next].Unrated
+			}
+			next++
+		}
+		loads, unrated = append(loads, load), append(unrated, count)
+	}
+
+	acuteDecay := 2.0 / (AcuteDays + 1)
+	chronicDecay := 2.0 / (ChronicDays + 1)
+	fitnessDecay := math.Exp(-1 / model.FitnessDays)
+	fatigueDecay := math.Exp(-1 / model.FatigueDays)
+
+	points := make([]Point, 0, len(loads))
+	var ewmaAcute, ewmaChronic, fitness, fatigue float64
+	for i, load := range loads {
+		ewmaAcute = load*acuteDecay + (1-acuteDecay)*ewmaAcute
+		ewmaChronic = load*chronicDecay + (1-chronicDecay)*ewmaChronic
+		fitness = fitness*fitnessDecay + load
+		fatigue = fatigue*fatigueDecay + load
+
+		acute := mean(window(loads, i, AcuteDays))
+		chronic := mean(window(loads, i, ChronicDays))
+		point := Point{
+			Date:        start.AddDate(0, 0, i).Format(time.DateOnly),
+			Load:        round(load),
+			AcuteLoad:   round(acute),
+			ChronicLoad: round(chronic),
+			EWMAAcute:   round(ewmaAcute),
+			EWMAChronic: round(ewmaChronic),
+			Fitness:     round(fitness),
+			Fatigue:     round(fatigue),
+			Form:        round(model.FitnessWeight*fitness - model.FatigueWeight*fatigue),
+
+			UnratedWorkouts: unrated[i],
+		}
+
+		if i+1 >= ChronicDays {
+			point.RollingACWR = ratio(acute, chronic)
+			point.EWMAACWR = ratio(ewmaAcute, ewmaChronic)
+			point.Flags = flags(point.RollingACWR, FlagRollingACWRLow, FlagRollingACWRHigh, point.Flags)
+			point.Flags = flags(point.EWMAACWR, FlagEWMAACWRLow, FlagEWMAACWRHigh, point.Flags)
+		}
+
+		week := window(loads, i, AcuteDays)
+		if len(week) == AcuteDays {
+			if sd := stddev(week); sd > 0 {
+				monotony := acute / sd
+				strain := round(acute * AcuteDays * monotony)
+				monotony = round(monotony)
+				point.Monotony, point.Strain = &monotony, &strain
+			}
+		}
+
+		points = append(points, point)
+	}
+
+	return points
+}
+
+// window returns up to size loads ending at index i.
+func window(loads []float64, i, size int) []float64 {
+	from := i + 1 - size
+	if from < 0 {
+		from = 0
+	}
+	return loads[from : i+1]
+}
+
+// mean is the mean of the values; a window cut short by the start of the history
+// averages the days it has.
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// stddev is the population standard deviation of the values.
+func stddev(values []float64) float64 {
+	m := mean(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += (v - m) * (v - m)
+	}
+	return math.Sqrt(sum / float64(len(values)))
+}
+
+// ratio returns acute/chronic rounded, or nil when there is no chronic load to compare to.
+func ratio(acute, chronic float64) *float64 {
+	if chronic <= 0 {
+		return nil
+	}
+	r := round(acute / chronic)
+	return &r
+}
+
+// flags appends the low or high flag when the ratio is outside the safe band.
+func flags(ratio *float64, low, high string, found []string) []string {
+	switch {
+	case ratio == nil:
+	case *ratio < SafeACWRLow:
+		found = append(found, low)
+	case *ratio > SafeACWRHigh:
+		found = append(found, high)
+	}
+	return found
+}
+
+// round keeps two decimals, plenty for loads in the hundreds and ratios around 1.
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
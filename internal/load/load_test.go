@@ -0,0 +1,75 @@
+package load
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC)
+
+// steady trains every other day with the given load for the number of days.
+func steady(days int, load float64) []Day {
+	found := []Day{}
+	for i := 0; i < days; i += 2 {
+		found = append(found, Day{Date: start.AddDate(0, 0, i), Load: load})
+	}
+	return found
+}
+
+func TestComputeEmpty(t *testing.T) {
+	assert.Empty(t, Compute(nil, start, DefaultModel))
+	assert.Empty(t, Compute([]Day{{Date: start.AddDate(0, 0, 1), Load: 300}}, start, DefaultModel))
+}
+
+func TestComputeRestDaysAndRatios(t *testing.T) {
+	days := steady(28, 400)
+	points := Compute(days, start.AddDate(0, 0, 27), DefaultModel)
+	require.Len(t, points, 28)
+
+	assert.Equal(t, "2026-03-02", points[0].Date)
+	assert.Equal(t, 400.0, points[0].Load)
+	assert.Equal(t, 0.0, points[1].Load)
+
+	// Not enough history for a ratio before day 28
+	assert.Nil(t, points[26].RollingACWR)
+	assert.Nil(t, points[26].EWMAACWR)
+
+	// 3 sessions in the last week against 14 in the month: 171.43 / 200
+	last := points[27]
+	require.NotNil(t, last.RollingACWR)
+	assert.Equal(t, 0.86, *last.RollingACWR)
+	assert.Empty(t, last.Flags)
+	require.NotNil(t, last.Monotony)
+	require.NotNil(t, last.Strain)
+}
+
+func TestComputeFlagsSpike(t *testing.T) {
+	days := steady(28, 200)
+	// A hard week on top of a light month
+	for i := 28; i < 35; i++ {
+		days = append(days, Day{Date: start.AddDate(0, 0, i), Load: 600})
+	}
+
+	points := Compute(days, start.AddDate(0, 0, 34), DefaultModel)
+	last := points[len(points)-1]
+	assert.Contains(t, last.Flags, FlagRollingACWRHigh)
+	assert.Contains(t, last.Flags, FlagEWMAACWRHigh)
+	assert.Nil(t, last.Monotony, "the same load every day has no variation")
+
+	// Fatigue builds faster than fitness, so form drops
+	assert.Greater(t, last.Fatigue, points[27].Fatigue)
+	assert.Less(t, last.Form, points[27].Form)
+}
+
+func TestComputeFlagsDetraining(t *testing.T) {
+	days := steady(28, 400)
+	points := Compute(days, start.AddDate(0, 0, 40), DefaultModel)
+
+	last := points[len(points)-1]
+	assert.Equal(t, 0.0, last.AcuteLoad)
+	assert.Contains(t, last.Flags, FlagRollingACWRLow)
+	assert.Contains(t, last.Flags, FlagEWMAACWRLow)
+}
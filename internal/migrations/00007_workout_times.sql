@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE workouts
+ADD COLUMN performed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+ADD COLUMN started_at TIMESTAMP WITH TIME ZONE,
+ADD COLUMN ended_at TIMESTAMP WITH TIME ZONE,
+ADD CONSTRAINT valid_workout_times CHECK (
+  started_at IS NULL OR ended_at IS NULL OR ended_at >= started_at
+);
+
+-- Existing workouts were logged without a date, so the best guess is when they were created
+UPDATE workouts SET performed_at = created_at WHERE created_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_workouts_user_performed_at ON workouts (user_id, performed_at DESC, id DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_workouts_user_performed_at;
+ALTER TABLE workouts
+DROP CONSTRAINT valid_workout_times,
+DROP COLUMN performed_at,
+DROP COLUMN started_at,
+DROP COLUMN ended_at;
+-- +goose StatementEnd
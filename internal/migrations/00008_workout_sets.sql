@@ -0,0 +1,66 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS workout_sets (
+  id BIGSERIAL PRIMARY KEY,
+  workout_entry_id BIGINT NOT NULL REFERENCES workout_entries(id) ON DELETE CASCADE,
+  set_number INTEGER NOT NULL,
+  reps INTEGER,
+  duration_seconds INTEGER,
+  weight DECIMAL(6, 2),
+  rpe DECIMAL(3, 1),
+  set_type VARCHAR(20) NOT NULL DEFAULT 'working',
+  completed BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (workout_entry_id, set_number),
+  CONSTRAINT valid_workout_set CHECK (
+    (reps IS NOT NULL OR duration_seconds IS NOT NULL) AND
+    (reps IS NULL OR duration_seconds IS NULL)
+  ),
+  CONSTRAINT valid_set_type CHECK (set_type IN ('warmup', 'working', 'drop', 'failure')),
+  CONSTRAINT valid_rpe CHECK (rpe IS NULL OR (rpe >= 1 AND rpe <= 10))
+);
+
+-- Expand every aggregate entry (3 x 10 @ 100) into identical working sets
+INSERT INTO workout_sets (workout_entry_id, set_number, reps, duration_seconds, weight)
+SELECT e.id, n.set_number, e.reps, e.duration_seconds, e.weight
+FROM workout_entries e
+CROSS JOIN LATERAL generate_series(1, GREATEST(e.sets, 1)) AS n(set_number);
+
+ALTER TABLE workout_entries
+DROP CONSTRAINT valid_workout_entry,
+DROP COLUMN sets,
+DROP COLUMN reps,
+DROP COLUMN duration_seconds,
+DROP COLUMN weight;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE workout_entries
+ADD COLUMN sets INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN reps INTEGER,
+ADD COLUMN duration_seconds INTEGER,
+ADD COLUMN weight DECIMAL(5, 2);
+
+-- Collapse sets back into one aggregate row per entry, keeping the top set
+UPDATE workout_entries e
+SET sets = s.set_count, reps = s.reps, duration_seconds = s.duration_seconds, weight = s.weight
+FROM (
+  SELECT workout_entry_id, COUNT(*) AS set_count, MAX(reps) AS reps,
+    CASE WHEN MAX(reps) IS NULL THEN MAX(duration_seconds) END AS duration_seconds, MAX(weight) AS weight
+  FROM workout_sets
+  GROUP BY workout_entry_id
+) s
+WHERE s.workout_entry_id = e.id;
+
+UPDATE workout_entries SET reps = 0 WHERE reps IS NULL AND duration_seconds IS NULL;
+
+ALTER TABLE workout_entries
+ALTER COLUMN sets DROP DEFAULT,
+ADD CONSTRAINT valid_workout_entry CHECK (
+  (reps IS NOT NULL OR duration_seconds IS NOT NULL) AND
+  (reps IS NULL OR duration_seconds IS NULL)
+);
+
+DROP TABLE workout_sets;
+-- +goose StatementEnd
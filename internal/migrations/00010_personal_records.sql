@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS personal_records (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  exercise_id BIGINT REFERENCES exercises(id) ON DELETE CASCADE,
+  exercise_name VARCHAR(255) NOT NULL,
+  record_type VARCHAR(30) NOT NULL,
+  value DECIMAL(12, 2) NOT NULL,
+  weight DECIMAL(6, 2),
+  reps INTEGER,
+  workout_id BIGINT NOT NULL REFERENCES workouts(id) ON DELETE CASCADE,
+  achieved_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT valid_record_type CHECK (
+    record_type IN ('heaviest_weight', 'max_reps_at_weight', 'best_e1rm', 'longest_duration', 'max_session_volume')
+  )
+);
+
+CREATE INDEX IF NOT EXISTS idx_personal_records_user ON personal_records (user_id, exercise_id, record_type);
+CREATE INDEX IF NOT EXISTS idx_personal_records_workout ON personal_records (workout_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE personal_records;
+-- +goose StatementEnd
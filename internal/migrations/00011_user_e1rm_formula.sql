@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+ADD COLUMN e1rm_formula VARCHAR(20) NOT NULL DEFAULT 'epley',
+ADD CONSTRAINT valid_e1rm_formula CHECK (e1rm_formula IN ('epley', 'brzycki', 'lombardi', 'wathan'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN e1rm_formula;
+-- +goose StatementEnd
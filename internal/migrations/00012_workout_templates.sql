@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS workout_templates (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name VARCHAR(255) NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_workout_templates_user ON workout_templates (user_id);
+
+CREATE TABLE IF NOT EXISTS template_entries (
+  id BIGSERIAL PRIMARY KEY,
+  template_id BIGINT NOT NULL REFERENCES workout_templates(id) ON DELETE CASCADE,
+  exercise_id BIGINT REFERENCES exercises(id) ON DELETE SET NULL,
+  exercise_name VARCHAR(255) NOT NULL,
+  target_sets INTEGER NOT NULL,
+  target_reps INTEGER,
+  target_duration_seconds INTEGER,
+  target_weight DECIMAL(6, 2),
+  notes TEXT NOT NULL DEFAULT '',
+  order_index INTEGER NOT NULL,
+  CONSTRAINT valid_template_entry CHECK (
+    target_sets > 0 AND
+    (target_reps IS NOT NULL OR target_duration_seconds IS NOT NULL) AND
+    (target_reps IS NULL OR target_duration_seconds IS NULL)
+  )
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE template_entries;
+DROP TABLE workout_templates;
+-- +goose StatementEnd
@@ -0,0 +1,59 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS programs (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name VARCHAR(255) NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_programs_user ON programs (user_id);
+
+-- A template can't be deleted while a program still uses it
+CREATE TABLE IF NOT EXISTS program_days (
+  id BIGSERIAL PRIMARY KEY,
+  program_id BIGINT NOT NULL REFERENCES programs(id) ON DELETE CASCADE,
+  week_number INTEGER NOT NULL CHECK (week_number > 0),
+  day_number INTEGER NOT NULL CHECK (day_number > 0),
+  name VARCHAR(255) NOT NULL DEFAULT '',
+  template_id BIGINT NOT NULL REFERENCES workout_templates(id) ON DELETE RESTRICT,
+  UNIQUE (program_id, week_number, day_number)
+);
+
+-- A rule without exercise_id and exercise_name applies to every exercise of the program
+CREATE TABLE IF NOT EXISTS progression_rules (
+  id BIGSERIAL PRIMARY KEY,
+  program_id BIGINT NOT NULL REFERENCES programs(id) ON DELETE CASCADE,
+  exercise_id BIGINT REFERENCES exercises(id) ON DELETE CASCADE,
+  exercise_name VARCHAR(255) NOT NULL DEFAULT '',
+  increment DECIMAL(6, 2) NOT NULL DEFAULT 0 CHECK (increment >= 0),
+  deload_percent DECIMAL(4, 1) NOT NULL DEFAULT 0 CHECK (deload_percent >= 0 AND deload_percent < 100),
+  deload_after_failures INTEGER NOT NULL DEFAULT 0 CHECK (deload_after_failures >= 0)
+);
+
+CREATE TABLE IF NOT EXISTS program_enrollments (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  program_id BIGINT NOT NULL REFERENCES programs(id) ON DELETE CASCADE,
+  started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  active BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Only one program can be followed at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_program_enrollments_active ON program_enrollments (user_id) WHERE active;
+
+-- Workouts logged for a program day drive the progression of the program
+ALTER TABLE workouts ADD COLUMN program_day_id BIGINT REFERENCES program_days(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_workouts_program_day ON workouts (program_day_id) WHERE program_day_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE workouts DROP COLUMN program_day_id;
+DROP TABLE program_enrollments;
+DROP TABLE progression_rules;
+DROP TABLE program_days;
+DROP TABLE programs;
+-- +goose StatementEnd
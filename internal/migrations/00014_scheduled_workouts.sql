@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS scheduled_workouts (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  template_id BIGINT REFERENCES workout_templates(id) ON DELETE SET NULL,
+  program_day_id BIGINT REFERENCES program_days(id) ON DELETE SET NULL,
+  title VARCHAR(255) NOT NULL,
+  notes TEXT NOT NULL DEFAULT '',
+  planned_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  duration_minutes INTEGER NOT NULL DEFAULT 60 CHECK (duration_minutes > 0),
+  status VARCHAR(20) NOT NULL DEFAULT 'planned' CHECK (status IN ('planned', 'completed', 'skipped')),
+  workout_id BIGINT REFERENCES workouts(id) ON DELETE SET NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_workouts_user_planned ON scheduled_workouts (user_id, planned_at);
+CREATE INDEX IF NOT EXISTS idx_scheduled_workouts_workout ON scheduled_workouts (workout_id) WHERE workout_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE scheduled_workouts;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS workout_entry_groups (
+  id BIGSERIAL PRIMARY KEY,
+  workout_id BIGINT NOT NULL REFERENCES workouts(id) ON DELETE CASCADE,
+  label VARCHAR(20) NOT NULL,
+  group_type VARCHAR(20) NOT NULL CHECK (group_type IN ('superset', 'circuit', 'emom', 'amrap')),
+  rounds INTEGER CHECK (rounds > 0),
+  rest_between_rounds_seconds INTEGER CHECK (rest_between_rounds_seconds >= 0),
+  UNIQUE (workout_id, label)
+);
+
+ALTER TABLE workout_entries ADD COLUMN group_id BIGINT REFERENCES workout_entry_groups(id) ON DELETE SET NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE workout_entries DROP COLUMN group_id;
+DROP TABLE workout_entry_groups;
+-- +goose StatementEnd
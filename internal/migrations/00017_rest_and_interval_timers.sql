@@ -0,0 +1,33 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Rest is recorded on the set it follows: prescribed is what the athlete planned, actual is what they took
+ALTER TABLE workout_sets
+  ADD COLUMN rest_seconds INTEGER CHECK (rest_seconds >= 0),
+  ADD COLUMN prescribed_rest_seconds INTEGER CHECK (prescribed_rest_seconds >= 0);
+
+ALTER TABLE live_session_sets
+  ADD COLUMN rest_seconds INTEGER CHECK (rest_seconds >= 0),
+  ADD COLUMN prescribed_rest_seconds INTEGER CHECK (prescribed_rest_seconds >= 0);
+
+-- Phase by phase timings of the interval timers run for an entry (Tabata, EMOM, custom)
+ALTER TABLE workout_entries ADD COLUMN interval_timings JSONB;
+
+CREATE TABLE IF NOT EXISTS live_session_timers (
+  id BIGSERIAL PRIMARY KEY,
+  session_id BIGINT NOT NULL REFERENCES live_sessions(id) ON DELETE CASCADE,
+  exercise_id BIGINT REFERENCES exercises(id) ON DELETE SET NULL,
+  exercise_name VARCHAR(255) NOT NULL,
+  timings JSONB NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_live_session_timers_session ON live_session_timers (session_id, id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE live_session_timers;
+ALTER TABLE workout_entries DROP COLUMN interval_timings;
+ALTER TABLE live_session_sets DROP COLUMN prescribed_rest_seconds, DROP COLUMN rest_seconds;
+ALTER TABLE workout_sets DROP COLUMN prescribed_rest_seconds, DROP COLUMN rest_seconds;
+-- +goose StatementEnd
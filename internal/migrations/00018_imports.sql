@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS imports (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  format VARCHAR(20) NOT NULL DEFAULT '',
+  filename VARCHAR(255) NOT NULL DEFAULT '',
+  status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+  total_rows INTEGER NOT NULL DEFAULT 0,
+  total_workouts INTEGER NOT NULL DEFAULT 0,
+  imported_workouts INTEGER NOT NULL DEFAULT 0,
+  duplicate_workouts INTEGER NOT NULL DEFAULT 0,
+  -- The report: rows that couldn't be imported and workouts that were already logged
+  skipped_rows JSONB NOT NULL DEFAULT '[]',
+  duplicates JSONB NOT NULL DEFAULT '[]',
+  error TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  started_at TIMESTAMP WITH TIME ZONE,
+  finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_imports_user ON imports (user_id, created_at DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE imports;
+-- +goose StatementEnd
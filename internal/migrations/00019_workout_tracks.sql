@@ -0,0 +1,47 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+ADD COLUMN max_heart_rate INTEGER CHECK (max_heart_rate BETWEEN 100 AND 250);
+
+-- A GPS or heart rate recording of a cardio workout, uploaded as a GPX or TCX file.
+-- The metrics are computed once on upload; heart_rate_zones holds the seconds spent in
+-- each of the 5 zones and is recomputed when the user changes their max heart rate.
+CREATE TABLE IF NOT EXISTS workout_tracks (
+  id BIGSERIAL PRIMARY KEY,
+  workout_id BIGINT NOT NULL REFERENCES workouts(id) ON DELETE CASCADE,
+  format VARCHAR(10) NOT NULL CHECK (format IN ('gpx', 'tcx')),
+  name VARCHAR(255) NOT NULL DEFAULT '',
+  started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  ended_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  point_count INTEGER NOT NULL,
+  distance_meters DOUBLE PRECISION NOT NULL DEFAULT 0,
+  elapsed_seconds INTEGER NOT NULL DEFAULT 0,
+  moving_seconds INTEGER NOT NULL DEFAULT 0,
+  elevation_gain_meters DOUBLE PRECISION NOT NULL DEFAULT 0,
+  elevation_loss_meters DOUBLE PRECISION NOT NULL DEFAULT 0,
+  average_heart_rate INTEGER,
+  max_heart_rate INTEGER,
+  heart_rate_zones INTEGER[],
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_workout_tracks_workout ON workout_tracks (workout_id);
+
+CREATE TABLE IF NOT EXISTS track_points (
+  track_id BIGINT NOT NULL REFERENCES workout_tracks(id) ON DELETE CASCADE,
+  point_index INTEGER NOT NULL,
+  recorded_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  latitude DOUBLE PRECISION,
+  longitude DOUBLE PRECISION,
+  elevation DOUBLE PRECISION,
+  heart_rate INTEGER,
+  PRIMARY KEY (track_id, point_index)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE track_points;
+DROP TABLE workout_tracks;
+ALTER TABLE users DROP COLUMN max_heart_rate;
+-- +goose StatementEnd
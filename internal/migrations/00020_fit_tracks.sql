@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE workout_tracks DROP CONSTRAINT workout_tracks_format_check;
+ALTER TABLE workout_tracks ADD CONSTRAINT workout_tracks_format_check CHECK (format IN ('gpx', 'tcx', 'fit'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM workout_tracks WHERE format = 'fit';
+ALTER TABLE workout_tracks DROP CONSTRAINT workout_tracks_format_check;
+ALTER TABLE workout_tracks ADD CONSTRAINT workout_tracks_format_check CHECK (format IN ('gpx', 'tcx'));
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Bodyweight, body fat and tape measurements. Circumferences are in cm, keyed by site
+-- (waist, left_arm, ...); the allowed sites are checked by the application.
+CREATE TABLE IF NOT EXISTS body_measurements (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  measured_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  bodyweight_kg DECIMAL(5, 2) CHECK (bodyweight_kg > 0),
+  body_fat_percent DECIMAL(4, 1) CHECK (body_fat_percent > 0 AND body_fat_percent < 100),
+  circumferences JSONB NOT NULL DEFAULT '{}',
+  notes TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_body_measurements_user ON body_measurements (user_id, measured_at DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE body_measurements;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Progress photos. The images themselves live in the blob store under blob_key and
+-- thumbnail_key; a photo can point at the measurement taken the same day.
+CREATE TABLE IF NOT EXISTS progress_photos (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  measurement_id BIGINT REFERENCES body_measurements(id) ON DELETE SET NULL,
+  taken_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  pose VARCHAR(20) NOT NULL DEFAULT 'other' CHECK (pose IN ('front', 'back', 'left_side', 'right_side', 'other')),
+  notes TEXT NOT NULL DEFAULT '',
+  width INTEGER NOT NULL,
+  height INTEGER NOT NULL,
+  size_bytes BIGINT NOT NULL,
+  blob_key TEXT NOT NULL,
+  thumbnail_key TEXT NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_progress_photos_user ON progress_photos (user_id, taken_at DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE progress_photos;
+-- +goose StatementEnd
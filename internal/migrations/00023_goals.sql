@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Goals and their progress. The progress columns are recomputed from the workouts and
+-- measurements every time those change, see evaluateGoals in the store.
+CREATE TABLE IF NOT EXISTS goals (
+  id BIGSERIAL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  type VARCHAR(20) NOT NULL CHECK (type IN ('strength', 'frequency', 'volume', 'distance', 'bodyweight')),
+  title VARCHAR(255) NOT NULL DEFAULT '',
+  exercise_id BIGINT REFERENCES exercises(id) ON DELETE CASCADE,
+  target_value DECIMAL(12, 2) NOT NULL CHECK (target_value > 0),
+  target_reps INTEGER CHECK (target_reps > 0),
+  starts_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  deadline TIMESTAMP WITH TIME ZONE CHECK (deadline > starts_at),
+  start_value DECIMAL(12, 2),
+  current_value DECIMAL(12, 2) NOT NULL DEFAULT 0,
+  progress_percent DECIMAL(4, 1) NOT NULL DEFAULT 0,
+  projected_at TIMESTAMP WITH TIME ZONE,
+  achieved_at TIMESTAMP WITH TIME ZONE,
+  evaluated_at TIMESTAMP WITH TIME ZONE,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_goals_user ON goals (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE goals;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE exercises
+ADD COLUMN met REAL,
+ADD CONSTRAINT valid_met CHECK (met IS NULL OR met BETWEEN 1 AND 25);
+
+-- NULL when the workout has no calories at all
+ALTER TABLE workouts
+ADD COLUMN calories_source VARCHAR(10),
+ADD CONSTRAINT valid_calories_source CHECK (calories_source IN ('measured', 'estimated'));
+
+-- Calories logged so far were all sent by clients
+UPDATE workouts SET calories_source = 'measured' WHERE calories_burned > 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE workouts DROP COLUMN calories_source;
+ALTER TABLE exercises DROP COLUMN met;
+-- +goose StatementEnd
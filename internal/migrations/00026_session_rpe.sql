@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- How hard the whole session felt on the CR-10 scale, asked about 30 minutes after it
+ALTER TABLE workouts
+ADD COLUMN session_rpe DECIMAL(3, 1),
+ADD CONSTRAINT valid_session_rpe CHECK (session_rpe IS NULL OR (session_rpe > 0 AND session_rpe <= 10));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE workouts DROP COLUMN session_rpe;
+-- +goose StatementEnd
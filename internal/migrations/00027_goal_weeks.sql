@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Frequency goals are met week by week: how many weeks reached their target, and when the
+-- first week short of it ends, after which the goal is missed
+ALTER TABLE goals
+ADD COLUMN weeks_met INTEGER,
+ADD COLUMN shortfall_at TIMESTAMP WITH TIME ZONE;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE goals DROP COLUMN weeks_met, DROP COLUMN shortfall_at;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The cumulative distance the device recorded at each point, e.g. by a treadmill foot pod
+ALTER TABLE track_points
+ADD COLUMN distance_meters DOUBLE PRECISION;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE track_points DROP COLUMN distance_meters;
+-- +goose StatementEnd
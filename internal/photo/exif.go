@@ -0,0 +1,160 @@
+package photo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"time"
+)
+
+// EXIF tags read from the photo. Everything else (GPS position, camera serial, ...) is dropped.
+const (
+	tagOrientation        = 0x0112
+	tagExifIFD            = 0x8769
+	tagDateTimeOriginal   = 0x9003
+	tagOffsetTimeOriginal = 0x9011
+)
+
+// exifInfo is what we keep from the EXIF block of a JPEG before stripping it.
+type exifInfo struct {
+	// Orientation is the EXIF orientation (1-8), how the pixels must be turned to display upright.
+	Orientation int
+	// TakenAt is when the picture was taken, in the camera's offset if it recorded one, UTC otherwise.
+	TakenAt *time.Time
+}
+
+// readExif looks for the EXIF APP1 segment of a JPEG. Missing or broken EXIF data
+// is not an error: the photo is simply taken as it is.
+func readExif(data []byte) exifInfo {
+	info := exifInfo{Orientation: 1}
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return info
+	}
+
+	for pos := 2; pos+4 <= len(data); {
+		if data[pos] != 0xFF {
+			return info
+		}
+		marker := data[pos+1]
+		// Start of scan: the metadata segments are all before the image data
+		if marker == 0xDA || marker == 0xD9 {
+			return info
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			return info
+		}
+		segment := data[pos+4 : pos+2+length]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			parseTIFF(segment[6:], &info)
+			return info
+		}
+		pos += 2 + length
+	}
+
+	return info
+}
+
+// ifdEntry is one 12 byte entry of a TIFF image file directory.
+type ifdEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte // the 4 byte value field, holding either the value or its offset
+}
+
+// parseTIFF reads the orientation from IFD0 and the original date from the Exif sub-IFD.
+func parseTIFF(tiff []byte, info *exifInfo) {
+	if len(tiff) < 8 {
+		return
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return
+	}
+
+	readIFD := func(offset uint32) []ifdEntry {
+		if uint64(offset)+2 > uint64(len(tiff)) {
+			return nil
+		}
+		count := int(order.Uint16(tiff[offset:]))
+		start := int(offset) + 2
+		if start+count*12 > len(tiff) {
+			return nil
+		}
+		entries := make([]ifdEntry, count)
+		for i := range entries {
+			raw := tiff[start+i*12:]
+			entries[i] = ifdEntry{
+				tag:   order.Uint16(raw),
+				typ:   order.Uint16(raw[2:]),
+				count: order.Uint32(raw[4:]),
+				value: raw[8:12],
+			}
+		}
+		return entries
+	}
+
+	// ascii returns an ASCII value; up to 4 bytes are stored inline, longer ones at an offset
+	ascii := func(entry ifdEntry) string {
+		const typeASCII = 2
+		if entry.typ != typeASCII {
+			return ""
+		}
+		raw := entry.value
+		if entry.count > 4 {
+			offset := order.Uint32(entry.value)
+			if uint64(offset)+uint64(entry.count) > uint64(len(tiff)) {
+				return ""
+			}
+			raw = tiff[offset : offset+entry.count]
+		} else {
+			raw = raw[:entry.count]
+		}
+		return strings.TrimRight(string(raw), "\x00 ")
+	}
+
+	var exifOffset uint32
+	for _, entry := range readIFD(order.Uint32(tiff[4:])) {
+		switch entry.tag {
+		case tagOrientation:
+			if orientation := int(order.Uint16(entry.value)); orientation >= 1 && orientation <= 8 {
+				info.Orientation = orientation
+			}
+		case tagExifIFD:
+			exifOffset = order.Uint32(entry.value)
+		}
+	}
+	if exifOffset == 0 {
+		return
+	}
+
+	var original, offset string
+	for _, entry := range readIFD(exifOffset) {
+		switch entry.tag {
+		case tagDateTimeOriginal:
+			original = ascii(entry)
+		case tagOffsetTimeOriginal:
+			offset = ascii(entry)
+		}
+	}
+	if original == "" {
+		return
+	}
+
+	takenAt, err := time.Parse("2006:01:02 15:04:05", original)
+	if err != nil {
+		return
+	}
+	if offset != "" {
+		if withOffset, err := time.Parse("2006:01:02 15:04:05-07:00", original+offset); err == nil {
+			takenAt = withOffset
+		}
+	}
+	info.TakenAt = &takenAt
+}
@@ -0,0 +1,183 @@
+package photo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	_ "image/png" // PNG uploads are decoded too, and stored as JPEG like the rest
+	"time"
+)
+
+const (
+	// MaxPixels rejects images that would take too much memory to decode (a 50 MP camera is the high end).
+	MaxPixels = 60_000_000
+	// ThumbnailSize is the longest side of a thumbnail, in pixels.
+	ThumbnailSize = 320
+	// ContentType is the type of every stored image and thumbnail.
+	ContentType = "image/jpeg"
+
+	jpegQuality = 88
+)
+
+// ErrUnsupported is returned for files that are not a JPEG or PNG image.
+var ErrUnsupported = errors.New("the photo must be a JPEG or PNG image")
+
+// Processed is an uploaded photo ready to be stored.
+type Processed struct {
+	// Image is the full size photo, upright, re-encoded as JPEG without any metadata.
+	Image []byte
+	// Thumbnail is a scaled down copy of the photo, also as JPEG.
+	Thumbnail []byte
+	Width     int
+	Height    int
+	// TakenAt is the EXIF capture date of the original, if it had one.
+	TakenAt *time.Time
+}
+
+// Process decodes an uploaded photo and re-encodes it. Re-encoding is how EXIF data is
+// stripped: only the pixels are kept, so the GPS position, camera details and any other
+// metadata never reach the storage. The EXIF orientation is applied to the pixels first,
+// since the stripped file can't carry it anymore.
+func Process(data []byte) (*Processed, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, fmt.Errorf("the photo cannot be larger than %d megapixels", MaxPixels/1_000_000)
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s image: %w", format, err)
+	}
+
+	info := exifInfo{Orientation: 1}
+	if format == "jpeg" {
+		info = readExif(data)
+	}
+
+	img := orient(toRGBA(decoded), info.Orientation)
+
+	full, err := encode(img)
+	if err != nil {
+		return nil, err
+	}
+	thumbnail, err := encode(Thumbnail(img, ThumbnailSize))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Processed{
+		Image:     full,
+		Thumbnail: thumbnail,
+		Width:     img.Rect.Dx(),
+		Height:    img.Rect.Dy(),
+		TakenAt:   info.TakenAt,
+	}, nil
+}
+
+func encode(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// toRGBA converts the decoded image to RGBA with its origin at 0,0, so the
+// transforms below can work on the pixel slice directly.
+func toRGBA(src image.Image) *image.RGBA {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Rect, src, bounds.Min, draw.Src)
+	return dst
+}
+
+// orient turns the pixels the way the EXIF orientation (1-8) says the photo must be displayed.
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	dw, dh := w, h
+	// 5 to 8 swap the axes
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	// source returns the pixel of src that ends up at x,y of the oriented image
+	source := func(x, y int) (int, int) {
+		switch orientation {
+		case 2: // mirrored
+			return w - 1 - x, y
+		case 3: // upside down
+			return w - 1 - x, h - 1 - y
+		case 4: // mirrored upside down
+			return x, h - 1 - y
+		case 5: // mirrored, rotated 90° counterclockwise
+			return y, x
+		case 6: // rotated 90° clockwise
+			return y, h - 1 - x
+		case 7: // mirrored, rotated 90° clockwise
+			return w - 1 - y, h - 1 - x
+		default: // 8: rotated 90° counterclockwise
+			return w - 1 - y, x
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			sx, sy := source(x, y)
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[src.PixOffset(sx, sy):src.PixOffset(sx, sy)+4])
+		}
+	}
+	return dst
+}
+
+// Thumbnail scales the image down so its longest side is size pixels, averaging the
+// block of source pixels behind each thumbnail pixel. Small images are returned as they are.
+func Thumbnail(src *image.RGBA, size int) *image.RGBA {
+	w, h := src.Rect.Dx(), src.Rect.Dy()
+	if w <= size && h <= size {
+		return src
+	}
+
+	dw, dh := size, h*size/w
+	if h > w {
+		dw, dh = w*size/h, size
+	}
+	dw, dh = max(dw, 1), max(dh, 1)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*h/dh, max((y+1)*h/dh, y*h/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*w/dw, max((x+1)*w/dw, x*w/dw+1)
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				offset := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					for c := 0; c < 4; c++ {
+						sum[c] += int(src.Pix[offset+c])
+					}
+					offset += 4
+				}
+			}
+
+			count := (x1 - x0) * (y1 - y0)
+			offset := dst.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				dst.Pix[offset+c] = uint8((sum[c] + count/2) / count)
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,152 @@
+package photo
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exifSegment builds a little endian APP1 segment with an orientation in IFD0
+// and a DateTimeOriginal/OffsetTimeOriginal pair in the Exif sub-IFD.
+func exifSegment(orientation uint16, takenAt, offset string) []byte {
+	var tiff bytes.Buffer
+	le := binary.LittleEndian
+	write := func(v any) { _ = binary.Write(&tiff, le, v) }
+
+	const ifd0 = 8
+	const exifIFD = ifd0 + 2 + 2*12 + 4
+	const strings = exifIFD + 2 + 2*12 + 4
+
+	tiff.WriteString("II")
+	write(uint16(42))
+	write(uint32(ifd0))
+
+	write(uint16(2))
+	write([]uint16{tagOrientation, 3})
+	write(uint32(1))
+	write([]uint16{orientation, 0})
+	write([]uint16{tagExifIFD, 4})
+	write(uint32(1))
+	write(uint32(exifIFD))
+	write(uint32(0))
+
+	write(uint16(2))
+	write([]uint16{tagDateTimeOriginal, 2})
+	write(uint32(len(takenAt) + 1))
+	write(uint32(strings))
+	write([]uint16{tagOffsetTimeOriginal, 2})
+	write(uint32(len(offset) + 1))
+	write(uint32(strings + len(takenAt) + 1))
+	write(uint32(0))
+
+	tiff.WriteString(takenAt + "\x00" + offset + "\x00")
+
+	payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// testJPEG encodes a w x h image, red on the left half and blue on the right,
+// with the EXIF segment (if any) right after the start of image marker.
+func testJPEG(t *testing.T, w, h int, exif []byte) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= w/2 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}))
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), exif...), data[2:]...)
+}
+
+func TestProcess(t *testing.T) {
+	data := testJPEG(t, 64, 32, exifSegment(6, "2026:03:14 07:30:00", "+02:00"))
+
+	processed, err := Process(data)
+	require.NoError(t, err)
+
+	// Rotated clockwise: the left (red) half is now on top
+	assert.Equal(t, 32, processed.Width)
+	assert.Equal(t, 64, processed.Height)
+	require.NotNil(t, processed.TakenAt)
+	assert.True(t, time.Date(2026, 3, 14, 5, 30, 0, 0, time.UTC).Equal(*processed.TakenAt))
+
+	assert.NotContains(t, string(processed.Image), "Exif")
+	assert.Equal(t, exifInfo{Orientation: 1}, readExif(processed.Image))
+
+	img, err := jpeg.Decode(bytes.NewReader(processed.Image))
+	require.NoError(t, err)
+	r, _, b, _ := img.At(16, 8).RGBA()
+	assert.Greater(t, r, b)
+	r, _, b, _ = img.At(16, 56).RGBA()
+	assert.Greater(t, b, r)
+}
+
+func TestProcessThumbnail(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1000, 400))))
+
+	processed, err := Process(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, 1000, processed.Width)
+	assert.Nil(t, processed.TakenAt)
+
+	thumbnail, err := jpeg.DecodeConfig(bytes.NewReader(processed.Thumbnail))
+	require.NoError(t, err)
+	assert.Equal(t, ThumbnailSize, thumbnail.Width)
+	assert.Equal(t, 128, thumbnail.Height)
+}
+
+func TestProcessRejectsOtherFiles(t *testing.T) {
+	_, err := Process([]byte("<gpx></gpx>"))
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
+
+func TestOrient(t *testing.T) {
+	// 3x2 image whose pixels are numbered 0-5 in reading order
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	for i := 0; i < 6; i++ {
+		src.Pix[i*4] = uint8(i)
+	}
+	read := func(img *image.RGBA) []uint8 {
+		var values []uint8
+		for i := 0; i < len(img.Pix); i += 4 {
+			values = append(values, img.Pix[i])
+		}
+		return values
+	}
+
+	tests := []struct {
+		orientation int
+		want        []uint8
+	}{
+		{1, []uint8{0, 1, 2, 3, 4, 5}},
+		{2, []uint8{2, 1, 0, 5, 4, 3}},
+		{3, []uint8{5, 4, 3, 2, 1, 0}},
+		{4, []uint8{3, 4, 5, 0, 1, 2}},
+		{5, []uint8{0, 3, 1, 4, 2, 5}},
+		{6, []uint8{3, 0, 4, 1, 5, 2}},
+		{7, []uint8{5, 2, 4, 1, 3, 0}},
+		{8, []uint8{2, 5, 1, 4, 0, 3}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, read(orient(src, tt.orientation)), "orientation %d", tt.orientation)
+	}
+}
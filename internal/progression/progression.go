@@ -0,0 +1,144 @@
+package progression
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Rule describes how the working weight of an exercise moves between sessions.
+// Increment is added after every successful session; after DeloadAfterFailures
+// failed sessions in a row the weight drops by DeloadPercent. Zero values disable either part.
+type Rule struct {
+	Increment           float64
+	DeloadPercent       float64
+	DeloadAfterFailures int
+}
+
+// Set is one logged set. It mirrors store.WorkoutSet without importing the store package.
+type Set struct {
+	Reps      *int
+	Weight    *float64
+	Warmup    bool
+	Completed bool
+}
+
+// Session is every set of one exercise logged in one program workout,
+// together with what that program day asked for.
+type Session struct {
+	WorkoutID   int
+	PerformedAt time.Time
+	TargetSets  int
+	TargetReps  int
+	Sets        []Set
+}
+
+// Prescription is the weight to use next time, plus how it was reached.
+// Weight is nil when there is no starting weight and nothing was logged yet.
+type Prescription struct {
+	Weight              *float64 `json:"weight"`
+	ConsecutiveFailures int      `json:"consecutive_failures"`
+	Deloaded            bool     `json:"deloaded"`
+	SessionsEvaluated   int      `json:"sessions_evaluated"`
+}
+
+// Evaluate replays the sessions in chronological order, starting from the given weight.
+// A session succeeds when at least TargetSets completed working sets reached TargetReps
+// at the prescribed weight or heavier. Sessions without any completed working set were
+// skipped, so they neither progress nor count as a failure.
+// Without a starting weight the heaviest working weight of the first session becomes the baseline.
+func Evaluate(start *float64, rule Rule, sessions []Session) Prescription {
+	ordered := make([]Session, len(sessions))
+	copy(ordered, sessions)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].PerformedAt.Equal(ordered[j].PerformedAt) {
+			return ordered[i].WorkoutID < ordered[j].WorkoutID
+		}
+		return ordered[i].PerformedAt.Before(ordered[j].PerformedAt)
+	})
+
+	result := Prescription{}
+	var weight float64
+	known := start != nil
+	if known {
+		weight = *start
+	}
+
+	for _, session := range ordered {
+		heaviest, worked := heaviestWorkingWeight(session.Sets)
+		if !worked {
+			continue
+		}
+		if !known {
+			weight, known = heaviest, true
+		}
+		result.SessionsEvaluated++
+
+		if succeeded(session, weight) {
+			weight += rule.Increment
+			result.ConsecutiveFailures = 0
+			result.Deloaded = false
+			continue
+		}
+
+		result.ConsecutiveFailures++
+		if rule.DeloadAfterFailures > 0 && result.ConsecutiveFailures >= rule.DeloadAfterFailures {
+			weight = roundToHalf(weight * (1 - rule.DeloadPercent/100))
+			result.ConsecutiveFailures = 0
+			result.Deloaded = true
+		}
+	}
+
+	if known {
+		result.Weight = &weight
+	}
+	return result
+}
+
+// heaviestWorkingWeight returns the heaviest completed working weight of the sets,
+// and whether any working set was completed at all.
+func heaviestWorkingWeight(sets []Set) (float64, bool) {
+	heaviest := 0.0
+	worked := false
+	for _, set := range sets {
+		if set.Warmup || !set.Completed {
+			continue
+		}
+		worked = true
+		if set.Weight != nil && *set.Weight > heaviest {
+			heaviest = *set.Weight
+		}
+	}
+	return heaviest, worked
+}
+
+// succeeded reports whether enough sets hit the target reps at the prescribed weight.
+func succeeded(session Session, weight float64) bool {
+	// Tolerate DECIMAL rounding, 102.5 stored and read back must still count as 102.5
+	const epsilon = 0.001
+
+	good := 0
+	for _, set := range session.Sets {
+		if set.Warmup || !set.Completed || set.Reps == nil {
+			continue
+		}
+		setWeight := 0.0
+		if set.Weight != nil {
+			setWeight = *set.Weight
+		}
+		if *set.Reps >= session.TargetReps && setWeight+epsilon >= weight {
+			good++
+		}
+	}
+
+	targetSets := session.TargetSets
+	if targetSets < 1 {
+		targetSets = 1
+	}
+	return good >= targetSets
+}
+
+// roundToHalf rounds a weight to the nearest 0.5, the smallest jump most gyms can load.
+func roundToHalf(weight float64) float64 {
+	return math.Round(weight*2) / 2
+}
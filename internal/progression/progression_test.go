@@ -0,0 +1,119 @@
+package progression
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+// session builds a 3x5 session where every working set was done with the given reps and weight
+func session(d, reps int, weight float64) Session {
+	sets := []Set{{Reps: intPtr(10), Weight: floatPtr(20), Warmup: true, Completed: true}}
+	for i := 0; i < 3; i++ {
+		sets = append(sets, Set{Reps: intPtr(reps), Weight: floatPtr(weight), Completed: true})
+	}
+	return Session{
+		WorkoutID:   d,
+		PerformedAt: time.Date(2026, 1, d, 18, 0, 0, 0, time.UTC),
+		TargetSets:  3,
+		TargetReps:  5,
+		Sets:        sets,
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	rule := Rule{Increment: 2.5, DeloadPercent: 10, DeloadAfterFailures: 3}
+
+	tests := []struct {
+		name         string
+		start        *float64
+		sessions     []Session
+		wantWeight   *float64
+		wantFailures int
+		wantDeloaded bool
+	}{
+		{
+			name:       "nothing logged yet",
+			start:      floatPtr(100),
+			wantWeight: floatPtr(100),
+		},
+		{
+			name:       "no start and nothing logged",
+			wantWeight: nil,
+		},
+		{
+			name:       "two successes add two increments",
+			start:      floatPtr(100),
+			sessions:   []Session{session(1, 5, 100), session(3, 5, 102.5)},
+			wantWeight: floatPtr(105),
+		},
+		{
+			name:         "missed reps is a failure",
+			start:        floatPtr(100),
+			sessions:     []Session{session(1, 5, 100), session(3, 4, 102.5)},
+			wantWeight:   floatPtr(102.5),
+			wantFailures: 1,
+		},
+		{
+			name:         "lighter than prescribed is a failure",
+			start:        floatPtr(100),
+			sessions:     []Session{session(1, 5, 95)},
+			wantWeight:   floatPtr(100),
+			wantFailures: 1,
+		},
+		{
+			name:         "three failures deload by 10 percent",
+			start:        floatPtr(100),
+			sessions:     []Session{session(5, 3, 100), session(1, 4, 100), session(3, 4, 100)},
+			wantWeight:   floatPtr(90),
+			wantDeloaded: true,
+		},
+		{
+			name:       "success after deload progresses again",
+			start:      floatPtr(100),
+			sessions:   []Session{session(1, 4, 100), session(3, 4, 100), session(5, 4, 100), session(7, 5, 90)},
+			wantWeight: floatPtr(92.5),
+		},
+		{
+			name:       "first session sets the baseline without a start",
+			sessions:   []Session{session(1, 5, 60)},
+			wantWeight: floatPtr(62.5),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Evaluate(tt.start, rule, tt.sessions)
+			if tt.wantWeight == nil {
+				assert.Nil(t, got.Weight)
+			} else {
+				require.NotNil(t, got.Weight)
+				assert.InDelta(t, *tt.wantWeight, *got.Weight, 0.001)
+			}
+			assert.Equal(t, tt.wantFailures, got.ConsecutiveFailures)
+			assert.Equal(t, tt.wantDeloaded, got.Deloaded)
+		})
+	}
+}
+
+func TestEvaluateSkippedSession(t *testing.T) {
+	skipped := session(3, 5, 100)
+	for i := range skipped.Sets {
+		skipped.Sets[i].Completed = false
+	}
+
+	got := Evaluate(floatPtr(100), Rule{Increment: 5}, []Session{skipped})
+	require.NotNil(t, got.Weight)
+	assert.Equal(t, 100.0, *got.Weight)
+	assert.Equal(t, 0, got.SessionsEvaluated)
+}
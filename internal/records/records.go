@@ -0,0 +1,158 @@
+package records
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/e1rm"
+)
+
+// Record types detected by Detect.
+const (
+	TypeHeaviestWeight   = "heaviest_weight"
+	TypeMaxRepsAtWeight  = "max_reps_at_weight"
+	TypeBestE1RM         = "best_e1rm"
+	TypeLongestDuration  = "longest_duration"
+	TypeMaxSessionVolume = "max_session_volume"
+)
+
+// Set is one logged set of an exercise. It mirrors store.WorkoutSet without
+// importing the store package, so the engine stays free of database concerns.
+type Set struct {
+	Reps            *int
+	DurationSeconds *int
+	Weight          *float64
+	Warmup          bool
+	Completed       bool
+}
+
+// Session is every set of one exercise performed in one workout.
+type Session struct {
+	WorkoutID   int
+	PerformedAt time.Time
+	Sets        []Set
+}
+
+// Record is a new best set by a session.
+// Value is what was beaten (kg, reps, seconds or kg·reps depending on Type);
+// Weight and Reps describe the set that achieved it, when it makes sense.
+type Record struct {
+	Type       string
+	Value      float64
+	Weight     *float64
+	Reps       *int
+	WorkoutID  int
+	AchievedAt time.Time
+}
+
+// Detect replays the sessions of a single exercise in chronological order and returns
+// every time a record was broken. The first session always sets the initial records.
+// Warmup and uncompleted sets never count, and e1RM records use the given formula.
+func Detect(sessions []Session, formula e1rm.Formula) []Record {
+	ordered := make([]Session, len(sessions))
+	copy(ordered, sessions)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].PerformedAt.Equal(ordered[j].PerformedAt) {
+			return ordered[i].WorkoutID < ordered[j].WorkoutID
+		}
+		return ordered[i].PerformedAt.Before(ordered[j].PerformedAt)
+	})
+
+	best := map[string]float64{}
+	bestRepsAtWeight := map[float64]int{}
+	var found []Record
+
+	for _, session := range ordered {
+		sessionBest := map[string]*Record{}
+		sessionRepsAtWeight := map[float64]int{}
+		volume := 0.0
+
+		// consider keeps the best candidate of this session for a record type
+		consider := func(recordType string, value float64, weight *float64, reps *int) {
+			current, ok := sessionBest[recordType]
+			if ok && current.Value >= value {
+				return
+			}
+			sessionBest[recordType] = &Record{
+				Type:       recordType,
+				Value:      value,
+				Weight:     weight,
+				Reps:       reps,
+				WorkoutID:  session.WorkoutID,
+				AchievedAt: session.PerformedAt,
+			}
+		}
+
+		for _, set := range session.Sets {
+			if set.Warmup || !set.Completed {
+				continue
+			}
+
+			if set.DurationSeconds != nil && *set.DurationSeconds > 0 {
+				consider(TypeLongestDuration, float64(*set.DurationSeconds), set.Weight, nil)
+			}
+
+			if set.Weight == nil || *set.Weight <= 0 {
+				continue
+			}
+			weight := *set.Weight
+			consider(TypeHeaviestWeight, weight, set.Weight, set.Reps)
+
+			if set.Reps == nil || *set.Reps <= 0 {
+				continue
+			}
+			reps := *set.Reps
+			volume += weight * float64(reps)
+			if reps > sessionRepsAtWeight[weight] {
+				sessionRepsAtWeight[weight] = reps
+			}
+			// Formulas get unreliable for long sets, so a set of 20 can't beat a heavy single
+			if estimate := formula.Estimate(weight, reps); estimate > 0 {
+				consider(TypeBestE1RM, estimate, set.Weight, set.Reps)
+			}
+		}
+
+		if volume > 0 {
+			consider(TypeMaxSessionVolume, volume, nil, nil)
+		}
+
+		for _, recordType := range []string{TypeHeaviestWeight, TypeBestE1RM, TypeLongestDuration, TypeMaxSessionVolume} {
+			candidate, ok := sessionBest[recordType]
+			if !ok {
+				continue
+			}
+			previous, seen := best[recordType]
+			if seen && candidate.Value <= previous {
+				continue
+			}
+			best[recordType] = candidate.Value
+			found = append(found, *candidate)
+		}
+
+		// Rep records are tracked separately per weight, heaviest weight first for stable output
+		weights := make([]float64, 0, len(sessionRepsAtWeight))
+		for weight := range sessionRepsAtWeight {
+			weights = append(weights, weight)
+		}
+		sort.Sort(sort.Reverse(sort.Float64Slice(weights)))
+		for _, weight := range weights {
+			reps := sessionRepsAtWeight[weight]
+			if reps <= bestRepsAtWeight[weight] {
+				continue
+			}
+			bestRepsAtWeight[weight] = reps
+
+			w, r := weight, reps
+			found = append(found, Record{
+				Type:       TypeMaxRepsAtWeight,
+				Value:      float64(reps),
+				Weight:     &w,
+				Reps:       &r,
+				WorkoutID:  session.WorkoutID,
+				AchievedAt: session.PerformedAt,
+			})
+		}
+	}
+
+	return found
+}
@@ -0,0 +1,75 @@
+package records
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/e1rm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func TestDetect(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2026, 1, d, 18, 0, 0, 0, time.UTC)
+	}
+
+	sessions := []Session{
+		// Logged out of order on purpose, Detect has to sort chronologically
+		{WorkoutID: 2, PerformedAt: day(8), Sets: []Set{
+			{Reps: intPtr(5), Weight: floatPtr(105), Completed: true},
+			{Reps: intPtr(3), Weight: floatPtr(110), Completed: false},
+		}},
+		{WorkoutID: 1, PerformedAt: day(1), Sets: []Set{
+			{Reps: intPtr(10), Weight: floatPtr(60), Warmup: true, Completed: true},
+			{Reps: intPtr(5), Weight: floatPtr(100), Completed: true},
+			{Reps: intPtr(5), Weight: floatPtr(100), Completed: true},
+		}},
+		{WorkoutID: 3, PerformedAt: day(15), Sets: []Set{
+			{Reps: intPtr(6), Weight: floatPtr(100), Completed: true},
+		}},
+	}
+
+	found := Detect(sessions, e1rm.Epley)
+
+	byWorkout := map[int][]string{}
+	for _, record := range found {
+		byWorkout[record.WorkoutID] = append(byWorkout[record.WorkoutID], record.Type)
+	}
+
+	// The first session sets every baseline; the warmup at 60kg doesn't count
+	assert.ElementsMatch(t, []string{TypeHeaviestWeight, TypeBestE1RM, TypeMaxSessionVolume, TypeMaxRepsAtWeight}, byWorkout[1])
+	// 105x5 beats the weight and e1RM, but the failed 110 single is ignored and 525kg of volume doesn't beat 1000kg
+	assert.ElementsMatch(t, []string{TypeHeaviestWeight, TypeBestE1RM, TypeMaxRepsAtWeight}, byWorkout[2])
+	// 6 reps at 100 is a rep record at that weight only
+	assert.ElementsMatch(t, []string{TypeMaxRepsAtWeight}, byWorkout[3])
+
+	for _, record := range found {
+		if record.WorkoutID == 2 && record.Type == TypeHeaviestWeight {
+			require.NotNil(t, record.Weight)
+			assert.Equal(t, 105.0, *record.Weight)
+		}
+	}
+}
+
+func TestDetectDuration(t *testing.T) {
+	sessions := []Session{
+		{WorkoutID: 1, PerformedAt: time.Unix(0, 0), Sets: []Set{{DurationSeconds: intPtr(60), Completed: true}}},
+		{WorkoutID: 2, PerformedAt: time.Unix(100, 0), Sets: []Set{{DurationSeconds: intPtr(45), Completed: true}}},
+		{WorkoutID: 3, PerformedAt: time.Unix(200, 0), Sets: []Set{{DurationSeconds: intPtr(90), Completed: true}}},
+	}
+
+	found := Detect(sessions, e1rm.Default)
+	require.Len(t, found, 2)
+	assert.Equal(t, 1, found[0].WorkoutID)
+	assert.Equal(t, 3, found[1].WorkoutID)
+	assert.Equal(t, 90.0, found[1].Value)
+}
@@ -9,14 +9,101 @@ import (
 func SetupRoutes(app *app.Application) *chi.Mux{
 	r := chi.NewRouter()
 
-	//since Health check func was a method of application struct, we can use it here without importing
-	r.Get("/workouts/{id}", app.WorkoutHandler.HandleWorkoutByID)
+	//Every route in this group resolves the bearer token (if any) into a user on the request context.
+	//RequireUser then rejects anonymous requests for the routes that need a logged in user.
+	r.Group(func(r chi.Router) {
+		r.Use(app.Middleware.Authenticate)
 
-	r.Post("/workouts", app.WorkoutHandler.HandleCreateWorkout)
-	r.Put("/workouts/{id}", app.WorkoutHandler.HandleUpdateWorkoutByID)
-	r.Delete("/workouts/{id}", app.WorkoutHandler.HandleDeleteWorkout)
+		r.Get("/workouts", app.Middleware.RequireUser(app.WorkoutHandler.HandleListWorkouts))
+		r.Get("/workouts/{id}", app.Middleware.RequireUser(app.WorkoutHandler.HandleWorkoutByID))
+
+		r.Post("/workouts", app.Middleware.RequireUser(app.WorkoutHandler.HandleCreateWorkout))
+		r.Put("/workouts/{id}", app.Middleware.RequireUser(app.WorkoutHandler.HandleUpdateWorkoutByID))
+		r.Delete("/workouts/{id}", app.Middleware.RequireUser(app.WorkoutHandler.HandleDeleteWorkout))
+
+		r.Post("/workouts/fit", app.Middleware.RequireUser(app.TrackHandler.HandleUploadFIT))
+		r.Post("/workouts/{id}/tracks", app.Middleware.RequireUser(app.TrackHandler.HandleUploadTrack))
+		r.Get("/workouts/{id}/tracks", app.Middleware.RequireUser(app.TrackHandler.HandleListTracks))
+		r.Get("/tracks/{id}", app.Middleware.RequireUser(app.TrackHandler.HandleGetTrack))
+		r.Delete("/tracks/{id}", app.Middleware.RequireUser(app.TrackHandler.HandleDeleteTrack))
+
+		r.Get("/exercises", app.Middleware.RequireUser(app.ExerciseHandler.HandleSearchExercises))
+		r.Get("/exercises/match", app.Middleware.RequireUser(app.ExerciseHandler.HandleMatchExercise))
+		r.Get("/exercises/{id}", app.Middleware.RequireUser(app.ExerciseHandler.HandleGetExercise))
+		r.Get("/exercises/{id}/e1rm-history", app.Middleware.RequireUser(app.RecordHandler.HandleE1RMHistory))
+		r.Post("/exercises", app.Middleware.RequireUser(app.ExerciseHandler.HandleCreateExercise))
+
+		r.Get("/users/{id}/records", app.Middleware.RequireUser(app.RecordHandler.HandleGetUserRecords))
+		r.Patch("/me/settings", app.Middleware.RequireUser(app.UserHandler.HandleUpdateSettings))
+		r.Get("/me/export", app.Middleware.RequireUser(app.ExportHandler.HandleExport))
+
+		r.Get("/me/measurements", app.Middleware.RequireUser(app.MeasurementHandler.HandleListMeasurements))
+		r.Post("/me/measurements", app.Middleware.RequireUser(app.MeasurementHandler.HandleCreateMeasurement))
+		r.Get("/me/measurements/trend", app.Middleware.RequireUser(app.MeasurementHandler.HandleGetTrend))
+		r.Get("/me/measurements/{id}", app.Middleware.RequireUser(app.MeasurementHandler.HandleGetMeasurement))
+		r.Put("/me/measurements/{id}", app.Middleware.RequireUser(app.MeasurementHandler.HandleUpdateMeasurement))
+		r.Delete("/me/measurements/{id}", app.Middleware.RequireUser(app.MeasurementHandler.HandleDeleteMeasurement))
+
+		r.Get("/me/photos", app.Middleware.RequireUser(app.PhotoHandler.HandleListPhotos))
+		r.Post("/me/photos", app.Middleware.RequireUser(app.PhotoHandler.HandleUploadPhoto))
+		r.Get("/me/photos/{id}", app.Middleware.RequireUser(app.PhotoHandler.HandleGetPhoto))
+		r.Put("/me/photos/{id}", app.Middleware.RequireUser(app.PhotoHandler.HandleUpdatePhoto))
+		r.Delete("/me/photos/{id}", app.Middleware.RequireUser(app.PhotoHandler.HandleDeletePhoto))
+
+		r.Get("/me/goals", app.Middleware.RequireUser(app.GoalHandler.HandleListGoals))
+		r.Post("/me/goals", app.Middleware.RequireUser(app.GoalHandler.HandleCreateGoal))
+		r.Get("/me/goals/{id}", app.Middleware.RequireUser(app.GoalHandler.HandleGetGoal))
+		r.Put("/me/goals/{id}", app.Middleware.RequireUser(app.GoalHandler.HandleUpdateGoal))
+		r.Delete("/me/goals/{id}", app.Middleware.RequireUser(app.GoalHandler.HandleDeleteGoal))
+
+		r.Get("/stats/volume", app.Middleware.RequireUser(app.StatsHandler.HandleGetVolume))
+		r.Get("/me/consistency", app.Middleware.RequireUser(app.StatsHandler.HandleGetConsistency))
+		r.Post("/tokens/load", app.Middleware.RequireUser(app.StatsHandler.HandleCreateLoadToken))
+		r.Delete("/tokens/load", app.Middleware.RequireUser(app.StatsHandler.HandleDeleteLoadTokens))
+		//Not wrapped in RequireUser: a coach reads the load with the load_token the user shared
+		r.Get("/users/{id}/load", app.StatsHandler.HandleGetLoad)
+
+		r.Get("/templates", app.Middleware.RequireUser(app.TemplateHandler.HandleListTemplates))
+		r.Get("/templates/{id}", app.Middleware.RequireUser(app.TemplateHandler.HandleGetTemplate))
+		r.Post("/templates", app.Middleware.RequireUser(app.TemplateHandler.HandleCreateTemplate))
+		r.Put("/templates/{id}", app.Middleware.RequireUser(app.TemplateHandler.HandleUpdateTemplate))
+		r.Delete("/templates/{id}", app.Middleware.RequireUser(app.TemplateHandler.HandleDeleteTemplate))
+		r.Post("/templates/{id}/instantiate", app.Middleware.RequireUser(app.TemplateHandler.HandleInstantiateTemplate))
+
+		r.Get("/programs", app.Middleware.RequireUser(app.ProgramHandler.HandleListPrograms))
+		r.Get("/programs/{id}", app.Middleware.RequireUser(app.ProgramHandler.HandleGetProgram))
+		r.Post("/programs", app.Middleware.RequireUser(app.ProgramHandler.HandleCreateProgram))
+		r.Delete("/programs/{id}", app.Middleware.RequireUser(app.ProgramHandler.HandleDeleteProgram))
+		r.Post("/programs/{id}/enroll", app.Middleware.RequireUser(app.ProgramHandler.HandleEnroll))
+		r.Get("/me/next-workout", app.Middleware.RequireUser(app.ProgramHandler.HandleGetNextWorkout))
+
+		r.Get("/schedule", app.Middleware.RequireUser(app.ScheduleHandler.HandleListSchedule))
+		r.Get("/schedule/{id}", app.Middleware.RequireUser(app.ScheduleHandler.HandleGetSchedule))
+		r.Post("/schedule", app.Middleware.RequireUser(app.ScheduleHandler.HandleCreateSchedule))
+		r.Put("/schedule/{id}", app.Middleware.RequireUser(app.ScheduleHandler.HandleUpdateSchedule))
+		r.Delete("/schedule/{id}", app.Middleware.RequireUser(app.ScheduleHandler.HandleDeleteSchedule))
+		r.Post("/tokens/calendar", app.Middleware.RequireUser(app.ScheduleHandler.HandleCreateCalendarToken))
+
+		r.Post("/imports", app.Middleware.RequireUser(app.ImportHandler.HandleCreateImport))
+		r.Get("/imports/{id}", app.Middleware.RequireUser(app.ImportHandler.HandleGetImport))
+
+		r.Post("/sessions", app.Middleware.RequireUser(app.SessionHandler.HandleStartSession))
+		r.Get("/sessions/{id}", app.Middleware.RequireUser(app.SessionHandler.HandleGetSession))
+		r.Post("/sessions/{id}/sets", app.Middleware.RequireUser(app.SessionHandler.HandleLogSet))
+		r.Post("/sessions/{id}/finish", app.Middleware.RequireUser(app.SessionHandler.HandleFinishSession))
+		r.Post("/sessions/{id}/timer", app.Middleware.RequireUser(app.SessionHandler.HandleStartTimer))
+		r.Delete("/sessions/{id}/timer", app.Middleware.RequireUser(app.SessionHandler.HandleStopTimer))
+		r.Delete("/sessions/{id}", app.Middleware.RequireUser(app.SessionHandler.HandleCancelSession))
+		//Not wrapped in RequireUser: watchers without an account authenticate with the session's watch_token
+		r.Get("/sessions/{id}/events", app.SessionHandler.HandleSessionEvents)
+	})
 
 	r.Post("/users", app.UserHandler.HandleRegisterUser)
+	r.Post("/tokens/authentication", app.TokenHandler.HandleCreateToken)
+	//The calendar feed carries its own secret token in the URL, calendar apps can't send headers
+	r.Get("/calendar/{token}.ics", app.ScheduleHandler.HandleCalendarFeed)
+	//Photo downloads are authorized by the HMAC signature of the link, so they work in an <img> tag
+	r.Get("/photos/{id}/{variant}", app.PhotoHandler.HandleDownloadPhoto)
 	
 	return r
-}
\ No newline at end of file
+}
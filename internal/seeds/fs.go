@@ -0,0 +1,11 @@
+package seeds
+
+import (
+	"embed"
+)
+
+//Reference data that is loaded into the database on startup, embedded the same way as the migrations
+//so the binary doesn't need the files next to it.
+
+//go:embed *.json
+var FS embed.FS
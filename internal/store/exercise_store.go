@@ -0,0 +1,606 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ErrUnknownExercise is returned when an entry references an exercise_id
+// that doesn't exist or belongs to another user.
+var ErrUnknownExercise = errors.New("unknown exercise")
+
+// Exercise is a canonical exercise from the catalog.
+// Built-in exercises have a nil UserID; custom exercises belong to the user that created them.
+type Exercise struct {
+	ID               int       `json:"id"`
+	UserID           *int      `json:"user_id"`
+	Name             string    `json:"name"`
+	Aliases          []string  `json:"aliases"`
+	PrimaryMuscles   []string  `json:"primary_muscles"`
+	SecondaryMuscles []string  `json:"secondary_muscles"`
+	Equipment        string    `json:"equipment"`
+	MovementPattern  string    `json:"movement_pattern"`
+	Unilateral       bool      `json:"unilateral"`
+	MET              *float64  `json:"met"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// ExerciseSuggestion is a catalog exercise with a name close to, but not the same as,
+// a free-text name. Score is the similarity between 0 and 1.
+type ExerciseSuggestion struct {
+	Exercise
+	Score float64 `json:"score"`
+}
+
+// ExerciseFilter narrows down SearchExercises. Empty fields are ignored.
+type ExerciseFilter struct {
+	UserID    int
+	Query     string
+	Muscle    string
+	Equipment string
+	Limit     int
+}
+
+// PostgresExerciseStore implements ExerciseStore using PostgreSQL as the backend.
+type PostgresExerciseStore struct {
+	db *sql.DB
+}
+
+// NewPostgresExerciseStore is a constructor for PostgresExerciseStore.
+func NewPostgresExerciseStore(db *sql.DB) *PostgresExerciseStore {
+	return &PostgresExerciseStore{db: db}
+}
+
+// ExerciseStore defines how the exercise catalog is read and extended.
+type ExerciseStore interface {
+	SearchExercises(filter ExerciseFilter) ([]Exercise, error)
+	GetExerciseByID(id int64, userID int) (*Exercise, error)
+	CreateExercise(*Exercise) error
+	MatchExercise(name string, userID int) (*Exercise, []ExerciseSuggestion, error)
+}
+
+// queryer is the part of *sql.DB and *sql.Tx we need for reads,
+// so the same helpers work inside and outside a transaction.
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// textArray lets a TEXT[] column be scanned into a []string.
+// A new type map is used each time because pgtype.Map isn't safe for concurrent use.
+func textArray(dst *[]string) sql.Scanner {
+	return pgtype.NewMap().SQLScanner(dst)
+}
+
+const exerciseColumns = `id, user_id, name, aliases, primary_muscles, secondary_muscles, equipment, movement_pattern, unilateral, met, created_at`
+
+// scanExercise reads one row selected with exerciseColumns.
+func scanExercise(row interface{ Scan(...interface{}) error }) (*Exercise, error) {
+	exercise := &Exercise{}
+	var userID sql.NullInt64
+	err := row.Scan(
+		&exercise.ID,
+		&userID,
+		&exercise.Name,
+		textArray(&exercise.Aliases),
+		textArray(&exercise.PrimaryMuscles),
+		textArray(&exercise.SecondaryMuscles),
+		&exercise.Equipment,
+		&exercise.MovementPattern,
+		&exercise.Unilateral,
+		&exercise.MET,
+		&exercise.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if userID.Valid {
+		id := int(userID.Int64)
+		exercise.UserID = &id
+	}
+	return exercise, nil
+}
+
+// loadExerciseCatalog returns every exercise visible to the user:
+// the built-in catalog plus their own custom exercises.
+func loadExerciseCatalog(q queryer, userID int) ([]Exercise, error) {
+	query := `SELECT ` + exerciseColumns + `
+	FROM exercises
+	WHERE user_id IS NULL OR user_id = $1
+	ORDER BY id
+	`
+
+	rows, err := q.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	catalog := []Exercise{}
+	for rows.Next() {
+		exercise, err := scanExercise(rows)
+		if err != nil {
+			return nil, err
+		}
+		catalog = append(catalog, *exercise)
+	}
+
+	return catalog, rows.Err()
+}
+
+// SearchExercises looks up exercises by name or alias (case-insensitive substring),
+// primary/secondary muscle and equipment. Custom exercises of the user are included.
+func (pg *PostgresExerciseStore) SearchExercises(filter ExerciseFilter) ([]Exercise, error) {
+	args := []interface{}{filter.UserID}
+	conditions := []string{"(user_id IS NULL OR user_id = $1)"}
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.Query != "" {
+		arg := addArg(filter.Query)
+		conditions = append(conditions, fmt.Sprintf(
+			"(name ILIKE '%%' || %s || '%%' OR EXISTS (SELECT 1 FROM unnest(aliases) a WHERE a ILIKE '%%' || %s || '%%'))", arg, arg))
+	}
+	if filter.Muscle != "" {
+		arg := addArg(strings.ToLower(filter.Muscle))
+		conditions = append(conditions, fmt.Sprintf("(%s = ANY(primary_muscles) OR %s = ANY(secondary_muscles))", arg, arg))
+	}
+	if filter.Equipment != "" {
+		conditions = append(conditions, "equipment = "+addArg(strings.ToLower(filter.Equipment)))
+	}
+
+	query := fmt.Sprintf(`SELECT %s
+	FROM exercises
+	WHERE %s
+	ORDER BY name
+	LIMIT %s
+	`, exerciseColumns, strings.Join(conditions, " AND "), addArg(filter.Limit))
+
+	rows, err := pg.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exercises := []Exercise{}
+	for rows.Next() {
+		exercise, err := scanExercise(rows)
+		if err != nil {
+			return nil, err
+		}
+		exercises = append(exercises, *exercise)
+	}
+
+	return exercises, rows.Err()
+}
+
+// GetExerciseByID fetches an exercise visible to the user.
+// Returns (nil, nil) if it doesn't exist or is another user's custom exercise.
+func (pg *PostgresExerciseStore) GetExerciseByID(id int64, userID int) (*Exercise, error) {
+	query := `SELECT ` + exerciseColumns + `
+	FROM exercises
+	WHERE id = $1 AND (user_id IS NULL OR user_id = $2)
+	`
+
+	exercise, err := scanExercise(pg.db.QueryRow(query, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return exercise, nil
+}
+
+// CreateExercise inserts a custom exercise for exercise.UserID.
+func (pg *PostgresExerciseStore) CreateExercise(exercise *Exercise) error {
+	query := `
+	INSERT INTO exercises (user_id, name, aliases, primary_muscles, secondary_muscles, equipment, movement_pattern, unilateral, met)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	RETURNING id, created_at
+	`
+
+	return pg.db.QueryRow(query,
+		exercise.UserID,
+		exercise.Name,
+		nonNilStrings(exercise.Aliases),
+		nonNilStrings(exercise.PrimaryMuscles),
+		nonNilStrings(exercise.SecondaryMuscles),
+		exercise.Equipment,
+		exercise.MovementPattern,
+		exercise.Unilateral,
+		exercise.MET,
+	).Scan(&exercise.ID, &exercise.CreatedAt)
+}
+
+// MatchExercise returns the catalog exercise a free-text name is linked to, nil if there
+// is none, and the exercises with a similar name as suggestions.
+func (pg *PostgresExerciseStore) MatchExercise(name string, userID int) (*Exercise, []ExerciseSuggestion, error) {
+	catalog, err := loadExerciseCatalog(pg.db, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return matchExercise(name, catalog), suggestExercises(name, catalog), nil
+}
+
+// SeedExercises upserts the built-in catalog from exercises.json in the given filesystem.
+// It is safe to run on every startup: existing catalog rows are updated in place, so
+// ids referenced by workout entries never change.
+func (pg *PostgresExerciseStore) SeedExercises(seedFS fs.FS) error {
+	data, err := fs.ReadFile(seedFS, "exercises.json")
+	if err != nil {
+		return err
+	}
+
+	var exercises []Exercise
+	err = json.Unmarshal(data, &exercises)
+	if err != nil {
+		return fmt.Errorf("decoding exercises.json: %w", err)
+	}
+
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+	INSERT INTO exercises (name, aliases, primary_muscles, secondary_muscles, equipment, movement_pattern, unilateral, met)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	ON CONFLICT (LOWER(name)) WHERE user_id IS NULL
+	DO UPDATE SET aliases = EXCLUDED.aliases, primary_muscles = EXCLUDED.primary_muscles,
+		secondary_muscles = EXCLUDED.secondary_muscles, equipment = EXCLUDED.equipment,
+		movement_pattern = EXCLUDED.movement_pattern, unilateral = EXCLUDED.unilateral, met = EXCLUDED.met
+	`
+
+	for _, exercise := range exercises {
+		_, err = tx.Exec(query,
+			exercise.Name,
+			nonNilStrings(exercise.Aliases),
+			nonNilStrings(exercise.PrimaryMuscles),
+			nonNilStrings(exercise.SecondaryMuscles),
+			exercise.Equipment,
+			exercise.MovementPattern,
+			exercise.Unilateral,
+			exercise.MET,
+		)
+		if err != nil {
+			return fmt.Errorf("seeding exercise %q: %w", exercise.Name, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// LinkLegacyEntries matches workout entries that were logged before the catalog
+// existed (exercise_id IS NULL) and links them to a catalog exercise.
+// Entries whose name isn't an exercise name or alias are left alone. Returns how many entries were linked.
+func (pg *PostgresExerciseStore) LinkLegacyEntries() (int, error) {
+	query := `
+	SELECT DISTINCT w.user_id, e.exercise_name
+	FROM workout_entries e
+	INNER JOIN workouts w ON w.id = e.workout_id
+	WHERE e.exercise_id IS NULL
+	ORDER BY w.user_id
+	`
+
+	rows, err := pg.db.Query(query)
+	if err != nil {
+		return 0, err
+	}
+
+	type legacyName struct {
+		userID int
+		name   string
+	}
+	var names []legacyName
+	for rows.Next() {
+		var n legacyName
+		err = rows.Scan(&n.userID, &n.name)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		names = append(names, n)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	updateQuery := `
+	UPDATE workout_entries e
+	SET exercise_id = $1
+	FROM workouts w
+	WHERE w.id = e.workout_id AND w.user_id = $2 AND e.exercise_name = $3 AND e.exercise_id IS NULL
+	`
+
+	linked := 0
+	catalogs := map[int][]Exercise{}
+	for _, n := range names {
+		catalog, ok := catalogs[n.userID]
+		if !ok {
+			catalog, err = loadExerciseCatalog(pg.db, n.userID)
+			if err != nil {
+				return linked, err
+			}
+			catalogs[n.userID] = catalog
+		}
+
+		match := matchExercise(n.name, catalog)
+		if match == nil {
+			continue
+		}
+
+		result, err := pg.db.Exec(updateQuery, match.ID, n.userID, n.name)
+		if err != nil {
+			return linked, err
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return linked, err
+		}
+		linked += int(affected)
+	}
+
+	return linked, nil
+}
+
+// exerciseLinker links free-text exercise names and ids to the catalog visible to one user.
+type exerciseLinker struct {
+	catalog []Exercise
+	byID    map[int]*Exercise
+}
+
+// newExerciseLinker loads the user's catalog once so many entries can be linked cheaply.
+func newExerciseLinker(q queryer, userID int) (*exerciseLinker, error) {
+	catalog, err := loadExerciseCatalog(q, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int]*Exercise, len(catalog))
+	for i := range catalog {
+		byID[catalog[i].ID] = &catalog[i]
+	}
+
+	return &exerciseLinker{catalog: catalog, byID: byID}, nil
+}
+
+// link resolves one exercise reference and returns the id and name to store.
+//   - An exerciseID must reference an exercise visible to the user; an empty name is
+//     filled in from the catalog.
+//   - A name alone is linked when it is the name or an alias of an exercise;
+//     other names stay unlinked (nil id).
+func (l *exerciseLinker) link(exerciseID *int, name string) (*int, string, error) {
+	if exerciseID != nil {
+		exercise, ok := l.byID[*exerciseID]
+		if !ok {
+			return nil, "", fmt.Errorf("%w: %d", ErrUnknownExercise, *exerciseID)
+		}
+		if name == "" {
+			name = exercise.Name
+		}
+		return exerciseID, name, nil
+	}
+
+	if match := matchExercise(name, l.catalog); match != nil {
+		id := match.ID
+		return &id, name, nil
+	}
+
+	return nil, name, nil
+}
+
+// entryKey identifies the exercise of an entry or set, so the same exercise can be
+// grouped or compared: linked ones by catalog id, unlinked ones by name.
+func entryKey(exerciseID *int, exerciseName string) string {
+	if exerciseID != nil {
+		return "id:" + strconv.Itoa(*exerciseID)
+	}
+	return "name:" + strings.ToLower(strings.TrimSpace(exerciseName))
+}
+
+// resolveEntryExercises links every entry of the workout to the catalog of its owner.
+func resolveEntryExercises(q queryer, workout *Workout) error {
+	if len(workout.Entries) == 0 {
+		return nil
+	}
+
+	linker, err := newExerciseLinker(q, workout.UserID)
+	if err != nil {
+		return err
+	}
+
+	for i := range workout.Entries {
+		entry := &workout.Entries[i]
+		entry.ExerciseID, entry.ExerciseName, err = linker.link(entry.ExerciseID, entry.ExerciseName)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// nonNilStrings makes sure a nil slice is stored as an empty array rather than NULL.
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// exerciseAbbreviations expands the shorthand lifters commonly type.
+var exerciseAbbreviations = map[string]string{
+	"bb":  "barbell",
+	"db":  "dumbbell",
+	"kb":  "kettlebell",
+	"bw":  "bodyweight",
+	"ohp": "overhead press",
+	"rdl": "romanian deadlift",
+}
+
+// Fuzzy matches are only ever suggested, never linked automatically: "Hack Squat" is
+// close to "Back Squat" but linking them would mix up their records.
+const (
+	// minExerciseSuggestionScore is the lowest similarity suggestExercises returns.
+	minExerciseSuggestionScore = 0.5
+	// maxExerciseSuggestions is how many suggestions suggestExercises returns at most.
+	maxExerciseSuggestions = 5
+)
+
+// normalizeExerciseName lowercases the name, strips punctuation, expands abbreviations
+// and singularizes plural words, so "BB Squats" and "barbell squat" compare equal.
+func normalizeExerciseName(name string) []string {
+	cleaned := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return ' '
+	}, strings.ToLower(name))
+
+	var tokens []string
+	for _, token := range strings.Fields(cleaned) {
+		if expanded, ok := exerciseAbbreviations[token]; ok {
+			tokens = append(tokens, strings.Fields(expanded)...)
+			continue
+		}
+		if len(token) > 3 && strings.HasSuffix(token, "s") && !strings.HasSuffix(token, "ss") {
+			token = strings.TrimSuffix(token, "s")
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens
+}
+
+// exerciseNameSimilarity scores a normalized name against a normalized candidate
+// between 0 and 1. It takes the better of a token overlap score (good for missing words,
+// e.g. "flat bench press" vs "bench press") and an edit distance score (good for typos,
+// e.g. "bech press"). Overlap is measured against the candidate's tokens, so a single
+// shared word like "press" or "squat" doesn't make a longer name a close match.
+func exerciseNameSimilarity(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	setA := make(map[string]bool, len(a))
+	for _, token := range a {
+		setA[token] = true
+	}
+	setB := make(map[string]bool, len(b))
+	for _, token := range b {
+		setB[token] = true
+	}
+
+	shared := 0
+	for token := range setA {
+		if setB[token] {
+			shared++
+		}
+	}
+	union := len(setA) + len(setB) - shared
+	containment := float64(shared) / float64(len(setB))
+	jaccard := float64(shared) / float64(union)
+	tokenScore := 0.7*containment + 0.3*jaccard
+
+	joinedA, joinedB := strings.Join(a, " "), strings.Join(b, " ")
+	editScore := 1 - float64(levenshtein(joinedA, joinedB))/float64(max(len(joinedA), len(joinedB)))
+
+	return max(tokenScore, editScore)
+}
+
+// matchExercise returns the catalog exercise whose name or alias is the same as name
+// once both are normalized (case, punctuation, plurals and abbreviations), or nil.
+// Only these matches are linked automatically, see suggestExercises for close ones.
+func matchExercise(name string, catalog []Exercise) *Exercise {
+	target := strings.Join(normalizeExerciseName(name), " ")
+	if target == "" {
+		return nil
+	}
+
+	for i := range catalog {
+		candidates := append([]string{catalog[i].Name}, catalog[i].Aliases...)
+		for _, candidate := range candidates {
+			if strings.Join(normalizeExerciseName(candidate), " ") == target {
+				return &catalog[i]
+			}
+		}
+	}
+
+	return nil
+}
+
+// suggestExercises returns the catalog exercises whose name or alias is similar to name,
+// best first, so a client can ask "did you mean ...?". Exercises that match exactly
+// aren't suggested, matchExercise already returns them.
+func suggestExercises(name string, catalog []Exercise) []ExerciseSuggestion {
+	target := normalizeExerciseName(name)
+	suggestions := []ExerciseSuggestion{}
+	if len(target) == 0 {
+		return suggestions
+	}
+	exact := matchExercise(name, catalog)
+
+	for i := range catalog {
+		if exact != nil && exact.ID == catalog[i].ID {
+			continue
+		}
+
+		best := 0.0
+		candidates := append([]string{catalog[i].Name}, catalog[i].Aliases...)
+		for _, candidate := range candidates {
+			best = max(best, exerciseNameSimilarity(target, normalizeExerciseName(candidate)))
+		}
+		if best >= minExerciseSuggestionScore {
+			suggestions = append(suggestions, ExerciseSuggestion{Exercise: catalog[i], Score: math.Round(best*100) / 100})
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Score > suggestions[j].Score
+	})
+	if len(suggestions) > maxExerciseSuggestions {
+		suggestions = suggestions[:maxExerciseSuggestions]
+	}
+	return suggestions
+}
+
+// levenshtein returns the edit distance between two strings.
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
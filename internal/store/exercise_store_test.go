@@ -0,0 +1,97 @@
+package store
+
+import (
+	"encoding/json"
+	"io/fs"
+	"testing"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/seeds"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// seededCatalog loads the built-in catalog the way SeedExercises reads it, with ids in file order.
+func seededCatalog(t *testing.T) []Exercise {
+	data, err := fs.ReadFile(seeds.FS, "exercises.json")
+	require.NoError(t, err)
+
+	var catalog []Exercise
+	require.NoError(t, json.Unmarshal(data, &catalog))
+	for i := range catalog {
+		catalog[i].ID = i + 1
+	}
+	return catalog
+}
+
+func TestMatchExercise(t *testing.T) {
+	catalog := []Exercise{
+		{ID: 1, Name: "Bench Press", Aliases: []string{"bench", "flat bench", "bb bench"}},
+		{ID: 2, Name: "Incline Bench Press", Aliases: []string{"incline bench"}},
+		{ID: 3, Name: "Back Squat", Aliases: []string{"squat", "barbell squat"}},
+		{ID: 4, Name: "Romanian Deadlift"},
+	}
+
+	tests := []struct {
+		name   string
+		input  string
+		wantID int
+	}{
+		{name: "exact name", input: "Bench Press", wantID: 1},
+		{name: "alias", input: "bench", wantID: 1},
+		{name: "abbreviation", input: "BB bench", wantID: 1},
+		{name: "abbreviation of an alias", input: "BB squat", wantID: 3},
+		{name: "plural", input: "Squats", wantID: 3},
+		{name: "abbreviation expands to full name", input: "RDL", wantID: 4},
+		{name: "typo is only a suggestion", input: "bech press", wantID: 0},
+		{name: "more specific variant", input: "incline bench", wantID: 2},
+		{name: "unrelated", input: "yoga flow", wantID: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match := matchExercise(tt.input, catalog)
+			if tt.wantID == 0 {
+				assert.Nil(t, match)
+				return
+			}
+
+			require.NotNil(t, match)
+			assert.Equal(t, tt.wantID, match.ID)
+		})
+	}
+}
+
+func TestMatchExerciseSeededCatalog(t *testing.T) {
+	catalog := seededCatalog(t)
+
+	// Different exercises that share a word with a catalog exercise must never be linked to it
+	for _, input := range []string{"Walking", "press", "Hack Squat", "Zercher Squat", "Smith Machine Squat", "Chin Up", "Trap Bar Deadlift"} {
+		t.Run(input, func(t *testing.T) {
+			assert.Nil(t, matchExercise(input, catalog))
+		})
+	}
+
+	match := matchExercise("walking lunges", catalog)
+	require.NotNil(t, match)
+	assert.Equal(t, "Lunge", match.Name)
+}
+
+func TestSuggestExercises(t *testing.T) {
+	catalog := seededCatalog(t)
+
+	suggestions := suggestExercises("bech press", catalog)
+	require.NotEmpty(t, suggestions)
+	assert.Equal(t, "Bench Press", suggestions[0].Name)
+
+	// A close variant is suggested, but the exact match itself isn't
+	suggestions = suggestExercises("Hack Squat", catalog)
+	require.NotEmpty(t, suggestions)
+	assert.Equal(t, "Back Squat", suggestions[0].Name)
+	assert.LessOrEqual(t, len(suggestions), maxExerciseSuggestions)
+
+	for _, suggestion := range suggestExercises("bench", catalog) {
+		assert.NotEqual(t, "Bench Press", suggestion.Name)
+	}
+
+	assert.Empty(t, suggestExercises("yoga flow", catalog))
+}
@@ -0,0 +1,489 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/goals"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/trend"
+)
+
+// Goal is something the user works towards, e.g. bench 100 kg by March or run 200 km this quarter.
+// The progress fields are computed by the store (see evaluateGoals), and Unit, TargetTotal
+// and Status are derived when the goal is read.
+type Goal struct {
+	ID           int        `json:"id"`
+	UserID       int        `json:"user_id"`
+	Type         string     `json:"type"`
+	Title        string     `json:"title"`
+	ExerciseID   *int       `json:"exercise_id"`
+	ExerciseName string     `json:"exercise_name,omitempty"`
+	TargetValue  float64    `json:"target_value"`
+	TargetReps   *int       `json:"target_reps,omitempty"`
+	StartsAt     time.Time  `json:"starts_at"`
+	Deadline     *time.Time `json:"deadline"`
+
+	StartValue      *float64   `json:"start_value"`
+	CurrentValue    float64    `json:"current_value"`
+	ProgressPercent float64    `json:"percent_complete"`
+	ProjectedAt     *time.Time `json:"projected_completion"`
+	AchievedAt      *time.Time `json:"achieved_at"`
+	EvaluatedAt     *time.Time `json:"evaluated_at"`
+	// WeeksMet is only set for frequency goals, see goals.Progress for both
+	WeeksMet    *int       `json:"weeks_met,omitempty"`
+	ShortfallAt *time.Time `json:"-"`
+
+	Unit        string  `json:"unit"`
+	TargetTotal float64 `json:"target_total"`
+	Weeks       int     `json:"weeks,omitempty"`
+	Status      string  `json:"status"`
+
+	// timezone of the user, the weeks of a frequency goal are calendar weeks there
+	timezone string
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate checks that the goal has what its type needs, and nothing it can't use.
+func (g *Goal) Validate() error {
+	if !goals.ValidType(g.Type) {
+		return fmt.Errorf("type must be one of %s", strings.Join(goals.Types, ", "))
+	}
+	if len(g.Title) > 255 {
+		return errors.New("title cannot be greater than 255 chars")
+	}
+	if g.TargetValue <= 0 {
+		return errors.New("target_value must be positive")
+	}
+	if g.Deadline != nil && !g.Deadline.After(g.StartsAt) {
+		return errors.New("deadline must be after starts_at")
+	}
+
+	if g.Type == goals.TypeStrength {
+		if g.ExerciseID == nil {
+			return errors.New("a strength goal needs an exercise_id")
+		}
+		if g.TargetReps == nil || *g.TargetReps < 1 || *g.TargetReps > 100 {
+			return errors.New("target_reps must be between 1 and 100")
+		}
+	} else if g.TargetReps != nil {
+		return errors.New("target_reps is only used by strength goals")
+	}
+	if g.ExerciseID != nil && g.Type != goals.TypeStrength && g.Type != goals.TypeVolume {
+		return fmt.Errorf("a %s goal can't have an exercise_id", g.Type)
+	}
+
+	switch g.Type {
+	case goals.TypeFrequency:
+		if g.Deadline == nil {
+			return errors.New("a frequency goal needs a deadline")
+		}
+		if g.TargetValue > 14 {
+			return errors.New("target_value of a frequency goal is workouts per week, at most 14")
+		}
+	case goals.TypeBodyweight:
+		if g.TargetValue < 20 || g.TargetValue > 400 {
+			return errors.New("target_value of a bodyweight goal must be between 20 and 400")
+		}
+	}
+
+	return nil
+}
+
+// derive fills in the fields that are not stored.
+func (g *Goal) derive(now time.Time) {
+	g.Unit = goals.Unit(g.Type)
+	g.TargetTotal = goals.TargetTotal(g.spec())
+	g.Weeks = len(goals.Weeks(g.spec()))
+	g.Status = goals.Status(g.AchievedAt, g.ShortfallAt, g.Deadline, now)
+}
+
+func (g *Goal) spec() goals.Goal {
+	location := (&User{Timezone: g.timezone}).Location()
+	return goals.Goal{Type: g.Type, TargetValue: g.TargetValue, StartsAt: g.StartsAt, Deadline: g.Deadline, Location: location}
+}
+
+// PostgresGoalStore implements GoalStore using PostgreSQL as the backend.
+type PostgresGoalStore struct {
+	db *sql.DB
+}
+
+// NewPostgresGoalStore is a constructor for PostgresGoalStore.
+func NewPostgresGoalStore(db *sql.DB) *PostgresGoalStore {
+	return &PostgresGoalStore{db: db}
+}
+
+// GoalStore defines how goals are persisted. Progress is kept up to date by the
+// workout, track and measurement stores, inside the transactions that change the data.
+type GoalStore interface {
+	CreateGoal(*Goal) error
+	GetGoalByID(id int64) (*Goal, error)
+	ListGoals(userID int) ([]Goal, error)
+	UpdateGoal(*Goal) error
+	DeleteGoal(id int64) error
+	GetGoalOwner(id int64) (int, error)
+}
+
+const goalColumns = `g.id, g.user_id, g.type, g.title, g.exercise_id, COALESCE(ex.name, ''), g.target_value, g.target_reps,
+	g.starts_at, g.deadline, g.start_value, g.current_value, g.progress_percent, g.projected_at, g.achieved_at,
+	g.evaluated_at, g.weeks_met, g.shortfall_at, u.timezone, g.created_at, g.updated_at`
+
+// scanGoal reads one row selected with goalColumns.
+func scanGoal(row interface{ Scan(...interface{}) error }) (*Goal, error) {
+	goal := &Goal{}
+	err := row.Scan(&goal.ID, &goal.UserID, &goal.Type, &goal.Title, &goal.ExerciseID, &goal.ExerciseName,
+		&goal.TargetValue, &goal.TargetReps, &goal.StartsAt, &goal.Deadline, &goal.StartValue, &goal.CurrentValue,
+		&goal.ProgressPercent, &goal.ProjectedAt, &goal.AchievedAt, &goal.EvaluatedAt, &goal.WeeksMet, &goal.ShortfallAt,
+		&goal.timezone, &goal.CreatedAt, &goal.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return goal, nil
+}
+
+// checkGoalExercise returns ErrUnknownExercise unless the exercise is in the catalog or is the user's own.
+func checkGoalExercise(tx *sql.Tx, goal *Goal) error {
+	if goal.ExerciseID == nil {
+		goal.ExerciseName = ""
+		return nil
+	}
+
+	err := tx.QueryRow(`SELECT name FROM exercises WHERE id = $1 AND (user_id IS NULL OR user_id = $2)`,
+		*goal.ExerciseID, goal.UserID).Scan(&goal.ExerciseName)
+	if err == sql.ErrNoRows {
+		return ErrUnknownExercise
+	}
+	return err
+}
+
+// CreateGoal saves a goal and evaluates it right away, so it starts with the progress
+// already made since StartsAt. StartsAt defaults to now.
+// Returns ErrUnknownExercise if the exercise isn't visible to the user.
+func (pg *PostgresGoalStore) CreateGoal(goal *Goal) error {
+	if goal.StartsAt.IsZero() {
+		goal.StartsAt = time.Now()
+	}
+
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = checkGoalExercise(tx, goal)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO goals (user_id, type, title, exercise_id, target_value, target_reps, starts_at, deadline)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING id, created_at, updated_at
+	`
+
+	err = tx.QueryRow(query, goal.UserID, goal.Type, goal.Title, goal.ExerciseID, goal.TargetValue,
+		goal.TargetReps, goal.StartsAt, goal.Deadline).Scan(&goal.ID, &goal.CreatedAt, &goal.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	err = evaluateGoal(tx, goal)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	goal.derive(time.Now())
+	return nil
+}
+
+// GetGoalByID returns (nil, nil) if the goal doesn't exist.
+func (pg *PostgresGoalStore) GetGoalByID(id int64) (*Goal, error) {
+	query := `
+	SELECT ` + goalColumns + `
+	FROM goals g
+	INNER JOIN users u ON u.id = g.user_id
+	LEFT JOIN exercises ex ON ex.id = g.exercise_id
+	WHERE g.id = $1
+	`
+
+	goal, err := scanGoal(pg.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	goal.derive(time.Now())
+	return goal, nil
+}
+
+// ListGoals returns the user's goals: the open ones first, the soonest deadline first.
+func (pg *PostgresGoalStore) ListGoals(userID int) ([]Goal, error) {
+	query := `
+	SELECT ` + goalColumns + `
+	FROM goals g
+	INNER JOIN users u ON u.id = g.user_id
+	LEFT JOIN exercises ex ON ex.id = g.exercise_id
+	WHERE g.user_id = $1
+	ORDER BY g.achieved_at IS NOT NULL, g.deadline NULLS LAST, g.id
+	`
+
+	rows, err := pg.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	found := []Goal{}
+	for rows.Next() {
+		goal, err := scanGoal(rows)
+		if err != nil {
+			return nil, err
+		}
+		goal.derive(now)
+		found = append(found, *goal)
+	}
+
+	return found, rows.Err()
+}
+
+// UpdateGoal saves the definition of a goal and evaluates it again.
+// Returns ErrUnknownExercise if the exercise isn't visible to the user,
+// and sql.ErrNoRows if the goal does not exist.
+func (pg *PostgresGoalStore) UpdateGoal(goal *Goal) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = checkGoalExercise(tx, goal)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	UPDATE goals
+	SET type = $1, title = $2, exercise_id = $3, target_value = $4, target_reps = $5, starts_at = $6,
+		deadline = $7, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $8
+	RETURNING updated_at
+	`
+
+	err = tx.QueryRow(query, goal.Type, goal.Title, goal.ExerciseID, goal.TargetValue, goal.TargetReps,
+		goal.StartsAt, goal.Deadline, goal.ID).Scan(&goal.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	err = evaluateGoal(tx, goal)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	goal.derive(time.Now())
+	return nil
+}
+
+// DeleteGoal removes a goal.
+// Returns sql.ErrNoRows if it does not exist.
+func (pg *PostgresGoalStore) DeleteGoal(id int64) error {
+	result, err := pg.db.Exec(`DELETE FROM goals WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetGoalOwner returns the user_id of the goal.
+// Returns sql.ErrNoRows if it does not exist.
+func (pg *PostgresGoalStore) GetGoalOwner(id int64) (int, error) {
+	var userID int
+	err := pg.db.QueryRow(`SELECT user_id FROM goals WHERE id = $1`, id).Scan(&userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// evaluateGoals recomputes the progress of every goal of the user. It runs inside the
+// transaction that changed their workouts, tracks or measurements, like the records do,
+// so progress is never out of sync with the data.
+func evaluateGoals(tx *sql.Tx, userID int) error {
+	rows, err := tx.Query(`
+	SELECT id, user_id, type, exercise_id, target_value, target_reps, starts_at, deadline
+	FROM goals
+	WHERE user_id = $1
+	`, userID)
+	if err != nil {
+		return err
+	}
+
+	var userGoals []Goal
+	for rows.Next() {
+		var goal Goal
+		err = rows.Scan(&goal.ID, &goal.UserID, &goal.Type, &goal.ExerciseID, &goal.TargetValue,
+			&goal.TargetReps, &goal.StartsAt, &goal.Deadline)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		userGoals = append(userGoals, goal)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range userGoals {
+		err = evaluateGoal(tx, &userGoals[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// evaluateGoal computes the progress of one goal from the data recorded since it
+// started (and before its deadline), and saves it.
+func evaluateGoal(tx *sql.Tx, goal *Goal) error {
+	// Every series query filters on $1 user, $2 start and $3 deadline (may be NULL)
+	window := func(column string) string {
+		return column + ` >= $2 AND ($3::TIMESTAMPTZ IS NULL OR ` + column + ` < $3)`
+	}
+	args := []interface{}{goal.UserID, goal.StartsAt, goal.Deadline}
+
+	// workingSets selects the working sets that were actually done, of the exercise in the
+	// exercise placeholder if it isn't NULL, and with at least the reps placeholder if there is one
+	workingSets := func(exercise, reps string) string {
+		from := `
+		FROM workout_sets s
+		INNER JOIN workout_entries e ON e.id = s.workout_entry_id
+		INNER JOIN workouts w ON w.id = e.workout_id
+		WHERE w.user_id = $1 AND s.completed AND s.set_type <> '` + SetTypeWarmup + `'
+			AND s.weight IS NOT NULL AND s.reps IS NOT NULL
+			AND (` + exercise + `::BIGINT IS NULL OR e.exercise_id = ` + exercise + `)`
+		if reps != "" {
+			from += ` AND s.reps >= ` + reps
+		}
+		return from
+	}
+
+	var seriesQuery, baselineQuery string
+	var baselineArgs []interface{}
+	switch goal.Type {
+	case goals.TypeStrength:
+		args = append(args, goal.ExerciseID, goal.TargetReps)
+		seriesQuery = `SELECT w.performed_at, MAX(s.weight)` + workingSets("$4", "$5") + ` AND ` + window("w.performed_at") + `
+		GROUP BY w.id, w.performed_at ORDER BY w.performed_at, w.id`
+		// The best lift before the goal is where it starts from
+		baselineQuery = `SELECT MAX(s.weight)` + workingSets("$3", "$4") + ` AND w.performed_at < $2`
+		baselineArgs = []interface{}{goal.UserID, goal.StartsAt, goal.ExerciseID, goal.TargetReps}
+	case goals.TypeVolume:
+		args = append(args, goal.ExerciseID)
+		seriesQuery = `SELECT w.performed_at, SUM(s.weight * s.reps)` + workingSets("$4", "") + ` AND ` + window("w.performed_at") + `
+		GROUP BY w.id, w.performed_at ORDER BY w.performed_at, w.id`
+	case goals.TypeFrequency:
+		// Weeks are calendar weeks of the user
+		err := tx.QueryRow(`SELECT timezone FROM users WHERE id = $1`, goal.UserID).Scan(&goal.timezone)
+		if err != nil {
+			return err
+		}
+		seriesQuery = `SELECT performed_at, 1 FROM workouts w WHERE user_id = $1 AND ` + window("performed_at") + `
+		ORDER BY performed_at, id`
+	case goals.TypeDistance:
+		seriesQuery = `
+		SELECT w.performed_at, SUM(t.distance_meters) / 1000
+		FROM workout_tracks t
+		INNER JOIN workouts w ON w.id = t.workout_id
+		WHERE w.user_id = $1 AND ` + window("w.performed_at") + `
+		GROUP BY w.id, w.performed_at ORDER BY w.performed_at, w.id`
+	case goals.TypeBodyweight:
+		seriesQuery = `
+		SELECT measured_at, bodyweight_kg FROM body_measurements
+		WHERE user_id = $1 AND bodyweight_kg IS NOT NULL AND ` + window("measured_at") + `
+		ORDER BY measured_at, id`
+		baselineQuery = `
+		SELECT bodyweight_kg FROM body_measurements
+		WHERE user_id = $1 AND bodyweight_kg IS NOT NULL AND measured_at < $2
+		ORDER BY measured_at DESC, id DESC LIMIT 1`
+		baselineArgs = []interface{}{goal.UserID, goal.StartsAt}
+	default:
+		return fmt.Errorf("unknown goal type %q", goal.Type)
+	}
+
+	rows, err := tx.Query(seriesQuery, args...)
+	if err != nil {
+		return err
+	}
+	var samples []trend.Sample
+	for rows.Next() {
+		var sample trend.Sample
+		if err = rows.Scan(&sample.Time, &sample.Value); err != nil {
+			rows.Close()
+			return err
+		}
+		samples = append(samples, sample)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	var baseline *float64
+	if baselineQuery != "" {
+		err = tx.QueryRow(baselineQuery, baselineArgs...).Scan(&baseline)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+	}
+
+	progress := goals.Evaluate(goal.spec(), baseline, samples)
+	goal.StartValue = progress.StartValue
+	goal.CurrentValue = progress.CurrentValue
+	goal.ProgressPercent = progress.Percent
+	goal.ProjectedAt = progress.ProjectedAt
+	goal.AchievedAt = progress.AchievedAt
+	goal.WeeksMet, goal.ShortfallAt = nil, progress.ShortfallAt
+	if goal.Type == goals.TypeFrequency {
+		goal.WeeksMet = &progress.WeeksMet
+	}
+
+	query := `
+	UPDATE goals
+	SET start_value = $1, current_value = $2, progress_percent = $3, projected_at = $4, achieved_at = $5,
+		weeks_met = $6, shortfall_at = $7, evaluated_at = CURRENT_TIMESTAMP
+	WHERE id = $8
+	RETURNING evaluated_at
+	`
+
+	return tx.QueryRow(query, goal.StartValue, goal.CurrentValue, goal.ProgressPercent, goal.ProjectedAt,
+		goal.AchievedAt, goal.WeeksMet, goal.ShortfallAt, goal.ID).Scan(&goal.EvaluatedAt)
+}
@@ -0,0 +1,43 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGoalValidate(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	deadline := start.AddDate(0, 3, 0)
+	before := start.AddDate(0, 0, -1)
+	bench, one := 1, 1
+
+	tests := []struct {
+		name    string
+		goal    Goal
+		wantErr string
+	}{
+		{name: "strength", goal: Goal{Type: "strength", ExerciseID: &bench, TargetReps: &one, TargetValue: 100, StartsAt: start, Deadline: &deadline}},
+		{name: "volume of one exercise", goal: Goal{Type: "volume", ExerciseID: &bench, TargetValue: 50000, StartsAt: start}},
+		{name: "frequency", goal: Goal{Type: "frequency", TargetValue: 4, StartsAt: start, Deadline: &deadline}},
+		{name: "unknown type", goal: Goal{Type: "fun", TargetValue: 1, StartsAt: start}, wantErr: "type must be one of"},
+		{name: "strength without exercise", goal: Goal{Type: "strength", TargetReps: &one, TargetValue: 100, StartsAt: start}, wantErr: "a strength goal needs an exercise_id"},
+		{name: "reps on a distance goal", goal: Goal{Type: "distance", TargetReps: &one, TargetValue: 200, StartsAt: start}, wantErr: "target_reps is only used by strength goals"},
+		{name: "exercise on a bodyweight goal", goal: Goal{Type: "bodyweight", ExerciseID: &bench, TargetValue: 80, StartsAt: start}, wantErr: "a bodyweight goal can't have an exercise_id"},
+		{name: "frequency without deadline", goal: Goal{Type: "frequency", TargetValue: 4, StartsAt: start}, wantErr: "a frequency goal needs a deadline"},
+		{name: "deadline before start", goal: Goal{Type: "distance", TargetValue: 200, StartsAt: start, Deadline: &before}, wantErr: "deadline must be after starts_at"},
+		{name: "no target", goal: Goal{Type: "distance", StartsAt: start}, wantErr: "target_value must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.goal.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
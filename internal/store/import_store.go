@@ -0,0 +1,297 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Statuses of an import job.
+const (
+	ImportStatusPending   = "pending"
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+// Import is a CSV upload of workout history, processed in the background.
+// The counters and the report grow while it runs, so clients can poll it for progress.
+type Import struct {
+	ID                int                `json:"id"`
+	UserID            int                `json:"user_id"`
+	Format            string             `json:"format"`
+	Filename          string             `json:"filename"`
+	Status            string             `json:"status"`
+	TotalRows         int                `json:"total_rows"`
+	TotalWorkouts     int                `json:"total_workouts"`
+	ImportedWorkouts  int                `json:"imported_workouts"`
+	DuplicateWorkouts int                `json:"duplicate_workouts"`
+	SkippedRows       []ImportSkippedRow `json:"skipped_rows"`
+	Duplicates        []ImportDuplicate  `json:"duplicates"`
+	Error             string             `json:"error,omitempty"`
+	CreatedAt         time.Time          `json:"created_at"`
+	StartedAt         *time.Time         `json:"started_at"`
+	FinishedAt        *time.Time         `json:"finished_at"`
+}
+
+// ImportSkippedRow is a line of the file that didn't make it into a workout, and why.
+type ImportSkippedRow struct {
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+}
+
+// ImportDuplicate is a workout of the file that was already logged as WorkoutID.
+// Line is the first line of the workout in the file.
+type ImportDuplicate struct {
+	Line        int       `json:"line"`
+	Title       string    `json:"title"`
+	PerformedAt time.Time `json:"performed_at"`
+	WorkoutID   int       `json:"workout_id"`
+}
+
+// PostgresImportStore implements ImportStore using PostgreSQL as the backend.
+type PostgresImportStore struct {
+	db *sql.DB
+}
+
+// NewPostgresImportStore is a constructor for PostgresImportStore.
+func NewPostgresImportStore(db *sql.DB) *PostgresImportStore {
+	return &PostgresImportStore{db: db}
+}
+
+// ImportStore defines how import jobs are persisted.
+// The workouts themselves are saved through the WorkoutStore.
+type ImportStore interface {
+	CreateImport(*Import) error
+	GetImportByID(id int64) (*Import, error)
+	UpdateImport(*Import) error
+	FindDuplicateWorkouts(userID int, workouts []*Workout) ([]int, error)
+	FailInterruptedImports() (int64, error)
+}
+
+// CreateImport queues a new import.
+func (pg *PostgresImportStore) CreateImport(imp *Import) error {
+	query := `
+	INSERT INTO imports (user_id, format, filename)
+	VALUES ($1, $2, $3)
+	RETURNING id, status, created_at
+	`
+
+	err := pg.db.QueryRow(query, imp.UserID, imp.Format, imp.Filename).Scan(&imp.ID, &imp.Status, &imp.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	imp.SkippedRows = []ImportSkippedRow{}
+	imp.Duplicates = []ImportDuplicate{}
+	return nil
+}
+
+// GetImportByID fetches an import with its report. Returns (nil, nil) if it doesn't exist.
+func (pg *PostgresImportStore) GetImportByID(id int64) (*Import, error) {
+	imp := &Import{}
+	var skippedRows, duplicates []byte
+
+	query := `
+	SELECT id, user_id, format, filename, status, total_rows, total_workouts, imported_workouts, duplicate_workouts,
+		skipped_rows, duplicates, error, created_at, started_at, finished_at
+	FROM imports
+	WHERE id = $1
+	`
+
+	err := pg.db.QueryRow(query, id).Scan(&imp.ID, &imp.UserID, &imp.Format, &imp.Filename, &imp.Status,
+		&imp.TotalRows, &imp.TotalWorkouts, &imp.ImportedWorkouts, &imp.DuplicateWorkouts,
+		&skippedRows, &duplicates, &imp.Error, &imp.CreatedAt, &imp.StartedAt, &imp.FinishedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(skippedRows, &imp.SkippedRows); err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(duplicates, &imp.Duplicates); err != nil {
+		return nil, err
+	}
+
+	return imp, nil
+}
+
+// UpdateImport saves the progress, report and status of an import.
+func (pg *PostgresImportStore) UpdateImport(imp *Import) error {
+	if imp.SkippedRows == nil {
+		imp.SkippedRows = []ImportSkippedRow{}
+	}
+	if imp.Duplicates == nil {
+		imp.Duplicates = []ImportDuplicate{}
+	}
+
+	skippedRows, err := json.Marshal(imp.SkippedRows)
+	if err != nil {
+		return err
+	}
+	duplicates, err := json.Marshal(imp.Duplicates)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	UPDATE imports
+	SET format = $1, status = $2, total_rows = $3, total_workouts = $4, imported_workouts = $5, duplicate_workouts = $6,
+		skipped_rows = $7, duplicates = $8, error = $9, started_at = $10, finished_at = $11
+	WHERE id = $12
+	`
+
+	result, err := pg.db.Exec(query, imp.Format, imp.Status, imp.TotalRows, imp.TotalWorkouts, imp.ImportedWorkouts,
+		imp.DuplicateWorkouts, skippedRows, duplicates, imp.Error, imp.StartedAt, imp.FinishedAt, imp.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// FindDuplicateWorkouts looks for workouts the user already logged for each of the given ones.
+// A workout is a duplicate when one on the same day (in the time zone of the imported workouts)
+// has exactly the same exercises, compared by catalog id when both are linked and by name otherwise.
+// The result has one element per workout: the id of the existing workout, or 0.
+func (pg *PostgresImportStore) FindDuplicateWorkouts(userID int, workouts []*Workout) ([]int, error) {
+	duplicates := make([]int, len(workouts))
+	if len(workouts) == 0 {
+		return duplicates, nil
+	}
+
+	location := workouts[0].PerformedAt.Location()
+	from, to := workouts[0].PerformedAt, workouts[0].PerformedAt
+	for _, workout := range workouts {
+		if workout.PerformedAt.Before(from) {
+			from = workout.PerformedAt
+		}
+		if workout.PerformedAt.After(to) {
+			to = workout.PerformedAt
+		}
+	}
+
+	// Pad the range by a day so workouts on the same calendar day but in another offset are seen
+	query := `
+	SELECT w.id, w.performed_at, e.exercise_id, e.exercise_name
+	FROM workouts w
+	LEFT JOIN workout_entries e ON e.workout_id = w.id
+	WHERE w.user_id = $1 AND w.performed_at >= $2 AND w.performed_at < $3
+	ORDER BY w.id
+	`
+
+	rows, err := pg.db.Query(query, userID, from.Add(-24*time.Hour), to.Add(24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type existingWorkout struct {
+		performedAt time.Time
+		keys        []string
+	}
+	existing := map[int]*existingWorkout{}
+	var order []int
+	for rows.Next() {
+		var workoutID int
+		var performedAt time.Time
+		var exerciseID *int
+		var exerciseName sql.NullString
+		err = rows.Scan(&workoutID, &performedAt, &exerciseID, &exerciseName)
+		if err != nil {
+			return nil, err
+		}
+
+		workout, ok := existing[workoutID]
+		if !ok {
+			workout = &existingWorkout{performedAt: performedAt}
+			existing[workoutID] = workout
+			order = append(order, workoutID)
+		}
+		if exerciseName.Valid {
+			workout.keys = append(workout.keys, entryKey(exerciseID, exerciseName.String))
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// The first workout logged wins when several share a fingerprint
+	byFingerprint := make(map[string]int, len(existing))
+	for _, workoutID := range order {
+		workout := existing[workoutID]
+		fingerprint := workoutFingerprint(workout.performedAt.In(location), workout.keys)
+		if _, ok := byFingerprint[fingerprint]; !ok {
+			byFingerprint[fingerprint] = workoutID
+		}
+	}
+
+	// Imported names are linked the same way CreateWorkout will link them
+	linker, err := newExerciseLinker(pg.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i, workout := range workouts {
+		keys := make([]string, 0, len(workout.Entries))
+		for _, entry := range workout.Entries {
+			exerciseID, exerciseName, err := linker.link(entry.ExerciseID, entry.ExerciseName)
+			if errors.Is(err, ErrUnknownExercise) {
+				exerciseID, exerciseName = nil, entry.ExerciseName
+			} else if err != nil {
+				return nil, err
+			}
+			keys = append(keys, entryKey(exerciseID, exerciseName))
+		}
+		duplicates[i] = byFingerprint[workoutFingerprint(workout.PerformedAt, keys)]
+	}
+
+	return duplicates, nil
+}
+
+// workoutFingerprint identifies a workout by its calendar day and the set of its exercises,
+// whatever order they were done in and however many entries share an exercise.
+func workoutFingerprint(performedAt time.Time, exerciseKeys []string) string {
+	unique := map[string]bool{}
+	keys := make([]string, 0, len(exerciseKeys))
+	for _, key := range exerciseKeys {
+		if !unique[key] {
+			unique[key] = true
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	return performedAt.Format("2006-01-02") + "|" + strings.Join(keys, "|")
+}
+
+// FailInterruptedImports marks imports that were queued or running when the server stopped as failed,
+// since their jobs only lived in memory. It runs at startup and returns how many were marked.
+func (pg *PostgresImportStore) FailInterruptedImports() (int64, error) {
+	query := `
+	UPDATE imports
+	SET status = 'failed', error = 'the import was interrupted by a server restart, upload the file again',
+		finished_at = CURRENT_TIMESTAMP
+	WHERE status IN ('pending', 'running')
+	`
+
+	result, err := pg.db.Exec(query)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
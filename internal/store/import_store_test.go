@@ -0,0 +1,70 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkoutFingerprint(t *testing.T) {
+	morning := time.Date(2024, 3, 15, 7, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		a, b  time.Time
+		keysA []string
+		keysB []string
+		equal bool
+	}{
+		{
+			name:  "same day, exercises in another order",
+			a:     morning,
+			b:     morning.Add(10 * time.Hour),
+			keysA: []string{"id:1", "name:farmer walk"},
+			keysB: []string{"name:farmer walk", "id:1"},
+			equal: true,
+		},
+		{
+			name:  "repeated exercise",
+			a:     morning,
+			b:     morning,
+			keysA: []string{"id:1", "id:2", "id:1"},
+			keysB: []string{"id:2", "id:1"},
+			equal: true,
+		},
+		{
+			name:  "another day",
+			a:     morning,
+			b:     morning.Add(24 * time.Hour),
+			keysA: []string{"id:1"},
+			keysB: []string{"id:1"},
+		},
+		{
+			name:  "another exercise",
+			a:     morning,
+			b:     morning,
+			keysA: []string{"id:1", "id:2"},
+			keysB: []string{"id:1", "id:3"},
+		},
+		{
+			name:  "subset of exercises",
+			a:     morning,
+			b:     morning,
+			keysA: []string{"id:1", "id:2"},
+			keysB: []string{"id:1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := workoutFingerprint(tt.a, tt.keysA)
+			b := workoutFingerprint(tt.b, tt.keysB)
+			if tt.equal {
+				assert.Equal(t, a, b)
+			} else {
+				assert.NotEqual(t, a, b)
+			}
+		})
+	}
+}
@@ -0,0 +1,385 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/trend"
+)
+
+// Metrics that can be followed over time, besides the circumference sites.
+const (
+	MetricBodyweight = "bodyweight"
+	MetricBodyFat    = "body_fat"
+)
+
+// MeasurementSites are the circumferences that can be logged, in cm.
+var MeasurementSites = []string{
+	"neck", "shoulders", "chest", "waist", "hips",
+	"left_arm", "right_arm", "left_forearm", "right_forearm",
+	"left_thigh", "right_thigh", "left_calf", "right_calf",
+}
+
+// BodyMeasurement is one weigh-in or tape measurement. Every value is optional,
+// but a measurement has at least one of them.
+type BodyMeasurement struct {
+	ID             int                `json:"id"`
+	UserID         int                `json:"user_id"`
+	MeasuredAt     time.Time          `json:"measured_at"`
+	BodyweightKg   *float64           `json:"bodyweight_kg"`
+	BodyFatPercent *float64           `json:"body_fat_percent"`
+	Circumferences map[string]float64 `json:"circumferences_cm"`
+	Notes          string             `json:"notes"`
+	CreatedAt      time.Time          `json:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+}
+
+// MeasurementFilter selects the measurements returned by ListMeasurements.
+type MeasurementFilter struct {
+	UserID int
+	From   *time.Time
+	To     *time.Time
+}
+
+// ValidMetric reports whether a metric can be followed with GetMeasurementSeries.
+func ValidMetric(metric string) bool {
+	if metric == MetricBodyweight || metric == MetricBodyFat {
+		return true
+	}
+	return validSite(metric)
+}
+
+func validSite(site string) bool {
+	for _, known := range MeasurementSites {
+		if site == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that the measurement has at least one value and that every value is plausible.
+func (m *BodyMeasurement) Validate() error {
+	if m.BodyweightKg == nil && m.BodyFatPercent == nil && len(m.Circumferences) == 0 {
+		return errors.New("a measurement needs a bodyweight_kg, body_fat_percent or circumferences_cm")
+	}
+	if m.BodyweightKg != nil && (*m.BodyweightKg < 20 || *m.BodyweightKg > 400) {
+		return errors.New("bodyweight_kg must be between 20 and 400")
+	}
+	if m.BodyFatPercent != nil && (*m.BodyFatPercent <= 0 || *m.BodyFatPercent >= 100) {
+		return errors.New("body_fat_percent must be between 0 and 100")
+	}
+
+	sites := make([]string, 0, len(m.Circumferences))
+	for site := range m.Circumferences {
+		sites = append(sites, site)
+	}
+	sort.Strings(sites)
+	for _, site := range sites {
+		if !validSite(site) {
+			return fmt.Errorf("unknown circumference site %q, expected one of %s", site, strings.Join(MeasurementSites, ", "))
+		}
+		if value := m.Circumferences[site]; value <= 0 || value > 300 {
+			return fmt.Errorf("the %s circumference must be between 0 and 300 cm", site)
+		}
+	}
+
+	return nil
+}
+
+// PostgresMeasurementStore implements MeasurementStore using PostgreSQL as the backend.
+type PostgresMeasurementStore struct {
+	db *sql.DB
+}
+
+// NewPostgresMeasurementStore is a constructor for PostgresMeasurementStore.
+func NewPostgresMeasurementStore(db *sql.DB) *PostgresMeasurementStore {
+	return &PostgresMeasurementStore{db: db}
+}
+
+// MeasurementStore defines how body measurements are persisted.
+type MeasurementStore interface {
+	CreateMeasurement(*BodyMeasurement) error
+	GetMeasurementByID(id int64) (*BodyMeasurement, error)
+	ListMeasurements(filter MeasurementFilter) ([]BodyMeasurement, error)
+	UpdateMeasurement(*BodyMeasurement) error
+	DeleteMeasurement(id int64) error
+	GetMeasurementOwner(id int64) (int, error)
+	GetMeasurementSeries(userID int, metric string, from, to *time.Time) ([]trend.Sample, error)
+	GetLatestBodyweight(userID int) (*float64, error)
+}
+
+const measurementColumns = `id, user_id, measured_at, bodyweight_kg, body_fat_percent, circumferences, notes, created_at, updated_at`
+
+// scanMeasurement reads one row selected with measurementColumns.
+func scanMeasurement(row interface{ Scan(...interface{}) error }) (*BodyMeasurement, error) {
+	measurement := &BodyMeasurement{}
+	var circumferences []byte
+	err := row.Scan(&measurement.ID, &measurement.UserID, &measurement.MeasuredAt, &measurement.BodyweightKg,
+		&measurement.BodyFatPercent, &circumferences, &measurement.Notes, &measurement.CreatedAt, &measurement.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(circumferences, &measurement.Circumferences); err != nil {
+		return nil, err
+	}
+	return measurement, nil
+}
+
+// marshalCircumferences encodes the circumferences for the JSONB column, {} when there are none.
+func marshalCircumferences(circumferences map[string]float64) ([]byte, error) {
+	if circumferences == nil {
+		circumferences = map[string]float64{}
+	}
+	return json.Marshal(circumferences)
+}
+
+// CreateMeasurement saves a measurement; MeasuredAt defaults to now.
+// Bodyweight goals are evaluated again in the same transaction.
+func (pg *PostgresMeasurementStore) CreateMeasurement(measurement *BodyMeasurement) error {
+	if measurement.MeasuredAt.IsZero() {
+		measurement.MeasuredAt = time.Now()
+	}
+	if measurement.Circumferences == nil {
+		measurement.Circumferences = map[string]float64{}
+	}
+	circumferences, err := marshalCircumferences(measurement.Circumferences)
+	if err != nil {
+		return err
+	}
+
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+	INSERT INTO body_measurements (user_id, measured_at, bodyweight_kg, body_fat_percent, circumferences, notes)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id, created_at, updated_at
+	`
+
+	err = tx.QueryRow(query, measurement.UserID, measurement.MeasuredAt, measurement.BodyweightKg,
+		measurement.BodyFatPercent, circumferences, measurement.Notes).
+		Scan(&measurement.ID, &measurement.CreatedAt, &measurement.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	err = evaluateGoals(tx, measurement.UserID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetMeasurementByID returns (nil, nil) if the measurement doesn't exist.
+func (pg *PostgresMeasurementStore) GetMeasurementByID(id int64) (*BodyMeasurement, error) {
+	query := `SELECT ` + measurementColumns + ` FROM body_measurements WHERE id = $1`
+
+	measurement, err := scanMeasurement(pg.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return measurement, nil
+}
+
+// ListMeasurements returns the user's measurements, most recent first.
+func (pg *PostgresMeasurementStore) ListMeasurements(filter MeasurementFilter) ([]BodyMeasurement, error) {
+	args := []interface{}{filter.UserID}
+	conditions := []string{"user_id = $1"}
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.From != nil {
+		conditions = append(conditions, "measured_at >= "+addArg(*filter.From))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "measured_at < "+addArg(*filter.To))
+	}
+
+	query := `
+	SELECT ` + measurementColumns + `
+	FROM body_measurements
+	WHERE ` + strings.Join(conditions, " AND ") + `
+	ORDER BY measured_at DESC, id DESC
+	`
+
+	rows, err := pg.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	measurements := []BodyMeasurement{}
+	for rows.Next() {
+		measurement, err := scanMeasurement(rows)
+		if err != nil {
+			return nil, err
+		}
+		measurements = append(measurements, *measurement)
+	}
+
+	return measurements, rows.Err()
+}
+
+// UpdateMeasurement replaces the values of a measurement.
+// Returns sql.ErrNoRows if it does not exist.
+func (pg *PostgresMeasurementStore) UpdateMeasurement(measurement *BodyMeasurement) error {
+	circumferences, err := marshalCircumferences(measurement.Circumferences)
+	if err != nil {
+		return err
+	}
+
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+	UPDATE body_measurements
+	SET measured_at = $1, bodyweight_kg = $2, body_fat_percent = $3, circumferences = $4, notes = $5,
+		updated_at = CURRENT_TIMESTAMP
+	WHERE id = $6
+	RETURNING updated_at, user_id
+	`
+
+	err = tx.QueryRow(query, measurement.MeasuredAt, measurement.BodyweightKg, measurement.BodyFatPercent,
+		circumferences, measurement.Notes, measurement.ID).Scan(&measurement.UpdatedAt, &measurement.UserID)
+	if err != nil {
+		return err
+	}
+
+	err = evaluateGoals(tx, measurement.UserID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteMeasurement removes a measurement.
+// Returns sql.ErrNoRows if it does not exist.
+func (pg *PostgresMeasurementStore) DeleteMeasurement(id int64) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var userID int
+	err = tx.QueryRow(`DELETE FROM body_measurements WHERE id = $1 RETURNING user_id`, id).Scan(&userID)
+	if err != nil {
+		return err
+	}
+
+	err = evaluateGoals(tx, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetMeasurementOwner returns the user_id of the measurement.
+// Returns sql.ErrNoRows if it does not exist.
+func (pg *PostgresMeasurementStore) GetMeasurementOwner(id int64) (int, error) {
+	var userID int
+	err := pg.db.QueryRow(`SELECT user_id FROM body_measurements WHERE id = $1`, id).Scan(&userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// GetMeasurementSeries returns the values of one metric (see ValidMetric) in time order,
+// skipping the measurements that don't have it. from and to are optional, to is exclusive.
+func (pg *PostgresMeasurementStore) GetMeasurementSeries(userID int, metric string, from, to *time.Time) ([]trend.Sample, error) {
+	args := []interface{}{userID}
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	var column string
+	switch {
+	case metric == MetricBodyweight:
+		column = "bodyweight_kg"
+	case metric == MetricBodyFat:
+		column = "body_fat_percent"
+	case validSite(metric):
+		column = "(circumferences->>" + addArg(metric) + ")::DOUBLE PRECISION"
+	default:
+		return nil, fmt.Errorf("unknown metric %q", metric)
+	}
+
+	conditions := []string{"user_id = $1", column + " IS NOT NULL"}
+	if from != nil {
+		conditions = append(conditions, "measured_at >= "+addArg(*from))
+	}
+	if to != nil {
+		conditions = append(conditions, "measured_at < "+addArg(*to))
+	}
+
+	query := `
+	SELECT measured_at, ` + column + `
+	FROM body_measurements
+	WHERE ` + strings.Join(conditions, " AND ") + `
+	ORDER BY measured_at, id
+	`
+
+	rows, err := pg.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	samples := []trend.Sample{}
+	for rows.Next() {
+		var sample trend.Sample
+		if err = rows.Scan(&sample.Time, &sample.Value); err != nil {
+			return nil, err
+		}
+		samples = append(samples, sample)
+	}
+
+	return samples, rows.Err()
+}
+
+// GetLatestBodyweight returns the most recent bodyweight the user logged, nil if they never did.
+func (pg *PostgresMeasurementStore) GetLatestBodyweight(userID int) (*float64, error) {
+	query := `
+	SELECT bodyweight_kg
+	FROM body_measurements
+	WHERE user_id = $1 AND bodyweight_kg IS NOT NULL
+	ORDER BY measured_at DESC, id DESC
+	LIMIT 1
+	`
+
+	var bodyweight float64
+	err := pg.db.QueryRow(query, userID).Scan(&bodyweight)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &bodyweight, nil
+}
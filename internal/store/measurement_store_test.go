@@ -0,0 +1,58 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/records"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBodyMeasurementValidate(t *testing.T) {
+	kg := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name        string
+		measurement BodyMeasurement
+		wantErr     string
+	}{
+		{name: "bodyweight only", measurement: BodyMeasurement{BodyweightKg: kg(81.4)}},
+		{name: "circumferences only", measurement: BodyMeasurement{Circumferences: map[string]float64{"waist": 84, "left_arm": 38.5}}},
+		{name: "empty", measurement: BodyMeasurement{Circumferences: map[string]float64{}}, wantErr: "a measurement needs a bodyweight_kg, body_fat_percent or circumferences_cm"},
+		{name: "bodyweight out of range", measurement: BodyMeasurement{BodyweightKg: kg(4)}, wantErr: "bodyweight_kg must be between 20 and 400"},
+		{name: "body fat out of range", measurement: BodyMeasurement{BodyFatPercent: kg(100)}, wantErr: "body_fat_percent must be between 0 and 100"},
+		{name: "unknown site", measurement: BodyMeasurement{Circumferences: map[string]float64{"ankle": 22}}, wantErr: `unknown circumference site "ankle"`},
+		{name: "negative circumference", measurement: BodyMeasurement{Circumferences: map[string]float64{"waist": -1}}, wantErr: "the waist circumference must be between 0 and 300 cm"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.measurement.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestApplyBodyweight(t *testing.T) {
+	found := []PersonalRecord{
+		{RecordType: records.TypeHeaviestWeight, Value: 120},
+		{RecordType: records.TypeBestE1RM, Value: 131},
+		{RecordType: records.TypeMaxSessionVolume, Value: 4200},
+	}
+
+	ApplyBodyweight(found, nil)
+	assert.Nil(t, found[0].BodyweightMultiple)
+
+	bodyweight := 80.0
+	ApplyBodyweight(found, &bodyweight)
+	assert.Equal(t, 1.5, *found[0].BodyweightMultiple)
+	assert.Equal(t, 1.64, *found[1].BodyweightMultiple)
+	assert.Nil(t, found[2].BodyweightMultiple)
+
+	points := []E1RMPoint{{E1RM: 100}}
+	ApplyBodyweightToHistory(points, &bodyweight)
+	assert.Equal(t, 1.25, *points[0].BodyweightMultiple)
+}
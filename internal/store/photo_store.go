@@ -0,0 +1,239 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrUnknownMeasurement is returned when a photo is linked to a measurement
+// that doesn't exist or belongs to another user.
+var ErrUnknownMeasurement = errors.New("unknown measurement")
+
+// PhotoPoses are the poses a progress photo can be tagged with, so the same
+// pose can be compared over time.
+var PhotoPoses = []string{"front", "back", "left_side", "right_side", "other"}
+
+// ProgressPhoto is the metadata of an uploaded progress photo.
+// The image and its thumbnail are kept in a blob.Store, under BlobKey and ThumbnailKey.
+type ProgressPhoto struct {
+	ID            int       `json:"id"`
+	UserID        int       `json:"user_id"`
+	MeasurementID *int      `json:"measurement_id"`
+	TakenAt       time.Time `json:"taken_at"`
+	Pose          string    `json:"pose"`
+	Notes         string    `json:"notes"`
+	Width         int       `json:"width"`
+	Height        int       `json:"height"`
+	SizeBytes     int64     `json:"size_bytes"`
+	BlobKey       string    `json:"-"`
+	ThumbnailKey  string    `json:"-"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+
+	// Signed download links, filled in by the API for every response. Not stored.
+	URL          string     `json:"url,omitempty"`
+	ThumbnailURL string     `json:"thumbnail_url,omitempty"`
+	URLExpiresAt *time.Time `json:"url_expires_at,omitempty"`
+}
+
+// PhotoFilter selects the photos returned by ListPhotos.
+type PhotoFilter struct {
+	UserID int
+	From   *time.Time
+	To     *time.Time
+	Pose   string
+}
+
+// ValidPose reports whether the pose is one of PhotoPoses.
+func ValidPose(pose string) bool {
+	for _, known := range PhotoPoses {
+		if pose == known {
+			return true
+		}
+	}
+	return false
+}
+
+// PostgresPhotoStore implements PhotoStore using PostgreSQL as the backend.
+type PostgresPhotoStore struct {
+	db *sql.DB
+}
+
+// NewPostgresPhotoStore is a constructor for PostgresPhotoStore.
+func NewPostgresPhotoStore(db *sql.DB) *PostgresPhotoStore {
+	return &PostgresPhotoStore{db: db}
+}
+
+// PhotoStore defines how progress photo metadata is persisted.
+type PhotoStore interface {
+	CreatePhoto(*ProgressPhoto) error
+	GetPhotoByID(id int64) (*ProgressPhoto, error)
+	ListPhotos(filter PhotoFilter) ([]ProgressPhoto, error)
+	UpdatePhoto(*ProgressPhoto) error
+	DeletePhoto(id int64) error
+	GetPhotoOwner(id int64) (int, error)
+}
+
+const photoColumns = `id, user_id, measurement_id, taken_at, pose, notes, width, height, size_bytes, blob_key, thumbnail_key, created_at, updated_at`
+
+// scanPhoto reads one row selected with photoColumns.
+func scanPhoto(row interface{ Scan(...interface{}) error }) (*ProgressPhoto, error) {
+	photo := &ProgressPhoto{}
+	err := row.Scan(&photo.ID, &photo.UserID, &photo.MeasurementID, &photo.TakenAt, &photo.Pose, &photo.Notes,
+		&photo.Width, &photo.Height, &photo.SizeBytes, &photo.BlobKey, &photo.ThumbnailKey, &photo.CreatedAt, &photo.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return photo, nil
+}
+
+// checkMeasurement returns ErrUnknownMeasurement unless the linked measurement belongs to the user.
+func (pg *PostgresPhotoStore) checkMeasurement(photo *ProgressPhoto) error {
+	if photo.MeasurementID == nil {
+		return nil
+	}
+
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM body_measurements WHERE id = $1 AND user_id = $2)`
+	err := pg.db.QueryRow(query, *photo.MeasurementID, photo.UserID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrUnknownMeasurement
+	}
+
+	return nil
+}
+
+// CreatePhoto saves the metadata of a photo whose blobs are already stored.
+// Returns ErrUnknownMeasurement if the linked measurement isn't the user's.
+func (pg *PostgresPhotoStore) CreatePhoto(photo *ProgressPhoto) error {
+	err := pg.checkMeasurement(photo)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO progress_photos (user_id, measurement_id, taken_at, pose, notes, width, height, size_bytes, blob_key, thumbnail_key)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	RETURNING id, created_at, updated_at
+	`
+
+	return pg.db.QueryRow(query, photo.UserID, photo.MeasurementID, photo.TakenAt, photo.Pose, photo.Notes,
+		photo.Width, photo.Height, photo.SizeBytes, photo.BlobKey, photo.ThumbnailKey).
+		Scan(&photo.ID, &photo.CreatedAt, &photo.UpdatedAt)
+}
+
+// GetPhotoByID returns (nil, nil) if the photo doesn't exist.
+func (pg *PostgresPhotoStore) GetPhotoByID(id int64) (*ProgressPhoto, error) {
+	query := `SELECT ` + photoColumns + ` FROM progress_photos WHERE id = $1`
+
+	photo, err := scanPhoto(pg.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return photo, nil
+}
+
+// ListPhotos returns the user's photos, most recent first.
+func (pg *PostgresPhotoStore) ListPhotos(filter PhotoFilter) ([]ProgressPhoto, error) {
+	args := []interface{}{filter.UserID}
+	conditions := []string{"user_id = $1"}
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.From != nil {
+		conditions = append(conditions, "taken_at >= "+addArg(*filter.From))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "taken_at < "+addArg(*filter.To))
+	}
+	if filter.Pose != "" {
+		conditions = append(conditions, "pose = "+addArg(filter.Pose))
+	}
+
+	query := `
+	SELECT ` + photoColumns + `
+	FROM progress_photos
+	WHERE ` + strings.Join(conditions, " AND ") + `
+	ORDER BY taken_at DESC, id DESC
+	`
+
+	rows, err := pg.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	photos := []ProgressPhoto{}
+	for rows.Next() {
+		photo, err := scanPhoto(rows)
+		if err != nil {
+			return nil, err
+		}
+		photos = append(photos, *photo)
+	}
+
+	return photos, rows.Err()
+}
+
+// UpdatePhoto saves the metadata of a photo; the image itself can't be changed.
+// Returns ErrUnknownMeasurement if the linked measurement isn't the user's,
+// and sql.ErrNoRows if the photo does not exist.
+func (pg *PostgresPhotoStore) UpdatePhoto(photo *ProgressPhoto) error {
+	err := pg.checkMeasurement(photo)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	UPDATE progress_photos
+	SET measurement_id = $1, taken_at = $2, pose = $3, notes = $4, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $5
+	RETURNING updated_at
+	`
+
+	return pg.db.QueryRow(query, photo.MeasurementID, photo.TakenAt, photo.Pose, photo.Notes, photo.ID).
+		Scan(&photo.UpdatedAt)
+}
+
+// DeletePhoto removes the metadata of a photo. Deleting its blobs is up to the caller.
+// Returns sql.ErrNoRows if it does not exist.
+func (pg *PostgresPhotoStore) DeletePhoto(id int64) error {
+	result, err := pg.db.Exec(`DELETE FROM progress_photos WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetPhotoOwner returns the user_id of the photo.
+// Returns sql.ErrNoRows if it does not exist.
+func (pg *PostgresPhotoStore) GetPhotoOwner(id int64) (int, error) {
+	var userID int
+	err := pg.db.QueryRow(`SELECT user_id FROM progress_photos WHERE id = $1`, id).Scan(&userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
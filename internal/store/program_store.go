@@ -0,0 +1,617 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/progression"
+)
+
+// ErrUnknownTemplate is returned when a program day references a template
+// that doesn't exist or belongs to another user.
+var ErrUnknownTemplate = errors.New("unknown template")
+
+// ErrUnknownProgramDay is returned when a workout is logged for a program day
+// that doesn't exist or belongs to another user's program.
+var ErrUnknownProgramDay = errors.New("unknown program day")
+
+// Program is a multi-week plan, e.g. a 12-week linear progression.
+// Every day of the program runs one of the user's templates, and the rules
+// decide how the weights of those templates move as sessions are logged.
+type Program struct {
+	ID          int               `json:"id"`
+	UserID      int               `json:"user_id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Days        []ProgramDay      `json:"days"`
+	Rules       []ProgressionRule `json:"rules"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// ProgramDay is one session of the program, e.g. week 2 day 3.
+type ProgramDay struct {
+	ID         int    `json:"id"`
+	Week       int    `json:"week"`
+	Day        int    `json:"day"`
+	Name       string `json:"name"`
+	TemplateID int    `json:"template_id"`
+}
+
+// ProgressionRule is a progression.Rule stored for a program.
+// It applies to the given exercise, or to every exercise when both ExerciseID and ExerciseName are empty.
+type ProgressionRule struct {
+	ID                  int     `json:"id"`
+	ExerciseID          *int    `json:"exercise_id"`
+	ExerciseName        string  `json:"exercise_name"`
+	Increment           float64 `json:"increment"`
+	DeloadPercent       float64 `json:"deload_percent"`
+	DeloadAfterFailures int     `json:"deload_after_failures"`
+}
+
+// Enrollment is a user following a program. Only workouts performed after
+// StartedAt count towards it, so a program can be restarted by enrolling again.
+type Enrollment struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	ProgramID int       `json:"program_id"`
+	StartedAt time.Time `json:"started_at"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NextWorkout is the concrete prescription for the next session of the active program.
+// Completed is true once every day of the program was logged, Day and Entries are empty then.
+type NextWorkout struct {
+	Enrollment  Enrollment        `json:"enrollment"`
+	ProgramName string            `json:"program_name"`
+	Completed   bool              `json:"completed"`
+	Day         *ProgramDay       `json:"day,omitempty"`
+	Title       string            `json:"title,omitempty"`
+	Entries     []PrescribedEntry `json:"entries,omitempty"`
+}
+
+// PrescribedEntry is a template entry with the weight computed by the progression rules.
+type PrescribedEntry struct {
+	ExerciseID      *int     `json:"exercise_id"`
+	ExerciseName    string   `json:"exercise_name"`
+	Sets            int      `json:"sets"`
+	Reps            *int     `json:"reps"`
+	DurationSeconds *int     `json:"duration_seconds"`
+	Weight          *float64 `json:"weight"`
+	Notes           string   `json:"notes"`
+	OrderIndex      int      `json:"order_index"`
+	// Progression is nil when no rule applies to the exercise
+	Progression *progression.Prescription `json:"progression,omitempty"`
+}
+
+// PostgresProgramStore implements ProgramStore using PostgreSQL as the backend.
+type PostgresProgramStore struct {
+	db *sql.DB
+}
+
+// NewPostgresProgramStore is a constructor for PostgresProgramStore.
+func NewPostgresProgramStore(db *sql.DB) *PostgresProgramStore {
+	return &PostgresProgramStore{db: db}
+}
+
+// ProgramStore defines how programs and enrollments are persisted.
+type ProgramStore interface {
+	CreateProgram(*Program) error
+	GetProgramByID(id int64) (*Program, error)
+	ListPrograms(userID int) ([]Program, error)
+	DeleteProgram(id int64) error
+	GetProgramOwner(id int64) (int, error)
+	Enroll(userID int, programID int64, startedAt time.Time) (*Enrollment, error)
+	GetNextWorkout(userID int) (*NextWorkout, error)
+}
+
+// CreateProgram inserts a program with its days and rules in one transaction.
+// Returns ErrUnknownTemplate if a day uses a template the user doesn't own.
+func (pg *PostgresProgramStore) CreateProgram(program *Program) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+	INSERT INTO programs (user_id, name, description)
+	VALUES ($1, $2, $3)
+	RETURNING id, created_at
+	`
+
+	err = tx.QueryRow(query, program.UserID, program.Name, program.Description).Scan(&program.ID, &program.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	dayQuery := `
+	INSERT INTO program_days (program_id, week_number, day_number, name, template_id)
+	SELECT $1, $2, $3, $4, t.id
+	FROM workout_templates t
+	WHERE t.id = $5 AND t.user_id = $6
+	RETURNING id
+	`
+
+	for i := range program.Days {
+		day := &program.Days[i]
+		err = tx.QueryRow(dayQuery, program.ID, day.Week, day.Day, day.Name, day.TemplateID, program.UserID).Scan(&day.ID)
+		if err == sql.ErrNoRows {
+			return ErrUnknownTemplate
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	linker, err := newExerciseLinker(tx, program.UserID)
+	if err != nil {
+		return err
+	}
+
+	ruleQuery := `
+	INSERT INTO progression_rules (program_id, exercise_id, exercise_name, increment, deload_percent, deload_after_failures)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id
+	`
+
+	for i := range program.Rules {
+		rule := &program.Rules[i]
+		// A rule without an exercise is the program wide default, there is nothing to link
+		if rule.ExerciseID != nil || strings.TrimSpace(rule.ExerciseName) != "" {
+			rule.ExerciseID, rule.ExerciseName, err = linker.link(rule.ExerciseID, rule.ExerciseName)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = tx.QueryRow(ruleQuery, program.ID, rule.ExerciseID, rule.ExerciseName, rule.Increment,
+			rule.DeloadPercent, rule.DeloadAfterFailures).Scan(&rule.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetProgramByID fetches a program with its days and rules.
+// Returns (nil, nil) if it doesn't exist.
+func (pg *PostgresProgramStore) GetProgramByID(id int64) (*Program, error) {
+	program := &Program{}
+
+	query := `
+	SELECT id, user_id, name, description, created_at
+	FROM programs
+	WHERE id = $1
+	`
+
+	err := pg.db.QueryRow(query, id).Scan(&program.ID, &program.UserID, &program.Name, &program.Description, &program.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = pg.loadProgramDetails(program)
+	if err != nil {
+		return nil, err
+	}
+
+	return program, nil
+}
+
+// ListPrograms returns every program of the user with their days and rules, sorted by name.
+func (pg *PostgresProgramStore) ListPrograms(userID int) ([]Program, error) {
+	query := `
+	SELECT id, user_id, name, description, created_at
+	FROM programs
+	WHERE user_id = $1
+	ORDER BY name, id
+	`
+
+	rows, err := pg.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	programs := []Program{}
+	for rows.Next() {
+		var program Program
+		err = rows.Scan(&program.ID, &program.UserID, &program.Name, &program.Description, &program.CreatedAt)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		programs = append(programs, program)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range programs {
+		err = pg.loadProgramDetails(&programs[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return programs, nil
+}
+
+// DeleteProgram removes a program; days, rules and enrollments are removed by ON DELETE CASCADE
+// and workouts logged for it are kept, only unlinked.
+// Returns sql.ErrNoRows if the program does not exist.
+func (pg *PostgresProgramStore) DeleteProgram(id int64) error {
+	result, err := pg.db.Exec(`DELETE FROM programs WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetProgramOwner returns the user_id of the program.
+// Returns sql.ErrNoRows if the program does not exist.
+func (pg *PostgresProgramStore) GetProgramOwner(id int64) (int, error) {
+	var userID int
+	err := pg.db.QueryRow(`SELECT user_id FROM programs WHERE id = $1`, id).Scan(&userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// Enroll starts the program for the user. Any other active enrollment is ended,
+// since a user follows one program at a time.
+func (pg *PostgresProgramStore) Enroll(userID int, programID int64, startedAt time.Time) (*Enrollment, error) {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE program_enrollments SET active = FALSE WHERE user_id = $1 AND active`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	enrollment := &Enrollment{UserID: userID, ProgramID: int(programID), StartedAt: startedAt, Active: true}
+
+	query := `
+	INSERT INTO program_enrollments (user_id, program_id, started_at)
+	VALUES ($1, $2, $3)
+	RETURNING id, created_at
+	`
+
+	err = tx.QueryRow(query, userID, programID, startedAt).Scan(&enrollment.ID, &enrollment.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	return enrollment, nil
+}
+
+// GetNextWorkout works out the next session of the user's active program.
+// The next day is the first one, in week/day order, that has no workout logged for it since
+// enrolling. For every exercise of that day's template the progression rules are replayed over
+// the sessions of the exercise logged for the program, starting from the template's target weight.
+// Returns (nil, nil) if the user isn't enrolled in any program.
+func (pg *PostgresProgramStore) GetNextWorkout(userID int) (*NextWorkout, error) {
+	next := &NextWorkout{}
+
+	query := `
+	SELECT e.id, e.user_id, e.program_id, e.started_at, e.active, e.created_at, p.name
+	FROM program_enrollments e
+	INNER JOIN programs p ON p.id = e.program_id
+	WHERE e.user_id = $1 AND e.active
+	`
+
+	enrollment := &next.Enrollment
+	err := pg.db.QueryRow(query, userID).Scan(&enrollment.ID, &enrollment.UserID, &enrollment.ProgramID,
+		&enrollment.StartedAt, &enrollment.Active, &enrollment.CreatedAt, &next.ProgramName)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	program := &Program{ID: enrollment.ProgramID}
+	err = pg.loadProgramDetails(program)
+	if err != nil {
+		return nil, err
+	}
+
+	logged, err := pg.loadProgramWorkouts(userID, enrollment.StartedAt, program.Days)
+	if err != nil {
+		return nil, err
+	}
+
+	done := map[int]bool{}
+	for _, workout := range logged {
+		done[*workout.ProgramDayID] = true
+	}
+	for i := range program.Days {
+		if !done[program.Days[i].ID] {
+			next.Day = &program.Days[i]
+			break
+		}
+	}
+	if next.Day == nil {
+		next.Completed = true
+		return next, nil
+	}
+
+	// Every logged session is judged against the template of the day it was logged for
+	templateStore := &PostgresTemplateStore{db: pg.db}
+	templates := map[int]*Template{}
+	templateOfDay := map[int]*Template{}
+	for _, day := range program.Days {
+		template, ok := templates[day.TemplateID]
+		if !ok {
+			template, err = templateStore.GetTemplateByID(int64(day.TemplateID))
+			if err != nil {
+				return nil, err
+			}
+			if template == nil {
+				template = &Template{}
+			}
+			templates[day.TemplateID] = template
+		}
+		templateOfDay[day.ID] = template
+	}
+
+	template := templateOfDay[next.Day.ID]
+	next.Title = template.Name
+	if next.Day.Name != "" {
+		next.Title = next.Day.Name
+	}
+
+	next.Entries = []PrescribedEntry{}
+	for _, templateEntry := range template.Entries {
+		entry := PrescribedEntry{
+			ExerciseID:      templateEntry.ExerciseID,
+			ExerciseName:    templateEntry.ExerciseName,
+			Sets:            templateEntry.TargetSets,
+			Reps:            templateEntry.TargetReps,
+			DurationSeconds: templateEntry.TargetDurationSeconds,
+			Weight:          templateEntry.TargetWeight,
+			Notes:           templateEntry.Notes,
+			OrderIndex:      templateEntry.OrderIndex,
+		}
+
+		rule, ok := matchProgressionRule(program.Rules, templateEntry.ExerciseID, templateEntry.ExerciseName)
+		// Only rep based work progresses by weight
+		if ok && templateEntry.TargetReps != nil {
+			sessions := programSessions(logged, templateOfDay, templateEntry)
+			prescription := progression.Evaluate(templateEntry.TargetWeight, progression.Rule{
+				Increment:           rule.Increment,
+				DeloadPercent:       rule.DeloadPercent,
+				DeloadAfterFailures: rule.DeloadAfterFailures,
+			}, sessions)
+			entry.Weight = prescription.Weight
+			entry.Progression = &prescription
+		}
+
+		next.Entries = append(next.Entries, entry)
+	}
+
+	return next, nil
+}
+
+// loadProgramDetails fills in the days (in week/day order) and rules of the program.
+func (pg *PostgresProgramStore) loadProgramDetails(program *Program) error {
+	dayQuery := `
+	SELECT id, week_number, day_number, name, template_id
+	FROM program_days
+	WHERE program_id = $1
+	ORDER BY week_number, day_number
+	`
+
+	rows, err := pg.db.Query(dayQuery, program.ID)
+	if err != nil {
+		return err
+	}
+
+	program.Days = []ProgramDay{}
+	for rows.Next() {
+		var day ProgramDay
+		err = rows.Scan(&day.ID, &day.Week, &day.Day, &day.Name, &day.TemplateID)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		program.Days = append(program.Days, day)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	ruleQuery := `
+	SELECT id, exercise_id, exercise_name, increment, deload_percent, deload_after_failures
+	FROM progression_rules
+	WHERE program_id = $1
+	ORDER BY id
+	`
+
+	rows, err = pg.db.Query(ruleQuery, program.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	program.Rules = []ProgressionRule{}
+	for rows.Next() {
+		var rule ProgressionRule
+		err = rows.Scan(&rule.ID, &rule.ExerciseID, &rule.ExerciseName, &rule.Increment, &rule.DeloadPercent, &rule.DeloadAfterFailures)
+		if err != nil {
+			return err
+		}
+		program.Rules = append(program.Rules, rule)
+	}
+
+	return rows.Err()
+}
+
+// loadProgramWorkouts returns the user's workouts logged for any of the days since the given time,
+// with their entries and sets.
+func (pg *PostgresProgramStore) loadProgramWorkouts(userID int, since time.Time, days []ProgramDay) ([]*Workout, error) {
+	if len(days) == 0 {
+		return nil, nil
+	}
+
+	dayIDs := make([]int64, len(days))
+	for i, day := range days {
+		dayIDs[i] = int64(day.ID)
+	}
+
+	query := `
+	SELECT id, performed_at, program_day_id
+	FROM workouts
+	WHERE user_id = $1 AND program_day_id = ANY($2) AND performed_at >= $3
+	ORDER BY performed_at, id
+	`
+
+	rows, err := pg.db.Query(query, userID, dayIDs, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	workouts := []*Workout{}
+	for rows.Next() {
+		workout := &Workout{UserID: userID}
+		err = rows.Scan(&workout.ID, &workout.PerformedAt, &workout.ProgramDayID)
+		if err != nil {
+			return nil, err
+		}
+		workouts = append(workouts, workout)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	workoutStore := &PostgresWorkoutStore{db: pg.db}
+	err = workoutStore.loadEntries(workouts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return workouts, nil
+}
+
+// matchProgressionRule picks the rule for an exercise: a rule for that exercise wins
+// over the program wide default.
+func matchProgressionRule(rules []ProgressionRule, exerciseID *int, exerciseName string) (ProgressionRule, bool) {
+	var fallback *ProgressionRule
+	for i := range rules {
+		rule := &rules[i]
+		if rule.ExerciseID == nil && rule.ExerciseName == "" {
+			if fallback == nil {
+				fallback = rule
+			}
+			continue
+		}
+		if sameExercise(rule.ExerciseID, rule.ExerciseName, exerciseID, exerciseName) {
+			return *rule, true
+		}
+	}
+
+	if fallback != nil {
+		return *fallback, true
+	}
+	return ProgressionRule{}, false
+}
+
+// sameExercise compares by catalog id when both sides are linked, by name otherwise.
+func sameExercise(idA *int, nameA string, idB *int, nameB string) bool {
+	if idA != nil && idB != nil {
+		return *idA == *idB
+	}
+	return strings.EqualFold(strings.TrimSpace(nameA), strings.TrimSpace(nameB))
+}
+
+// programSessions turns the logged workouts into progression sessions of one exercise.
+// Targets come from the template of the day each workout was logged for; when that
+// template doesn't list the exercise the targets of the upcoming entry are used.
+func programSessions(logged []*Workout, templateOfDay map[int]*Template, target TemplateEntry) []progression.Session {
+	sessions := []progression.Session{}
+	for _, workout := range logged {
+		targetSets, targetReps := target.TargetSets, *target.TargetReps
+		if template := templateOfDay[*workout.ProgramDayID]; template != nil {
+			for _, templateEntry := range template.Entries {
+				if templateEntry.TargetReps != nil &&
+					sameExercise(templateEntry.ExerciseID, templateEntry.ExerciseName, target.ExerciseID, target.ExerciseName) {
+					targetSets, targetReps = templateEntry.TargetSets, *templateEntry.TargetReps
+					break
+				}
+			}
+		}
+
+		session := progression.Session{
+			WorkoutID:   workout.ID,
+			PerformedAt: workout.PerformedAt,
+			TargetSets:  targetSets,
+			TargetReps:  targetReps,
+		}
+		for _, entry := range workout.Entries {
+			if !sameExercise(entry.ExerciseID, entry.ExerciseName, target.ExerciseID, target.ExerciseName) {
+				continue
+			}
+			for _, set := range entry.Sets {
+				session.Sets = append(session.Sets, progression.Set{
+					Reps:      set.Reps,
+					Weight:    set.Weight,
+					Warmup:    set.SetType == SetTypeWarmup,
+					Completed: set.Completed,
+				})
+			}
+		}
+		if len(session.Sets) > 0 {
+			sessions = append(sessions, session)
+		}
+	}
+
+	return sessions
+}
+
+// checkProgramDay makes sure a workout is only linked to a day of its owner's programs.
+func checkProgramDay(q queryer, userID int, programDayID *int) error {
+	if programDayID == nil {
+		return nil
+	}
+
+	query := `
+	SELECT p.user_id
+	FROM program_days d
+	INNER JOIN programs p ON p.id = d.program_id
+	WHERE d.id = $1
+	`
+
+	var owner int
+	err := q.QueryRow(query, *programDayID).Scan(&owner)
+	if err == sql.ErrNoRows || (err == nil && owner != userID) {
+		return ErrUnknownProgramDay
+	}
+	return err
+}
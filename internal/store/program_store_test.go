@@ -0,0 +1,39 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchProgressionRule(t *testing.T) {
+	squatID := 3
+	benchID := 1
+	rules := []ProgressionRule{
+		{ID: 1, Increment: 2.5},
+		{ID: 2, ExerciseID: &squatID, ExerciseName: "Back Squat", Increment: 5},
+		{ID: 3, ExerciseName: "Farmer Carry", Increment: 10},
+	}
+
+	tests := []struct {
+		name       string
+		exerciseID *int
+		exercise   string
+		wantID     int
+	}{
+		{name: "exercise rule beats the default", exerciseID: &squatID, exercise: "Back Squat", wantID: 2},
+		{name: "unlinked exercise matches by name", exercise: "farmer carry", wantID: 3},
+		{name: "falls back to the default", exerciseID: &benchID, exercise: "Bench Press", wantID: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, ok := matchProgressionRule(rules, tt.exerciseID, tt.exercise)
+			assert.True(t, ok)
+			assert.Equal(t, tt.wantID, rule.ID)
+		})
+	}
+
+	_, ok := matchProgressionRule(rules[1:], &benchID, "Bench Press")
+	assert.False(t, ok)
+}
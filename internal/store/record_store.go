@@ -0,0 +1,512 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/e1rm"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/records"
+)
+
+// PersonalRecord is a best set (or session) for an exercise.
+// A new row is stored every time a record is broken, so older rows form the history
+// and the most recent row per exercise/type is the current record.
+type PersonalRecord struct {
+	ID           int       `json:"id"`
+	UserID       int       `json:"user_id"`
+	ExerciseID   *int      `json:"exercise_id"`
+	ExerciseName string    `json:"exercise_name"`
+	RecordType   string    `json:"record_type"`
+	Value        float64   `json:"value"`
+	Weight       *float64  `json:"weight"`
+	Reps         *int      `json:"reps"`
+	WorkoutID    int       `json:"workout_id"`
+	AchievedAt   time.Time `json:"achieved_at"`
+
+	// BodyweightMultiple is Value divided by the user's latest bodyweight, for the
+	// weight based records. Not stored, see ApplyBodyweight.
+	BodyweightMultiple *float64 `json:"bodyweight_multiple,omitempty"`
+}
+
+// RecordFilter narrows down GetRecordsForUser.
+// History includes every record that was later beaten, not just the current ones.
+type RecordFilter struct {
+	UserID     int
+	ExerciseID *int
+	RecordType string
+	History    bool
+}
+
+// PostgresRecordStore implements RecordStore using PostgreSQL as the backend.
+type PostgresRecordStore struct {
+	db *sql.DB
+}
+
+// NewPostgresRecordStore is a constructor for PostgresRecordStore.
+func NewPostgresRecordStore(db *sql.DB) *PostgresRecordStore {
+	return &PostgresRecordStore{db: db}
+}
+
+// RecordStore reads personal records. Records are written by the WorkoutStore,
+// inside the same transaction that saves the workout.
+type RecordStore interface {
+	GetRecordsForUser(filter RecordFilter) ([]PersonalRecord, error)
+	GetRecordsForWorkout(workoutID int64) ([]PersonalRecord, error)
+	RebuildUserRecords(userID int) error
+	GetE1RMHistory(filter E1RMHistoryFilter) ([]E1RMPoint, error)
+}
+
+// E1RMHistoryFilter selects the sets GetE1RMHistory turns into a time series.
+// Days are bucketed in Location (UTC if nil).
+type E1RMHistoryFilter struct {
+	UserID     int
+	ExerciseID int
+	From       *time.Time
+	To         *time.Time
+	Formula    e1rm.Formula
+	Location   *time.Location
+}
+
+// E1RMPoint is the best estimated 1RM of one day and the set that produced it.
+type E1RMPoint struct {
+	Date      string  `json:"date"`
+	E1RM      float64 `json:"e1rm"`
+	Weight    float64 `json:"weight"`
+	Reps      int     `json:"reps"`
+	WorkoutID int     `json:"workout_id"`
+
+	// BodyweightMultiple is E1RM divided by the user's latest bodyweight, see ApplyBodyweightToHistory.
+	BodyweightMultiple *float64 `json:"bodyweight_multiple,omitempty"`
+}
+
+// bodyweightMultiple returns value / bodyweight rounded to two decimals.
+func bodyweightMultiple(value, bodyweight float64) *float64 {
+	multiple := math.Round(value/bodyweight*100) / 100
+	return &multiple
+}
+
+// ApplyBodyweight sets the relative strength of the heaviest weight and best e1RM records,
+// e.g. 1.5 for a 120 kg squat at 80 kg bodyweight. Nothing is set without a bodyweight.
+func ApplyBodyweight(found []PersonalRecord, bodyweight *float64) {
+	if bodyweight == nil || *bodyweight <= 0 {
+		return
+	}
+	for i := range found {
+		if found[i].RecordType == records.TypeHeaviestWeight || found[i].RecordType == records.TypeBestE1RM {
+			found[i].BodyweightMultiple = bodyweightMultiple(found[i].Value, *bodyweight)
+		}
+	}
+}
+
+// ApplyBodyweightToHistory sets the relative strength of every e1RM point.
+// The latest bodyweight is used for every point, which is what "how strong am I now" compares against.
+func ApplyBodyweightToHistory(points []E1RMPoint, bodyweight *float64) {
+	if bodyweight == nil || *bodyweight <= 0 {
+		return
+	}
+	for i := range points {
+		points[i].BodyweightMultiple = bodyweightMultiple(points[i].E1RM, *bodyweight)
+	}
+}
+
+const recordColumns = `id, user_id, exercise_id, exercise_name, record_type, value, weight, reps, workout_id, achieved_at`
+
+// scanRecords reads every row selected with recordColumns.
+func scanRecords(rows *sql.Rows) ([]PersonalRecord, error) {
+	defer rows.Close()
+
+	found := []PersonalRecord{}
+	for rows.Next() {
+		var record PersonalRecord
+		err := rows.Scan(
+			&record.ID,
+			&record.UserID,
+			&record.ExerciseID,
+			&record.ExerciseName,
+			&record.RecordType,
+			&record.Value,
+			&record.Weight,
+			&record.Reps,
+			&record.WorkoutID,
+			&record.AchievedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, record)
+	}
+
+	return found, rows.Err()
+}
+
+// GetRecordsForUser returns the user's personal records, newest first.
+// Without History only the current record per exercise and type is returned
+// (per exercise, type and weight for max_reps_at_weight).
+func (pg *PostgresRecordStore) GetRecordsForUser(filter RecordFilter) ([]PersonalRecord, error) {
+	args := []interface{}{filter.UserID}
+	conditions := []string{"user_id = $1"}
+	if filter.ExerciseID != nil {
+		args = append(args, *filter.ExerciseID)
+		conditions = append(conditions, fmt.Sprintf("exercise_id = $%d", len(args)))
+	}
+	if filter.RecordType != "" {
+		args = append(args, filter.RecordType)
+		conditions = append(conditions, fmt.Sprintf("record_type = $%d", len(args)))
+	}
+	where := strings.Join(conditions, " AND ")
+
+	query := `SELECT ` + recordColumns + ` FROM personal_records WHERE ` + where + ` ORDER BY achieved_at DESC, id DESC`
+	if !filter.History {
+		// Records only ever improve, so the latest row of each group is the current best.
+		// Other record types store the lifted weight too, only max_reps_at_weight is kept per weight.
+		group := `COALESCE(exercise_id::TEXT, LOWER(exercise_name)), record_type,
+			CASE WHEN record_type = '` + records.TypeMaxRepsAtWeight + `' THEN weight END`
+		query = `SELECT ` + recordColumns + ` FROM (
+			SELECT DISTINCT ON (` + group + `) *
+			FROM personal_records
+			WHERE ` + where + `
+			ORDER BY ` + group + `, achieved_at DESC, id DESC
+		) current
+		ORDER BY exercise_name, record_type, weight DESC NULLS FIRST`
+	}
+
+	rows, err := pg.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanRecords(rows)
+}
+
+// GetRecordsForWorkout returns the records set by a workout, e.g. to celebrate them right after logging it.
+func (pg *PostgresRecordStore) GetRecordsForWorkout(workoutID int64) ([]PersonalRecord, error) {
+	query := `SELECT ` + recordColumns + `
+	FROM personal_records
+	WHERE workout_id = $1
+	ORDER BY exercise_name, record_type, weight DESC NULLS FIRST
+	`
+
+	rows, err := pg.db.Query(query, workoutID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanRecords(rows)
+}
+
+// exerciseKeys identifies a set of exercises of one user: catalog exercises by id,
+// and entries that aren't linked to the catalog by their lowercased name.
+type exerciseKeys struct {
+	ids   []int64
+	names []string
+}
+
+// add records the exercise of an entry, ignoring duplicates.
+func (k *exerciseKeys) add(exerciseID *int, exerciseName string) {
+	if exerciseID != nil {
+		for _, id := range k.ids {
+			if id == int64(*exerciseID) {
+				return
+			}
+		}
+		k.ids = append(k.ids, int64(*exerciseID))
+		return
+	}
+
+	name := strings.ToLower(exerciseName)
+	for _, n := range k.names {
+		if n == name {
+			return
+		}
+	}
+	k.names = append(k.names, name)
+}
+
+// empty reports whether there is nothing to recalculate.
+func (k *exerciseKeys) empty() bool {
+	return len(k.ids) == 0 && len(k.names) == 0
+}
+
+// entryExerciseKeys reads the exercises currently stored for a workout,
+// so records can be recalculated for exercises that are removed by an update or delete.
+func entryExerciseKeys(q queryer, workoutID int, keys *exerciseKeys) error {
+	rows, err := q.Query(`SELECT exercise_id, exercise_name FROM workout_entries WHERE workout_id = $1`, workoutID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var exerciseID *int
+		var exerciseName string
+		if err = rows.Scan(&exerciseID, &exerciseName); err != nil {
+			return err
+		}
+		keys.add(exerciseID, exerciseName)
+	}
+
+	return rows.Err()
+}
+
+// recalculateRecords rebuilds the record history of the given exercises for a user.
+// Every session of those exercises is replayed through the records engine, which keeps
+// history correct when a workout is back-logged, edited or deleted.
+func recalculateRecords(tx *sql.Tx, userID int, keys exerciseKeys) error {
+	if keys.empty() {
+		return nil
+	}
+
+	// e1RM records follow the formula the user picked
+	var formulaName string
+	err := tx.QueryRow(`SELECT e1rm_formula FROM users WHERE id = $1`, userID).Scan(&formulaName)
+	if err != nil {
+		return err
+	}
+	formula, err := e1rm.Parse(formulaName)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+	DELETE FROM personal_records
+	WHERE user_id = $1 AND (exercise_id = ANY($2) OR (exercise_id IS NULL AND LOWER(exercise_name) = ANY($3)))
+	`, userID, keys.ids, keys.names)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	SELECT w.id, w.performed_at, e.exercise_id, COALESCE(ex.name, e.exercise_name),
+		s.reps, s.duration_seconds, s.weight, s.set_type, s.completed
+	FROM workout_sets s
+	INNER JOIN workout_entries e ON e.id = s.workout_entry_id
+	INNER JOIN workouts w ON w.id = e.workout_id
+	LEFT JOIN exercises ex ON ex.id = e.exercise_id
+	WHERE w.user_id = $1 AND (e.exercise_id = ANY($2) OR (e.exercise_id IS NULL AND LOWER(e.exercise_name) = ANY($3)))
+	ORDER BY w.performed_at, w.id, e.order_index, s.set_number
+	`
+
+	rows, err := tx.Query(query, userID, keys.ids, keys.names)
+	if err != nil {
+		return err
+	}
+
+	// Group the sets by exercise, then by workout, keeping the chronological order
+	type exerciseHistory struct {
+		exerciseID *int
+		name       string
+		sessions   []records.Session
+	}
+	var order []string
+	histories := map[string]*exerciseHistory{}
+
+	for rows.Next() {
+		var workoutID int
+		var performedAt time.Time
+		var exerciseID *int
+		var exerciseName, setType string
+		var set records.Set
+		err = rows.Scan(&workoutID, &performedAt, &exerciseID, &exerciseName,
+			&set.Reps, &set.DurationSeconds, &set.Weight, &setType, &set.Completed)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		set.Warmup = setType == SetTypeWarmup
+
+		key := "name:" + strings.ToLower(exerciseName)
+		if exerciseID != nil {
+			key = fmt.Sprintf("id:%d", *exerciseID)
+		}
+		history, ok := histories[key]
+		if !ok {
+			history = &exerciseHistory{exerciseID: exerciseID, name: exerciseName}
+			histories[key] = history
+			order = append(order, key)
+		}
+
+		last := len(history.sessions) - 1
+		if last < 0 || history.sessions[last].WorkoutID != workoutID {
+			history.sessions = append(history.sessions, records.Session{WorkoutID: workoutID, PerformedAt: performedAt})
+			last++
+		}
+		history.sessions[last].Sets = append(history.sessions[last].Sets, set)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	insertQuery := `
+	INSERT INTO personal_records (user_id, exercise_id, exercise_name, record_type, value, weight, reps, workout_id, achieved_at)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	for _, key := range order {
+		history := histories[key]
+		for _, record := range records.Detect(history.sessions, formula) {
+			_, err = tx.Exec(insertQuery, userID, history.exerciseID, history.name, record.Type,
+				record.Value, record.Weight, record.Reps, record.WorkoutID, record.AchievedAt)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// BackfillRecords computes the record history of users who logged workouts before
+// personal records existed. Users that already have records are skipped, so it is cheap
+// to run on every startup. Returns how many users were backfilled.
+func (pg *PostgresRecordStore) BackfillRecords() (int, error) {
+	query := `
+	SELECT DISTINCT w.user_id
+	FROM workouts w
+	INNER JOIN workout_entries e ON e.workout_id = w.id
+	WHERE NOT EXISTS (SELECT 1 FROM personal_records pr WHERE pr.user_id = w.user_id)
+	`
+
+	rows, err := pg.db.Query(query)
+	if err != nil {
+		return 0, err
+	}
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err = rows.Scan(&userID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for i, userID := range userIDs {
+		err = pg.RebuildUserRecords(userID)
+		if err != nil {
+			return i, err
+		}
+	}
+
+	return len(userIDs), nil
+}
+
+// RebuildUserRecords recalculates the records of every exercise the user has logged.
+func (pg *PostgresRecordStore) RebuildUserRecords(userID int) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = rebuildUserRecords(tx, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// rebuildUserRecords recalculates the records of every exercise the user has logged inside
+// the transaction, e.g. the one that switches them to another e1RM formula.
+func rebuildUserRecords(tx *sql.Tx, userID int) error {
+	rows, err := tx.Query(`
+	SELECT DISTINCT e.exercise_id, e.exercise_name
+	FROM workout_entries e
+	INNER JOIN workouts w ON w.id = e.workout_id
+	WHERE w.user_id = $1
+	`, userID)
+	if err != nil {
+		return err
+	}
+
+	keys := exerciseKeys{}
+	for rows.Next() {
+		var exerciseID *int
+		var exerciseName string
+		if err = rows.Scan(&exerciseID, &exerciseName); err != nil {
+			rows.Close()
+			return err
+		}
+		keys.add(exerciseID, exerciseName)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	return recalculateRecords(tx, userID, keys)
+}
+
+// GetE1RMHistory returns the best estimated 1RM per day for one exercise, oldest first.
+// Only completed, non-warmup sets with weight and at most e1rm.MaxReps reps are considered.
+func (pg *PostgresRecordStore) GetE1RMHistory(filter E1RMHistoryFilter) ([]E1RMPoint, error) {
+	location := filter.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	args := []interface{}{filter.UserID, filter.ExerciseID, e1rm.MaxReps}
+	conditions := []string{
+		"w.user_id = $1",
+		"e.exercise_id = $2",
+		"s.reps BETWEEN 1 AND $3",
+		"s.weight > 0",
+		"s.completed",
+		"s.set_type <> 'warmup'",
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conditions = append(conditions, fmt.Sprintf("w.performed_at >= $%d", len(args)))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conditions = append(conditions, fmt.Sprintf("w.performed_at < $%d", len(args)))
+	}
+
+	query := `
+	SELECT w.id, w.performed_at, s.reps, s.weight
+	FROM workout_sets s
+	INNER JOIN workout_entries e ON e.id = s.workout_entry_id
+	INNER JOIN workouts w ON w.id = e.workout_id
+	WHERE ` + strings.Join(conditions, " AND ") + `
+	ORDER BY w.performed_at, w.id
+	`
+
+	rows, err := pg.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// The formula is applied in Go so every formula shares the same query
+	points := []E1RMPoint{}
+	for rows.Next() {
+		var workoutID, reps int
+		var performedAt time.Time
+		var weight float64
+		if err = rows.Scan(&workoutID, &performedAt, &reps, &weight); err != nil {
+			return nil, err
+		}
+
+		estimate := filter.Formula.Estimate(weight, reps)
+		date := performedAt.In(location).Format(time.DateOnly)
+
+		last := len(points) - 1
+		if last >= 0 && points[last].Date == date {
+			if estimate > points[last].E1RM {
+				points[last] = E1RMPoint{Date: date, E1RM: estimate, Weight: weight, Reps: reps, WorkoutID: workoutID}
+			}
+			continue
+		}
+		points = append(points, E1RMPoint{Date: date, E1RM: estimate, Weight: weight, Reps: reps, WorkoutID: workoutID})
+	}
+
+	return points, rows.Err()
+}
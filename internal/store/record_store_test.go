@@ -0,0 +1,63 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/records"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetRecordsForUserCurrent(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	workoutStore := NewPostgresWorkoutStore(db)
+	recordStore := NewPostgresRecordStore(db)
+	userStore := NewPostgresUserStore(db)
+
+	user := &User{Username: "lifter", Email: "lifter@example.com"}
+	require.NoError(t, user.PasswordHash.Set("securepassword"))
+	require.NoError(t, userStore.CreateUser(user))
+
+	// Three sessions, each beating the heaviest weight and e1RM of the one before
+	start := time.Date(2026, time.March, 2, 18, 0, 0, 0, time.UTC)
+	for i, weight := range []float64{100, 105, 110} {
+		_, err := workoutStore.CreateWorkout(&Workout{
+			UserID:      user.ID,
+			Title:       "bench day",
+			PerformedAt: start.AddDate(0, 0, 7*i),
+			Entries: []WorkoutEntry{
+				{
+					ExerciseName: "Bench press",
+					Sets: []WorkoutSet{
+						{SetNumber: 1, Reps: IntPtr(5), Weight: FloatPtr(weight), SetType: SetTypeWorking, Completed: true},
+					},
+					OrderIndex: 1,
+				},
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	found, err := recordStore.GetRecordsForUser(RecordFilter{UserID: user.ID})
+	require.NoError(t, err)
+
+	byType := map[string][]PersonalRecord{}
+	for _, record := range found {
+		byType[record.RecordType] = append(byType[record.RecordType], record)
+	}
+
+	require.Len(t, byType[records.TypeHeaviestWeight], 1)
+	assert.Equal(t, 110.0, byType[records.TypeHeaviestWeight][0].Value)
+	require.Len(t, byType[records.TypeBestE1RM], 1)
+	assert.Equal(t, 110.0, *byType[records.TypeBestE1RM][0].Weight)
+	require.Len(t, byType[records.TypeMaxSessionVolume], 1)
+	// Reps at weight stay one record per weight
+	assert.Len(t, byType[records.TypeMaxRepsAtWeight], 3)
+
+	history, err := recordStore.GetRecordsForUser(RecordFilter{UserID: user.ID, RecordType: records.TypeHeaviestWeight, History: true})
+	require.NoError(t, err)
+	assert.Len(t, history, 3)
+}
@@ -0,0 +1,296 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Statuses of a scheduled workout.
+const (
+	ScheduleStatusPlanned   = "planned"
+	ScheduleStatusCompleted = "completed"
+	ScheduleStatusSkipped   = "skipped"
+)
+
+// ScheduledWorkout is a session planned on the calendar. It can point to the template
+// or program day it runs; when the title is left empty it is taken from either of them.
+// WorkoutID is set once the session was logged.
+type ScheduledWorkout struct {
+	ID              int       `json:"id"`
+	UserID          int       `json:"user_id"`
+	TemplateID      *int      `json:"template_id"`
+	ProgramDayID    *int      `json:"program_day_id"`
+	Title           string    `json:"title"`
+	Notes           string    `json:"notes"`
+	PlannedAt       time.Time `json:"planned_at"`
+	DurationMinutes int       `json:"duration_minutes"`
+	Status          string    `json:"status"`
+	WorkoutID       *int      `json:"workout_id"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// ScheduleFilter selects the scheduled workouts returned by ListScheduledWorkouts.
+type ScheduleFilter struct {
+	UserID int
+	From   *time.Time
+	To     *time.Time
+	Status string
+}
+
+// PostgresScheduleStore implements ScheduleStore using PostgreSQL as the backend.
+type PostgresScheduleStore struct {
+	db *sql.DB
+}
+
+// NewPostgresScheduleStore is a constructor for PostgresScheduleStore.
+func NewPostgresScheduleStore(db *sql.DB) *PostgresScheduleStore {
+	return &PostgresScheduleStore{db: db}
+}
+
+// ScheduleStore defines how the workout calendar is persisted.
+type ScheduleStore interface {
+	CreateScheduledWorkout(*ScheduledWorkout) error
+	GetScheduledWorkoutByID(id int64) (*ScheduledWorkout, error)
+	ListScheduledWorkouts(filter ScheduleFilter) ([]ScheduledWorkout, error)
+	UpdateScheduledWorkout(*ScheduledWorkout) error
+	DeleteScheduledWorkout(id int64) error
+	GetScheduledWorkoutOwner(id int64) (int, error)
+}
+
+// CreateScheduledWorkout plans a session.
+// Returns ErrUnknownTemplate or ErrUnknownProgramDay if they don't belong to the user.
+func (pg *PostgresScheduleStore) CreateScheduledWorkout(scheduled *ScheduledWorkout) error {
+	err := pg.resolveSchedule(scheduled)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO scheduled_workouts (user_id, template_id, program_day_id, title, notes, planned_at, duration_minutes, status)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING id, created_at, updated_at
+	`
+
+	return pg.db.QueryRow(query, scheduled.UserID, scheduled.TemplateID, scheduled.ProgramDayID, scheduled.Title,
+		scheduled.Notes, scheduled.PlannedAt, scheduled.DurationMinutes, scheduled.Status).
+		Scan(&scheduled.ID, &scheduled.CreatedAt, &scheduled.UpdatedAt)
+}
+
+// GetScheduledWorkoutByID returns (nil, nil) if the scheduled workout doesn't exist.
+func (pg *PostgresScheduleStore) GetScheduledWorkoutByID(id int64) (*ScheduledWorkout, error) {
+	query := `
+	SELECT id, user_id, template_id, program_day_id, title, notes, planned_at, duration_minutes, status, workout_id, created_at, updated_at
+	FROM scheduled_workouts
+	WHERE id = $1
+	`
+
+	scheduled := &ScheduledWorkout{}
+	err := pg.db.QueryRow(query, id).Scan(&scheduled.ID, &scheduled.UserID, &scheduled.TemplateID, &scheduled.ProgramDayID,
+		&scheduled.Title, &scheduled.Notes, &scheduled.PlannedAt, &scheduled.DurationMinutes, &scheduled.Status,
+		&scheduled.WorkoutID, &scheduled.CreatedAt, &scheduled.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return scheduled, nil
+}
+
+// ListScheduledWorkouts returns the user's scheduled workouts in chronological order.
+func (pg *PostgresScheduleStore) ListScheduledWorkouts(filter ScheduleFilter) ([]ScheduledWorkout, error) {
+	args := []interface{}{filter.UserID}
+	conditions := []string{"user_id = $1"}
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.From != nil {
+		conditions = append(conditions, "planned_at >= "+addArg(*filter.From))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "planned_at < "+addArg(*filter.To))
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = "+addArg(filter.Status))
+	}
+
+	query := `
+	SELECT id, user_id, template_id, program_day_id, title, notes, planned_at, duration_minutes, status, workout_id, created_at, updated_at
+	FROM scheduled_workouts
+	WHERE ` + strings.Join(conditions, " AND ") + `
+	ORDER BY planned_at, id
+	`
+
+	rows, err := pg.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scheduledWorkouts := []ScheduledWorkout{}
+	for rows.Next() {
+		var scheduled ScheduledWorkout
+		err = rows.Scan(&scheduled.ID, &scheduled.UserID, &scheduled.TemplateID, &scheduled.ProgramDayID,
+			&scheduled.Title, &scheduled.Notes, &scheduled.PlannedAt, &scheduled.DurationMinutes, &scheduled.Status,
+			&scheduled.WorkoutID, &scheduled.CreatedAt, &scheduled.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		scheduledWorkouts = append(scheduledWorkouts, scheduled)
+	}
+
+	return scheduledWorkouts, rows.Err()
+}
+
+// UpdateScheduledWorkout replaces the editable fields of a scheduled workout.
+// Moving it back to planned unlinks the logged workout.
+// Returns sql.ErrNoRows if it does not exist.
+func (pg *PostgresScheduleStore) UpdateScheduledWorkout(scheduled *ScheduledWorkout) error {
+	err := pg.resolveSchedule(scheduled)
+	if err != nil {
+		return err
+	}
+	if scheduled.Status == ScheduleStatusPlanned {
+		scheduled.WorkoutID = nil
+	}
+
+	query := `
+	UPDATE scheduled_workouts
+	SET template_id = $1, program_day_id = $2, title = $3, notes = $4, planned_at = $5, duration_minutes = $6,
+		status = $7, workout_id = $8, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $9
+	RETURNING updated_at
+	`
+
+	return pg.db.QueryRow(query, scheduled.TemplateID, scheduled.ProgramDayID, scheduled.Title, scheduled.Notes,
+		scheduled.PlannedAt, scheduled.DurationMinutes, scheduled.Status, scheduled.WorkoutID, scheduled.ID).
+		Scan(&scheduled.UpdatedAt)
+}
+
+// DeleteScheduledWorkout removes a scheduled workout; a linked workout is kept.
+// Returns sql.ErrNoRows if it does not exist.
+func (pg *PostgresScheduleStore) DeleteScheduledWorkout(id int64) error {
+	result, err := pg.db.Exec(`DELETE FROM scheduled_workouts WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetScheduledWorkoutOwner returns the user_id of the scheduled workout.
+// Returns sql.ErrNoRows if it does not exist.
+func (pg *PostgresScheduleStore) GetScheduledWorkoutOwner(id int64) (int, error) {
+	var userID int
+	err := pg.db.QueryRow(`SELECT user_id FROM scheduled_workouts WHERE id = $1`, id).Scan(&userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// resolveSchedule checks that the template and program day belong to the user and
+// fills in the defaults: the title of the program day or template, 60 minutes, planned.
+func (pg *PostgresScheduleStore) resolveSchedule(scheduled *ScheduledWorkout) error {
+	if scheduled.Status == "" {
+		scheduled.Status = ScheduleStatusPlanned
+	}
+	if scheduled.DurationMinutes == 0 {
+		scheduled.DurationMinutes = 60
+	}
+
+	var templateName, dayName string
+	if scheduled.TemplateID != nil {
+		query := `SELECT name FROM workout_templates WHERE id = $1 AND user_id = $2`
+		err := pg.db.QueryRow(query, *scheduled.TemplateID, scheduled.UserID).Scan(&templateName)
+		if err == sql.ErrNoRows {
+			return ErrUnknownTemplate
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if scheduled.ProgramDayID != nil {
+		err := checkProgramDay(pg.db, scheduled.UserID, scheduled.ProgramDayID)
+		if err != nil {
+			return err
+		}
+
+		query := `
+		SELECT COALESCE(NULLIF(d.name, ''), p.name || ' - week ' || d.week_number || ' day ' || d.day_number)
+		FROM program_days d
+		INNER JOIN programs p ON p.id = d.program_id
+		WHERE d.id = $1
+		`
+		err = pg.db.QueryRow(query, *scheduled.ProgramDayID).Scan(&dayName)
+		if err != nil {
+			return err
+		}
+	}
+
+	scheduled.Title = strings.TrimSpace(scheduled.Title)
+	if scheduled.Title == "" {
+		scheduled.Title = dayName
+	}
+	if scheduled.Title == "" {
+		scheduled.Title = templateName
+	}
+	if scheduled.Title == "" {
+		return errors.New("title is required without a template or program day")
+	}
+
+	return nil
+}
+
+// linkScheduledWorkout completes the planned session that a newly logged workout fulfils.
+// Only sessions planned within a day of the workout are considered. A session planned for the same program day wins, otherwise the titles have to match
+// (instantiating a template copies its name into the workout title); the closest planned time breaks ties.
+func linkScheduledWorkout(tx *sql.Tx, workout *Workout) error {
+	query := `
+	UPDATE scheduled_workouts
+	SET status = 'completed', workout_id = $1, updated_at = CURRENT_TIMESTAMP
+	WHERE id = (
+		SELECT s.id
+		FROM scheduled_workouts s
+		WHERE s.user_id = $2 AND s.status = 'planned'
+			AND s.planned_at BETWEEN $3::TIMESTAMPTZ - INTERVAL '24 hours' AND $3::TIMESTAMPTZ + INTERVAL '24 hours'
+			AND (s.program_day_id = $4 OR LOWER(s.title) = LOWER($5))
+		ORDER BY (s.program_day_id IS NOT DISTINCT FROM $4) DESC, ABS(EXTRACT(EPOCH FROM s.planned_at - $3::TIMESTAMPTZ)), s.id
+		LIMIT 1
+	)
+	`
+
+	_, err := tx.Exec(query, workout.ID, workout.UserID, workout.PerformedAt, workout.ProgramDayID,
+		strings.TrimSpace(workout.Title))
+	return err
+}
+
+// unlinkScheduledWorkouts puts the sessions completed by a workout back to planned,
+// used before the workout is deleted.
+func unlinkScheduledWorkouts(tx *sql.Tx, workoutID int64) error {
+	query := `
+	UPDATE scheduled_workouts
+	SET status = 'planned', workout_id = NULL, updated_at = CURRENT_TIMESTAMP
+	WHERE workout_id = $1
+	`
+
+	_, err := tx.Exec(query, workoutID)
+	return err
+}
@@ -0,0 +1,468 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/timer"
+)
+
+// Statuses of a live session. A session is "finishing" while its workout is being created,
+// which keeps two finish requests from saving the same workout twice.
+const (
+	SessionStatusActive    = "active"
+	SessionStatusFinishing = "finishing"
+	SessionStatusFinished  = "finished"
+	SessionStatusCancelled = "cancelled"
+)
+
+// ErrSessionNotActive is returned when sets are logged to, or a finish is attempted on,
+// a session that is no longer in progress.
+var ErrSessionNotActive = errors.New("session is not active")
+
+// LiveSession is a workout in progress, logged set by set from the gym floor.
+// Finishing it turns it into a regular Workout.
+type LiveSession struct {
+	ID             int            `json:"id"`
+	UserID         int            `json:"user_id"`
+	Title          string         `json:"title"`
+	Status         string         `json:"status"`
+	StartedAt      time.Time      `json:"started_at"`
+	EndedAt        *time.Time     `json:"ended_at"`
+	WorkoutID      *int           `json:"workout_id"`
+	Sets           []SessionSet   `json:"sets"`
+	Timers         []SessionTimer `json:"timers"`
+	CreatedAt      time.Time      `json:"created_at"`
+	WatchTokenHash []byte         `json:"-"`
+}
+
+// SessionSet is one set logged during a live session.
+// SetNumber counts per exercise and is assigned by the store.
+// PrescribedRestSeconds is the rest the athlete planned after this set; RestSeconds is the rest
+// they actually took, filled in by the store when the next set of the session is logged.
+type SessionSet struct {
+	ID                    int       `json:"id"`
+	ExerciseID            *int      `json:"exercise_id"`
+	ExerciseName          string    `json:"exercise_name"`
+	SetNumber             int       `json:"set_number"`
+	Reps                  *int      `json:"reps"`
+	DurationSeconds       *int      `json:"duration_seconds"`
+	Weight                *float64  `json:"weight"`
+	RPE                   *float64  `json:"rpe"`
+	SetType               string    `json:"set_type"`
+	Completed             bool      `json:"completed"`
+	RestSeconds           *int      `json:"rest_seconds"`
+	PrescribedRestSeconds *int      `json:"prescribed_rest_seconds"`
+	LoggedAt              time.Time `json:"logged_at"`
+}
+
+// SessionTimer is an interval timer that ran during a live session, with what actually happened.
+// The timer belongs to an exercise so its timings end up on that exercise's workout entry.
+type SessionTimer struct {
+	ID           int       `json:"id"`
+	ExerciseID   *int      `json:"exercise_id"`
+	ExerciseName string    `json:"exercise_name"`
+	Timings      timer.Log `json:"timings"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ToWorkout builds the workout a finished session becomes. Sets are grouped into one
+// entry per exercise, in the order the exercises were first logged.
+// Timer timings are attached to the entry of their exercise; an exercise that was only
+// timed gets one duration set per work phase, so the work shows up like any other set.
+func (s *LiveSession) ToWorkout(endedAt time.Time) *Workout {
+	startedAt := s.StartedAt
+	workout := &Workout{
+		UserID:      s.UserID,
+		Title:       s.Title,
+		PerformedAt: startedAt,
+		StartedAt:   &startedAt,
+		EndedAt:     &endedAt,
+		Entries:     []WorkoutEntry{},
+	}
+
+	entryIndex := map[string]int{}
+	entryFor := func(exerciseID *int, exerciseName string) *WorkoutEntry {
+		key := entryKey(exerciseID, exerciseName)
+		i, ok := entryIndex[key]
+		if !ok {
+			i = len(workout.Entries)
+			entryIndex[key] = i
+			workout.Entries = append(workout.Entries, WorkoutEntry{
+				ExerciseID:   exerciseID,
+				ExerciseName: exerciseName,
+				OrderIndex:   i + 1,
+			})
+		}
+		return &workout.Entries[i]
+	}
+
+	for _, set := range s.Sets {
+		entry := entryFor(set.ExerciseID, set.ExerciseName)
+		entry.Sets = append(entry.Sets, WorkoutSet{
+			SetNumber:             len(entry.Sets) + 1,
+			Reps:                  set.Reps,
+			DurationSeconds:       set.DurationSeconds,
+			Weight:                set.Weight,
+			RPE:                   set.RPE,
+			SetType:               set.SetType,
+			Completed:             set.Completed,
+			RestSeconds:           set.RestSeconds,
+			PrescribedRestSeconds: set.PrescribedRestSeconds,
+		})
+	}
+
+	for _, sessionTimer := range s.Timers {
+		entry := entryFor(sessionTimer.ExerciseID, sessionTimer.ExerciseName)
+		timedOnly := len(entry.Intervals) > 0 || len(entry.Sets) == 0
+		entry.Intervals = append(entry.Intervals, sessionTimer.Timings)
+		if !timedOnly {
+			continue
+		}
+
+		for _, phase := range sessionTimer.Timings.Phases {
+			if phase.Kind != timer.PhaseWork {
+				continue
+			}
+			seconds := int(math.Round(phase.ActualSeconds))
+			if seconds < 1 {
+				continue
+			}
+			entry.Sets = append(entry.Sets, WorkoutSet{
+				SetNumber:       len(entry.Sets) + 1,
+				DurationSeconds: &seconds,
+				SetType:         SetTypeWorking,
+				Completed:       phase.ActualSeconds >= float64(phase.PlannedSeconds),
+			})
+		}
+	}
+
+	return workout
+}
+
+// PostgresSessionStore implements SessionStore using PostgreSQL as the backend.
+// Sessions are persisted as they are logged so a crashed phone or server loses nothing.
+type PostgresSessionStore struct {
+	db *sql.DB
+}
+
+// NewPostgresSessionStore is a constructor for PostgresSessionStore.
+func NewPostgresSessionStore(db *sql.DB) *PostgresSessionStore {
+	return &PostgresSessionStore{db: db}
+}
+
+// SessionStore defines how live sessions are persisted.
+type SessionStore interface {
+	CreateSession(*LiveSession) error
+	GetSessionByID(id int64) (*LiveSession, error)
+	AddSessionSet(sessionID int64, userID int, set *SessionSet) error
+	AddSessionTimer(sessionID int64, userID int, sessionTimer *SessionTimer) error
+	SetSessionStatus(id int64, from, to string) error
+	CompleteSession(id int64, workoutID int, endedAt time.Time) error
+	CancelSession(id int64) error
+}
+
+// CreateSession starts a new session; the caller sets WatchTokenHash.
+func (pg *PostgresSessionStore) CreateSession(session *LiveSession) error {
+	query := `
+	INSERT INTO live_sessions (user_id, title, watch_token_hash, started_at)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, status, created_at
+	`
+
+	err := pg.db.QueryRow(query, session.UserID, session.Title, session.WatchTokenHash, session.StartedAt).
+		Scan(&session.ID, &session.Status, &session.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	session.Sets = []SessionSet{}
+	session.Timers = []SessionTimer{}
+	return nil
+}
+
+// GetSessionByID fetches a session with its sets in the order they were logged.
+// Returns (nil, nil) if it doesn't exist.
+func (pg *PostgresSessionStore) GetSessionByID(id int64) (*LiveSession, error) {
+	session := &LiveSession{}
+
+	query := `
+	SELECT id, user_id, title, status, started_at, ended_at, workout_id, created_at, watch_token_hash
+	FROM live_sessions
+	WHERE id = $1
+	`
+
+	err := pg.db.QueryRow(query, id).Scan(&session.ID, &session.UserID, &session.Title, &session.Status,
+		&session.StartedAt, &session.EndedAt, &session.WorkoutID, &session.CreatedAt, &session.WatchTokenHash)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	setQuery := `
+	SELECT id, exercise_id, exercise_name, set_number, reps, duration_seconds, weight, rpe, set_type, completed,
+		rest_seconds, prescribed_rest_seconds, logged_at
+	FROM live_session_sets
+	WHERE session_id = $1
+	ORDER BY id
+	`
+
+	rows, err := pg.db.Query(setQuery, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	session.Sets = []SessionSet{}
+	for rows.Next() {
+		var set SessionSet
+		err = rows.Scan(&set.ID, &set.ExerciseID, &set.ExerciseName, &set.SetNumber, &set.Reps, &set.DurationSeconds,
+			&set.Weight, &set.RPE, &set.SetType, &set.Completed, &set.RestSeconds, &set.PrescribedRestSeconds, &set.LoggedAt)
+		if err != nil {
+			return nil, err
+		}
+		session.Sets = append(session.Sets, set)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	timerQuery := `
+	SELECT id, exercise_id, exercise_name, timings, created_at
+	FROM live_session_timers
+	WHERE session_id = $1
+	ORDER BY id
+	`
+
+	timerRows, err := pg.db.Query(timerQuery, id)
+	if err != nil {
+		return nil, err
+	}
+	defer timerRows.Close()
+
+	session.Timers = []SessionTimer{}
+	for timerRows.Next() {
+		var sessionTimer SessionTimer
+		var timings []byte
+		err = timerRows.Scan(&sessionTimer.ID, &sessionTimer.ExerciseID, &sessionTimer.ExerciseName, &timings, &sessionTimer.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(timings, &sessionTimer.Timings); err != nil {
+			return nil, err
+		}
+		session.Timers = append(session.Timers, sessionTimer)
+	}
+
+	return session, timerRows.Err()
+}
+
+// AddSessionSet links the set to the exercise catalog, numbers it and appends it to the session.
+// The session row is locked so concurrent sets of the same exercise get distinct numbers.
+// The rest actually taken after the previous set of the session is recorded on that set:
+// the time between both were logged, minus this set's duration when it is a timed set.
+// Returns ErrSessionNotActive if the session is no longer in progress.
+func (pg *PostgresSessionStore) AddSessionSet(sessionID int64, userID int, set *SessionSet) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow(`SELECT status FROM live_sessions WHERE id = $1 FOR UPDATE`, sessionID).Scan(&status)
+	if err != nil {
+		return err
+	}
+	if status != SessionStatusActive {
+		return ErrSessionNotActive
+	}
+
+	linker, err := newExerciseLinker(tx, userID)
+	if err != nil {
+		return err
+	}
+	set.ExerciseID, set.ExerciseName, err = linker.link(set.ExerciseID, set.ExerciseName)
+	if err != nil {
+		return err
+	}
+	if set.SetType == "" {
+		set.SetType = SetTypeWorking
+	}
+
+	countQuery := `
+	SELECT COUNT(*)
+	FROM live_session_sets
+	WHERE session_id = $1 AND (exercise_id = $2 OR ($2 IS NULL AND exercise_id IS NULL AND LOWER(exercise_name) = LOWER($3)))
+	`
+
+	err = tx.QueryRow(countQuery, sessionID, set.ExerciseID, set.ExerciseName).Scan(&set.SetNumber)
+	if err != nil {
+		return err
+	}
+	set.SetNumber++
+
+	var previousID int
+	var previousLoggedAt time.Time
+	previousQuery := `SELECT id, logged_at FROM live_session_sets WHERE session_id = $1 ORDER BY id DESC LIMIT 1`
+	err = tx.QueryRow(previousQuery, sessionID).Scan(&previousID, &previousLoggedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	query := `
+	INSERT INTO live_session_sets (session_id, exercise_id, exercise_name, set_number, reps, duration_seconds,
+		weight, rpe, set_type, completed, prescribed_rest_seconds)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	RETURNING id, logged_at
+	`
+
+	err = tx.QueryRow(query, sessionID, set.ExerciseID, set.ExerciseName, set.SetNumber, set.Reps, set.DurationSeconds,
+		set.Weight, set.RPE, set.SetType, set.Completed, set.PrescribedRestSeconds).Scan(&set.ID, &set.LoggedAt)
+	if err != nil {
+		return err
+	}
+
+	if previousID != 0 {
+		rest := actualRest(previousLoggedAt, set.LoggedAt, set.DurationSeconds)
+		_, err = tx.Exec(`UPDATE live_session_sets SET rest_seconds = $1 WHERE id = $2`, rest, previousID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// actualRest is the rest between a set logged at previous and the next one logged at next.
+// A timed set is logged when it ends, so its duration is not rest.
+func actualRest(previous, next time.Time, durationSeconds *int) int {
+	rest := next.Sub(previous)
+	if durationSeconds != nil {
+		rest -= time.Duration(*durationSeconds) * time.Second
+	}
+	if rest < 0 {
+		return 0
+	}
+	return int(rest.Round(time.Second) / time.Second)
+}
+
+// AddSessionTimer links the timer to the exercise catalog and saves it with its timings.
+// Timers are saved when they end, which can be while the session is being finished,
+// so unlike sets they are accepted as long as the session wasn't finished or cancelled.
+func (pg *PostgresSessionStore) AddSessionTimer(sessionID int64, userID int, sessionTimer *SessionTimer) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow(`SELECT status FROM live_sessions WHERE id = $1 FOR UPDATE`, sessionID).Scan(&status)
+	if err != nil {
+		return err
+	}
+	if status != SessionStatusActive && status != SessionStatusFinishing {
+		return ErrSessionNotActive
+	}
+
+	linker, err := newExerciseLinker(tx, userID)
+	if err != nil {
+		return err
+	}
+	sessionTimer.ExerciseID, sessionTimer.ExerciseName, err = linker.link(sessionTimer.ExerciseID, sessionTimer.ExerciseName)
+	if err != nil {
+		return err
+	}
+
+	timings, err := json.Marshal(sessionTimer.Timings)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO live_session_timers (session_id, exercise_id, exercise_name, timings)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at
+	`
+
+	err = tx.QueryRow(query, sessionID, sessionTimer.ExerciseID, sessionTimer.ExerciseName, timings).
+		Scan(&sessionTimer.ID, &sessionTimer.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetSessionStatus moves the session from one status to another.
+// Returns ErrSessionNotActive if it wasn't in the expected status.
+func (pg *PostgresSessionStore) SetSessionStatus(id int64, from, to string) error {
+	result, err := pg.db.Exec(`UPDATE live_sessions SET status = $1 WHERE id = $2 AND status = $3`, to, id, from)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrSessionNotActive
+	}
+
+	return nil
+}
+
+// CompleteSession marks a finishing session as finished and links the workout it became.
+func (pg *PostgresSessionStore) CompleteSession(id int64, workoutID int, endedAt time.Time) error {
+	query := `
+	UPDATE live_sessions
+	SET status = 'finished', workout_id = $1, ended_at = $2
+	WHERE id = $3 AND status = 'finishing'
+	`
+
+	result, err := pg.db.Exec(query, workoutID, endedAt, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrSessionNotActive
+	}
+
+	return nil
+}
+
+// CancelSession abandons an active session; its sets are kept but never become a workout.
+func (pg *PostgresSessionStore) CancelSession(id int64) error {
+	query := `
+	UPDATE live_sessions
+	SET status = 'cancelled', ended_at = CURRENT_TIMESTAMP
+	WHERE id = $1 AND status = 'active'
+	`
+
+	result, err := pg.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrSessionNotActive
+	}
+
+	return nil
+}
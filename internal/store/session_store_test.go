@@ -0,0 +1,118 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/timer"
+)
+
+func TestLiveSessionToWorkout(t *testing.T) {
+	benchID := 1
+	start := time.Date(2026, 4, 2, 17, 0, 0, 0, time.UTC)
+	end := start.Add(62 * time.Minute)
+
+	session := &LiveSession{
+		UserID:    5,
+		Title:     "Upper",
+		StartedAt: start,
+		Sets: []SessionSet{
+			{ExerciseID: &benchID, ExerciseName: "Bench Press", Reps: IntPtr(5), Weight: FloatPtr(100), SetType: SetTypeWorking, Completed: true,
+				RestSeconds: IntPtr(150), PrescribedRestSeconds: IntPtr(120)},
+			{ExerciseName: "Plank", DurationSeconds: IntPtr(60), SetType: SetTypeWorking, Completed: true},
+			{ExerciseID: &benchID, ExerciseName: "Bench Press", Reps: IntPtr(4), Weight: FloatPtr(100), SetType: SetTypeWorking, Completed: true},
+			{ExerciseName: "plank ", DurationSeconds: IntPtr(45), SetType: SetTypeWorking, Completed: false},
+		},
+	}
+
+	workout := session.ToWorkout(end)
+	require.NoError(t, workout.ResolveTimes())
+	assert.Equal(t, 5, workout.UserID)
+	assert.Equal(t, "Upper", workout.Title)
+	assert.Equal(t, 62, workout.DurationMinutes)
+	assert.Equal(t, start, workout.PerformedAt)
+
+	// One entry per exercise, in the order they were first logged
+	require.Len(t, workout.Entries, 2)
+	bench, plank := workout.Entries[0], workout.Entries[1]
+	assert.Equal(t, "Bench Press", bench.ExerciseName)
+	assert.Equal(t, 1, bench.OrderIndex)
+	require.Len(t, bench.Sets, 2)
+	assert.Equal(t, 2, bench.Sets[1].SetNumber)
+	assert.Equal(t, 4, *bench.Sets[1].Reps)
+	assert.Equal(t, 150, *bench.Sets[0].RestSeconds)
+	assert.Equal(t, 120, *bench.Sets[0].PrescribedRestSeconds)
+
+	assert.Equal(t, 2, plank.OrderIndex)
+	require.Len(t, plank.Sets, 2)
+	assert.False(t, plank.Sets[1].Completed)
+}
+
+func TestLiveSessionToWorkoutTimers(t *testing.T) {
+	start := time.Date(2026, 4, 2, 17, 0, 0, 0, time.UTC)
+	tabata := timer.Log{
+		Protocol:  timer.ProtocolTabata,
+		StartedAt: start,
+		Phases: []timer.PhaseLog{
+			{Kind: timer.PhaseWork, Round: 1, PlannedSeconds: 20, ActualSeconds: 20},
+			{Kind: timer.PhaseRest, Round: 1, PlannedSeconds: 10, ActualSeconds: 11},
+			{Kind: timer.PhaseWork, Round: 2, PlannedSeconds: 20, ActualSeconds: 12.4},
+		},
+	}
+
+	session := &LiveSession{
+		StartedAt: start,
+		Sets: []SessionSet{
+			{ExerciseName: "Burpee", Reps: IntPtr(10), SetType: SetTypeWorking, Completed: true},
+		},
+		Timers: []SessionTimer{
+			{ExerciseName: "Air Bike", Timings: tabata},
+			{ExerciseName: "burpee", Timings: tabata},
+		},
+	}
+
+	workout := session.ToWorkout(start.Add(10 * time.Minute))
+	require.Len(t, workout.Entries, 2)
+
+	// Logged sets are kept as they are, the timings are added next to them
+	burpee := workout.Entries[0]
+	assert.Len(t, burpee.Sets, 1)
+	require.Len(t, burpee.Intervals, 1)
+
+	// A timed-only exercise gets a duration set per work phase
+	bike := workout.Entries[1]
+	assert.Equal(t, "Air Bike", bike.ExerciseName)
+	assert.Equal(t, 2, bike.OrderIndex)
+	require.Len(t, bike.Intervals, 1)
+	assert.Equal(t, tabata, bike.Intervals[0])
+	require.Len(t, bike.Sets, 2)
+	assert.Equal(t, 20, *bike.Sets[0].DurationSeconds)
+	assert.True(t, bike.Sets[0].Completed)
+	assert.Equal(t, 12, *bike.Sets[1].DurationSeconds)
+	assert.False(t, bike.Sets[1].Completed)
+}
+
+func TestActualRest(t *testing.T) {
+	previous := time.Date(2026, 4, 2, 17, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		next            time.Time
+		durationSeconds *int
+		want            int
+	}{
+		{name: "reps set", next: previous.Add(95 * time.Second), want: 95},
+		{name: "rounds to the second", next: previous.Add(95600 * time.Millisecond), want: 96},
+		{name: "timed set", next: previous.Add(150 * time.Second), durationSeconds: IntPtr(60), want: 90},
+		{name: "never negative", next: previous.Add(30 * time.Second), durationSeconds: IntPtr(60), want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, actualRest(previous, tt.next, tt.durationSeconds))
+		})
+	}
+}
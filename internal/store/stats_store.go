@@ -0,0 +1,304 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/consistency"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/load"
+)
+
+// Periods and groupings accepted by GetVolume.
+const (
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+
+	GroupByExercise    = "exercise"
+	GroupByMuscleGroup = "muscle_group"
+)
+
+// VolumeFilter selects the workouts aggregated by GetVolume.
+// Periods are bucketed in Location (UTC if nil), so a Sunday evening session in
+// New York doesn't end up in the next week.
+type VolumeFilter struct {
+	UserID   int
+	Period   string
+	GroupBy  string
+	From     *time.Time
+	To       *time.Time
+	Location *time.Location
+}
+
+// VolumeGroup is the set volume of one exercise or muscle group inside a period.
+// Tonnage is sets × reps × weight summed over every completed, non-warmup set.
+type VolumeGroup struct {
+	Key             string  `json:"key"`
+	ExerciseID      *int    `json:"exercise_id,omitempty"`
+	Tonnage         float64 `json:"tonnage"`
+	Sets            int     `json:"sets"`
+	Reps            int     `json:"reps"`
+	DurationSeconds int     `json:"duration_seconds"`
+}
+
+// VolumeBucket is the training volume of one week or month.
+// Duration and calories are workout level totals, so they aren't split by group.
+type VolumeBucket struct {
+	PeriodStart     string        `json:"period_start"`
+	Workouts        int           `json:"workouts"`
+	DurationMinutes int           `json:"duration_minutes"`
+	CaloriesBurned  int           `json:"calories_burned"`
+	Tonnage         float64       `json:"tonnage"`
+	Sets            int           `json:"sets"`
+	Reps            int           `json:"reps"`
+	Groups          []VolumeGroup `json:"groups,omitempty"`
+}
+
+// PostgresStatsStore implements StatsStore using PostgreSQL as the backend.
+// Every aggregation happens in SQL, workouts are never loaded one by one.
+type PostgresStatsStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStatsStore is a constructor for PostgresStatsStore.
+func NewPostgresStatsStore(db *sql.DB) *PostgresStatsStore {
+	return &PostgresStatsStore{db: db}
+}
+
+// StatsStore defines the analytics queries.
+type StatsStore interface {
+	GetVolume(filter VolumeFilter) ([]VolumeBucket, error)
+	GetDailyActivity(userID int, location *time.Location, from, to *time.Time) ([]consistency.Day, error)
+	GetDailyLoad(userID int, location *time.Location) ([]load.Day, error)
+}
+
+// GetVolume aggregates training volume per week or month, oldest period first.
+// With GroupBy set, every bucket also lists the volume per exercise or primary muscle group;
+// a set counts fully towards each primary muscle of its exercise.
+func (pg *PostgresStatsStore) GetVolume(filter VolumeFilter) ([]VolumeBucket, error) {
+	if filter.Period != PeriodWeek && filter.Period != PeriodMonth {
+		return nil, fmt.Errorf("unknown period %q", filter.Period)
+	}
+	location := filter.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	// $1 user, $2 period, $3 timezone; the date range follows
+	args := []interface{}{filter.UserID, filter.Period, location.String()}
+	conditions := []string{"w.user_id = $1"}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conditions = append(conditions, fmt.Sprintf("w.performed_at >= $%d", len(args)))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conditions = append(conditions, fmt.Sprintf("w.performed_at < $%d", len(args)))
+	}
+	where := strings.Join(conditions, " AND ")
+	bucket := `date_trunc($2, w.performed_at AT TIME ZONE $3)::DATE`
+
+	workoutQuery := `
+	SELECT ` + bucket + ` AS bucket, COUNT(*), COALESCE(SUM(w.duration_minutes), 0), COALESCE(SUM(w.calories_burned), 0)
+	FROM workouts w
+	WHERE ` + where + `
+	GROUP BY bucket
+	ORDER BY bucket
+	`
+
+	rows, err := pg.db.Query(workoutQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := []VolumeBucket{}
+	byStart := map[string]*VolumeBucket{}
+	for rows.Next() {
+		var start time.Time
+		var b VolumeBucket
+		err = rows.Scan(&start, &b.Workouts, &b.DurationMinutes, &b.CaloriesBurned)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		b.PeriodStart = start.Format(time.DateOnly)
+		buckets = append(buckets, b)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range buckets {
+		byStart[buckets[i].PeriodStart] = &buckets[i]
+	}
+
+	setJoins := `
+	FROM workout_sets s
+	INNER JOIN workout_entries e ON e.id = s.workout_entry_id
+	INNER JOIN workouts w ON w.id = e.workout_id
+	LEFT JOIN exercises ex ON ex.id = e.exercise_id`
+	setWhere := where + ` AND s.completed AND s.set_type <> 'warmup'`
+	setAggregates := `COALESCE(SUM(s.reps * s.weight), 0), COUNT(*), COALESCE(SUM(s.reps), 0), COALESCE(SUM(s.duration_seconds), 0)`
+
+	totalsQuery := `
+	SELECT ` + bucket + ` AS bucket, COALESCE(SUM(s.reps * s.weight), 0), COUNT(*), COALESCE(SUM(s.reps), 0)` + setJoins + `
+	WHERE ` + setWhere + `
+	GROUP BY bucket
+	`
+
+	rows, err = pg.db.Query(totalsQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var start time.Time
+		var tonnage float64
+		var sets, reps int
+		err = rows.Scan(&start, &tonnage, &sets, &reps)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if b, ok := byStart[start.Format(time.DateOnly)]; ok {
+			b.Tonnage, b.Sets, b.Reps = tonnage, sets, reps
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var groupQuery string
+	switch filter.GroupBy {
+	case "":
+		return buckets, nil
+	case GroupByExercise:
+		groupQuery = `
+		SELECT ` + bucket + ` AS bucket, COALESCE(ex.name, e.exercise_name) AS group_key, MAX(ex.id), ` + setAggregates + setJoins + `
+		WHERE ` + setWhere + `
+		GROUP BY bucket, group_key
+		ORDER BY bucket, 4 DESC, group_key
+		`
+	case GroupByMuscleGroup:
+		groupQuery = `
+		SELECT ` + bucket + ` AS bucket, m.muscle AS group_key, NULL::BIGINT, ` + setAggregates + setJoins + `
+		CROSS JOIN LATERAL unnest(COALESCE(NULLIF(ex.primary_muscles, '{}'), ARRAY['unclassified'])) AS m(muscle)
+		WHERE ` + setWhere + `
+		GROUP BY bucket, group_key
+		ORDER BY bucket, 4 DESC, group_key
+		`
+	default:
+		return nil, fmt.Errorf("unknown group_by %q", filter.GroupBy)
+	}
+
+	rows, err = pg.db.Query(groupQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var start time.Time
+		var group VolumeGroup
+		err = rows.Scan(&start, &group.Key, &group.ExerciseID, &group.Tonnage, &group.Sets, &group.Reps, &group.DurationSeconds)
+		if err != nil {
+			return nil, err
+		}
+		if b, ok := byStart[start.Format(time.DateOnly)]; ok {
+			b.Groups = append(b.Groups, group)
+		}
+	}
+
+	return buckets, rows.Err()
+}
+
+// GetDailyActivity returns the workouts, minutes and tonnage of every day the user trained,
+// oldest first. Days are taken in location, and from/to (both optional, "to" exclusive)
+// limit the performed_at range.
+func (pg *PostgresStatsStore) GetDailyActivity(userID int, location *time.Location, from, to *time.Time) ([]consistency.Day, error) {
+	if location == nil {
+		location = time.UTC
+	}
+
+	args := []interface{}{userID, location.String()}
+	conditions := []string{"w.user_id = $1"}
+	if from != nil {
+		args = append(args, *from)
+		conditions = append(conditions, fmt.Sprintf("w.performed_at >= $%d", len(args)))
+	}
+	if to != nil {
+		args = append(args, *to)
+		conditions = append(conditions, fmt.Sprintf("w.performed_at < $%d", len(args)))
+	}
+
+	query := `
+	SELECT (w.performed_at AT TIME ZONE $2)::DATE AS day, COUNT(*),
+		COALESCE(SUM(w.duration_minutes), 0), COALESCE(SUM(v.tonnage), 0)
+	FROM workouts w
+	LEFT JOIN LATERAL (
+		SELECT SUM(s.reps * s.weight) AS tonnage
+		FROM workout_sets s
+		INNER JOIN workout_entries e ON e.id = s.workout_entry_id
+		WHERE e.workout_id = w.id AND s.completed AND s.set_type <> 'warmup'
+	) v ON TRUE
+	WHERE ` + strings.Join(conditions, " AND ") + `
+	GROUP BY day
+	ORDER BY day
+	`
+
+	rows, err := pg.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := []consistency.Day{}
+	for rows.Next() {
+		var day consistency.Day
+		err = rows.Scan(&day.Date, &day.Workouts, &day.DurationMinutes, &day.Tonnage)
+		if err != nil {
+			return nil, err
+		}
+		days = append(days, day)
+	}
+
+	return days, rows.Err()
+}
+
+// GetDailyLoad returns the session RPE load (session_rpe × duration_minutes) of every day
+// the user trained, oldest first, with days taken in location. The whole history is
+// returned: the chronic load and the fitness-fatigue model build up from the first day.
+func (pg *PostgresStatsStore) GetDailyLoad(userID int, location *time.Location) ([]load.Day, error) {
+	if location == nil {
+		location = time.UTC
+	}
+
+	query := `
+	SELECT (performed_at AT TIME ZONE $2)::DATE AS day,
+		COALESCE(SUM(session_rpe * duration_minutes), 0)::FLOAT8,
+		COUNT(*) FILTER (WHERE session_rpe IS NULL)
+	FROM workouts
+	WHERE user_id = $1
+	GROUP BY day
+	ORDER BY day
+	`
+
+	rows, err := pg.db.Query(query, userID, location.String())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := []load.Day{}
+	for rows.Next() {
+		var day load.Day
+		err = rows.Scan(&day.Date, &day.Load, &day.Unrated)
+		if err != nil {
+			return nil, err
+		}
+		days = append(days, day)
+	}
+
+	return days, rows.Err()
+}
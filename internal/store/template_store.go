@@ -0,0 +1,312 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Template is a reusable workout plan such as "Push Day A".
+type Template struct {
+	ID          int             `json:"id"`
+	UserID      int             `json:"user_id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Entries     []TemplateEntry `json:"entries"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// TemplateEntry is one planned exercise of a template.
+// Like a set, it targets either reps or a duration; the weight is optional.
+type TemplateEntry struct {
+	ID                    int      `json:"id"`
+	ExerciseID            *int     `json:"exercise_id"`
+	ExerciseName          string   `json:"exercise_name"`
+	TargetSets            int      `json:"target_sets"`
+	TargetReps            *int     `json:"target_reps"`
+	TargetDurationSeconds *int     `json:"target_duration_seconds"`
+	TargetWeight          *float64 `json:"target_weight"`
+	Notes                 string   `json:"notes"`
+	OrderIndex            int      `json:"order_index"`
+}
+
+// ToWorkout builds an unsaved workout prefilled with the template's entries.
+// Every target becomes one planned set, left uncompleted so it doesn't count
+// towards records or volume until it is actually done.
+func (t *Template) ToWorkout() *Workout {
+	workout := &Workout{
+		UserID:      t.UserID,
+		Title:       t.Name,
+		Description: t.Description,
+		Entries:     make([]WorkoutEntry, 0, len(t.Entries)),
+	}
+
+	for _, templateEntry := range t.Entries {
+		entry := WorkoutEntry{
+			ExerciseID:   templateEntry.ExerciseID,
+			ExerciseName: templateEntry.ExerciseName,
+			Notes:        templateEntry.Notes,
+			OrderIndex:   templateEntry.OrderIndex,
+		}
+
+		for i := 0; i < templateEntry.TargetSets; i++ {
+			entry.Sets = append(entry.Sets, WorkoutSet{
+				SetNumber:       i + 1,
+				Reps:            templateEntry.TargetReps,
+				DurationSeconds: templateEntry.TargetDurationSeconds,
+				Weight:          templateEntry.TargetWeight,
+				SetType:         SetTypeWorking,
+				Completed:       false,
+			})
+		}
+
+		workout.Entries = append(workout.Entries, entry)
+	}
+
+	return workout
+}
+
+// PostgresTemplateStore implements TemplateStore using PostgreSQL as the backend.
+type PostgresTemplateStore struct {
+	db *sql.DB
+}
+
+// NewPostgresTemplateStore is a constructor for PostgresTemplateStore.
+func NewPostgresTemplateStore(db *sql.DB) *PostgresTemplateStore {
+	return &PostgresTemplateStore{db: db}
+}
+
+// TemplateStore defines how workout templates are persisted.
+type TemplateStore interface {
+	CreateTemplate(*Template) error
+	GetTemplateByID(id int64) (*Template, error)
+	ListTemplates(userID int) ([]Template, error)
+	UpdateTemplate(*Template) error
+	DeleteTemplate(id int64) error
+	GetTemplateOwner(id int64) (int, error)
+}
+
+// CreateTemplate inserts a template and its entries in one transaction.
+func (pg *PostgresTemplateStore) CreateTemplate(template *Template) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+	INSERT INTO workout_templates (user_id, name, description)
+	VALUES ($1, $2, $3)
+	RETURNING id, created_at, updated_at
+	`
+
+	err = tx.QueryRow(query, template.UserID, template.Name, template.Description).
+		Scan(&template.ID, &template.CreatedAt, &template.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	err = insertTemplateEntries(tx, template)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetTemplateByID fetches a template with its entries.
+// Returns (nil, nil) if it doesn't exist.
+func (pg *PostgresTemplateStore) GetTemplateByID(id int64) (*Template, error) {
+	template := &Template{}
+
+	query := `
+	SELECT id, user_id, name, description, created_at, updated_at
+	FROM workout_templates
+	WHERE id = $1
+	`
+
+	err := pg.db.QueryRow(query, id).Scan(&template.ID, &template.UserID, &template.Name, &template.Description,
+		&template.CreatedAt, &template.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = pg.loadTemplateEntries(template)
+	if err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// ListTemplates returns every template of the user with their entries, sorted by name.
+func (pg *PostgresTemplateStore) ListTemplates(userID int) ([]Template, error) {
+	query := `
+	SELECT id, user_id, name, description, created_at, updated_at
+	FROM workout_templates
+	WHERE user_id = $1
+	ORDER BY name, id
+	`
+
+	rows, err := pg.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := []Template{}
+	for rows.Next() {
+		var template Template
+		err = rows.Scan(&template.ID, &template.UserID, &template.Name, &template.Description,
+			&template.CreatedAt, &template.UpdatedAt)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range templates {
+		err = pg.loadTemplateEntries(&templates[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return templates, nil
+}
+
+// UpdateTemplate replaces the template fields and all of its entries.
+// Returns sql.ErrNoRows if the template does not exist.
+func (pg *PostgresTemplateStore) UpdateTemplate(template *Template) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+	UPDATE workout_templates
+	SET name = $1, description = $2, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $3
+	RETURNING user_id, updated_at
+	`
+
+	err = tx.QueryRow(query, template.Name, template.Description, template.ID).Scan(&template.UserID, &template.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM template_entries WHERE template_id = $1`, template.ID)
+	if err != nil {
+		return err
+	}
+
+	err = insertTemplateEntries(tx, template)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteTemplate removes a template; its entries are removed by ON DELETE CASCADE.
+// Returns sql.ErrNoRows if the template does not exist.
+func (pg *PostgresTemplateStore) DeleteTemplate(id int64) error {
+	result, err := pg.db.Exec(`DELETE FROM workout_templates WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetTemplateOwner returns the user_id of the template.
+// Returns sql.ErrNoRows if the template does not exist.
+func (pg *PostgresTemplateStore) GetTemplateOwner(id int64) (int, error) {
+	var userID int
+	err := pg.db.QueryRow(`SELECT user_id FROM workout_templates WHERE id = $1`, id).Scan(&userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// insertTemplateEntries links the entries to the exercise catalog and inserts them inside tx.
+func insertTemplateEntries(tx *sql.Tx, template *Template) error {
+	if len(template.Entries) == 0 {
+		return nil
+	}
+
+	linker, err := newExerciseLinker(tx, template.UserID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO template_entries (template_id, exercise_id, exercise_name, target_sets, target_reps,
+		target_duration_seconds, target_weight, notes, order_index)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	RETURNING id
+	`
+
+	for i := range template.Entries {
+		entry := &template.Entries[i]
+		entry.ExerciseID, entry.ExerciseName, err = linker.link(entry.ExerciseID, entry.ExerciseName)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(query, template.ID, entry.ExerciseID, entry.ExerciseName, entry.TargetSets, entry.TargetReps,
+			entry.TargetDurationSeconds, entry.TargetWeight, entry.Notes, entry.OrderIndex).Scan(&entry.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadTemplateEntries fills in the entries of the template in order.
+func (pg *PostgresTemplateStore) loadTemplateEntries(template *Template) error {
+	query := `
+	SELECT id, exercise_id, exercise_name, target_sets, target_reps, target_duration_seconds, target_weight, notes, order_index
+	FROM template_entries
+	WHERE template_id = $1
+	ORDER BY order_index, id
+	`
+
+	rows, err := pg.db.Query(query, template.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	template.Entries = []TemplateEntry{}
+	for rows.Next() {
+		var entry TemplateEntry
+		err = rows.Scan(&entry.ID, &entry.ExerciseID, &entry.ExerciseName, &entry.TargetSets, &entry.TargetReps,
+			&entry.TargetDurationSeconds, &entry.TargetWeight, &entry.Notes, &entry.OrderIndex)
+		if err != nil {
+			return err
+		}
+		template.Entries = append(template.Entries, entry)
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,46 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateToWorkout(t *testing.T) {
+	exerciseID := 3
+	reps := 5
+	weight := 100.0
+	duration := 60
+
+	template := &Template{
+		UserID:      7,
+		Name:        "Push Day A",
+		Description: "heavy",
+		Entries: []TemplateEntry{
+			{ExerciseID: &exerciseID, ExerciseName: "Bench Press", TargetSets: 3, TargetReps: &reps, TargetWeight: &weight, OrderIndex: 1},
+			{ExerciseName: "Plank", TargetSets: 2, TargetDurationSeconds: &duration, OrderIndex: 2},
+		},
+	}
+
+	workout := template.ToWorkout()
+	assert.Equal(t, 7, workout.UserID)
+	assert.Equal(t, "Push Day A", workout.Title)
+	require.Len(t, workout.Entries, 2)
+
+	bench := workout.Entries[0]
+	assert.Equal(t, &exerciseID, bench.ExerciseID)
+	require.Len(t, bench.Sets, 3)
+	for i, set := range bench.Sets {
+		assert.Equal(t, i+1, set.SetNumber)
+		assert.Equal(t, 5, *set.Reps)
+		assert.Equal(t, 100.0, *set.Weight)
+		assert.Equal(t, SetTypeWorking, set.SetType)
+		assert.False(t, set.Completed)
+	}
+
+	plank := workout.Entries[1]
+	require.Len(t, plank.Sets, 2)
+	assert.Nil(t, plank.Sets[0].Reps)
+	assert.Equal(t, 60, *plank.Sets[0].DurationSeconds)
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/tokens"
+)
+
+// PostgresTokenStore implements TokenStore using PostgreSQL as the backend.
+type PostgresTokenStore struct {
+	db *sql.DB
+}
+
+// NewPostgresTokenStore is a constructor for PostgresTokenStore.
+func NewPostgresTokenStore(db *sql.DB) *PostgresTokenStore {
+	return &PostgresTokenStore{
+		db: db,
+	}
+}
+
+// TokenStore defines how tokens are persisted.
+// Tokens are looked up through UserStore.GetUserToken, so this interface only writes.
+type TokenStore interface {
+	Insert(token *tokens.Token) error
+	CreateNewToken(userID int, ttl time.Duration, scope string) (*tokens.Token, error)
+	DeleteAllTokensForUser(userID int, scope string) error
+}
+
+// CreateNewToken generates a fresh token for the user and saves its hash.
+// The returned token still carries the plain-text value so it can be sent to the client.
+func (s *PostgresTokenStore) CreateNewToken(userID int, ttl time.Duration, scope string) (*tokens.Token, error) {
+	token, err := tokens.GenerateToken(userID, ttl, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Insert(token)
+	return token, err
+}
+
+// Insert stores the token hash, owner, expiry and scope.
+func (s *PostgresTokenStore) Insert(token *tokens.Token) error {
+	query := `
+	INSERT INTO tokens (hash, user_id, expiry, scope)
+	VALUES ($1, $2, $3, $4)
+	`
+
+	_, err := s.db.Exec(query, token.Hash, token.UserID, token.Expiry, token.Scope)
+	return err
+}
+
+// DeleteAllTokensForUser revokes every token of the given scope owned by the user.
+func (s *PostgresTokenStore) DeleteAllTokensForUser(userID int, scope string) error {
+	query := `
+	DELETE FROM tokens
+	WHERE scope = $1 AND user_id = $2
+	`
+
+	_, err := s.db.Exec(query, scope, userID)
+	return err
+}
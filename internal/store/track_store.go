@@ -0,0 +1,356 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/track"
+)
+
+// trackPointBatch is how many points go into one INSERT; each point takes 8 parameters,
+// which keeps a statement well under the 65535 parameters Postgres accepts.
+const trackPointBatch = 1000
+
+// WorkoutTrack is a GPS or heart rate recording attached to a workout.
+// Points are only loaded when a single track is fetched, the list only carries the metrics.
+type WorkoutTrack struct {
+	ID         int           `json:"id"`
+	WorkoutID  int           `json:"workout_id"`
+	Format     string        `json:"format"`
+	Name       string        `json:"name"`
+	StartedAt  time.Time     `json:"started_at"`
+	EndedAt    time.Time     `json:"ended_at"`
+	PointCount int           `json:"point_count"`
+	Metrics    track.Metrics `json:"metrics"`
+	CreatedAt  time.Time     `json:"created_at"`
+	Points     []track.Point `json:"points,omitempty"`
+}
+
+// PostgresTrackStore implements TrackStore using PostgreSQL as the backend.
+type PostgresTrackStore struct {
+	db *sql.DB
+}
+
+// NewPostgresTrackStore is a constructor for PostgresTrackStore.
+func NewPostgresTrackStore(db *sql.DB) *PostgresTrackStore {
+	return &PostgresTrackStore{db: db}
+}
+
+// TrackStore defines how workout tracks and their points are persisted.
+type TrackStore interface {
+	CreateTrack(*WorkoutTrack) error
+	ListTracks(workoutID int64) ([]WorkoutTrack, error)
+	GetTrackByID(id int64) (*WorkoutTrack, error)
+	DeleteTrack(id int64) error
+}
+
+// intArray lets an INTEGER[] column be scanned into a []int; NULL leaves the slice nil.
+func intArray(dst *[]int) sql.Scanner {
+	return pgtype.NewMap().SQLScanner(dst)
+}
+
+const trackMetricsColumns = `distance_meters, elapsed_seconds, moving_seconds, elevation_gain_meters, elevation_loss_meters,
+	average_heart_rate, max_heart_rate, heart_rate_zones`
+
+// trackMetricsDest returns the scan destinations of trackMetricsColumns.
+func trackMetricsDest(metrics *track.Metrics) []interface{} {
+	return []interface{}{
+		&metrics.DistanceMeters,
+		&metrics.ElapsedSeconds,
+		&metrics.MovingSeconds,
+		&metrics.ElevationGainMeters,
+		&metrics.ElevationLossMeters,
+		&metrics.AverageHeartRate,
+		&metrics.MaxHeartRate,
+		intArray(&metrics.HeartRateZones),
+	}
+}
+
+// CreateTrack saves a track and all its points in one transaction.
+// The metrics must already be computed; only their totals are stored.
+func (pg *PostgresTrackStore) CreateTrack(workoutTrack *WorkoutTrack) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	metrics := workoutTrack.Metrics
+	workoutTrack.PointCount = len(workoutTrack.Points)
+
+	query := `
+	INSERT INTO workout_tracks (workout_id, format, name, started_at, ended_at, point_count,
+		distance_meters, elapsed_seconds, moving_seconds, elevation_gain_meters, elevation_loss_meters,
+		average_heart_rate, max_heart_rate, heart_rate_zones)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	RETURNING id, created_at
+	`
+
+	err = tx.QueryRow(query, workoutTrack.WorkoutID, workoutTrack.Format, workoutTrack.Name,
+		workoutTrack.StartedAt, workoutTrack.EndedAt, workoutTrack.PointCount,
+		metrics.DistanceMeters, metrics.ElapsedSeconds, metrics.MovingSeconds,
+		metrics.ElevationGainMeters, metrics.ElevationLossMeters,
+		metrics.AverageHeartRate, metrics.MaxHeartRate, metrics.HeartRateZones,
+	).Scan(&workoutTrack.ID, &workoutTrack.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(workoutTrack.Points); start += trackPointBatch {
+		end := min(start+trackPointBatch, len(workoutTrack.Points))
+		err = insertTrackPoints(tx, workoutTrack.ID, start, workoutTrack.Points[start:end])
+		if err != nil {
+			return err
+		}
+	}
+
+	// The distance counts towards the user's goals
+	var userID int
+	err = tx.QueryRow(`SELECT user_id FROM workouts WHERE id = $1`, workoutTrack.WorkoutID).Scan(&userID)
+	if err != nil {
+		return err
+	}
+	err = evaluateGoals(tx, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertTrackPoints writes a batch of points with a single multi-row INSERT.
+// offset is the index of the first point of the batch within the track.
+func insertTrackPoints(tx *sql.Tx, trackID int, offset int, points []track.Point) error {
+	values := make([]string, len(points))
+	args := make([]interface{}, 0, len(points)*8)
+	for i, point := range points {
+		n := len(args)
+		values[i] = fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8)
+		args = append(args, trackID, offset+i, point.Time, point.Lat, point.Lon, point.Elevation, point.HeartRate,
+			point.DistanceMeters)
+	}
+
+	query := `
+	INSERT INTO track_points (track_id, point_index, recorded_at, latitude, longitude, elevation, heart_rate, distance_meters)
+	VALUES ` + strings.Join(values, ", ")
+
+	_, err := tx.Exec(query, args...)
+	return err
+}
+
+// ListTracks returns the tracks of a workout in the order they were recorded, without their points.
+func (pg *PostgresTrackStore) ListTracks(workoutID int64) ([]WorkoutTrack, error) {
+	query := `
+	SELECT id, workout_id, format, name, started_at, ended_at, point_count, created_at, ` + trackMetricsColumns + `
+	FROM workout_tracks
+	WHERE workout_id = $1
+	ORDER BY started_at, id
+	`
+
+	rows, err := pg.db.Query(query, workoutID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tracks := []WorkoutTrack{}
+	for rows.Next() {
+		workoutTrack, err := scanTrack(rows)
+		if err != nil {
+			return nil, err
+		}
+		tracks = append(tracks, *workoutTrack)
+	}
+
+	return tracks, rows.Err()
+}
+
+// scanTrack reads one row of ListTracks or GetTrackByID.
+func scanTrack(row interface{ Scan(...interface{}) error }) (*WorkoutTrack, error) {
+	workoutTrack := &WorkoutTrack{}
+	dest := []interface{}{
+		&workoutTrack.ID,
+		&workoutTrack.WorkoutID,
+		&workoutTrack.Format,
+		&workoutTrack.Name,
+		&workoutTrack.StartedAt,
+		&workoutTrack.EndedAt,
+		&workoutTrack.PointCount,
+		&workoutTrack.CreatedAt,
+	}
+
+	err := row.Scan(append(dest, trackMetricsDest(&workoutTrack.Metrics)...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	workoutTrack.Metrics.Derive()
+	return workoutTrack, nil
+}
+
+// GetTrackByID fetches a track with all its points. Returns (nil, nil) if it doesn't exist.
+func (pg *PostgresTrackStore) GetTrackByID(id int64) (*WorkoutTrack, error) {
+	query := `
+	SELECT id, workout_id, format, name, started_at, ended_at, point_count, created_at, ` + trackMetricsColumns + `
+	FROM workout_tracks
+	WHERE id = $1
+	`
+
+	workoutTrack, err := scanTrack(pg.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	workoutTrack.Points, err = loadTrackPoints(pg.db, workoutTrack.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return workoutTrack, nil
+}
+
+// loadTrackPoints returns the points of a track in recording order.
+func loadTrackPoints(q queryer, trackID int) ([]track.Point, error) {
+	query := `
+	SELECT recorded_at, latitude, longitude, elevation, heart_rate, distance_meters
+	FROM track_points
+	WHERE track_id = $1
+	ORDER BY point_index
+	`
+
+	rows, err := q.Query(query, trackID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := []track.Point{}
+	for rows.Next() {
+		var point track.Point
+		err = rows.Scan(&point.Time, &point.Lat, &point.Lon, &point.Elevation, &point.HeartRate, &point.DistanceMeters)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, point)
+	}
+
+	return points, rows.Err()
+}
+
+// DeleteTrack removes a track; its points go with it.
+// Returns sql.ErrNoRows if the track doesn't exist.
+func (pg *PostgresTrackStore) DeleteTrack(id int64) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+	DELETE FROM workout_tracks t
+	USING workouts w
+	WHERE t.id = $1 AND w.id = t.workout_id
+	RETURNING w.user_id
+	`
+
+	var userID int
+	err = tx.QueryRow(query, id).Scan(&userID)
+	if err != nil {
+		return err
+	}
+
+	err = evaluateGoals(tx, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// rebuildHeartRateZones recomputes the zones of every track of the user from the stored points
+// inside the transaction that sets or changes their maximum heart rate. A nil maxHeartRate
+// clears the zones.
+func rebuildHeartRateZones(tx *sql.Tx, userID int, maxHeartRate *int) error {
+	rows, err := tx.Query(`
+	SELECT t.id
+	FROM workout_tracks t
+	INNER JOIN workouts w ON w.id = t.workout_id
+	WHERE w.user_id = $1 AND t.average_heart_rate IS NOT NULL
+	`, userID)
+	if err != nil {
+		return err
+	}
+
+	var trackIDs []int
+	for rows.Next() {
+		var trackID int
+		if err = rows.Scan(&trackID); err != nil {
+			rows.Close()
+			return err
+		}
+		trackIDs = append(trackIDs, trackID)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, trackID := range trackIDs {
+		points, err := loadTrackPoints(tx, trackID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`UPDATE workout_tracks SET heart_rate_zones = $1 WHERE id = $2`,
+			track.Zones(points, maxHeartRate), trackID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadCardio sets the combined metrics of the tracks of each workout, for loadEntries.
+// Workouts without tracks keep a nil Cardio.
+func loadCardio(q queryer, ids []int64, byID map[int]*Workout) error {
+	query := `
+	SELECT workout_id, ` + trackMetricsColumns + `
+	FROM workout_tracks
+	WHERE workout_id = ANY($1)
+	ORDER BY workout_id, started_at, id
+	`
+
+	rows, err := q.Query(query, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	metricsByWorkout := make(map[int][]track.Metrics)
+	for rows.Next() {
+		var workoutID int
+		var metrics track.Metrics
+		err = rows.Scan(append([]interface{}{&workoutID}, trackMetricsDest(&metrics)...)...)
+		if err != nil {
+			return err
+		}
+		metricsByWorkout[workoutID] = append(metricsByWorkout[workoutID], metrics)
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for workoutID, all := range metricsByWorkout {
+		byID[workoutID].Cardio = track.Combine(all)
+	}
+
+	return nil
+}
@@ -1,10 +1,13 @@
 package store
 
 import (
+	"crypto/sha256"
 	"database/sql"
 	"errors"
 	"golang.org/x/crypto/bcrypt"
 	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/e1rm"
 )
 
 // password represents a user’s password.
@@ -56,10 +59,42 @@ type User struct {
 	Email        string    `json:"email"`
 	PasswordHash password  `json:"-"`
 	Bio          string    `json:"bio"`
+	E1RMFormula  string    `json:"e1rm_formula"`
+	MaxHeartRate *int      `json:"max_heart_rate"`
+	Timezone     string    `json:"timezone"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
+// AnonymousUser represents a request that carries no (valid) token.
+// The auth middleware places it on the context so handlers never see a nil user.
+var AnonymousUser = &User{}
+
+// PreferredE1RMFormula returns the formula the user picked for estimated 1RMs,
+// falling back to the default if it isn't set.
+func (u *User) PreferredE1RMFormula() e1rm.Formula {
+	formula, err := e1rm.Parse(u.E1RMFormula)
+	if err != nil {
+		return e1rm.Default
+	}
+	return formula
+}
+
+// Location returns the user's timezone, used to decide which day a workout falls on.
+// It falls back to UTC if the setting is missing or no longer known.
+func (u *User) Location() *time.Location {
+	location, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// IsAnonymous reports whether the user is the AnonymousUser sentinel.
+func (u *User) IsAnonymous() bool {
+	return u == AnonymousUser
+}
+
 // PostgresUserStore implements UserStore using PostgreSQL as the backend.
 // It wraps a sql.DB connection.
 type PostgresUserStore struct {
@@ -79,6 +114,8 @@ type UserStore interface {
 	CreateUser(*User) error
 	GetUserByUsername(username string) (*User, error)
 	UpdateUser(*User) error
+	UpdateSettings(user *User, rebuildRecords, rebuildZones bool) error
+	GetUserToken(scope, tokenPlainText string) (*User, error)
 }
 
 // CreateUser inserts a new user into the database.
@@ -88,7 +125,7 @@ func (s *PostgresUserStore) CreateUser(user *User) error {
 	query := `
 	INSERT INTO users (username, email, password_hash, bio)
 	VALUES ($1, $2, $3, $4)
-	RETURNING id, created_at, updated_at
+	RETURNING id, e1rm_formula, timezone, created_at, updated_at
 	`
 
 	// Use QueryRow + Scan to capture the generated fields.
@@ -97,7 +134,7 @@ func (s *PostgresUserStore) CreateUser(user *User) error {
 		user.Email,
 		user.PasswordHash.hash, // store only the hash, never the plain text
 		user.Bio,
-	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	).Scan(&user.ID, &user.E1RMFormula, &user.Timezone, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
 		return err
@@ -112,7 +149,7 @@ func (s *PostgresUserStore) GetUserByUsername(username string) (*User, error) {
 		PasswordHash: password{},
 	}
 	query := `
-	SELECT id, username, email, password_hash, bio, created_at, updated_at
+	SELECT id, username, email, password_hash, bio, e1rm_formula, max_heart_rate, timezone, created_at, updated_at
 	FROM users
 	WHERE username = $1
 	`
@@ -123,6 +160,9 @@ func (s *PostgresUserStore) GetUserByUsername(username string) (*User, error) {
 		&user.Email,
 		&user.PasswordHash.hash, // hydrate password hash for login checks
 		&user.Bio,
+		&user.E1RMFormula,
+		&user.MaxHeartRate,
+		&user.Timezone,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -139,18 +179,18 @@ func (s *PostgresUserStore) GetUserByUsername(username string) (*User, error) {
 }
 
 // UpdateUser updates basic user fields in the database.
-// Updates: username, email, bio.
+// Updates: username, email, bio, e1rm_formula, max_heart_rate, timezone.
 // updated_at is set to CURRENT_TIMESTAMP automatically.
 // Returns sql.ErrNoRows if user ID does not exist.
 func (s *PostgresUserStore) UpdateUser(user *User) error {
 	query := `
 	UPDATE users
-	SET username = $1, email = $2, bio = $3, updated_at = CURRENT_TIMESTAMP
-	WHERE id = $4
+	SET username = $1, email = $2, bio = $3, e1rm_formula = $4, max_heart_rate = $5, timezone = $6, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $7
 	RETURNING updated_at
 	`
 
-	result, err := s.db.Exec(query, user.Username, user.Email, user.Bio, user.ID)
+	result, err := s.db.Exec(query, user.Username, user.Email, user.Bio, user.E1RMFormula, user.MaxHeartRate, user.Timezone, user.ID)
 	if err != nil {
 		return err
 	}
@@ -167,3 +207,85 @@ func (s *PostgresUserStore) UpdateUser(user *User) error {
 
 	return nil
 }
+
+// UpdateSettings saves the user like UpdateUser and, in the same transaction, rebuilds what
+// the settings are used for: the records after a new e1RM formula, the heart rate zones of
+// the tracks after a new maximum heart rate. A failed rebuild keeps the old settings, so
+// the client can simply retry.
+// Returns sql.ErrNoRows if user ID does not exist.
+func (s *PostgresUserStore) UpdateSettings(user *User, rebuildRecords, rebuildZones bool) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+	UPDATE users
+	SET username = $1, email = $2, bio = $3, e1rm_formula = $4, max_heart_rate = $5, timezone = $6, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $7
+	RETURNING updated_at
+	`
+
+	err = tx.QueryRow(query, user.Username, user.Email, user.Bio, user.E1RMFormula, user.MaxHeartRate, user.Timezone,
+		user.ID).Scan(&user.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	// The rebuilds read the settings saved above
+	if rebuildRecords {
+		err = rebuildUserRecords(tx, user.ID)
+		if err != nil {
+			return err
+		}
+	}
+	if rebuildZones {
+		err = rebuildHeartRateZones(tx, user.ID, user.MaxHeartRate)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetUserToken resolves a plain-text token into the user that owns it.
+// The token is hashed the same way it was stored, and expired tokens are ignored.
+// Returns (nil, nil) if the token is unknown, expired or has a different scope.
+func (s *PostgresUserStore) GetUserToken(scope, tokenPlainText string) (*User, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlainText))
+
+	query := `
+	SELECT u.id, u.username, u.email, u.password_hash, u.bio, u.e1rm_formula, u.max_heart_rate, u.timezone, u.created_at, u.updated_at
+	FROM users u
+	INNER JOIN tokens t ON t.user_id = u.id
+	WHERE t.hash = $1 AND t.scope = $2 AND t.expiry > $3
+	`
+
+	user := &User{
+		PasswordHash: password{},
+	}
+
+	err := s.db.QueryRow(query, tokenHash[:], scope, time.Now()).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.PasswordHash.hash,
+		&user.Bio,
+		&user.E1RMFormula,
+		&user.MaxHeartRate,
+		&user.Timezone,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
@@ -1,6 +1,22 @@
 package store
 
-import "database/sql"
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/calories"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/e1rm"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/timer"
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/track"
+)
 
 type Workout struct {
 	ID              int            `json:"id"`
@@ -9,19 +25,276 @@ type Workout struct {
 	Description     string         `json:"description"`
 	DurationMinutes int            `json:"duration_minutes"`
 	CaloriesBurned  int            `json:"calories_burned"`
+	CaloriesSource  string         `json:"calories_source,omitempty"`
+	SessionRPE      *float64       `json:"session_rpe"`
+	PerformedAt     time.Time      `json:"performed_at"`
+	StartedAt       *time.Time     `json:"started_at"`
+	EndedAt         *time.Time     `json:"ended_at"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	ProgramDayID    *int           `json:"program_day_id"`
+	Groups          []EntryGroup   `json:"groups,omitempty"`
 	Entries         []WorkoutEntry `json:"entries"`
+	Cardio          *track.Metrics `json:"cardio,omitempty"`
 }
 
-// We used pointer because we explicitly wanted to check if the value is nil or not
+// Where the calories of a workout come from, see Workout.CaloriesSource.
+// Workouts without any calories have no source.
+const (
+	CaloriesMeasured  = "measured"
+	CaloriesEstimated = "estimated"
+)
+
+// ResolveTimes fills in the time fields the client is allowed to omit.
+//   - PerformedAt defaults to StartedAt, or to now if neither was given.
+//   - DurationMinutes is derived from StartedAt/EndedAt when it is 0.
+//
+// Returns an error if the session ends before it starts.
+func (w *Workout) ResolveTimes() error {
+	if w.StartedAt != nil && w.EndedAt != nil && w.EndedAt.Before(*w.StartedAt) {
+		return errors.New("ended_at must be after started_at")
+	}
+
+	if w.PerformedAt.IsZero() {
+		if w.StartedAt != nil {
+			w.PerformedAt = *w.StartedAt
+		} else {
+			w.PerformedAt = time.Now()
+		}
+	}
+
+	if w.DurationMinutes == 0 && w.StartedAt != nil && w.EndedAt != nil {
+		w.DurationMinutes = int(w.EndedAt.Sub(*w.StartedAt).Round(time.Minute) / time.Minute)
+	}
+
+	return nil
+}
+
+// ValidateSessionRPE checks that the session RPE, if any, is on the 0-10 scale.
+// It is rated for the session as a whole, independently of the RPE of its sets.
+func (w *Workout) ValidateSessionRPE() error {
+	if w.SessionRPE != nil && (*w.SessionRPE <= 0 || *w.SessionRPE > 10) {
+		return errors.New("session_rpe must be above 0 and at most 10")
+	}
+	return nil
+}
+
+// WorkoutEntry is one exercise inside a workout. The actual work is logged per set in Sets.
+// ExerciseID links the entry to the exercise catalog; when the client only sends a name
+// it is linked when it is the name or an alias of a catalog exercise, and left nil otherwise.
 type WorkoutEntry struct {
-	ID              int      `json:"id"`
-	ExerciseName    string   `json:"exercise_name"`
-	Sets            int      `json:"sets"`
-	Reps            *int     `json:"reps"`
-	DurationSeconds *int     `json:"duration_seconds"`
-	Weight          *float64 `json:"weight"`
-	Notes           string   `json:"notes"`
-	OrderIndex      int      `json:"order_index"`
+	ID           int          `json:"id"`
+	ExerciseID   *int         `json:"exercise_id"`
+	ExerciseName string       `json:"exercise_name"`
+	Sets         []WorkoutSet `json:"sets"`
+	Notes        string       `json:"notes"`
+	OrderIndex   int          `json:"order_index"`
+	Group        string       `json:"group,omitempty"`
+	Intervals    []timer.Log  `json:"intervals,omitempty"`
+	BestE1RM     *float64     `json:"best_e1rm,omitempty"`
+}
+
+// Group types accepted in EntryGroup.Type.
+const (
+	GroupTypeSuperset = "superset"
+	GroupTypeCircuit  = "circuit"
+	GroupTypeEMOM     = "emom"
+	GroupTypeAMRAP    = "amrap"
+)
+
+// EntryGroup ties consecutive entries of a workout together, e.g. a superset or a circuit.
+// Entries join a group through WorkoutEntry.Group, which holds the group's Label ("A", "B", ...),
+// since the client doesn't know database ids when it sends a new workout.
+// For EMOM and AMRAP, Rounds is the number of minutes or rounds done.
+type EntryGroup struct {
+	ID                       int    `json:"id"`
+	Label                    string `json:"label"`
+	Type                     string `json:"type"`
+	Rounds                   *int   `json:"rounds"`
+	RestBetweenRoundsSeconds *int   `json:"rest_between_rounds_seconds"`
+}
+
+// ValidateGroups checks the groups of the workout and which entries use them:
+// labels are unique, every group is used and every label an entry uses exists, and
+// the entries of a group are contiguous in order_index order. Supersets and circuits
+// need at least two exercises, otherwise they are just a regular entry.
+func (w *Workout) ValidateGroups() error {
+	groups := make(map[string]*EntryGroup, len(w.Groups))
+	for i := range w.Groups {
+		group := &w.Groups[i]
+		group.Label = strings.TrimSpace(group.Label)
+		if group.Label == "" {
+			return errors.New("every group needs a label")
+		}
+		if len(group.Label) > 20 {
+			return errors.New("group labels cannot be longer than 20 chars")
+		}
+		if _, ok := groups[group.Label]; ok {
+			return fmt.Errorf("group %q is listed twice", group.Label)
+		}
+		switch group.Type {
+		case GroupTypeSuperset, GroupTypeCircuit, GroupTypeEMOM, GroupTypeAMRAP:
+		default:
+			return fmt.Errorf("group %q: type must be superset, circuit, emom or amrap", group.Label)
+		}
+		if group.Rounds != nil && *group.Rounds < 1 {
+			return fmt.Errorf("group %q: rounds must be at least 1", group.Label)
+		}
+		if group.RestBetweenRoundsSeconds != nil && *group.RestBetweenRoundsSeconds < 0 {
+			return fmt.Errorf("group %q: rest_between_rounds_seconds cannot be negative", group.Label)
+		}
+		groups[group.Label] = group
+	}
+
+	// Entries are stored and returned by order_index, so that is the order that has to be contiguous
+	ordered := make([]WorkoutEntry, len(w.Entries))
+	copy(ordered, w.Entries)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].OrderIndex < ordered[j].OrderIndex
+	})
+
+	counts := make(map[string]int, len(groups))
+	closed := make(map[string]bool, len(groups))
+	previous := ""
+	for _, entry := range ordered {
+		if entry.Group != previous && previous != "" {
+			closed[previous] = true
+		}
+		previous = entry.Group
+		if entry.Group == "" {
+			continue
+		}
+		if _, ok := groups[entry.Group]; !ok {
+			return fmt.Errorf("entry %q uses unknown group %q", entry.ExerciseName, entry.Group)
+		}
+		if closed[entry.Group] {
+			return fmt.Errorf("the entries of group %q must be next to each other", entry.Group)
+		}
+		counts[entry.Group]++
+	}
+
+	for _, group := range w.Groups {
+		count := counts[group.Label]
+		if count == 0 {
+			return fmt.Errorf("group %q has no entries", group.Label)
+		}
+		if count < 2 && (group.Type == GroupTypeSuperset || group.Type == GroupTypeCircuit) {
+			return fmt.Errorf("group %q: a %s needs at least two entries", group.Label, group.Type)
+		}
+	}
+
+	return nil
+}
+
+// Set types accepted in WorkoutSet.SetType.
+const (
+	SetTypeWarmup  = "warmup"
+	SetTypeWorking = "working"
+	SetTypeDrop    = "drop"
+	SetTypeFailure = "failure"
+)
+
+// WorkoutSet is a single set of an entry.
+// We used pointer because we explicitly wanted to check if the value is nil or not:
+// a set has either Reps or DurationSeconds, and Weight/RPE are optional.
+// RestSeconds and PrescribedRestSeconds are the rest taken and planned after this set.
+type WorkoutSet struct {
+	ID                    int      `json:"id"`
+	SetNumber             int      `json:"set_number"`
+	Reps                  *int     `json:"reps"`
+	DurationSeconds       *int     `json:"duration_seconds"`
+	Weight                *float64 `json:"weight"`
+	RPE                   *float64 `json:"rpe"`
+	SetType               string   `json:"set_type"`
+	Completed             bool     `json:"completed"`
+	RestSeconds           *int     `json:"rest_seconds"`
+	PrescribedRestSeconds *int     `json:"prescribed_rest_seconds"`
+	E1RM                  *float64 `json:"e1rm,omitempty"`
+}
+
+// ApplyE1RM fills in the estimated one rep max of every set with reps and weight,
+// and the best estimate of each entry, using the owner's formula.
+// Estimates aren't stored, they are computed whenever a workout is returned.
+func (w *Workout) ApplyE1RM(formula e1rm.Formula) {
+	for i := range w.Entries {
+		entry := &w.Entries[i]
+		entry.BestE1RM = nil
+
+		for j := range entry.Sets {
+			set := &entry.Sets[j]
+			set.E1RM = nil
+			if set.Reps == nil || set.Weight == nil || set.SetType == SetTypeWarmup || !set.Completed {
+				continue
+			}
+
+			estimate := formula.Estimate(*set.Weight, *set.Reps)
+			if estimate == 0 {
+				continue
+			}
+			set.E1RM = &estimate
+			if entry.BestE1RM == nil || estimate > *entry.BestE1RM {
+				entry.BestE1RM = &estimate
+			}
+		}
+	}
+}
+
+// UnmarshalJSON defaults set_type to "working" and completed to true,
+// since that is what a logged set is unless the client says otherwise.
+func (s *WorkoutSet) UnmarshalJSON(data []byte) error {
+	type setAlias WorkoutSet // alias drops this method, so Unmarshal doesn't recurse
+	set := setAlias{SetType: SetTypeWorking, Completed: true}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return err
+	}
+
+	*s = WorkoutSet(set)
+	return nil
+}
+
+// UnmarshalJSON accepts both the per-set format ("sets": [{...}, ...]) and the
+// older aggregate format ("sets": 3, "reps": 10, "weight": 100) used by existing clients.
+// Aggregate payloads are expanded into identical working sets.
+func (e *WorkoutEntry) UnmarshalJSON(data []byte) error {
+	type entryAlias WorkoutEntry
+	var raw struct {
+		entryAlias
+		Sets            json.RawMessage `json:"sets"`
+		Reps            *int            `json:"reps"`
+		DurationSeconds *int            `json:"duration_seconds"`
+		Weight          *float64        `json:"weight"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*e = WorkoutEntry(raw.entryAlias)
+
+	sets := bytes.TrimSpace(raw.Sets)
+	if len(sets) == 0 || bytes.Equal(sets, []byte("null")) {
+		return nil
+	}
+
+	if sets[0] == '[' {
+		return json.Unmarshal(sets, &e.Sets)
+	}
+
+	var count int
+	if err := json.Unmarshal(sets, &count); err != nil {
+		return errors.New("sets must be a list of sets or a number")
+	}
+	for i := 0; i < count; i++ {
+		e.Sets = append(e.Sets, WorkoutSet{
+			SetNumber:       i + 1,
+			Reps:            raw.Reps,
+			DurationSeconds: raw.DurationSeconds,
+			Weight:          raw.Weight,
+			SetType:         SetTypeWorking,
+			Completed:       true,
+		})
+	}
+
+	return nil
 }
 
 // PostgresWorkoutStore is a store struct that encapsulates a Postgres database connection.
@@ -41,6 +314,43 @@ type WorkoutStore interface {
 	GetWorkoutById(id int64) (*Workout, error)
 	UpdateWorkout(*Workout) error
 	DeleteWorkout(id int64) error
+	GetWorkoutOwner(id int64) (int, error)
+	ListWorkouts(filter WorkoutFilter) (*WorkoutPage, error)
+	GetLastWeight(userID int, exerciseID *int, exerciseName string) (*float64, error)
+	EachWorkout(userID int, fn func(*Workout) error) error
+}
+
+// Sort options accepted by ListWorkouts.
+const (
+	WorkoutSortNewest   = "newest"
+	WorkoutSortLongest  = "longest"
+	WorkoutSortCalories = "calories"
+)
+
+// ErrInvalidCursor is returned by ListWorkouts when the cursor can't be decoded
+// or was issued for a different sort order.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// WorkoutFilter describes which of a user's workouts ListWorkouts should return.
+// Pointer fields are optional filters; nil means "don't filter on this".
+type WorkoutFilter struct {
+	UserID       int
+	From         *time.Time
+	To           *time.Time
+	Title        string
+	MinDuration  *int
+	ExerciseName string
+	Sort         string
+	Cursor       string
+	Limit        int
+}
+
+// WorkoutPage is a single page of ListWorkouts results.
+// NextCursor is empty when there are no more pages.
+type WorkoutPage struct {
+	Workouts   []Workout
+	NextCursor string
+	TotalCount int
 }
 
 // CreateWorkout inserts a new workout along with its entries into the database.
@@ -55,29 +365,56 @@ func (pg *PostgresWorkoutStore) CreateWorkout(workout *Workout) (*Workout, error
 
 	// Insert the workout into the 'workouts' table.
 	// $1, $2... are placeholders to safely inject parameters and prevent SQL injection.
+	err = checkProgramDay(tx, workout.UserID, workout.ProgramDayID)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
-		INSERT INTO workouts (user_id, title, description, duration_minutes, calories_burned)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id 
+		INSERT INTO workouts (user_id, title, description, duration_minutes, calories_burned, performed_at, started_at, ended_at, program_day_id, session_rpe)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at, updated_at
 	`
-	// Execute the query and scan the generated ID back into workout.ID
-	err = tx.QueryRow(query, workout.UserID, workout.Title, workout.Description, workout.DurationMinutes, workout.CaloriesBurned).Scan(&workout.ID)
+	// Execute the query and scan the generated ID and timestamps back into the workout
+	err = tx.QueryRow(query, workout.UserID, workout.Title, workout.Description, workout.DurationMinutes, workout.CaloriesBurned,
+		workout.PerformedAt, workout.StartedAt, workout.EndedAt, workout.ProgramDayID, workout.SessionRPE).Scan(&workout.ID, &workout.CreatedAt, &workout.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
 
-	// Insert each WorkoutEntry into the 'workout_entries' table.
-	for _, entry := range workout.Entries {
-		entryQuery := `
-			INSERT INTO workout_entries (workout_id, exercise_name, sets, reps, duration_seconds, weight, notes, order_index)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-			RETURNING id
-    	`
-		// Scan the generated entry ID into entry.ID
-		err = tx.QueryRow(entryQuery, workout.ID, entry.ExerciseName, entry.Sets, entry.Reps, entry.DurationSeconds, entry.Weight, entry.Notes, entry.OrderIndex).Scan(&entry.ID)
-		if err != nil {
-			return nil, err
-		}
+	// Link entries to the exercise catalog, then insert each WorkoutEntry (and its sets)
+	// into the 'workout_entries' and 'workout_sets' tables.
+	err = resolveEntryExercises(tx, workout)
+	if err != nil {
+		return nil, err
+	}
+	err = insertEntries(tx, workout)
+	if err != nil {
+		return nil, err
+	}
+
+	// Calories the client didn't send are estimated from the linked exercises
+	err = resolveCalories(tx, workout)
+	if err != nil {
+		return nil, err
+	}
+
+	// Detect personal records set by this workout before committing, so they are never out of sync
+	err = recalculateRecords(tx, workout.UserID, workoutExerciseKeys(workout))
+	if err != nil {
+		return nil, err
+	}
+
+	// Tick off the planned session this workout fulfils, if any
+	err = linkScheduledWorkout(tx, workout)
+	if err != nil {
+		return nil, err
+	}
+
+	// The workout counts towards the user's goals
+	err = evaluateGoals(tx, workout.UserID)
+	if err != nil {
+		return nil, err
 	}
 
 	// Commit the transaction. If this succeeds, all inserts are permanently saved.
@@ -96,11 +433,12 @@ func (pg *PostgresWorkoutStore) GetWorkoutById(id int64) (*Workout, error) {
 
 	// Query the workouts table for the basic workout information
 	query := `
-	SELECT id, title, description, duration_minutes, calories_burned
+	SELECT id, user_id, title, description, duration_minutes, calories_burned, COALESCE(calories_source, ''), performed_at, started_at, ended_at, created_at, updated_at, program_day_id, session_rpe
 	FROM workouts
 	WHERE id = $1
 	`
-	err := pg.db.QueryRow(query, id).Scan(&workout.ID, &workout.Title, &workout.Description, &workout.DurationMinutes, &workout.CaloriesBurned)
+	err := pg.db.QueryRow(query, id).Scan(&workout.ID, &workout.UserID, &workout.Title, &workout.Description, &workout.DurationMinutes, &workout.CaloriesBurned, &workout.CaloriesSource,
+		&workout.PerformedAt, &workout.StartedAt, &workout.EndedAt, &workout.CreatedAt, &workout.UpdatedAt, &workout.ProgramDayID, &workout.SessionRPE)
 
 	if err == sql.ErrNoRows {
 		// Return nil if no workout is found
@@ -110,41 +448,85 @@ func (pg *PostgresWorkoutStore) GetWorkoutById(id int64) (*Workout, error) {
 		return nil, err
 	}
 
-	// Query all associated entries for the workout
-	entryQuery := `
-	SELECT id, exercise_name, sets, reps, duration_seconds, weight, notes, order_index
-	FROM workout_entries
-	WHERE workout_id = $1
-	ORDER BY order_index
+	// Query all associated entries (and their sets) for the workout
+	err = pg.loadEntries(workout)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return the complete workout struct with its entries
+	return workout, nil
+}
+
+// eachWorkoutBatch is how many workouts EachWorkout loads at a time.
+const eachWorkoutBatch = 100
+
+// EachWorkout calls fn with every workout of the user, oldest first, entries and sets included.
+// Workouts are loaded in batches so memory use stays flat however long the history is.
+// fn must not keep the workout after it returns; an error from fn stops the iteration and is returned.
+func (pg *PostgresWorkoutStore) EachWorkout(userID int, fn func(*Workout) error) error {
+	query := `
+	SELECT id, user_id, title, description, duration_minutes, calories_burned, COALESCE(calories_source, ''), performed_at, started_at, ended_at, created_at, updated_at, program_day_id, session_rpe
+	FROM workouts
+	WHERE user_id = $1 AND ($2::timestamptz IS NULL OR (performed_at, id) > ($2, $3))
+	ORDER BY performed_at, id
+	LIMIT $4
 	`
 
-	rows, err := pg.db.Query(entryQuery, id)
+	var afterTime *time.Time
+	afterID := 0
+	for {
+		batch, err := pg.workoutBatch(query, userID, afterTime, afterID)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		workouts := make([]*Workout, len(batch))
+		for i := range batch {
+			workouts[i] = &batch[i]
+		}
+		err = pg.loadEntries(workouts...)
+		if err != nil {
+			return err
+		}
+
+		for _, workout := range workouts {
+			if err = fn(workout); err != nil {
+				return err
+			}
+		}
+
+		last := batch[len(batch)-1]
+		afterTime, afterID = &last.PerformedAt, last.ID
+		if len(batch) < eachWorkoutBatch {
+			return nil
+		}
+	}
+}
+
+// workoutBatch runs one page of the EachWorkout query.
+func (pg *PostgresWorkoutStore) workoutBatch(query string, userID int, afterTime *time.Time, afterID int) ([]Workout, error) {
+	rows, err := pg.db.Query(query, userID, afterTime, afterID, eachWorkoutBatch)
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close() // Close the rows when done
+	defer rows.Close()
 
-	// Loop through each row and append to workout.Entries
+	batch := make([]Workout, 0, eachWorkoutBatch)
 	for rows.Next() {
-		var entry WorkoutEntry
-		err = rows.Scan(
-			&entry.ID,
-			&entry.ExerciseName,
-			&entry.Sets,
-			&entry.Reps,
-			&entry.DurationSeconds,
-			&entry.Weight,
-			&entry.Notes,
-			&entry.OrderIndex,
-		)
+		var workout Workout
+		err = rows.Scan(&workout.ID, &workout.UserID, &workout.Title, &workout.Description, &workout.DurationMinutes, &workout.CaloriesBurned, &workout.CaloriesSource,
+			&workout.PerformedAt, &workout.StartedAt, &workout.EndedAt, &workout.CreatedAt, &workout.UpdatedAt, &workout.ProgramDayID, &workout.SessionRPE)
 		if err != nil {
 			return nil, err
 		}
-		workout.Entries = append(workout.Entries, entry)
+		batch = append(batch, workout)
 	}
 
-	// Return the complete workout struct with its entries
-	return workout, nil
+	return batch, rows.Err()
 }
 
 func (pg *PostgresWorkoutStore) UpdateWorkout(workout *Workout) error {
@@ -156,78 +538,649 @@ func (pg *PostgresWorkoutStore) UpdateWorkout(workout *Workout) error {
 
 	defer tx.Rollback()
 
+	err = checkProgramDay(tx, workout.UserID, workout.ProgramDayID)
+	if err != nil {
+		return err
+	}
+
 	query := `
 	UPDATE workouts
-	SET title = $1, description = $2, duration_minutes = $3, calories_burned = $4
-	WHERE id = $5
+	SET title = $1, description = $2, duration_minutes = $3, calories_burned = $4,
+		performed_at = $5, started_at = $6, ended_at = $7, program_day_id = $8, session_rpe = $9, updated_at = CURRENT_TIMESTAMP
+	WHERE id = $10
+	RETURNING updated_at, user_id
 	`
 
-	result, err := tx.Exec(query, workout.Title, workout.Description, workout.DurationMinutes, workout.CaloriesBurned, workout.ID)
+	//Scanning updated_at also tells us if the row existed: no row → sql.ErrNoRows
+	err = tx.QueryRow(query, workout.Title, workout.Description, workout.DurationMinutes, workout.CaloriesBurned,
+		workout.PerformedAt, workout.StartedAt, workout.EndedAt, workout.ProgramDayID, workout.SessionRPE, workout.ID).Scan(&workout.UpdatedAt, &workout.UserID)
 	if err != nil {
 		return err
 	}
 
-	//To check how many rows were affected
-	rowsAffected, err := result.RowsAffected()
+	//Remember the exercises before the update, their records may have to be recalculated too
+	touched := exerciseKeys{}
+	err = entryExerciseKeys(tx, workout.ID, &touched)
 	if err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
+	//We are deleting the entries and their groups and reinitiating them again
+	_, err = tx.Exec(`DELETE FROM workout_entries WHERE workout_id = $1`, workout.ID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM workout_entry_groups WHERE workout_id = $1`, workout.ID)
+	if err != nil {
+		return err
 	}
 
-	//We are deleting the entries and reinitiating them again
-	_, err = tx.Exec(`DELETE FROM workout_entries WHERE workout_id = $1`, workout.ID)
+	//Re-insert every exercise along with its sets
+	err = resolveEntryExercises(tx, workout)
+	if err != nil {
+		return err
+	}
+	err = insertEntries(tx, workout)
+	if err != nil {
+		return err
+	}
+
+	err = resolveCalories(tx, workout)
 	if err != nil {
 		return err
 	}
 
-	//We use a loop for each exercise, updating it
 	for _, entry := range workout.Entries {
-		query := `
-    INSERT INTO workout_entries (workout_id, exercise_name, sets, reps, duration_seconds, weight, notes, order_index)
-    VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-    `
-
-		//Update the workout entry
-		_, err := tx.Exec(query,
-			workout.ID,
-			entry.ExerciseName,
-			entry.Sets,
-			entry.Reps,
-			entry.DurationSeconds,
-			entry.Weight,
-			entry.Notes,
-			entry.OrderIndex,
-		)
+		touched.add(entry.ExerciseID, entry.ExerciseName)
+	}
+	err = recalculateRecords(tx, workout.UserID, touched)
+	if err != nil {
+		return err
+	}
 
-		if err != nil {
-			return err
-		}
+	err = evaluateGoals(tx, workout.UserID)
+	if err != nil {
+		return err
 	}
 
 	return tx.Commit()
 }
 
-func (pg *PostgresWorkoutStore) DeleteWorkout(id int64) error{
+func (pg *PostgresWorkoutStore) DeleteWorkout(id int64) error {
+	//Deleting a workout can un-set records, so the delete and the recalculation share a transaction
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	touched := exerciseKeys{}
+	err = entryExerciseKeys(tx, int(id), &touched)
+	if err != nil {
+		return err
+	}
+
+	//A session completed by this workout becomes planned again
+	err = unlinkScheduledWorkouts(tx, id)
+	if err != nil {
+		return err
+	}
+
 	query := `
 	DELETE from workouts
 	WHERE id = $1
+	RETURNING user_id
 	`
 
-	result, err := pg.db.Exec(query, id)
+	var userID int
+	err = tx.QueryRow(query, id).Scan(&userID)
+	if err != nil {
+		return err
+	}
+
+	err = recalculateRecords(tx, userID, touched)
 	if err != nil {
 		return err
 	}
-	rowsAffected, err := result.RowsAffected()
+
+	err = evaluateGoals(tx, userID)
 	if err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
+	return tx.Commit()
+}
+
+// GetLastWeight returns the heaviest completed working weight of the most recent workout
+// in which the user did this exercise (matched by catalog id, or by name when unlinked).
+// Returns (nil, nil) if the exercise was never logged with weight.
+func (pg *PostgresWorkoutStore) GetLastWeight(userID int, exerciseID *int, exerciseName string) (*float64, error) {
+	query := `
+	SELECT MAX(s.weight)
+	FROM workout_sets s
+	INNER JOIN workout_entries e ON e.id = s.workout_entry_id
+	INNER JOIN workouts w ON w.id = e.workout_id
+	WHERE w.id = (
+		SELECT w2.id
+		FROM workouts w2
+		INNER JOIN workout_entries e2 ON e2.workout_id = w2.id
+		INNER JOIN workout_sets s2 ON s2.workout_entry_id = e2.id
+		WHERE w2.user_id = $1
+			AND (e2.exercise_id = $2 OR ($2 IS NULL AND LOWER(e2.exercise_name) = LOWER($3)))
+			AND s2.completed AND s2.weight IS NOT NULL AND s2.set_type <> 'warmup'
+		ORDER BY w2.performed_at DESC, w2.id DESC
+		LIMIT 1
+	)
+	AND (e.exercise_id = $2 OR ($2 IS NULL AND LOWER(e.exercise_name) = LOWER($3)))
+	AND s.completed AND s.set_type <> 'warmup'
+	`
+
+	var weight *float64
+	err := pg.db.QueryRow(query, userID, exerciseID, exerciseName).Scan(&weight)
+	if err != nil {
+		return nil, err
+	}
+
+	return weight, nil
+}
+
+// workoutExerciseKeys collects the exercises of every entry in the workout.
+func workoutExerciseKeys(workout *Workout) exerciseKeys {
+	keys := exerciseKeys{}
+	for _, entry := range workout.Entries {
+		keys.add(entry.ExerciseID, entry.ExerciseName)
+	}
+	return keys
+}
+
+// GetWorkoutOwner returns the user_id of the workout without loading its entries.
+// Handlers use it to check ownership before reading, updating or deleting a workout.
+// Returns sql.ErrNoRows if the workout does not exist.
+func (pg *PostgresWorkoutStore) GetWorkoutOwner(workoutID int64) (int, error) {
+	var userID int
+
+	query := `
+	SELECT user_id
+	FROM workouts
+	WHERE id = $1
+	`
+
+	err := pg.db.QueryRow(query, workoutID).Scan(&userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// workoutSortColumns maps each sort option to the SQL expression used for ordering.
+// Every sort is descending and uses the workout id as a tie-breaker so the cursor is stable.
+var workoutSortColumns = map[string]string{
+	WorkoutSortNewest:   "w.performed_at",
+	WorkoutSortLongest:  "w.duration_minutes",
+	WorkoutSortCalories: "COALESCE(w.calories_burned, 0)",
+}
+
+// ListWorkouts returns one page of the user's workouts matching the filter.
+// Pagination is keyset based: the cursor encodes the sort value and id of the last
+// workout on the previous page, so pages stay consistent while new workouts are added.
+func (pg *PostgresWorkoutStore) ListWorkouts(filter WorkoutFilter) (*WorkoutPage, error) {
+	if filter.Sort == "" {
+		filter.Sort = WorkoutSortNewest
+	}
+	sortColumn, ok := workoutSortColumns[filter.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort option %q", filter.Sort)
+	}
+
+	// Build the WHERE clause and its arguments together so placeholders stay in sync
+	args := []interface{}{filter.UserID}
+	conditions := []string{"w.user_id = $1"}
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.From != nil {
+		conditions = append(conditions, "w.performed_at >= "+addArg(*filter.From))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "w.performed_at < "+addArg(*filter.To))
+	}
+	if filter.Title != "" {
+		conditions = append(conditions, "w.title ILIKE '%' || "+addArg(filter.Title)+" || '%'")
+	}
+	if filter.MinDuration != nil {
+		conditions = append(conditions, "w.duration_minutes >= "+addArg(*filter.MinDuration))
+	}
+	if filter.ExerciseName != "" {
+		// Match the logged name as well as the catalog name/aliases of the linked exercise
+		arg := addArg(filter.ExerciseName)
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM workout_entries we
+			LEFT JOIN exercises ex ON ex.id = we.exercise_id
+			WHERE we.workout_id = w.id AND (
+				we.exercise_name ILIKE `+arg+` OR ex.name ILIKE `+arg+` OR LOWER(`+arg+`) = ANY(ex.aliases)
+			)
+		)`)
+	}
+
+	page := &WorkoutPage{Workouts: []Workout{}}
+
+	// The total ignores the cursor so clients can show "x of N"
+	countQuery := `SELECT COUNT(*) FROM workouts w WHERE ` + strings.Join(conditions, " AND ")
+	err := pg.db.QueryRow(countQuery, args...).Scan(&page.TotalCount)
+	if err != nil {
+		return nil, err
+	}
+
+	if filter.Cursor != "" {
+		sortValue, lastID, err := decodeWorkoutCursor(filter.Sort, filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s, w.id) < (%s, %s)", sortColumn, addArg(sortValue), addArg(lastID)))
+	}
+
+	// Fetch one extra row to know whether there is a next page
+	query := fmt.Sprintf(`
+	SELECT w.id, w.user_id, w.title, w.description, w.duration_minutes, COALESCE(w.calories_burned, 0), COALESCE(w.calories_source, ''),
+		w.performed_at, w.started_at, w.ended_at, w.created_at, w.updated_at, w.program_day_id, w.session_rpe
+	FROM workouts w
+	WHERE %s
+	ORDER BY %s DESC, w.id DESC
+	LIMIT %s
+	`, strings.Join(conditions, " AND "), sortColumn, addArg(filter.Limit+1))
+
+	rows, err := pg.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var workout Workout
+		err = rows.Scan(
+			&workout.ID,
+			&workout.UserID,
+			&workout.Title,
+			&workout.Description,
+			&workout.DurationMinutes,
+			&workout.CaloriesBurned,
+			&workout.CaloriesSource,
+			&workout.PerformedAt,
+			&workout.StartedAt,
+			&workout.EndedAt,
+			&workout.CreatedAt,
+			&workout.UpdatedAt,
+			&workout.ProgramDayID,
+			&workout.SessionRPE,
+		)
+		if err != nil {
+			return nil, err
+		}
+		page.Workouts = append(page.Workouts, workout)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Workouts) > filter.Limit {
+		page.Workouts = page.Workouts[:filter.Limit]
+		last := page.Workouts[len(page.Workouts)-1]
+
+		var sortValue string
+		switch filter.Sort {
+		case WorkoutSortNewest:
+			sortValue = last.PerformedAt.Format(time.RFC3339Nano)
+		case WorkoutSortLongest:
+			sortValue = strconv.Itoa(last.DurationMinutes)
+		case WorkoutSortCalories:
+			sortValue = strconv.Itoa(last.CaloriesBurned)
+		}
+		page.NextCursor = encodeWorkoutCursor(filter.Sort, sortValue, last.ID)
+	}
+
+	pageWorkouts := make([]*Workout, len(page.Workouts))
+	for i := range page.Workouts {
+		pageWorkouts[i] = &page.Workouts[i]
+	}
+	err = pg.loadEntries(pageWorkouts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// insertEntries inserts the groups of the workout, every entry and the sets of each entry inside tx.
+// Generated ids are written back into workout.Groups and workout.Entries, and set numbers
+// default to their position in the list when the client didn't send them.
+// Groups are expected to be validated with ValidateGroups already.
+func insertEntries(tx *sql.Tx, workout *Workout) error {
+	groupQuery := `
+	INSERT INTO workout_entry_groups (workout_id, label, group_type, rounds, rest_between_rounds_seconds)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id
+	`
+	entryQuery := `
+	INSERT INTO workout_entries (workout_id, exercise_id, exercise_name, notes, order_index, group_id, interval_timings)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING id
+	`
+	setQuery := `
+	INSERT INTO workout_sets (workout_entry_id, set_number, reps, duration_seconds, weight, rpe, set_type, completed,
+		rest_seconds, prescribed_rest_seconds)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	RETURNING id
+	`
+
+	groupIDs := make(map[string]int, len(workout.Groups))
+	for i := range workout.Groups {
+		group := &workout.Groups[i]
+		err := tx.QueryRow(groupQuery, workout.ID, group.Label, group.Type, group.Rounds, group.RestBetweenRoundsSeconds).Scan(&group.ID)
+		if err != nil {
+			return err
+		}
+		groupIDs[group.Label] = group.ID
+	}
+
+	for i := range workout.Entries {
+		entry := &workout.Entries[i]
+		var groupID *int
+		if id, ok := groupIDs[entry.Group]; ok {
+			groupID = &id
+		}
+
+		// Timings are only ever read back as a whole, so they are kept as JSON
+		var intervals []byte
+		if len(entry.Intervals) > 0 {
+			var err error
+			intervals, err = json.Marshal(entry.Intervals)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := tx.QueryRow(entryQuery, workout.ID, entry.ExerciseID, entry.ExerciseName, entry.Notes, entry.OrderIndex, groupID, intervals).Scan(&entry.ID)
+		if err != nil {
+			return err
+		}
+
+		for j := range entry.Sets {
+			set := &entry.Sets[j]
+			if set.SetNumber == 0 {
+				set.SetNumber = j + 1
+			}
+			if set.SetType == "" {
+				set.SetType = SetTypeWorking
+			}
+
+			err = tx.QueryRow(setQuery, entry.ID, set.SetNumber, set.Reps, set.DurationSeconds, set.Weight, set.RPE, set.SetType, set.Completed,
+				set.RestSeconds, set.PrescribedRestSeconds).Scan(&set.ID)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// loadEntries fills in the groups, entries, sets and cardio metrics of every workout
+// with four queries, instead of queries per workout.
+func (pg *PostgresWorkoutStore) loadEntries(workouts ...*Workout) error {
+	if len(workouts) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(workouts))
+	byID := make(map[int]*Workout, len(workouts))
+	for i, workout := range workouts {
+		ids[i] = int64(workout.ID)
+		byID[workout.ID] = workout
+	}
+
+	groupQuery := `
+	SELECT workout_id, id, label, group_type, rounds, rest_between_rounds_seconds
+	FROM workout_entry_groups
+	WHERE workout_id = ANY($1)
+	ORDER BY workout_id, id
+	`
+
+	groupRows, err := pg.db.Query(groupQuery, ids)
+	if err != nil {
+		return err
+	}
+	defer groupRows.Close()
+
+	for groupRows.Next() {
+		var workoutID int
+		var group EntryGroup
+		err = groupRows.Scan(&workoutID, &group.ID, &group.Label, &group.Type, &group.Rounds, &group.RestBetweenRoundsSeconds)
+		if err != nil {
+			return err
+		}
+		workout := byID[workoutID]
+		workout.Groups = append(workout.Groups, group)
+	}
+	if err = groupRows.Err(); err != nil {
+		return err
+	}
+
+	query := `
+	SELECT e.workout_id, e.id, e.exercise_id, e.exercise_name, e.notes, e.order_index, COALESCE(g.label, ''), e.interval_timings
+	FROM workout_entries e
+	LEFT JOIN workout_entry_groups g ON g.id = e.group_id
+	WHERE e.workout_id = ANY($1)
+	ORDER BY e.workout_id, e.order_index, e.id
+	`
+
+	rows, err := pg.db.Query(query, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var workoutID int
+		var entry WorkoutEntry
+		var intervals []byte
+		err = rows.Scan(
+			&workoutID,
+			&entry.ID,
+			&entry.ExerciseID,
+			&entry.ExerciseName,
+			&entry.Notes,
+			&entry.OrderIndex,
+			&entry.Group,
+			&intervals,
+		)
+		if err != nil {
+			return err
+		}
+		if len(intervals) > 0 {
+			if err = json.Unmarshal(intervals, &entry.Intervals); err != nil {
+				return err
+			}
+		}
+		workout := byID[workoutID]
+		workout.Entries = append(workout.Entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	// Only take pointers once every entry has been appended, so they stay valid
+	entriesByID := make(map[int]*WorkoutEntry)
+	for _, workout := range workouts {
+		for i := range workout.Entries {
+			entriesByID[workout.Entries[i].ID] = &workout.Entries[i]
+		}
+	}
+
+	setQuery := `
+	SELECT s.workout_entry_id, s.id, s.set_number, s.reps, s.duration_seconds, s.weight, s.rpe, s.set_type, s.completed,
+		s.rest_seconds, s.prescribed_rest_seconds
+	FROM workout_sets s
+	INNER JOIN workout_entries e ON e.id = s.workout_entry_id
+	WHERE e.workout_id = ANY($1)
+	ORDER BY s.workout_entry_id, s.set_number
+	`
+
+	setRows, err := pg.db.Query(setQuery, ids)
+	if err != nil {
+		return err
+	}
+	defer setRows.Close()
+
+	for setRows.Next() {
+		var entryID int
+		var set WorkoutSet
+		err = setRows.Scan(
+			&entryID,
+			&set.ID,
+			&set.SetNumber,
+			&set.Reps,
+			&set.DurationSeconds,
+			&set.Weight,
+			&set.RPE,
+			&set.SetType,
+			&set.Completed,
+			&set.RestSeconds,
+			&set.PrescribedRestSeconds,
+		)
+		if err != nil {
+			return err
+		}
+		entry := entriesByID[entryID]
+		entry.Sets = append(entry.Sets, set)
+	}
+	if err = setRows.Err(); err != nil {
+		return err
+	}
+
+	return loadCardio(pg.db, ids, byID)
+}
+
+// encodeWorkoutCursor packs the sort option, sort value and id into an opaque string.
+// The sort option is included so a cursor can't be reused with a different ordering.
+func encodeWorkoutCursor(sort, sortValue string, id int) string {
+	raw := fmt.Sprintf("%s|%s|%d", sort, sortValue, id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeWorkoutCursor reverses encodeWorkoutCursor and converts the sort value
+// back into the type the ORDER BY column expects.
+func decodeWorkoutCursor(sort, cursor string) (interface{}, int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 || parts[0] != sort {
+		return nil, 0, ErrInvalidCursor
+	}
+
+	id, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+
+	if sort == WorkoutSortNewest {
+		t, err := time.Parse(time.RFC3339Nano, parts[1])
+		if err != nil {
+			return nil, 0, ErrInvalidCursor
+		}
+		return t, id, nil
+	}
+
+	value, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+	return value, id, nil
+}
+
+// resolveCalories marks calories sent by the client as measured, and estimates them from
+// the workout's entries, duration and the user's bodyweight otherwise. Estimated calories
+// are estimated again on every update, so they follow changes to the workout; the entries
+// must already be linked to the catalog.
+func resolveCalories(tx *sql.Tx, workout *Workout) error {
+	if workout.CaloriesSource != CaloriesEstimated && workout.CaloriesBurned > 0 {
+		workout.CaloriesSource = CaloriesMeasured
+	} else {
+		estimated, err := estimateCalories(tx, workout)
+		if err != nil {
+			return err
+		}
+		workout.CaloriesBurned, workout.CaloriesSource = estimated, ""
+		if estimated > 0 {
+			workout.CaloriesSource = CaloriesEstimated
+		}
+	}
+
+	_, err := tx.Exec(`UPDATE workouts SET calories_burned = $1, calories_source = NULLIF($2, '') WHERE id = $3`,
+		workout.CaloriesBurned, workout.CaloriesSource, workout.ID)
+	return err
+}
+
+// estimateCalories runs the MET based estimate for the workout. Entries use the MET value
+// of their catalog exercise, or of its movement pattern; time not covered by the entries
+// uses the activity the title names. The bodyweight is the one logged closest before the
+// workout, or the first one logged after it.
+func estimateCalories(tx *sql.Tx, workout *Workout) (int, error) {
+	session := calories.Session{DurationMinutes: workout.DurationMinutes}
+	if met, ok := calories.ActivityMET(workout.Title); ok {
+		session.ActivityMET = met
+	}
+
+	query := `
+	SELECT bodyweight_kg
+	FROM body_measurements
+	WHERE user_id = $1 AND bodyweight_kg IS NOT NULL
+	ORDER BY measured_at > $2, ABS(EXTRACT(EPOCH FROM measured_at - $2))
+	LIMIT 1
+	`
+	err := tx.QueryRow(query, workout.UserID, workout.PerformedAt).Scan(&session.BodyweightKg)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	if len(workout.Entries) > 0 {
+		catalog, err := loadExerciseCatalog(tx, workout.UserID)
+		if err != nil {
+			return 0, err
+		}
+		byID := make(map[int]*Exercise, len(catalog))
+		for i := range catalog {
+			byID[catalog[i].ID] = &catalog[i]
+		}
+
+		for _, entry := range workout.Entries {
+			met := calories.DefaultMET
+			if entry.ExerciseID != nil {
+				if exercise, ok := byID[*entry.ExerciseID]; ok {
+					met = calories.PatternMET(exercise.MovementPattern)
+					if exercise.MET != nil {
+						met = *exercise.MET
+					}
+				}
+			}
+
+			sets := make([]calories.Set, 0, len(entry.Sets))
+			for _, set := range entry.Sets {
+				sets = append(sets, calories.Set{
+					Reps:            set.Reps,
+					DurationSeconds: set.DurationSeconds,
+					RestSeconds:     set.RestSeconds,
+					Completed:       set.Completed,
+				})
+			}
+			session.Entries = append(session.Entries, calories.Entry{MET: met, Sets: sets})
+		}
+	}
+
+	return calories.Estimate(session), nil
+}
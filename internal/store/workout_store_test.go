@@ -2,8 +2,11 @@ package store
 
 import (
 	"database/sql"
+	"encoding/json"
 	"testing"
+	"time"
 
+	"github.com/Krishna-Mehta-135/go-workout-tracker/internal/migrations"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -15,8 +18,8 @@ func setupTestDB(t *testing.T) *sql.DB {
 		t.Fatalf("opening test db: %v", err)
 	}
 
-	// run the migratoins for our test db
-	err = Migrate(db, "../../migrations/")
+	// run the migratoins for our test db, the same embedded ones the app runs
+	err = MigrateFS(db, migrations.FS, ".")
 	if err != nil {
 		t.Fatalf("migrating test db error: %v", err)
 	}
@@ -63,11 +66,13 @@ func TestCreateWorkout(t *testing.T) {
 				Entries: []WorkoutEntry{
 					{
 						ExerciseName: "Bench press",
-						Sets:         3,
-						Reps:         IntPtr(10),
-						Weight:       FloatPtr(135.5),
-						Notes:        "warm up properly",
-						OrderIndex:   1,
+						Sets: []WorkoutSet{
+							{SetNumber: 1, Reps: IntPtr(12), Weight: FloatPtr(95), SetType: SetTypeWarmup, Completed: true},
+							{SetNumber: 2, Reps: IntPtr(10), Weight: FloatPtr(135.5), SetType: SetTypeWorking, Completed: true},
+							{SetNumber: 3, Reps: IntPtr(6), Weight: FloatPtr(135.5), RPE: FloatPtr(9.5), SetType: SetTypeFailure, Completed: false},
+						},
+						Notes:      "warm up properly",
+						OrderIndex: 1,
 					},
 				},
 			},
@@ -84,19 +89,19 @@ func TestCreateWorkout(t *testing.T) {
 				Entries: []WorkoutEntry{
 					{
 						ExerciseName: "Plank",
-						Sets:         3,
-						Reps:         IntPtr(60),
-						Notes:        "keep form",
-						OrderIndex:   1,
+						Sets: []WorkoutSet{
+							{Reps: IntPtr(60), SetType: SetTypeWorking, Completed: true},
+						},
+						Notes:      "keep form",
+						OrderIndex: 1,
 					},
 					{
-						ExerciseName:    "squats",
-						Sets:            4,
-						Reps:            IntPtr(12),
-						DurationSeconds: IntPtr(60),
-						Weight:          FloatPtr(185.0),
-						Notes:           "full depth",
-						OrderIndex:      2,
+						ExerciseName: "squats",
+						Sets: []WorkoutSet{
+							{Reps: IntPtr(12), DurationSeconds: IntPtr(60), Weight: FloatPtr(185.0), SetType: SetTypeWorking, Completed: true},
+						},
+						Notes:      "full depth",
+						OrderIndex: 2,
 					},
 				},
 			},
@@ -125,7 +130,12 @@ func TestCreateWorkout(t *testing.T) {
 
 			for i := range retrieved.Entries {
 				assert.Equal(t, tt.workout.Entries[i].ExerciseName, retrieved.Entries[i].ExerciseName)
-				assert.Equal(t, tt.workout.Entries[i].Sets, retrieved.Entries[i].Sets)
+				assert.Equal(t, len(tt.workout.Entries[i].Sets), len(retrieved.Entries[i].Sets))
+				for j := range retrieved.Entries[i].Sets {
+					assert.Equal(t, tt.workout.Entries[i].Sets[j].SetNumber, retrieved.Entries[i].Sets[j].SetNumber)
+					assert.Equal(t, tt.workout.Entries[i].Sets[j].SetType, retrieved.Entries[i].Sets[j].SetType)
+					assert.Equal(t, tt.workout.Entries[i].Sets[j].Completed, retrieved.Entries[i].Sets[j].Completed)
+				}
 				assert.Equal(t, tt.workout.Entries[i].OrderIndex, retrieved.Entries[i].OrderIndex)
 			}
 
@@ -133,6 +143,149 @@ func TestCreateWorkout(t *testing.T) {
 	}
 }
 
+func TestResolveTimes(t *testing.T) {
+	start := time.Date(2026, 3, 1, 18, 0, 0, 0, time.UTC)
+	end := start.Add(75 * time.Minute)
+
+	t.Run("derives duration and performed_at from start/end", func(t *testing.T) {
+		workout := &Workout{StartedAt: &start, EndedAt: &end}
+		require.NoError(t, workout.ResolveTimes())
+		assert.Equal(t, 75, workout.DurationMinutes)
+		assert.Equal(t, start, workout.PerformedAt)
+	})
+
+	t.Run("keeps an explicit duration", func(t *testing.T) {
+		workout := &Workout{StartedAt: &start, EndedAt: &end, DurationMinutes: 60}
+		require.NoError(t, workout.ResolveTimes())
+		assert.Equal(t, 60, workout.DurationMinutes)
+	})
+
+	t.Run("rejects end before start", func(t *testing.T) {
+		workout := &Workout{StartedAt: &end, EndedAt: &start}
+		assert.Error(t, workout.ResolveTimes())
+	})
+}
+
+func TestWorkoutEntryUnmarshalJSON(t *testing.T) {
+	t.Run("per-set payload keeps each set and fills defaults", func(t *testing.T) {
+		var entry WorkoutEntry
+		err := json.Unmarshal([]byte(`{"exercise_name":"Bench","sets":[{"reps":5,"weight":100},{"reps":3,"weight":110,"set_type":"failure","completed":false}]}`), &entry)
+		require.NoError(t, err)
+		require.Len(t, entry.Sets, 2)
+		assert.Equal(t, SetTypeWorking, entry.Sets[0].SetType)
+		assert.True(t, entry.Sets[0].Completed)
+		assert.Equal(t, SetTypeFailure, entry.Sets[1].SetType)
+		assert.False(t, entry.Sets[1].Completed)
+	})
+
+	t.Run("aggregate payload expands into working sets", func(t *testing.T) {
+		var entry WorkoutEntry
+		err := json.Unmarshal([]byte(`{"exercise_name":"Bench","sets":3,"reps":10,"weight":60}`), &entry)
+		require.NoError(t, err)
+		require.Len(t, entry.Sets, 3)
+		for i, set := range entry.Sets {
+			assert.Equal(t, i+1, set.SetNumber)
+			assert.Equal(t, 10, *set.Reps)
+			assert.Equal(t, 60.0, *set.Weight)
+		}
+	})
+}
+
+func TestValidateGroups(t *testing.T) {
+	entries := func(groups ...string) []WorkoutEntry {
+		list := make([]WorkoutEntry, len(groups))
+		for i, group := range groups {
+			list[i] = WorkoutEntry{ExerciseName: "exercise", OrderIndex: i + 1, Group: group}
+		}
+		return list
+	}
+
+	tests := []struct {
+		name    string
+		workout Workout
+		wantErr bool
+	}{
+		{
+			name:    "no groups",
+			workout: Workout{Entries: entries("", "")},
+		},
+		{
+			name: "superset and emom",
+			workout: Workout{
+				Groups:  []EntryGroup{{Label: "A", Type: GroupTypeSuperset, Rounds: IntPtr(3)}, {Label: "B", Type: GroupTypeEMOM}},
+				Entries: entries("", "A", "A", "B"),
+			},
+		},
+		{
+			name: "entries out of slice order but contiguous by order_index",
+			workout: Workout{
+				Groups: []EntryGroup{{Label: "A", Type: GroupTypeSuperset}},
+				Entries: []WorkoutEntry{
+					{ExerciseName: "row", OrderIndex: 3, Group: "A"},
+					{ExerciseName: "squat", OrderIndex: 1},
+					{ExerciseName: "bench", OrderIndex: 2, Group: "A"},
+				},
+			},
+		},
+		{
+			name: "group split by another entry",
+			workout: Workout{
+				Groups:  []EntryGroup{{Label: "A", Type: GroupTypeCircuit}},
+				Entries: entries("A", "", "A"),
+			},
+			wantErr: true,
+		},
+		{
+			name:    "unknown label",
+			workout: Workout{Entries: entries("Z")},
+			wantErr: true,
+		},
+		{
+			name: "unused group",
+			workout: Workout{
+				Groups:  []EntryGroup{{Label: "A", Type: GroupTypeAMRAP}},
+				Entries: entries(""),
+			},
+			wantErr: true,
+		},
+		{
+			name: "superset with one exercise",
+			workout: Workout{
+				Groups:  []EntryGroup{{Label: "A", Type: GroupTypeSuperset}},
+				Entries: entries("A"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown type",
+			workout: Workout{
+				Groups:  []EntryGroup{{Label: "A", Type: "giant"}},
+				Entries: entries("A", "A"),
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate label",
+			workout: Workout{
+				Groups:  []EntryGroup{{Label: "A", Type: GroupTypeEMOM}, {Label: "A", Type: GroupTypeEMOM}},
+				Entries: entries("A"),
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.workout.ValidateGroups()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
 func IntPtr(i int) *int {
 	return &i
 }
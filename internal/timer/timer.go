@@ -0,0 +1,288 @@
+package timer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Protocol names accepted by NewProtocol.
+const (
+	ProtocolTabata = "tabata"
+	ProtocolEMOM   = "emom"
+	ProtocolCustom = "custom"
+)
+
+// Phase kinds.
+const (
+	PhaseWork = "work"
+	PhaseRest = "rest"
+)
+
+// Event types emitted while a timer runs.
+const (
+	EventTick         = "timer_tick"
+	EventPhaseChanged = "timer_phase_changed"
+	EventFinished     = "timer_finished"
+)
+
+// ErrAlreadyRunning is returned when a session already has a running timer.
+var ErrAlreadyRunning = errors.New("a timer is already running for this session")
+
+// Phase is one planned work or rest interval.
+type Phase struct {
+	Kind    string `json:"kind"`
+	Round   int    `json:"round"`
+	Seconds int    `json:"seconds"`
+}
+
+// Protocol is the full schedule a timer runs through.
+type Protocol struct {
+	Name   string  `json:"name"`
+	Phases []Phase `json:"phases"`
+}
+
+// ProtocolOptions configures NewProtocol. Zero values fall back to the protocol's defaults.
+type ProtocolOptions struct {
+	Rounds      int
+	WorkSeconds int
+	RestSeconds int
+	// Phases is used by the custom protocol instead of rounds of work and rest
+	Phases []Phase
+}
+
+// NewProtocol builds the phases of a named protocol:
+//   - tabata: 8 rounds of 20s work and 10s rest by default
+//   - emom: one 60s work phase per minute; whatever is left of the minute is rest, so there are no rest phases
+//   - custom: rounds of work and rest, or an explicit list of phases
+//
+// The last rest phase is dropped, the protocol ends when the work is done.
+func NewProtocol(name string, opts ProtocolOptions) (Protocol, error) {
+	protocol := Protocol{Name: name}
+
+	switch name {
+	case ProtocolTabata:
+		rounds, work, rest := withDefault(opts.Rounds, 8), withDefault(opts.WorkSeconds, 20), withDefault(opts.RestSeconds, 10)
+		protocol.Phases = alternate(rounds, work, rest)
+	case ProtocolEMOM:
+		rounds, work := withDefault(opts.Rounds, 10), withDefault(opts.WorkSeconds, 60)
+		protocol.Phases = alternate(rounds, work, 0)
+	case ProtocolCustom:
+		if len(opts.Phases) > 0 {
+			protocol.Phases = make([]Phase, len(opts.Phases))
+			copy(protocol.Phases, opts.Phases)
+			for i := range protocol.Phases {
+				if protocol.Phases[i].Round == 0 {
+					protocol.Phases[i].Round = i + 1
+				}
+			}
+			break
+		}
+		if opts.Rounds < 1 || opts.WorkSeconds < 1 {
+			return Protocol{}, errors.New("custom timers need rounds and work_seconds, or a list of phases")
+		}
+		protocol.Phases = alternate(opts.Rounds, opts.WorkSeconds, opts.RestSeconds)
+	default:
+		return Protocol{}, fmt.Errorf("unknown protocol %q", name)
+	}
+
+	for _, phase := range protocol.Phases {
+		if phase.Kind != PhaseWork && phase.Kind != PhaseRest {
+			return Protocol{}, fmt.Errorf("phase kind must be %s or %s", PhaseWork, PhaseRest)
+		}
+		if phase.Seconds < 1 {
+			return Protocol{}, errors.New("every phase needs at least one second")
+		}
+	}
+	if len(protocol.Phases) > 500 {
+		return Protocol{}, errors.New("a timer can have at most 500 phases")
+	}
+
+	return protocol, nil
+}
+
+// withDefault returns value, or fallback when value isn't set.
+func withDefault(value, fallback int) int {
+	if value > 0 {
+		return value
+	}
+	return fallback
+}
+
+// alternate builds rounds of work followed by rest, without a trailing rest.
+func alternate(rounds, work, rest int) []Phase {
+	phases := make([]Phase, 0, rounds*2)
+	for round := 1; round <= rounds; round++ {
+		phases = append(phases, Phase{Kind: PhaseWork, Round: round, Seconds: work})
+		if rest > 0 && round < rounds {
+			phases = append(phases, Phase{Kind: PhaseRest, Round: round, Seconds: rest})
+		}
+	}
+	return phases
+}
+
+// PhaseLog is what actually happened during a phase.
+type PhaseLog struct {
+	Kind           string    `json:"kind"`
+	Round          int       `json:"round"`
+	PlannedSeconds int       `json:"planned_seconds"`
+	ActualSeconds  float64   `json:"actual_seconds"`
+	StartedAt      time.Time `json:"started_at"`
+	EndedAt        time.Time `json:"ended_at"`
+}
+
+// Log is the record of a timer run. Completed is false when it was stopped early,
+// in which case the last phase is cut short.
+type Log struct {
+	Protocol  string     `json:"protocol"`
+	Completed bool       `json:"completed"`
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   time.Time  `json:"ended_at"`
+	Phases    []PhaseLog `json:"phases"`
+}
+
+// Event is emitted to the clients of the session while the timer runs.
+type Event struct {
+	Type             string  `json:"type"`
+	Phase            *Phase  `json:"phase,omitempty"`
+	PhaseIndex       int     `json:"phase_index"`
+	RemainingSeconds float64 `json:"remaining_seconds"`
+	Log              *Log    `json:"log,omitempty"`
+}
+
+// Service runs at most one timer per session in the background.
+type Service struct {
+	mu       sync.Mutex
+	running  map[int]*run
+	interval time.Duration
+}
+
+// run is a timer in progress; stopped closes once its goroutine is gone.
+type run struct {
+	cancel  context.CancelFunc
+	stopped chan struct{}
+}
+
+// NewService creates a Service that ticks once per second.
+func NewService() *Service {
+	return &Service{running: map[int]*run{}, interval: time.Second}
+}
+
+// Start runs the protocol for the session. emit receives every tick and phase change;
+// done receives the log exactly once, when the protocol ends or the timer is stopped.
+// Both are called from the timer goroutine.
+func (s *Service) Start(sessionID int, protocol Protocol, emit func(Event), done func(Log)) error {
+	if len(protocol.Phases) == 0 {
+		return errors.New("the protocol has no phases")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.running[sessionID]; ok {
+		return ErrAlreadyRunning
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	current := &run{cancel: cancel, stopped: make(chan struct{})}
+	s.running[sessionID] = current
+
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		log := Run(ctx, protocol, time.Now(), ticker.C, time.Now, emit)
+
+		s.mu.Lock()
+		delete(s.running, sessionID)
+		s.mu.Unlock()
+
+		done(log)
+		close(current.stopped)
+	}()
+
+	return nil
+}
+
+// Stop ends the session's timer early and waits until its log was handed to done.
+// Returns false if no timer was running.
+func (s *Service) Stop(sessionID int) bool {
+	s.mu.Lock()
+	current, ok := s.running[sessionID]
+	s.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	current.cancel()
+	<-current.stopped
+	return true
+}
+
+// Running reports whether the session has a timer in progress.
+func (s *Service) Running(sessionID int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.running[sessionID]
+	return ok
+}
+
+// Run drives the protocol with the given ticks until every phase is done or ctx is cancelled.
+// Phase boundaries are taken from the tick times, so the log holds the timings clients saw;
+// now is only used to close the current phase when the timer is stopped.
+func Run(ctx context.Context, protocol Protocol, start time.Time, ticks <-chan time.Time, now func() time.Time, emit func(Event)) Log {
+	log := Log{Protocol: protocol.Name, StartedAt: start, Phases: make([]PhaseLog, 0, len(protocol.Phases))}
+
+	index := 0
+	phaseStart := start
+	closePhase := func(end time.Time) {
+		phase := protocol.Phases[index]
+		log.Phases = append(log.Phases, PhaseLog{
+			Kind:           phase.Kind,
+			Round:          phase.Round,
+			PlannedSeconds: phase.Seconds,
+			ActualSeconds:  end.Sub(phaseStart).Seconds(),
+			StartedAt:      phaseStart,
+			EndedAt:        end,
+		})
+	}
+	phaseChanged := func() {
+		phase := protocol.Phases[index]
+		emit(Event{Type: EventPhaseChanged, Phase: &phase, PhaseIndex: index, RemainingSeconds: float64(phase.Seconds)})
+	}
+
+	phaseChanged()
+	for {
+		select {
+		case <-ctx.Done():
+			end := now()
+			closePhase(end)
+			log.EndedAt = end
+			emit(Event{Type: EventFinished, PhaseIndex: index, Log: &log})
+			return log
+
+		case tick := <-ticks:
+			planned := time.Duration(protocol.Phases[index].Seconds) * time.Second
+			if tick.Sub(phaseStart) < planned {
+				phase := protocol.Phases[index]
+				remaining := (planned - tick.Sub(phaseStart)).Seconds()
+				emit(Event{Type: EventTick, Phase: &phase, PhaseIndex: index, RemainingSeconds: remaining})
+				continue
+			}
+
+			closePhase(tick)
+			phaseStart = tick
+			index++
+			if index == len(protocol.Phases) {
+				log.Completed = true
+				log.EndedAt = tick
+				emit(Event{Type: EventFinished, PhaseIndex: index - 1, Log: &log})
+				return log
+			}
+			phaseChanged()
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package timer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewProtocol(t *testing.T) {
+	tests := []struct {
+		name     string
+		protocol string
+		opts     ProtocolOptions
+		wantErr  bool
+		want     []Phase
+	}{
+		{
+			name:     "tabata defaults",
+			protocol: ProtocolTabata,
+			want: func() []Phase {
+				var phases []Phase
+				for round := 1; round <= 8; round++ {
+					phases = append(phases, Phase{Kind: PhaseWork, Round: round, Seconds: 20})
+					if round < 8 {
+						phases = append(phases, Phase{Kind: PhaseRest, Round: round, Seconds: 10})
+					}
+				}
+				return phases
+			}(),
+		},
+		{
+			name:     "emom has no rest phases",
+			protocol: ProtocolEMOM,
+			opts:     ProtocolOptions{Rounds: 3},
+			want: []Phase{
+				{Kind: PhaseWork, Round: 1, Seconds: 60},
+				{Kind: PhaseWork, Round: 2, Seconds: 60},
+				{Kind: PhaseWork, Round: 3, Seconds: 60},
+			},
+		},
+		{
+			name:     "custom rounds",
+			protocol: ProtocolCustom,
+			opts:     ProtocolOptions{Rounds: 2, WorkSeconds: 40, RestSeconds: 20},
+			want: []Phase{
+				{Kind: PhaseWork, Round: 1, Seconds: 40},
+				{Kind: PhaseRest, Round: 1, Seconds: 20},
+				{Kind: PhaseWork, Round: 2, Seconds: 40},
+			},
+		},
+		{
+			name:     "custom phases are numbered",
+			protocol: ProtocolCustom,
+			opts:     ProtocolOptions{Phases: []Phase{{Kind: PhaseWork, Seconds: 30}, {Kind: PhaseRest, Seconds: 90}}},
+			want: []Phase{
+				{Kind: PhaseWork, Round: 1, Seconds: 30},
+				{Kind: PhaseRest, Round: 2, Seconds: 90},
+			},
+		},
+		{
+			name:     "custom without rounds",
+			protocol: ProtocolCustom,
+			wantErr:  true,
+		},
+		{
+			name:     "unknown phase kind",
+			protocol: ProtocolCustom,
+			opts:     ProtocolOptions{Phases: []Phase{{Kind: "sprint", Seconds: 30}}},
+			wantErr:  true,
+		},
+		{
+			name:     "zero length phase",
+			protocol: ProtocolCustom,
+			opts:     ProtocolOptions{Phases: []Phase{{Kind: PhaseWork}}},
+			wantErr:  true,
+		},
+		{
+			name:     "unknown protocol",
+			protocol: "fartlek",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			protocol, err := NewProtocol(tt.protocol, tt.opts)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.protocol, protocol.Name)
+			assert.Equal(t, tt.want, protocol.Phases)
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	start := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	second := func(n int) time.Time { return start.Add(time.Duration(n) * time.Second) }
+	protocol := Protocol{Name: ProtocolCustom, Phases: []Phase{
+		{Kind: PhaseWork, Round: 1, Seconds: 2},
+		{Kind: PhaseRest, Round: 1, Seconds: 1},
+		{Kind: PhaseWork, Round: 2, Seconds: 2},
+	}}
+
+	t.Run("runs every phase", func(t *testing.T) {
+		ticks := make(chan time.Time, 10)
+		// The rest phase ends a second late, as it would on a busy server
+		for _, n := range []int{1, 2, 3, 4, 5, 6} {
+			ticks <- second(n)
+		}
+
+		var events []Event
+		log := Run(context.Background(), protocol, start, ticks, time.Now, func(event Event) {
+			events = append(events, event)
+		})
+
+		assert.True(t, log.Completed)
+		assert.Equal(t, start, log.StartedAt)
+		assert.Equal(t, second(5), log.EndedAt)
+		require.Len(t, log.Phases, 3)
+		assert.Equal(t, 2.0, log.Phases[0].ActualSeconds)
+		assert.Equal(t, 1.0, log.Phases[1].ActualSeconds)
+		assert.Equal(t, 1, log.Phases[1].PlannedSeconds)
+		assert.Equal(t, 2.0, log.Phases[2].ActualSeconds)
+		assert.Equal(t, second(3), log.Phases[2].StartedAt)
+
+		var types []string
+		for _, event := range events {
+			types = append(types, event.Type)
+		}
+		assert.Equal(t, []string{
+			EventPhaseChanged, EventTick,
+			EventPhaseChanged,
+			EventPhaseChanged, EventTick,
+			EventFinished,
+		}, types)
+		assert.Equal(t, 1.0, events[1].RemainingSeconds)
+	})
+
+	t.Run("stopped early", func(t *testing.T) {
+		ticks := make(chan time.Time, 1)
+		ticks <- second(1)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		var finished *Log
+		log := Run(ctx, protocol, start, ticks, func() time.Time { return start.Add(1500 * time.Millisecond) }, func(event Event) {
+			if event.Type == EventTick {
+				cancel()
+			}
+			if event.Type == EventFinished {
+				finished = event.Log
+			}
+		})
+
+		assert.False(t, log.Completed)
+		require.Len(t, log.Phases, 1)
+		assert.Equal(t, 1.5, log.Phases[0].ActualSeconds)
+		require.NotNil(t, finished)
+		assert.False(t, finished.Completed)
+	})
+}
+
+func TestServiceStop(t *testing.T) {
+	service := NewService()
+	protocol, err := NewProtocol(ProtocolEMOM, ProtocolOptions{Rounds: 1})
+	require.NoError(t, err)
+
+	logs := make(chan Log, 1)
+	require.NoError(t, service.Start(1, protocol, func(Event) {}, func(log Log) { logs <- log }))
+	assert.True(t, service.Running(1))
+	assert.ErrorIs(t, service.Start(1, protocol, func(Event) {}, func(Log) {}), ErrAlreadyRunning)
+
+	assert.True(t, service.Stop(1))
+	assert.False(t, service.Running(1))
+	assert.False(t, service.Stop(1))
+
+	select {
+	case log := <-logs:
+		assert.False(t, log.Completed)
+		assert.Len(t, log.Phases, 1)
+	default:
+		t.Fatal("done was not called before Stop returned")
+	}
+}
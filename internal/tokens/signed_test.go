@@ -0,0 +1,22 @@
+package tokens
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestURLSigner(t *testing.T) {
+	signer := NewURLSigner([]byte("secret"))
+	now := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+	expiry := now.Add(time.Hour)
+	signature := signer.Sign("/photos/12/full", expiry)
+
+	assert.NoError(t, signer.Verify("/photos/12/full", expiry, signature, now))
+	assert.ErrorIs(t, signer.Verify("/photos/12/full", expiry, signature, expiry), ErrSignatureExpired)
+	assert.ErrorIs(t, signer.Verify("/photos/13/full", expiry, signature, now), ErrInvalidSignature)
+	assert.ErrorIs(t, signer.Verify("/photos/12/full", expiry.Add(time.Hour), signature, now), ErrInvalidSignature)
+	assert.ErrorIs(t, signer.Verify("/photos/12/full", expiry, "not base64!", now), ErrInvalidSignature)
+	assert.ErrorIs(t, NewURLSigner([]byte("other")).Verify("/photos/12/full", expiry, signature, now), ErrInvalidSignature)
+}
@@ -0,0 +1,51 @@
+package tokens
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"time"
+)
+
+// ScopeAuth is the scope given to tokens issued by the login endpoint.
+// Scopes let us reuse the same table for other kinds of tokens later on.
+// ScopeCalendar tokens only unlock the read-only iCalendar feed, they can't authenticate API calls.
+// ScopeLoad tokens let whoever the user hands them to, e.g. a coach, read the user's training load.
+const (
+	ScopeAuth     = "authentication"
+	ScopeCalendar = "calendar"
+	ScopeLoad     = "load"
+)
+
+// Token is an opaque, expiring credential handed out to a user.
+// Only the Plaintext is ever sent to the client, and only once;
+// the database keeps the SHA-256 Hash so a leaked table can't be replayed.
+type Token struct {
+	Plaintext string    `json:"token"`
+	Hash      []byte    `json:"-"`
+	UserID    int       `json:"-"`
+	Expiry    time.Time `json:"expiry"`
+	Scope     string    `json:"-"`
+}
+
+// GenerateToken creates a new random token for the given user that expires after ttl.
+// 32 bytes of randomness are base32 encoded (without padding) so the token is URL safe.
+func GenerateToken(userID int, ttl time.Duration, scope string) (*Token, error) {
+	token := &Token{
+		UserID: userID,
+		Expiry: time.Now().Add(ttl),
+		Scope:  scope,
+	}
+
+	randomBytes := make([]byte, 32)
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	token.Plaintext = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
+	hash := sha256.Sum256([]byte(token.Plaintext))
+	token.Hash = hash[:]
+
+	return token, nil
+}
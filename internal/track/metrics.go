@@ -0,0 +1,282 @@
+package track
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// earthRadiusMeters is the mean radius used by the haversine distance.
+	earthRadiusMeters = 6371008.8
+
+	// minMovingSpeed is the speed (m/s) below which a segment counts as standing still.
+	// Slow walking is about 1 m/s, GPS drift while stopped stays well under 0.5 m/s.
+	minMovingSpeed = 0.5
+
+	// maxPointGap is the longest gap between two points still counted as moving;
+	// a longer gap is an auto-pause or the device losing its fix.
+	maxPointGap = 60 * time.Second
+
+	// elevationThreshold is how much the elevation must change before it counts as a climb
+	// or a descent, so barometer and GPS noise on flat ground doesn't add up.
+	elevationThreshold = 2.0
+
+	// ZoneCount is the number of heart rate zones.
+	ZoneCount = 5
+)
+
+// zoneFloors are the lower bounds of the zones as a fraction of the maximum heart rate:
+// zone 1 starts at 50%, zone 5 at 90%. Time below 50% isn't counted in any zone.
+var zoneFloors = [ZoneCount]float64{0.5, 0.6, 0.7, 0.8, 0.9}
+
+// Metrics are the cardio metrics of one track, or of all the tracks of a workout.
+//   - PaceSecondsPerKm and AverageSpeedKPH are over the moving time, nil without a distance
+//   - heart rate fields are nil when the recording has no heart rate
+//   - HeartRateZones is the time in seconds spent in each of the 5 zones; it is only
+//     computed when the user has set their maximum heart rate
+type Metrics struct {
+	DistanceMeters      float64  `json:"distance_meters"`
+	ElapsedSeconds      int      `json:"elapsed_seconds"`
+	MovingSeconds       int      `json:"moving_seconds"`
+	PaceSecondsPerKm    *float64 `json:"pace_seconds_per_km"`
+	AverageSpeedKPH     *float64 `json:"average_speed_kph"`
+	ElevationGainMeters float64  `json:"elevation_gain_meters"`
+	ElevationLossMeters float64  `json:"elevation_loss_meters"`
+	AverageHeartRate    *int     `json:"average_heart_rate"`
+	MaxHeartRate        *int     `json:"max_heart_rate"`
+	HeartRateZones      []int    `json:"heart_rate_zones,omitempty"`
+}
+
+// Compute derives the metrics of a track from its points, which must be in time order.
+// The distance the device recorded wins over the distance between positions when the
+// track has one, since it is what the sensor measured rather than GPS jitter.
+// maxHeartRate is the user's maximum heart rate, nil if unknown.
+func Compute(points []Point, maxHeartRate *int) Metrics {
+	var metrics Metrics
+	if len(points) == 0 {
+		return metrics
+	}
+
+	metrics.ElapsedSeconds = int(points[len(points)-1].Time.Sub(points[0].Time).Seconds())
+
+	recorded := hasRecordedDistance(points)
+	// The recorded distance is cumulative, so points without one in between lose nothing
+	lastDistance := points[0].DistanceMeters
+
+	var moving time.Duration
+	for i := 1; i < len(points); i++ {
+		prev, next := points[i-1], points[i]
+		gap := next.Time.Sub(prev.Time)
+
+		var distance float64
+		measured := false
+		switch {
+		case recorded:
+			if next.DistanceMeters != nil && lastDistance != nil {
+				distance = math.Max(0, *next.DistanceMeters-*lastDistance)
+				measured = true
+			}
+			if next.DistanceMeters != nil {
+				lastDistance = next.DistanceMeters
+			}
+		case prev.Lat != nil && next.Lat != nil:
+			distance = haversine(*prev.Lat, *prev.Lon, *next.Lat, *next.Lon)
+			measured = true
+		}
+		metrics.DistanceMeters += distance
+
+		// Without a distance (an indoor recording without sensor) every recorded second counts as moving
+		if gap > 0 && gap <= maxPointGap && (!measured || distance/gap.Seconds() >= minMovingSpeed) {
+			moving += gap
+		}
+	}
+	metrics.MovingSeconds = int(moving.Seconds())
+	metrics.DistanceMeters = round(metrics.DistanceMeters, 1)
+
+	metrics.ElevationGainMeters, metrics.ElevationLossMeters = elevationChange(points)
+	metrics.AverageHeartRate, metrics.MaxHeartRate = heartRate(points)
+	metrics.HeartRateZones = Zones(points, maxHeartRate)
+	metrics.Derive()
+
+	return metrics
+}
+
+// hasRecordedDistance reports whether any point carries the distance recorded by the device.
+func hasRecordedDistance(points []Point) bool {
+	for _, point := range points {
+		if point.DistanceMeters != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Zones returns the seconds spent in each heart rate zone, nil when the maximum heart rate
+// is unknown or the track has no heart rate. The heart rate of a point holds until the next
+// one; gaps longer than maxPointGap are pauses and count in no zone.
+func Zones(points []Point, maxHeartRate *int) []int {
+	if maxHeartRate == nil || *maxHeartRate <= 0 {
+		return nil
+	}
+
+	var zones [ZoneCount]time.Duration
+	hasHeartRate := false
+	for i := 1; i < len(points); i++ {
+		prev := points[i-1]
+		gap := points[i].Time.Sub(prev.Time)
+		if prev.HeartRate == nil || gap <= 0 || gap > maxPointGap {
+			continue
+		}
+		hasHeartRate = true
+
+		fraction := float64(*prev.HeartRate) / float64(*maxHeartRate)
+		for zone := ZoneCount - 1; zone >= 0; zone-- {
+			if fraction >= zoneFloors[zone] {
+				zones[zone] += gap
+				break
+			}
+		}
+	}
+	if !hasHeartRate {
+		return nil
+	}
+
+	seconds := make([]int, ZoneCount)
+	for zone, duration := range zones {
+		seconds[zone] = int(duration.Seconds())
+	}
+	return seconds
+}
+
+// Combine adds up the metrics of several tracks of one workout, e.g. a run recorded in two parts.
+// The average heart rate is weighted by the elapsed time of each track.
+func Combine(all []Metrics) *Metrics {
+	if len(all) == 0 {
+		return nil
+	}
+	if len(all) == 1 {
+		combined := all[0]
+		combined.Derive()
+		return &combined
+	}
+
+	var combined Metrics
+	var heartRateSum float64
+	var heartRateSeconds int
+	for _, metrics := range all {
+		combined.DistanceMeters += metrics.DistanceMeters
+		combined.ElapsedSeconds += metrics.ElapsedSeconds
+		combined.MovingSeconds += metrics.MovingSeconds
+		combined.ElevationGainMeters += metrics.ElevationGainMeters
+		combined.ElevationLossMeters += metrics.ElevationLossMeters
+
+		if metrics.AverageHeartRate != nil {
+			heartRateSum += float64(*metrics.AverageHeartRate * metrics.ElapsedSeconds)
+			heartRateSeconds += metrics.ElapsedSeconds
+		}
+		if metrics.MaxHeartRate != nil && (combined.MaxHeartRate == nil || *metrics.MaxHeartRate > *combined.MaxHeartRate) {
+			maxHeartRate := *metrics.MaxHeartRate
+			combined.MaxHeartRate = &maxHeartRate
+		}
+		if metrics.HeartRateZones != nil {
+			if combined.HeartRateZones == nil {
+				combined.HeartRateZones = make([]int, ZoneCount)
+			}
+			for zone, seconds := range metrics.HeartRateZones {
+				combined.HeartRateZones[zone] += seconds
+			}
+		}
+	}
+
+	combined.DistanceMeters = round(combined.DistanceMeters, 1)
+	combined.ElevationGainMeters = round(combined.ElevationGainMeters, 1)
+	combined.ElevationLossMeters = round(combined.ElevationLossMeters, 1)
+	if heartRateSeconds > 0 {
+		average := int(math.Round(heartRateSum / float64(heartRateSeconds)))
+		combined.AverageHeartRate = &average
+	}
+	combined.Derive()
+
+	return &combined
+}
+
+// Derive sets the fields derived from the totals: the pace and the average speed,
+// from the distance and the moving time. Only the totals are stored.
+func (m *Metrics) Derive() {
+	m.PaceSecondsPerKm, m.AverageSpeedKPH = nil, nil
+	if m.DistanceMeters <= 0 || m.MovingSeconds <= 0 {
+		return
+	}
+
+	pace := round(float64(m.MovingSeconds)/(m.DistanceMeters/1000), 1)
+	speed := round(m.DistanceMeters/float64(m.MovingSeconds)*3.6, 2)
+	m.PaceSecondsPerKm = &pace
+	m.AverageSpeedKPH = &speed
+}
+
+// elevationChange adds up climbs and descents larger than elevationThreshold.
+// The reference elevation only moves once the threshold is crossed, so small
+// oscillations around it are ignored while a steady climb is counted in full.
+func elevationChange(points []Point) (gain, loss float64) {
+	var reference *float64
+	for _, point := range points {
+		if point.Elevation == nil {
+			continue
+		}
+		elevation := *point.Elevation
+		if reference == nil {
+			reference = &elevation
+			continue
+		}
+
+		diff := elevation - *reference
+		if diff >= elevationThreshold {
+			gain += diff
+			reference = &elevation
+		} else if diff <= -elevationThreshold {
+			loss -= diff
+			reference = &elevation
+		}
+	}
+	return round(gain, 1), round(loss, 1)
+}
+
+// heartRate returns the average and maximum heart rate over the points that have one.
+func heartRate(points []Point) (average, max *int) {
+	var sum, count int
+	for _, point := range points {
+		if point.HeartRate == nil || *point.HeartRate <= 0 {
+			continue
+		}
+		sum += *point.HeartRate
+		count++
+		if max == nil || *point.HeartRate > *max {
+			value := *point.HeartRate
+			max = &value
+		}
+	}
+	if count == 0 {
+		return nil, nil
+	}
+
+	value := int(math.Round(float64(sum) / float64(count)))
+	return &value, max
+}
+
+// haversine returns the great-circle distance in meters between two coordinates.
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1 := lat1 * math.Pi / 180
+	phi2 := lat2 * math.Pi / 180
+	deltaPhi := (lat2 - lat1) * math.Pi / 180
+	deltaLambda := (lon2 - lon1) * math.Pi / 180
+
+	a := math.Sin(deltaPhi/2)*math.Sin(deltaPhi/2) +
+		math.Cos(phi1)*math.Cos(phi2)*math.Sin(deltaLambda/2)*math.Sin(deltaLambda/2)
+	return 2 * earthRadiusMeters * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// round rounds to the given number of decimals.
+func round(value float64, decimals int) float64 {
+	factor := math.Pow(10, float64(decimals))
+	return math.Round(value*factor) / factor
+}
@@ -0,0 +1,205 @@
+package track
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Formats of a track. Parse reads GPX and TCX; FIT files are decoded by the fit package.
+const (
+	FormatGPX = "gpx"
+	FormatTCX = "tcx"
+	FormatFIT = "fit"
+)
+
+// Point is one recorded trackpoint. Indoor recordings (treadmill, trainer) have no position,
+// and elevation and heart rate depend on the device, so they are all optional.
+// DistanceMeters is the distance covered since the start as the device measured it
+// (a foot pod or wheel sensor), which TCX files carry with or without a position.
+type Point struct {
+	Time           time.Time `json:"time"`
+	Lat            *float64  `json:"lat"`
+	Lon            *float64  `json:"lon"`
+	Elevation      *float64  `json:"elevation"`
+	HeartRate      *int      `json:"heart_rate"`
+	DistanceMeters *float64  `json:"distance_meters,omitempty"`
+}
+
+// Track is a parsed recording, its points in time order.
+type Track struct {
+	Format string
+	Name   string
+	Points []Point
+}
+
+// Parse reads a GPX or TCX file; the format is recognized from the root element.
+// Points without a timestamp are dropped, since nothing can be computed from them.
+// Returns an error if the file isn't GPX or TCX or has fewer than two timed points.
+func Parse(data []byte) (*Track, error) {
+	format, err := detectFormat(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var track *Track
+	switch format {
+	case FormatGPX:
+		track, err = parseGPX(data)
+	case FormatTCX:
+		track, err = parseTCX(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s file: %w", format, err)
+	}
+
+	sort.SliceStable(track.Points, func(i, j int) bool {
+		return track.Points[i].Time.Before(track.Points[j].Time)
+	})
+	if len(track.Points) < 2 {
+		return nil, errors.New("the track needs at least two points with a time")
+	}
+
+	return track, nil
+}
+
+// detectFormat looks at the root element of the document.
+func detectFormat(data []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", errors.New("the file is not XML")
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			switch strings.ToLower(start.Name.Local) {
+			case "gpx":
+				return FormatGPX, nil
+			case "trainingcenterdatabase":
+				return FormatTCX, nil
+			}
+			return "", fmt.Errorf("unsupported file, expected GPX or TCX but the root element is <%s>", start.Name.Local)
+		}
+	}
+	return "", errors.New("the file is empty")
+}
+
+// gpxFile maps the parts of a GPX 1.1 file we use. Heart rate comes from the Garmin
+// TrackPointExtension most devices and apps write.
+type gpxFile struct {
+	Name   string `xml:"metadata>name"`
+	Tracks []struct {
+		Name     string `xml:"name"`
+		Segments []struct {
+			Points []struct {
+				Lat       float64  `xml:"lat,attr"`
+				Lon       float64  `xml:"lon,attr"`
+				Elevation *float64 `xml:"ele"`
+				Time      string   `xml:"time"`
+				HeartRate *int     `xml:"extensions>TrackPointExtension>hr"`
+			} `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+}
+
+func parseGPX(data []byte) (*Track, error) {
+	var file gpxFile
+	if err := xml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	track := &Track{Format: FormatGPX, Name: file.Name}
+	for _, trk := range file.Tracks {
+		if track.Name == "" {
+			track.Name = trk.Name
+		}
+		for _, segment := range trk.Segments {
+			for _, point := range segment.Points {
+				recordedAt, ok := parseTime(point.Time)
+				if !ok {
+					continue
+				}
+				lat, lon := point.Lat, point.Lon
+				track.Points = append(track.Points, Point{
+					Time:      recordedAt,
+					Lat:       &lat,
+					Lon:       &lon,
+					Elevation: point.Elevation,
+					HeartRate: point.HeartRate,
+				})
+			}
+		}
+	}
+
+	return track, nil
+}
+
+// tcxFile maps the parts of a Garmin Training Center file we use.
+type tcxFile struct {
+	Activities []struct {
+		Sport string `xml:"Sport,attr"`
+		Laps  []struct {
+			Points []struct {
+				Time           string   `xml:"Time"`
+				Lat            *float64 `xml:"Position>LatitudeDegrees"`
+				Lon            *float64 `xml:"Position>LongitudeDegrees"`
+				Elevation      *float64 `xml:"AltitudeMeters"`
+				HeartRate      *int     `xml:"HeartRateBpm>Value"`
+				DistanceMeters *float64 `xml:"DistanceMeters"`
+			} `xml:"Track>Trackpoint"`
+		} `xml:"Lap"`
+	} `xml:"Activities>Activity"`
+}
+
+func parseTCX(data []byte) (*Track, error) {
+	var file tcxFile
+	if err := xml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	track := &Track{Format: FormatTCX}
+	for _, activity := range file.Activities {
+		if track.Name == "" {
+			track.Name = activity.Sport
+		}
+		for _, lap := range activity.Laps {
+			for _, point := range lap.Points {
+				recordedAt, ok := parseTime(point.Time)
+				if !ok {
+					continue
+				}
+				// A position needs both coordinates
+				if point.Lat == nil || point.Lon == nil {
+					point.Lat, point.Lon = nil, nil
+				}
+				track.Points = append(track.Points, Point{
+					Time:           recordedAt,
+					Lat:            point.Lat,
+					Lon:            point.Lon,
+					Elevation:      point.Elevation,
+					HeartRate:      point.HeartRate,
+					DistanceMeters: point.DistanceMeters,
+				})
+			}
+		}
+	}
+
+	return track, nil
+}
+
+// parseTime reads the ISO 8601 timestamps of both formats.
+func parseTime(value string) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(value))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,193 @@
+package track
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testGPX = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1"
+  xmlns:gpxtpx="http://www.garmin.com/xmlschemas/TrackPointExtension/v1">
+  <metadata><name>Morning Run</name></metadata>
+  <trk>
+    <trkseg>
+      <trkpt lat="45.0010" lon="7.0"><ele>101</ele><time>2026-03-01T07:00:30Z</time>
+        <extensions><gpxtpx:TrackPointExtension><gpxtpx:hr>150</gpxtpx:hr></gpxtpx:TrackPointExtension></extensions></trkpt>
+      <trkpt lat="45.0000" lon="7.0"><ele>100</ele><time>2026-03-01T07:00:00Z</time>
+        <extensions><gpxtpx:TrackPointExtension><gpxtpx:hr>120</gpxtpx:hr></gpxtpx:TrackPointExtension></extensions></trkpt>
+      <trkpt lat="45.0020" lon="7.0"><ele>105</ele><time>2026-03-01T07:01:00Z</time>
+        <extensions><gpxtpx:TrackPointExtension><gpxtpx:hr>170</gpxtpx:hr></gpxtpx:TrackPointExtension></extensions></trkpt>
+      <trkpt lat="45.0020" lon="7.0"><ele>104</ele><time>2026-03-01T07:01:30Z</time>
+        <extensions><gpxtpx:TrackPointExtension><gpxtpx:hr>175</gpxtpx:hr></gpxtpx:TrackPointExtension></extensions></trkpt>
+      <trkpt lat="45.0030" lon="7.0"><ele>104</ele></trkpt>
+    </trkseg>
+  </trk>
+</gpx>`
+
+const testTCX = `<?xml version="1.0" encoding="UTF-8"?>
+<TrainingCenterDatabase xmlns="http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2">
+  <Activities>
+    <Activity Sport="Running">
+      <Lap StartTime="2026-03-01T07:00:00Z">
+        <Track>
+          <Trackpoint>
+            <Time>2026-03-01T07:00:00Z</Time>
+            <Position><LatitudeDegrees>45.0</LatitudeDegrees><LongitudeDegrees>7.0</LongitudeDegrees></Position>
+            <AltitudeMeters>100</AltitudeMeters>
+            <HeartRateBpm><Value>130</Value></HeartRateBpm>
+          </Trackpoint>
+          <Trackpoint>
+            <Time>2026-03-01T07:00:10Z</Time>
+            <HeartRateBpm><Value>140</Value></HeartRateBpm>
+          </Trackpoint>
+          <Trackpoint>
+            <Time>2026-03-01T07:00:20Z</Time>
+            <Position><LatitudeDegrees>45.001</LatitudeDegrees><LongitudeDegrees>7.0</LongitudeDegrees></Position>
+          </Trackpoint>
+        </Track>
+      </Lap>
+    </Activity>
+  </Activities>
+</TrainingCenterDatabase>`
+
+// testTreadmillTCX is an indoor run: no positions, only the distance of the foot pod.
+const testTreadmillTCX = `<?xml version="1.0" encoding="UTF-8"?>
+<TrainingCenterDatabase xmlns="http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2">
+  <Activities>
+    <Activity Sport="Running">
+      <Lap StartTime="2026-03-01T07:00:00Z">
+        <DistanceMeters>1000</DistanceMeters>
+        <Track>
+          <Trackpoint><Time>2026-03-01T07:00:00Z</Time><DistanceMeters>0</DistanceMeters></Trackpoint>
+          <Trackpoint><Time>2026-03-01T07:00:30Z</Time><DistanceMeters>100</DistanceMeters></Trackpoint>
+          <Trackpoint><Time>2026-03-01T07:01:00Z</Time><HeartRateBpm><Value>150</Value></HeartRateBpm></Trackpoint>
+          <Trackpoint><Time>2026-03-01T07:01:30Z</Time><DistanceMeters>300</DistanceMeters></Trackpoint>
+          <Trackpoint><Time>2026-03-01T07:02:00Z</Time><DistanceMeters>300</DistanceMeters></Trackpoint>
+        </Track>
+      </Lap>
+    </Activity>
+  </Activities>
+</TrainingCenterDatabase>`
+
+func TestParseGPX(t *testing.T) {
+	track, err := Parse([]byte(testGPX))
+	require.NoError(t, err)
+	assert.Equal(t, FormatGPX, track.Format)
+	assert.Equal(t, "Morning Run", track.Name)
+
+	require.Len(t, track.Points, 4, "the point without a time is dropped")
+	first := track.Points[0]
+	assert.Equal(t, time.Date(2026, 3, 1, 7, 0, 0, 0, time.UTC), first.Time, "points are sorted by time")
+	assert.Equal(t, 45.0, *first.Lat)
+	assert.Equal(t, 100.0, *first.Elevation)
+	assert.Equal(t, 120, *first.HeartRate)
+}
+
+func TestParseTCX(t *testing.T) {
+	track, err := Parse([]byte(testTCX))
+	require.NoError(t, err)
+	assert.Equal(t, FormatTCX, track.Format)
+	assert.Equal(t, "Running", track.Name)
+
+	require.Len(t, track.Points, 3)
+	assert.Nil(t, track.Points[1].Lat)
+	assert.Equal(t, 140, *track.Points[1].HeartRate)
+	assert.Nil(t, track.Points[2].HeartRate)
+	assert.Nil(t, track.Points[2].Elevation)
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"not xml", "date,exercise\n"},
+		{"empty", ""},
+		{"other root", `<kml></kml>`},
+		{"single point", `<gpx><trk><trkseg><trkpt lat="1" lon="1"><time>2026-03-01T07:00:00Z</time></trkpt></trkseg></trk></gpx>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCompute(t *testing.T) {
+	track, err := Parse([]byte(testGPX))
+	require.NoError(t, err)
+
+	maxHeartRate := 200
+	metrics := Compute(track.Points, &maxHeartRate)
+	assert.InDelta(t, 222.4, metrics.DistanceMeters, 0.1)
+	assert.Equal(t, 90, metrics.ElapsedSeconds)
+	assert.Equal(t, 60, metrics.MovingSeconds, "the last 30 seconds are spent standing")
+	assert.InDelta(t, 269.8, *metrics.PaceSecondsPerKm, 0.1)
+	assert.InDelta(t, 13.34, *metrics.AverageSpeedKPH, 0.01)
+	assert.Equal(t, 5.0, metrics.ElevationGainMeters)
+	assert.Equal(t, 0.0, metrics.ElevationLossMeters, "a 1m drop is noise")
+	assert.Equal(t, 154, *metrics.AverageHeartRate)
+	assert.Equal(t, 175, *metrics.MaxHeartRate)
+	assert.Equal(t, []int{0, 30, 30, 30, 0}, metrics.HeartRateZones)
+
+	withoutMax := Compute(track.Points, nil)
+	assert.Nil(t, withoutMax.HeartRateZones)
+}
+
+func TestComputeRecordedDistance(t *testing.T) {
+	track, err := Parse([]byte(testTreadmillTCX))
+	require.NoError(t, err)
+	require.Len(t, track.Points, 5)
+	assert.Nil(t, track.Points[1].Lat)
+	assert.Equal(t, 100.0, *track.Points[1].DistanceMeters)
+	assert.Nil(t, track.Points[2].DistanceMeters)
+
+	metrics := Compute(track.Points, nil)
+	assert.Equal(t, 300.0, metrics.DistanceMeters, "the distance is cumulative, the point without one loses nothing")
+	assert.Equal(t, 120, metrics.ElapsedSeconds)
+	assert.Equal(t, 90, metrics.MovingSeconds, "the last 30 seconds the belt stood still")
+	assert.Equal(t, 300.0, *metrics.PaceSecondsPerKm)
+}
+
+func TestComputeWithoutPosition(t *testing.T) {
+	start := time.Date(2026, 3, 1, 7, 0, 0, 0, time.UTC)
+	points := []Point{
+		{Time: start},
+		{Time: start.Add(30 * time.Second)},
+		{Time: start.Add(10 * time.Minute)},
+		{Time: start.Add(10*time.Minute + 30*time.Second)},
+	}
+
+	metrics := Compute(points, nil)
+	assert.Equal(t, 0.0, metrics.DistanceMeters)
+	assert.Equal(t, 630, metrics.ElapsedSeconds)
+	assert.Equal(t, 60, metrics.MovingSeconds, "the 9.5 minute gap is a pause")
+	assert.Nil(t, metrics.PaceSecondsPerKm)
+	assert.Nil(t, metrics.AverageHeartRate)
+}
+
+func TestCombine(t *testing.T) {
+	assert.Nil(t, Combine(nil))
+
+	avgA, maxA := 140, 160
+	avgB, maxB := 160, 180
+	combined := Combine([]Metrics{
+		{DistanceMeters: 3000, ElapsedSeconds: 1000, MovingSeconds: 900, ElevationGainMeters: 10,
+			AverageHeartRate: &avgA, MaxHeartRate: &maxA, HeartRateZones: []int{0, 100, 800, 100, 0}},
+		{DistanceMeters: 2000, ElapsedSeconds: 500, MovingSeconds: 600, ElevationGainMeters: 5,
+			AverageHeartRate: &avgB, MaxHeartRate: &maxB},
+	})
+
+	assert.Equal(t, 5000.0, combined.DistanceMeters)
+	assert.Equal(t, 1500, combined.MovingSeconds)
+	assert.Equal(t, 300.0, *combined.PaceSecondsPerKm)
+	assert.Equal(t, 15.0, combined.ElevationGainMeters)
+	assert.Equal(t, 147, *combined.AverageHeartRate)
+	assert.Equal(t, 180, *combined.MaxHeartRate)
+	assert.Equal(t, []int{0, 100, 800, 100, 0}, combined.HeartRateZones)
+}
@@ -0,0 +1,152 @@
+package trend
+
+import (
+	"math"
+	"time"
+)
+
+// DefaultWindow is the number of days averaged when the caller doesn't pick a window.
+// A week smooths out the day to day swings of bodyweight (water, food, training).
+const DefaultWindow = 7
+
+// Sample is one measured value.
+type Sample struct {
+	Time  time.Time
+	Value float64
+}
+
+// Point is one day of the trend: the mean of the values measured that day, and the
+// moving average of the daily values over the window ending that day.
+type Point struct {
+	Date    string  `json:"date"`
+	Value   float64 `json:"value"`
+	Average float64 `json:"average"`
+}
+
+// MovingAverage groups samples into days in loc and returns one point per day with a
+// measurement, oldest first. The average covers the days with a measurement within the
+// last window calendar days, so a gap in measuring doesn't stretch the window.
+// Samples must be in time order.
+func MovingAverage(samples []Sample, window int, loc *time.Location) []Point {
+	if window < 1 {
+		window = DefaultWindow
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	type day struct {
+		date  time.Time
+		sum   float64
+		count int
+	}
+	var days []day
+	for _, sample := range samples {
+		local := sample.Time.In(loc)
+		date := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+		if last := len(days) - 1; last >= 0 && days[last].date.Equal(date) {
+			days[last].sum += sample.Value
+			days[last].count++
+			continue
+		}
+		days = append(days, day{date: date, sum: sample.Value, count: 1})
+	}
+
+	points := make([]Point, len(days))
+	start := 0
+	var windowSum float64
+	for i, d := range days {
+		mean := d.sum / float64(d.count)
+		windowSum += mean
+
+		// Drop the days that fell out of the window; dates are UTC midnights, so days are exact
+		for d.date.Sub(days[start].date) >= time.Duration(window)*24*time.Hour {
+			windowSum -= days[start].sum / float64(days[start].count)
+			start++
+		}
+
+		points[i] = Point{
+			Date:    d.date.Format(time.DateOnly),
+			Value:   round(mean),
+			Average: round(windowSum / float64(i-start+1)),
+		}
+	}
+
+	return points
+}
+
+// Change is the difference between the last and the first moving average, 0 for fewer than two points.
+func Change(points []Point) float64 {
+	if len(points) < 2 {
+		return 0
+	}
+	return round(points[len(points)-1].Average - points[0].Average)
+}
+
+// round keeps two decimals, the precision measurements are stored with.
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// Line is a least squares fit of sample values over time.
+type Line struct {
+	origin    time.Time
+	slope     float64 // value per second
+	intercept float64 // value at origin
+}
+
+// Fit fits a straight line through the samples. ok is false when there are fewer
+// than two samples or they were all taken at the same time.
+func Fit(samples []Sample) (line Line, ok bool) {
+	if len(samples) < 2 {
+		return Line{}, false
+	}
+
+	// Seconds are counted from the first sample to keep the sums small
+	origin := samples[0].Time
+	var sumX, sumY, sumXX, sumXY float64
+	for _, sample := range samples {
+		x := sample.Time.Sub(origin).Seconds()
+		sumX += x
+		sumY += sample.Value
+		sumXX += x * x
+		sumXY += x * sample.Value
+	}
+
+	n := float64(len(samples))
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return Line{}, false
+	}
+
+	slope := (n*sumXY - sumX*sumY) / denominator
+	return Line{
+		origin:    origin,
+		slope:     slope,
+		intercept: (sumY - slope*sumX) / n,
+	}, true
+}
+
+// At returns the value of the line at t.
+func (l Line) At(t time.Time) float64 {
+	return l.intercept + l.slope*t.Sub(l.origin).Seconds()
+}
+
+// Reaches returns when the line reaches value, looking forward from after.
+// ok is false when the line is flat or heading away from the value.
+func (l Line) Reaches(value float64, after time.Time) (at time.Time, ok bool) {
+	needed := value - l.At(after)
+	if needed == 0 {
+		return after, true
+	}
+	if needed*l.slope <= 0 {
+		return time.Time{}, false
+	}
+
+	seconds := needed / l.slope
+	// Far beyond anything a goal could care about, and beyond what a Duration holds
+	if seconds > 100*365*24*60*60 {
+		return time.Time{}, false
+	}
+	return after.Add(time.Duration(seconds * float64(time.Second))), true
+}
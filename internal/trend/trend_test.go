@@ -0,0 +1,109 @@
+package trend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMovingAverage(t *testing.T) {
+	day := func(d, hour int) time.Time {
+		return time.Date(2026, 3, d, hour, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name    string
+		samples []Sample
+		window  int
+		loc     *time.Location
+		want    []Point
+	}{
+		{
+			name:    "empty",
+			samples: nil,
+			window:  7,
+			want:    []Point{},
+		},
+		{
+			name: "averages the days within the window",
+			samples: []Sample{
+				{day(1, 7), 80}, {day(2, 7), 81}, {day(3, 7), 79}, {day(5, 7), 82},
+			},
+			window: 3,
+			want: []Point{
+				{Date: "2026-03-01", Value: 80, Average: 80},
+				{Date: "2026-03-02", Value: 81, Average: 80.5},
+				{Date: "2026-03-03", Value: 79, Average: 80},
+				{Date: "2026-03-05", Value: 82, Average: 80.5},
+			},
+		},
+		{
+			name:    "several samples on one day count as their mean",
+			samples: []Sample{{day(1, 7), 80}, {day(1, 20), 81}, {day(2, 7), 79}},
+			window:  7,
+			want: []Point{
+				{Date: "2026-03-01", Value: 80.5, Average: 80.5},
+				{Date: "2026-03-02", Value: 79, Average: 79.75},
+			},
+		},
+		{
+			name:    "days are taken in the time zone",
+			samples: []Sample{{day(1, 23), 80}, {day(2, 1), 82}},
+			window:  1,
+			loc:     time.FixedZone("UTC+2", 2*60*60),
+			want: []Point{
+				{Date: "2026-03-02", Value: 81, Average: 81},
+			},
+		},
+		{
+			name:    "a window below 1 uses the default",
+			samples: []Sample{{day(1, 7), 80}, {day(7, 7), 82}, {day(8, 7), 84}},
+			window:  0,
+			want: []Point{
+				{Date: "2026-03-01", Value: 80, Average: 80},
+				{Date: "2026-03-07", Value: 82, Average: 81},
+				{Date: "2026-03-08", Value: 84, Average: 83},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, MovingAverage(tt.samples, tt.window, tt.loc))
+		})
+	}
+}
+
+func TestChange(t *testing.T) {
+	assert.Equal(t, 0.0, Change(nil))
+	assert.Equal(t, 0.0, Change([]Point{{Average: 80}}))
+	assert.Equal(t, -1.25, Change([]Point{{Average: 80}, {Average: 79}, {Average: 78.75}}))
+}
+
+func TestFit(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time { return start.AddDate(0, 0, d) }
+
+	_, ok := Fit([]Sample{{day(0), 80}})
+	assert.False(t, ok)
+	_, ok = Fit([]Sample{{day(0), 80}, {day(0), 81}})
+	assert.False(t, ok)
+
+	// Losing 0.5 kg per week
+	line, ok := Fit([]Sample{{day(0), 90}, {day(7), 89.5}, {day(14), 89}})
+	assert.True(t, ok)
+	assert.InDelta(t, 89.75, line.At(day(3).Add(12*time.Hour)), 0.001)
+
+	at, ok := line.Reaches(88, day(14))
+	assert.True(t, ok)
+	assert.Equal(t, day(28), at)
+
+	_, ok = line.Reaches(95, day(14))
+	assert.False(t, ok, "the line heads away from the value")
+
+	flat, ok := Fit([]Sample{{day(0), 100}, {day(7), 100}})
+	assert.True(t, ok)
+	_, ok = flat.Reaches(110, day(7))
+	assert.False(t, ok)
+}
@@ -3,14 +3,26 @@ package utils
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
+	"unicode/utf8"
 
 	"github.com/go-chi/chi/v5"
 )
 
 type Envelope map[string]interface{}
 
+// Truncate shortens s to at most n characters. VARCHAR columns count characters, not bytes,
+// and cutting a multi-byte character in half leaves invalid UTF-8 that Postgres rejects.
+func Truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
 // WriteJSON writes the given data as a JSON response with the specified status code.
 func WriteJSON(w http.ResponseWriter, status int, data interface{}) error {
 	js, err := json.MarshalIndent(data, "", " ")
@@ -39,3 +51,38 @@ func ReadIdParam(r *http.Request) (int64, error) {
 
 	return id, nil
 }
+
+// ReadIntQuery reads an integer query parameter.
+// Returns defaultValue if the parameter is missing, or an error if it isn't a number.
+func ReadIntQuery(r *http.Request, key string, defaultValue int) (int, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer", key)
+	}
+
+	return i, nil
+}
+
+// ReadTimeQuery reads a date query parameter.
+// Both full RFC3339 timestamps and plain dates (2006-01-02, interpreted as UTC midnight) are accepted.
+// Returns (nil, nil) if the parameter is missing.
+func ReadTimeQuery(r *http.Request, key string) (*time.Time, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return &t, nil
+	}
+
+	return nil, fmt.Errorf("%s must be a date (YYYY-MM-DD) or RFC3339 timestamp", key)
+}